@@ -53,7 +53,7 @@ func InitCmd() error {
 	rootCmd.AddCommand(removeUserFromGroupCmd)
 
 	// Set createUser flags
-	createUserCmd.Flags().StringVarP(&scopes, "scopes", "s", "", "Which scopes to grant [rcudiom]")
+	createUserCmd.Flags().StringVarP(&scopes, "scopes", "s", "", "Which scopes to grant [rcudiome]")
 
 	// Set removeUser flags
 	removeUserCmd.Flags().StringVarP(&target, "target", "t", "", "Target UID of the user to be removed")
@@ -62,7 +62,7 @@ func InitCmd() error {
 	}
 
 	// Set createGroup flags
-	createGroupCmd.Flags().StringVarP(&scopes, "scopes", "s", "", "Which scopes to grant [rcudiom]")
+	createGroupCmd.Flags().StringVarP(&scopes, "scopes", "s", "", "Which scopes to grant [rcudiome]")
 
 	// Set addUserToGroup flags
 	addUserToGroupCmd.Flags().StringVarP(&target, "target", "t", "", "UID of the user to be added to a group")
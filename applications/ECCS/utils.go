@@ -53,6 +53,8 @@ func ReadScopes(scopesString string) ([]encryptonize.Scope, error) {
 			scopes = append(scopes, encryptonize.ScopeObjectPermissions)
 		case "m":
 			scopes = append(scopes, encryptonize.ScopeUserManagement)
+		case "e":
+			scopes = append(scopes, encryptonize.ScopeDeterministic)
 		default:
 			return nil, fmt.Errorf("Invalid scope %v", string(scope))
 		}
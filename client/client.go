@@ -142,6 +142,8 @@ func (c *Client) parseScopes(scopes []Scope) ([]string, error) {
 			scopeStrings = append(scopeStrings, "OBJECTPERMISSIONS")
 		case ScopeUserManagement:
 			scopeStrings = append(scopeStrings, "USERMANAGEMENT")
+		case ScopeDeterministic:
+			scopeStrings = append(scopeStrings, "DETERMINISTIC")
 		default:
 			return nil, errors.New("invalid scope")
 		}
@@ -299,6 +301,390 @@ func (c *Client) Decrypt(objectID string, ciphertext, associatedData []byte) (*D
 	return response, nil
 }
 
+// GenerateDataKey generates a 256-bit data key for encrypting data locally. The key is returned both
+// in plaintext and in wrapped form, and the wrapped form can later be unwrapped with
+// `DecryptDataKey`.
+func (c *Client) GenerateDataKey() (*GenerateDataKeyResponse, error) {
+	response := &GenerateDataKeyResponse{}
+	if err := c.invoke("enc.Encryptonize.GenerateDataKey", "", response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// DecryptDataKey unwraps a data key previously generated with `GenerateDataKey`.
+func (c *Client) DecryptDataKey(objectID string, wrappedDataKey []byte) (*DecryptDataKeyResponse, error) {
+	requestJSON, err := json.Marshal(request{ObjectID: objectID, WrappedDataKey: wrappedDataKey})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DecryptDataKeyResponse{}
+	if err := c.invoke("enc.Encryptonize.DecryptDataKey", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// CreateIngestKey creates a new key pair for encrypting data with `IngestEncrypt` and returns the
+// Object ID and public key of the key pair.
+func (c *Client) CreateIngestKey() (*CreateIngestKeyResponse, error) {
+	response := &CreateIngestKeyResponse{}
+	if err := c.invoke("enc.Encryptonize.CreateIngestKey", "", response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// GetIngestPublicKey returns the public key of the ingest key with the given Object ID.
+func (c *Client) GetIngestPublicKey(oid string) (*GetIngestPublicKeyResponse, error) {
+	requestJSON, err := json.Marshal(request{ObjectID: oid})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetIngestPublicKeyResponse{}
+	if err := c.invoke("enc.Encryptonize.GetIngestPublicKey", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Ingest converts a `ciphertext` produced by `IngestEncrypt` for the ingest key with the given Object
+// ID into a new object. The response is the same as that of `Encrypt`.
+func (c *Client) Ingest(oid string, ciphertext, associatedData []byte) (*EncryptResponse, error) {
+	requestJSON, err := json.Marshal(request{ObjectID: oid, Ciphertext: ciphertext, AssociatedData: associatedData})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &EncryptResponse{}
+	if err := c.invoke("enc.Encryptonize.Ingest", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// ImportKey imports a `wrappedKey` produced by `WrapImportKey` for the ingest key with the given
+// Object ID. Returns the Object ID of a new object, which is protected by the imported key.
+func (c *Client) ImportKey(oid string, wrappedKey []byte) (*ImportKeyResponse, error) {
+	requestJSON, err := json.Marshal(request{ObjectID: oid, WrappedKey: wrappedKey})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ImportKeyResponse{}
+	if err := c.invoke("enc.Encryptonize.ImportKey", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// EncryptWithKey encrypts the `plaintext` and tags both `plaintext` and `associatedData` under the
+// imported key with the given Object ID. The ciphertext can be decrypted with `Decrypt`. Fails once
+// the key has reached its encryption limit.
+func (c *Client) EncryptWithKey(oid string, plaintext, associatedData []byte) (*EncryptResponse, error) {
+	requestJSON, err := json.Marshal(request{ObjectID: oid, Plaintext: plaintext, AssociatedData: associatedData})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &EncryptResponse{}
+	if err := c.invoke("enc.Encryptonize.EncryptWithKey", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// EncryptUnderKey encrypts the `plaintext` and tags both `plaintext` and `associatedData` under the
+// imported key with the given Object ID, and returns the ciphertext with the Object ID of a new
+// object. The ciphertext can be decrypted with `Decrypt`. Fails once the key has reached its
+// encryption limit.
+func (c *Client) EncryptUnderKey(keyID string, plaintext, associatedData []byte) (*EncryptResponse, error) {
+	requestJSON, err := json.Marshal(request{KeyID: keyID, Plaintext: plaintext, AssociatedData: associatedData})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &EncryptResponse{}
+	if err := c.invoke("enc.Encryptonize.Encrypt", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// CreateDeterministicKey returns the Object ID of the deterministic encryption key of the user's own
+// group, creating the key if needed. Requires the DETERMINISTIC scope.
+func (c *Client) CreateDeterministicKey() (*CreateDeterministicKeyResponse, error) {
+	response := &CreateDeterministicKeyResponse{}
+	if err := c.invoke("enc.Encryptonize.CreateDeterministicKey", "", response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// CreateGroupDeterministicKey returns the Object ID of the deterministic encryption key of the group
+// with the given ID, creating the key if needed. All members of the group get the same key, so equal
+// values encrypted by them give equal ciphertexts. Requires the DETERMINISTIC scope and membership of
+// the group.
+func (c *Client) CreateGroupDeterministicKey(groupID string) (*CreateDeterministicKeyResponse, error) {
+	requestJSON, err := json.Marshal(request{GroupID: groupID})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &CreateDeterministicKeyResponse{}
+	if err := c.invoke("enc.Encryptonize.CreateDeterministicKey", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// EncryptDeterministic encrypts the `plaintext` and tags both `plaintext` and `associatedData` under
+// the deterministic key with the given Object ID. Equal plaintexts and associated data encrypt to
+// equal ciphertexts, so ciphertexts can be compared for equality. Requires the DETERMINISTIC scope.
+func (c *Client) EncryptDeterministic(oid string, plaintext, associatedData []byte) (*EncryptResponse, error) {
+	requestJSON, err := json.Marshal(request{ObjectID: oid, Plaintext: plaintext, AssociatedData: associatedData})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &EncryptResponse{}
+	if err := c.invoke("enc.Encryptonize.EncryptDeterministic", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// DecryptDeterministic decrypts a `ciphertext` produced by `EncryptDeterministic` and verifies the
+// integrity of the `ciphertext` and `associatedData`.
+func (c *Client) DecryptDeterministic(oid string, ciphertext, associatedData []byte) (*DecryptResponse, error) {
+	requestJSON, err := json.Marshal(request{ObjectID: oid, Ciphertext: ciphertext, AssociatedData: associatedData})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DecryptResponse{}
+	if err := c.invoke("enc.Encryptonize.DecryptDeterministic", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+/////////////////////////////////////////////////////////////////////////
+//                        Message Authentication                       //
+/////////////////////////////////////////////////////////////////////////
+
+// CreateMACKey creates a new key for authenticating messages and returns the Object ID of the key.
+// Access to the key is managed through the permissions of the Object ID.
+func (c *Client) CreateMACKey() (*CreateMACKeyResponse, error) {
+	response := &CreateMACKeyResponse{}
+	if err := c.invoke("mac.Encryptonize.CreateKey", "", response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Tag computes the authentication tag of `message` under the key with the given Object ID.
+func (c *Client) Tag(oid string, message []byte) (*TagResponse, error) {
+	requestJSON, err := json.Marshal(request{ObjectID: oid, Message: message})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &TagResponse{}
+	if err := c.invoke("mac.Encryptonize.Tag", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Verify checks whether `tag` is a valid authentication tag of `message` under the key with the given
+// Object ID.
+func (c *Client) Verify(oid string, message, tag []byte) (*VerifyResponse, error) {
+	requestJSON, err := json.Marshal(request{ObjectID: oid, Message: message, Tag: tag})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &VerifyResponse{}
+	if err := c.invoke("mac.Encryptonize.Verify", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+/////////////////////////////////////////////////////////////////////////
+//                               Signing                               //
+/////////////////////////////////////////////////////////////////////////
+
+// CreateSigningKey creates a new Ed25519 signing key and returns the Object ID of the key. Access to
+// the key is managed through the permissions of the Object ID.
+func (c *Client) CreateSigningKey() (*CreateSigningKeyResponse, error) {
+	response := &CreateSigningKeyResponse{}
+	if err := c.invoke("sign.Encryptonize.CreateSigningKey", "", response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Sign signs `message` with the signing key with the given Object ID.
+func (c *Client) Sign(oid string, message []byte) (*SignResponse, error) {
+	requestJSON, err := json.Marshal(request{ObjectID: oid, Message: message})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &SignResponse{}
+	if err := c.invoke("sign.Encryptonize.Sign", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// VerifySignature checks whether `signature` is a valid signature of `message` under the signing key
+// with the given Object ID.
+func (c *Client) VerifySignature(oid string, message, signature []byte) (*VerifyResponse, error) {
+	requestJSON, err := json.Marshal(request{ObjectID: oid, Message: message, Signature: signature})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &VerifyResponse{}
+	if err := c.invoke("sign.Encryptonize.Verify", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// GetPublicKey exports the public key of the signing key with the given Object ID in the requested
+// format.
+func (c *Client) GetPublicKey(oid string, format PublicKeyFormat) (*GetPublicKeyResponse, error) {
+	requestJSON, err := json.Marshal(request{ObjectID: oid, Format: format})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetPublicKeyResponse{}
+	if err := c.invoke("sign.Encryptonize.GetPublicKey", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+/////////////////////////////////////////////////////////////////////////
+//                     Format-Preserving Encryption                    //
+/////////////////////////////////////////////////////////////////////////
+
+// CreateFPEKey creates a new format-preserving encryption key and returns the Object ID of the key.
+// Access to the key is managed through the permissions of the Object ID.
+func (c *Client) CreateFPEKey() (*CreateFPEKeyResponse, error) {
+	response := &CreateFPEKeyResponse{}
+	if err := c.invoke("fpe.Encryptonize.CreateKey", "", response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// TokenizeFPE encrypts `plaintext` into a token of the same length over `alphabet` under the key
+// with the given Object ID. An empty alphabet selects the decimal digits.
+func (c *Client) TokenizeFPE(oid, plaintext, alphabet string, tweak []byte) (*TokenizeFPEResponse, error) {
+	requestJSON, err := json.Marshal(fpeRequest{ObjectID: oid, Plaintext: plaintext, Alphabet: alphabet, Tweak: tweak})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &TokenizeFPEResponse{}
+	if err := c.invoke("fpe.Encryptonize.Tokenize", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// DetokenizeFPE decrypts a token created by `TokenizeFPE` under the key with the given Object ID. The
+// alphabet and tweak must match the ones used for tokenization.
+func (c *Client) DetokenizeFPE(oid, token, alphabet string, tweak []byte) (*DetokenizeFPEResponse, error) {
+	requestJSON, err := json.Marshal(fpeRequest{ObjectID: oid, Token: token, Alphabet: alphabet, Tweak: tweak})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DetokenizeFPEResponse{}
+	if err := c.invoke("fpe.Encryptonize.Detokenize", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+/////////////////////////////////////////////////////////////////////////
+//                             Tokenization                            //
+/////////////////////////////////////////////////////////////////////////
+
+// CreateNamespace creates a new tokenization namespace and returns its Object ID. Access to the
+// tokens of the namespace is managed through the permissions of the Object ID.
+func (c *Client) CreateNamespace() (*CreateNamespaceResponse, error) {
+	response := &CreateNamespaceResponse{}
+	if err := c.invoke("vault.Encryptonize.CreateNamespace", "", response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Tokenize stores `plaintext` in the namespace with the given Object ID and returns a random token of
+// `length` characters from `alphabet`. An empty alphabet or a zero length selects the default. If
+// `unique` is set, the same plaintext is given the same token within the namespace.
+func (c *Client) Tokenize(oid string, plaintext []byte, alphabet string, length uint32, unique bool) (*TokenizeResponse, error) {
+	requestJSON, err := json.Marshal(request{ObjectID: oid, Plaintext: plaintext, Alphabet: alphabet, Length: length, Unique: unique})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &TokenizeResponse{}
+	if err := c.invoke("vault.Encryptonize.Tokenize", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Detokenize resolves a token of the namespace with the given Object ID into the original plaintext.
+func (c *Client) Detokenize(oid, token string) (*DetokenizeResponse, error) {
+	requestJSON, err := json.Marshal(request{ObjectID: oid, Token: token})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DetokenizeResponse{}
+	if err := c.invoke("vault.Encryptonize.Detokenize", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
 /////////////////////////////////////////////////////////////////////////
 //                               Storage                               //
 /////////////////////////////////////////////////////////////////////////
@@ -319,6 +705,23 @@ func (c *Client) Store(plaintext, associatedData []byte) (*StoreResponse, error)
 	return response, nil
 }
 
+// StoreUnderKey encrypts the `plaintext` and tags both `plaintext` and `associatedData` under the
+// imported key with the given Object ID, and stores the result in a new object. Fails once the key
+// has reached its encryption limit.
+func (c *Client) StoreUnderKey(keyID string, plaintext, associatedData []byte) (*StoreResponse, error) {
+	requestJSON, err := json.Marshal(request{KeyID: keyID, Plaintext: plaintext, AssociatedData: associatedData})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &StoreResponse{}
+	if err := c.invoke("storage.Encryptonize.Store", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
 // Retrieve decrypts a previously stored object returning the ciphertext.
 func (c *Client) Retrieve(oid string) (*RetrieveResponse, error) {
 	requestJSON, err := json.Marshal(request{ObjectID: oid})
@@ -355,6 +758,16 @@ func (c *Client) Delete(oid string) error {
 	return c.invoke("storage.Encryptonize.Delete", string(requestJSON), &struct{}{})
 }
 
+// Rekey re-encrypts previously stored data under a new object key.
+func (c *Client) Rekey(oid string) error {
+	requestJSON, err := json.Marshal(request{ObjectID: oid})
+	if err != nil {
+		return err
+	}
+
+	return c.invoke("storage.Encryptonize.Rekey", string(requestJSON), &struct{}{})
+}
+
 /////////////////////////////////////////////////////////////////////////
 //                             Permissions                             //
 /////////////////////////////////////////////////////////////////////////
@@ -393,3 +806,45 @@ func (c *Client) RemovePermission(oid, target string) error {
 
 	return c.invoke("authz.Encryptonize.RemovePermission", string(requestJSON), &struct{}{})
 }
+
+// RemovePermissionAndRekey removes permissions for the `target` to the requested object and
+// re-encrypts the object under a new key. Only objects stored with `Store` can be re-keyed.
+func (c *Client) RemovePermissionAndRekey(oid, target string) error {
+	requestJSON, err := json.Marshal(request{ObjectID: oid, Target: target, Rekey: true})
+	if err != nil {
+		return err
+	}
+
+	return c.invoke("authz.Encryptonize.RemovePermission", string(requestJSON), &struct{}{})
+}
+
+// Shred destroys the key of the requested object, such that the object can no longer be decrypted,
+// and returns a receipt of the erasure.
+func (c *Client) Shred(oid string) (*ShredResponse, error) {
+	requestJSON, err := json.Marshal(request{ObjectID: oid})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ShredResponse{}
+	if err := c.invoke("authz.Encryptonize.Shred", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// GetErasureReceipt returns the signed erasure receipt of a shredded object.
+func (c *Client) GetErasureReceipt(oid string) (*GetErasureReceiptResponse, error) {
+	requestJSON, err := json.Marshal(request{ObjectID: oid})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetErasureReceiptResponse{}
+	if err := c.invoke("authz.Encryptonize.GetErasureReceipt", string(requestJSON), response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
@@ -194,6 +194,433 @@ func (c *ClientWR) Decrypt(objectID string, ciphertext, associatedData []byte) (
 	return response, nil
 }
 
+// GenerateDataKey generates a 256-bit data key for encrypting data locally. The key is returned both
+// in plaintext and in wrapped form, and the wrapped form can later be unwrapped with
+// `DecryptDataKey`.
+func (c *ClientWR) GenerateDataKey() (*GenerateDataKeyResponse, error) {
+	var response *GenerateDataKeyResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.GenerateDataKey()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// DecryptDataKey unwraps a data key previously generated with `GenerateDataKey`.
+func (c *ClientWR) DecryptDataKey(objectID string, wrappedDataKey []byte) (*DecryptDataKeyResponse, error) {
+	var response *DecryptDataKeyResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.DecryptDataKey(objectID, wrappedDataKey)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// CreateIngestKey creates a new key pair for encrypting data with `IngestEncrypt` and returns the
+// Object ID and public key of the key pair.
+func (c *ClientWR) CreateIngestKey() (*CreateIngestKeyResponse, error) {
+	var response *CreateIngestKeyResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.CreateIngestKey()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// GetIngestPublicKey returns the public key of the ingest key with the given Object ID.
+func (c *ClientWR) GetIngestPublicKey(oid string) (*GetIngestPublicKeyResponse, error) {
+	var response *GetIngestPublicKeyResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.GetIngestPublicKey(oid)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Ingest converts a `ciphertext` produced by `IngestEncrypt` for the ingest key with the given Object
+// ID into a new object. The response is the same as that of `Encrypt`.
+func (c *ClientWR) Ingest(oid string, ciphertext, associatedData []byte) (*EncryptResponse, error) {
+	var response *EncryptResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.Ingest(oid, ciphertext, associatedData)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// ImportKey imports a `wrappedKey` produced by `WrapImportKey` for the ingest key with the given
+// Object ID. Returns the Object ID of a new object, which is protected by the imported key.
+func (c *ClientWR) ImportKey(oid string, wrappedKey []byte) (*ImportKeyResponse, error) {
+	var response *ImportKeyResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.ImportKey(oid, wrappedKey)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// EncryptWithKey encrypts the `plaintext` and tags both `plaintext` and `associatedData` under the
+// imported key with the given Object ID. The ciphertext can be decrypted with `Decrypt`. Fails once
+// the key has reached its encryption limit.
+func (c *ClientWR) EncryptWithKey(oid string, plaintext, associatedData []byte) (*EncryptResponse, error) {
+	var response *EncryptResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.EncryptWithKey(oid, plaintext, associatedData)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// EncryptUnderKey encrypts the `plaintext` and tags both `plaintext` and `associatedData` under the
+// imported key with the given Object ID, and returns the ciphertext with the Object ID of a new
+// object. The ciphertext can be decrypted with `Decrypt`. Fails once the key has reached its
+// encryption limit.
+func (c *ClientWR) EncryptUnderKey(keyID string, plaintext, associatedData []byte) (*EncryptResponse, error) {
+	var response *EncryptResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.EncryptUnderKey(keyID, plaintext, associatedData)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// CreateDeterministicKey returns the Object ID of the deterministic encryption key of the user's own
+// group, creating the key if needed. Requires the DETERMINISTIC scope.
+func (c *ClientWR) CreateDeterministicKey() (*CreateDeterministicKeyResponse, error) {
+	var response *CreateDeterministicKeyResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.CreateDeterministicKey()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// CreateGroupDeterministicKey returns the Object ID of the deterministic encryption key of the group
+// with the given ID, creating the key if needed. All members of the group get the same key, so equal
+// values encrypted by them give equal ciphertexts. Requires the DETERMINISTIC scope and membership of
+// the group.
+func (c *ClientWR) CreateGroupDeterministicKey(groupID string) (*CreateDeterministicKeyResponse, error) {
+	var response *CreateDeterministicKeyResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.CreateGroupDeterministicKey(groupID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// EncryptDeterministic encrypts the `plaintext` and tags both `plaintext` and `associatedData` under
+// the deterministic key with the given Object ID. Equal plaintexts and associated data encrypt to
+// equal ciphertexts, so ciphertexts can be compared for equality. Requires the DETERMINISTIC scope.
+func (c *ClientWR) EncryptDeterministic(oid string, plaintext, associatedData []byte) (*EncryptResponse, error) {
+	var response *EncryptResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.EncryptDeterministic(oid, plaintext, associatedData)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// DecryptDeterministic decrypts a `ciphertext` produced by `EncryptDeterministic` and verifies the
+// integrity of the `ciphertext` and `associatedData`.
+func (c *ClientWR) DecryptDeterministic(oid string, ciphertext, associatedData []byte) (*DecryptResponse, error) {
+	var response *DecryptResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.DecryptDeterministic(oid, ciphertext, associatedData)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// EncryptLocal encrypts the `plaintext` and tags both `plaintext` and `associatedData` under a new
+// data key without sending the data to the Encryptonize service. The returned ciphertext contains the
+// wrapped data key and the Object ID needed to decrypt it. Access to the ciphertext is managed through
+// the permissions of the Object ID.
+func (c *ClientWR) EncryptLocal(plaintext, associatedData []byte) (*EncryptResponse, error) {
+	var response *EncryptResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.EncryptLocal(plaintext, associatedData)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// DecryptLocal decrypts a `ciphertext` produced by `EncryptLocal` and verifies the integrity of the
+// `ciphertext` and `associatedData`. The data key is unwrapped by the Encryptonize service, which
+// requires the caller to have access to the object, but the data is decrypted locally.
+func (c *ClientWR) DecryptLocal(ciphertext, associatedData []byte) (*DecryptResponse, error) {
+	var response *DecryptResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.DecryptLocal(ciphertext, associatedData)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+/////////////////////////////////////////////////////////////////////////
+//                        Message Authentication                       //
+/////////////////////////////////////////////////////////////////////////
+
+// CreateMACKey creates a new key for authenticating messages and returns the Object ID of the key.
+// Access to the key is managed through the permissions of the Object ID.
+func (c *ClientWR) CreateMACKey() (*CreateMACKeyResponse, error) {
+	var response *CreateMACKeyResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.CreateMACKey()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Tag computes the authentication tag of `message` under the key with the given Object ID.
+func (c *ClientWR) Tag(oid string, message []byte) (*TagResponse, error) {
+	var response *TagResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.Tag(oid, message)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Verify checks whether `tag` is a valid authentication tag of `message` under the key with the given
+// Object ID.
+func (c *ClientWR) Verify(oid string, message, tag []byte) (*VerifyResponse, error) {
+	var response *VerifyResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.Verify(oid, message, tag)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+/////////////////////////////////////////////////////////////////////////
+//                               Signing                               //
+/////////////////////////////////////////////////////////////////////////
+
+// CreateSigningKey creates a new Ed25519 signing key and returns the Object ID of the key. Access to
+// the key is managed through the permissions of the Object ID.
+func (c *ClientWR) CreateSigningKey() (*CreateSigningKeyResponse, error) {
+	var response *CreateSigningKeyResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.CreateSigningKey()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Sign signs `message` with the signing key with the given Object ID.
+func (c *ClientWR) Sign(oid string, message []byte) (*SignResponse, error) {
+	var response *SignResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.Sign(oid, message)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// VerifySignature checks whether `signature` is a valid signature of `message` under the signing key
+// with the given Object ID.
+func (c *ClientWR) VerifySignature(oid string, message, signature []byte) (*VerifyResponse, error) {
+	var response *VerifyResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.VerifySignature(oid, message, signature)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// GetPublicKey exports the public key of the signing key with the given Object ID in the requested
+// format.
+func (c *ClientWR) GetPublicKey(oid string, format PublicKeyFormat) (*GetPublicKeyResponse, error) {
+	var response *GetPublicKeyResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.GetPublicKey(oid, format)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+/////////////////////////////////////////////////////////////////////////
+//                     Format-Preserving Encryption                    //
+/////////////////////////////////////////////////////////////////////////
+
+// CreateFPEKey creates a new format-preserving encryption key and returns the Object ID of the key.
+// Access to the key is managed through the permissions of the Object ID.
+func (c *ClientWR) CreateFPEKey() (*CreateFPEKeyResponse, error) {
+	var response *CreateFPEKeyResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.CreateFPEKey()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// TokenizeFPE encrypts `plaintext` into a token of the same length over `alphabet` under the key
+// with the given Object ID. An empty alphabet selects the decimal digits.
+func (c *ClientWR) TokenizeFPE(oid, plaintext, alphabet string, tweak []byte) (*TokenizeFPEResponse, error) {
+	var response *TokenizeFPEResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.TokenizeFPE(oid, plaintext, alphabet, tweak)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// DetokenizeFPE decrypts a token created by `TokenizeFPE` under the key with the given Object ID. The
+// alphabet and tweak must match the ones used for tokenization.
+func (c *ClientWR) DetokenizeFPE(oid, token, alphabet string, tweak []byte) (*DetokenizeFPEResponse, error) {
+	var response *DetokenizeFPEResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.DetokenizeFPE(oid, token, alphabet, tweak)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+/////////////////////////////////////////////////////////////////////////
+//                             Tokenization                            //
+/////////////////////////////////////////////////////////////////////////
+
+// CreateNamespace creates a new tokenization namespace and returns its Object ID. Access to the
+// tokens of the namespace is managed through the permissions of the Object ID.
+func (c *ClientWR) CreateNamespace() (*CreateNamespaceResponse, error) {
+	var response *CreateNamespaceResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.CreateNamespace()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Tokenize stores `plaintext` in the namespace with the given Object ID and returns a random token of
+// `length` characters from `alphabet`. An empty alphabet or a zero length selects the default. If
+// `unique` is set, the same plaintext is given the same token within the namespace.
+func (c *ClientWR) Tokenize(oid string, plaintext []byte, alphabet string, length uint32, unique bool) (*TokenizeResponse, error) {
+	var response *TokenizeResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.Tokenize(oid, plaintext, alphabet, length, unique)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Detokenize resolves a token of the namespace with the given Object ID into the original plaintext.
+func (c *ClientWR) Detokenize(oid, token string) (*DetokenizeResponse, error) {
+	var response *DetokenizeResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.Detokenize(oid, token)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
 /////////////////////////////////////////////////////////////////////////
 //                               Storage                               //
 /////////////////////////////////////////////////////////////////////////
@@ -213,6 +640,22 @@ func (c *ClientWR) Store(plaintext, associatedData []byte) (*StoreResponse, erro
 	return response, nil
 }
 
+// StoreUnderKey encrypts the `plaintext` and tags both `plaintext` and `associatedData` under the
+// imported key with the given Object ID, and stores the result in a new object. Fails once the key
+// has reached its encryption limit.
+func (c *ClientWR) StoreUnderKey(keyID string, plaintext, associatedData []byte) (*StoreResponse, error) {
+	var response *StoreResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.StoreUnderKey(keyID, plaintext, associatedData)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
 // Retrieve decrypts a previously stored object returning the ciphertext.
 func (c *ClientWR) Retrieve(oid string) (*RetrieveResponse, error) {
 	var response *RetrieveResponse
@@ -242,6 +685,13 @@ func (c *ClientWR) Delete(oid string) error {
 	})
 }
 
+// Rekey re-encrypts previously stored data under a new object key.
+func (c *ClientWR) Rekey(oid string) error {
+	return c.withRefresh(func() error {
+		return c.Client.Rekey(oid)
+	})
+}
+
 /////////////////////////////////////////////////////////////////////////
 //                             Permissions                             //
 /////////////////////////////////////////////////////////////////////////
@@ -273,3 +723,34 @@ func (c *ClientWR) RemovePermission(oid, target string) error {
 		return c.Client.RemovePermission(oid, target)
 	})
 }
+
+// RemovePermissionAndRekey removes permissions for the `target` to the requested object and
+// re-encrypts the object under a new key. Only objects stored with `Store` can be re-keyed.
+func (c *ClientWR) RemovePermissionAndRekey(oid, target string) error {
+	return c.withRefresh(func() error {
+		return c.Client.RemovePermissionAndRekey(oid, target)
+	})
+}
+
+// Shred destroys the key of the requested object, such that the object can no longer be decrypted,
+// and returns a receipt of the erasure.
+func (c *ClientWR) Shred(oid string) (*ShredResponse, error) {
+	var response *ShredResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.Shred(oid)
+		return err
+	})
+	return response, err
+}
+
+// GetErasureReceipt returns the signed erasure receipt of a shredded object.
+func (c *ClientWR) GetErasureReceipt(oid string) (*GetErasureReceiptResponse, error) {
+	var response *GetErasureReceiptResponse
+	err := c.withRefresh(func() error {
+		var err error
+		response, err = c.Client.GetErasureReceipt(oid)
+		return err
+	})
+	return response, err
+}
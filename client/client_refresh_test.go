@@ -103,6 +103,253 @@ func TestEncryptWR(t *testing.T) {
 	}
 }
 
+func TestEncryptLocalWR(t *testing.T) {
+	c, err := NewClientWR(context.Background(), endpoint, certPath, uid, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	plaintext := []byte("foo")
+	associatedData := []byte("bar")
+	encryptResponse, err := c.EncryptLocal(plaintext, associatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decryptResponse, err := c.DecryptLocal(encryptResponse.Ciphertext, encryptResponse.AssociatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decryptResponse.Plaintext) != string(plaintext) {
+		t.Fatal("Decryption returned wrong plaintext")
+	}
+}
+
+func TestIngestWR(t *testing.T) {
+	c, err := NewClientWR(context.Background(), endpoint, certPath, uid, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	createIngestKeyResponse, err := c.CreateIngestKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKeyResponse, err := c.GetIngestPublicKey(createIngestKeyResponse.ObjectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte("foo")
+	associatedData := []byte("bar")
+	ciphertext, err := IngestEncrypt(publicKeyResponse.PublicKey, plaintext, associatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ingestResponse, err := c.Ingest(createIngestKeyResponse.ObjectID, ciphertext, associatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decryptResponse, err := c.Decrypt(ingestResponse.ObjectID, ingestResponse.Ciphertext, ingestResponse.AssociatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decryptResponse.Plaintext) != string(plaintext) {
+		t.Fatal("Decryption returned wrong plaintext")
+	}
+}
+
+func TestImportKeyWR(t *testing.T) {
+	c, err := NewClientWR(context.Background(), endpoint, certPath, uid, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	createIngestKeyResponse, err := c.CreateIngestKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := []byte("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA")
+	wrappedKey, err := WrapImportKey(createIngestKeyResponse.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	importKeyResponse, err := c.ImportKey(createIngestKeyResponse.ObjectID, wrappedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte("foo")
+	associatedData := []byte("bar")
+	encryptResponse, err := c.EncryptWithKey(importKeyResponse.ObjectID, plaintext, associatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decryptResponse, err := c.Decrypt(encryptResponse.ObjectID, encryptResponse.Ciphertext, encryptResponse.AssociatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decryptResponse.Plaintext) != string(plaintext) {
+		t.Fatal("Decryption returned wrong plaintext")
+	}
+}
+
+func TestEncryptDeterministicWR(t *testing.T) {
+	c, err := NewClientWR(context.Background(), endpoint, certPath, uid, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	createDeterministicKeyResponse, err := c.CreateDeterministicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte("foo")
+	associatedData := []byte("bar")
+	first, err := c.EncryptDeterministic(createDeterministicKeyResponse.ObjectID, plaintext, associatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.EncryptDeterministic(createDeterministicKeyResponse.ObjectID, plaintext, associatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first.Ciphertext) != string(second.Ciphertext) {
+		t.Fatal("Deterministic encryption returned different ciphertexts")
+	}
+
+	decryptResponse, err := c.DecryptDeterministic(first.ObjectID, first.Ciphertext, first.AssociatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decryptResponse.Plaintext) != string(plaintext) {
+		t.Fatal("Decryption returned wrong plaintext")
+	}
+}
+
+func TestMACWR(t *testing.T) {
+	c, err := NewClientWR(context.Background(), endpoint, certPath, uid, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	createMACKeyResponse, err := c.CreateMACKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := []byte("foo")
+	tagResponse, err := c.Tag(createMACKeyResponse.ObjectID, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifyResponse, err := c.Verify(createMACKeyResponse.ObjectID, message, tagResponse.Tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verifyResponse.Valid {
+		t.Fatal("Verification rejected valid tag")
+	}
+}
+
+func TestSigningWR(t *testing.T) {
+	c, err := NewClientWR(context.Background(), endpoint, certPath, uid, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	createSigningKeyResponse, err := c.CreateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := []byte("foo")
+	signResponse, err := c.Sign(createSigningKeyResponse.ObjectID, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifyResponse, err := c.VerifySignature(createSigningKeyResponse.ObjectID, message, signResponse.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verifyResponse.Valid {
+		t.Fatal("Verification rejected valid signature")
+	}
+
+	if _, err := c.GetPublicKey(createSigningKeyResponse.ObjectID, PublicKeyFormatPEM); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFPEWR(t *testing.T) {
+	c, err := NewClientWR(context.Background(), endpoint, certPath, uid, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	createFPEKeyResponse, err := c.CreateFPEKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := "4111111111111111"
+	tokenizeResponse, err := c.TokenizeFPE(createFPEKeyResponse.ObjectID, plaintext, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	detokenizeResponse, err := c.DetokenizeFPE(createFPEKeyResponse.ObjectID, tokenizeResponse.Token, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detokenizeResponse.Plaintext != plaintext {
+		t.Fatal("Detokenized plaintext does not match")
+	}
+}
+
+func TestTokenizeWR(t *testing.T) {
+	c, err := NewClientWR(context.Background(), endpoint, certPath, uid, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	createNamespaceResponse, err := c.CreateNamespace()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte("foo")
+	tokenizeResponse, err := c.Tokenize(createNamespaceResponse.ObjectID, plaintext, "", 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	detokenizeResponse, err := c.Detokenize(createNamespaceResponse.ObjectID, tokenizeResponse.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(detokenizeResponse.Plaintext) != string(plaintext) {
+		t.Fatal("Detokenized plaintext does not match")
+	}
+}
+
 func TestStoreWR(t *testing.T) {
 	c, err := NewClientWR(context.Background(), endpoint, certPath, uid, password)
 	if err != nil {
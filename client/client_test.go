@@ -21,6 +21,7 @@ var scopes = []Scope{
 	ScopeIndex,
 	ScopeObjectPermissions,
 	ScopeUserManagement,
+	ScopeDeterministic,
 }
 
 func TestMain(m *testing.M) {
@@ -137,6 +138,302 @@ func TestEncrypt(t *testing.T) {
 	}
 }
 
+func TestEncryptLocal(t *testing.T) {
+	c, err := NewClient(context.Background(), endpoint, certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.LoginUser(uid, password); err != nil {
+		t.Fatal(err)
+	}
+
+	createUserResponse, err := c.CreateUser(scopes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.LoginUser(createUserResponse.UserID, createUserResponse.Password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte("foo")
+	associatedData := []byte("bar")
+	encryptResponse, err := c.EncryptLocal(plaintext, associatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decryptResponse, err := c.DecryptLocal(encryptResponse.Ciphertext, encryptResponse.AssociatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decryptResponse.Plaintext) != string(plaintext) {
+		t.Fatal("Decryption returned wrong plaintext")
+	}
+
+	if _, err := c.DecryptLocal(encryptResponse.Ciphertext, []byte("baz")); err == nil {
+		t.Fatal("Decryption with wrong associated data should fail")
+	}
+}
+
+func TestIngest(t *testing.T) {
+	c, err := NewClient(context.Background(), endpoint, certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.LoginUser(uid, password); err != nil {
+		t.Fatal(err)
+	}
+
+	createIngestKeyResponse, err := c.CreateIngestKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte("foo")
+	associatedData := []byte("bar")
+	ciphertext, err := IngestEncrypt(createIngestKeyResponse.PublicKey, plaintext, associatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ingestResponse, err := c.Ingest(createIngestKeyResponse.ObjectID, ciphertext, associatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decryptResponse, err := c.Decrypt(ingestResponse.ObjectID, ingestResponse.Ciphertext, ingestResponse.AssociatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decryptResponse.Plaintext) != string(plaintext) {
+		t.Fatal("Decryption returned wrong plaintext")
+	}
+}
+
+func TestImportKey(t *testing.T) {
+	c, err := NewClient(context.Background(), endpoint, certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.LoginUser(uid, password); err != nil {
+		t.Fatal(err)
+	}
+
+	createIngestKeyResponse, err := c.CreateIngestKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := []byte("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA")
+	wrappedKey, err := WrapImportKey(createIngestKeyResponse.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	importKeyResponse, err := c.ImportKey(createIngestKeyResponse.ObjectID, wrappedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte("foo")
+	associatedData := []byte("bar")
+	encryptResponse, err := c.EncryptWithKey(importKeyResponse.ObjectID, plaintext, associatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decryptResponse, err := c.Decrypt(encryptResponse.ObjectID, encryptResponse.Ciphertext, encryptResponse.AssociatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decryptResponse.Plaintext) != string(plaintext) {
+		t.Fatal("Decryption returned wrong plaintext")
+	}
+}
+
+func TestEncryptDeterministic(t *testing.T) {
+	c, err := NewClient(context.Background(), endpoint, certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.LoginUser(uid, password); err != nil {
+		t.Fatal(err)
+	}
+
+	createDeterministicKeyResponse, err := c.CreateDeterministicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte("foo")
+	associatedData := []byte("bar")
+	first, err := c.EncryptDeterministic(createDeterministicKeyResponse.ObjectID, plaintext, associatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.EncryptDeterministic(createDeterministicKeyResponse.ObjectID, plaintext, associatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first.Ciphertext) != string(second.Ciphertext) {
+		t.Fatal("Deterministic encryption returned different ciphertexts")
+	}
+
+	decryptResponse, err := c.DecryptDeterministic(first.ObjectID, first.Ciphertext, first.AssociatedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decryptResponse.Plaintext) != string(plaintext) {
+		t.Fatal("Decryption returned wrong plaintext")
+	}
+}
+
+func TestMAC(t *testing.T) {
+	c, err := NewClient(context.Background(), endpoint, certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.LoginUser(uid, password); err != nil {
+		t.Fatal(err)
+	}
+
+	createMACKeyResponse, err := c.CreateMACKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := []byte("foo")
+	tagResponse, err := c.Tag(createMACKeyResponse.ObjectID, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifyResponse, err := c.Verify(createMACKeyResponse.ObjectID, message, tagResponse.Tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verifyResponse.Valid {
+		t.Fatal("Verification rejected valid tag")
+	}
+
+	verifyResponse, err = c.Verify(createMACKeyResponse.ObjectID, []byte("bar"), tagResponse.Tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verifyResponse.Valid {
+		t.Fatal("Verification accepted tag of another message")
+	}
+}
+
+func TestSigning(t *testing.T) {
+	c, err := NewClient(context.Background(), endpoint, certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.LoginUser(uid, password); err != nil {
+		t.Fatal(err)
+	}
+
+	createSigningKeyResponse, err := c.CreateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := []byte("foo")
+	signResponse, err := c.Sign(createSigningKeyResponse.ObjectID, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifyResponse, err := c.VerifySignature(createSigningKeyResponse.ObjectID, message, signResponse.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verifyResponse.Valid {
+		t.Fatal("Verification rejected valid signature")
+	}
+
+	for _, format := range []PublicKeyFormat{PublicKeyFormatPEM, PublicKeyFormatJWK} {
+		publicKeyResponse, err := c.GetPublicKey(createSigningKeyResponse.ObjectID, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if publicKeyResponse.PublicKey == "" {
+			t.Fatalf("Empty %s public key", format)
+		}
+	}
+}
+
+func TestFPE(t *testing.T) {
+	c, err := NewClient(context.Background(), endpoint, certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.LoginUser(uid, password); err != nil {
+		t.Fatal(err)
+	}
+
+	createFPEKeyResponse, err := c.CreateFPEKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := "4111111111111111"
+	tokenizeResponse, err := c.TokenizeFPE(createFPEKeyResponse.ObjectID, plaintext, "", []byte("tweak"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokenizeResponse.Token) != len(plaintext) {
+		t.Fatalf("Token %s does not preserve the length of the plaintext", tokenizeResponse.Token)
+	}
+
+	detokenizeResponse, err := c.DetokenizeFPE(createFPEKeyResponse.ObjectID, tokenizeResponse.Token, "", []byte("tweak"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detokenizeResponse.Plaintext != plaintext {
+		t.Fatal("Detokenized plaintext does not match")
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	c, err := NewClient(context.Background(), endpoint, certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.LoginUser(uid, password); err != nil {
+		t.Fatal(err)
+	}
+
+	createNamespaceResponse, err := c.CreateNamespace()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte("foo")
+	tokenizeResponse, err := c.Tokenize(createNamespaceResponse.ObjectID, plaintext, "", 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	detokenizeResponse, err := c.Detokenize(createNamespaceResponse.ObjectID, tokenizeResponse.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(detokenizeResponse.Plaintext) != string(plaintext) {
+		t.Fatal("Detokenized plaintext does not match")
+	}
+}
+
 func TestStore(t *testing.T) {
 	c, err := NewClient(context.Background(), endpoint, certPath)
 	if err != nil {
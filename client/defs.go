@@ -14,6 +14,8 @@
 
 package client
 
+import "time"
+
 /////////////////////////////////////////////////////////////////////////
 //                               Utility                               //
 /////////////////////////////////////////////////////////////////////////
@@ -41,6 +43,7 @@ const (
 	ScopeIndex
 	ScopeObjectPermissions
 	ScopeUserManagement
+	ScopeDeterministic
 )
 
 type CreateUserResponse struct {
@@ -67,6 +70,105 @@ type DecryptResponse struct {
 	AssociatedData []byte `json:"associatedData"`
 }
 
+type GenerateDataKeyResponse struct {
+	DataKey        []byte `json:"dataKey"`
+	WrappedDataKey []byte `json:"wrappedDataKey"`
+	ObjectID       string `json:"objectId"`
+}
+
+type DecryptDataKeyResponse struct {
+	DataKey []byte `json:"dataKey"`
+}
+
+type CreateIngestKeyResponse struct {
+	ObjectID  string `json:"objectId"`
+	PublicKey []byte `json:"publicKey"`
+}
+
+type GetIngestPublicKeyResponse struct {
+	PublicKey []byte `json:"publicKey"`
+}
+
+type ImportKeyResponse struct {
+	ObjectID string `json:"objectId"`
+}
+
+type CreateDeterministicKeyResponse struct {
+	ObjectID string `json:"objectId"`
+}
+
+/////////////////////////////////////////////////////////////////////////
+//                        Message Authentication                       //
+/////////////////////////////////////////////////////////////////////////
+
+type CreateMACKeyResponse struct {
+	ObjectID string `json:"objectId"`
+}
+
+type TagResponse struct {
+	Tag []byte `json:"tag"`
+}
+
+type VerifyResponse struct {
+	Valid bool `json:"valid"`
+}
+
+/////////////////////////////////////////////////////////////////////////
+//                               Signing                               //
+/////////////////////////////////////////////////////////////////////////
+
+// PublicKeyFormat is the encoding of exported public keys
+type PublicKeyFormat string
+
+const (
+	PublicKeyFormatPEM PublicKeyFormat = "PEM"
+	PublicKeyFormatJWK PublicKeyFormat = "JWK"
+)
+
+type CreateSigningKeyResponse struct {
+	ObjectID string `json:"objectId"`
+}
+
+type SignResponse struct {
+	Signature []byte `json:"signature"`
+}
+
+type GetPublicKeyResponse struct {
+	PublicKey string `json:"publicKey"`
+}
+
+/////////////////////////////////////////////////////////////////////////
+//                     Format-Preserving Encryption                    //
+/////////////////////////////////////////////////////////////////////////
+
+type CreateFPEKeyResponse struct {
+	ObjectID string `json:"objectId"`
+}
+
+type TokenizeFPEResponse struct {
+	Token string `json:"token"`
+}
+
+type DetokenizeFPEResponse struct {
+	Plaintext string `json:"plaintext"`
+}
+
+/////////////////////////////////////////////////////////////////////////
+//                             Tokenization                            //
+/////////////////////////////////////////////////////////////////////////
+
+type CreateNamespaceResponse struct {
+	ObjectID string `json:"objectId"`
+}
+
+type TokenizeResponse struct {
+	Token string `json:"token"`
+}
+
+type DetokenizeResponse struct {
+	Plaintext []byte `json:"plaintext"`
+}
+
 /////////////////////////////////////////////////////////////////////////
 //                               Storage                               //
 /////////////////////////////////////////////////////////////////////////
@@ -88,6 +190,22 @@ type GetPermissionsResponse struct {
 	GroupIDs []string `json:"groupIds"`
 }
 
+type ErasureReceipt struct {
+	ObjectID   string    `json:"objectId"`
+	ShreddedAt time.Time `json:"shreddedAt"`
+	Digest     []byte    `json:"digest"`
+	Signature  []byte    `json:"signature"`
+	KeyID      uint32    `json:"keyId"`
+}
+
+type ShredResponse struct {
+	Receipt ErasureReceipt `json:"receipt"`
+}
+
+type GetErasureReceiptResponse struct {
+	Receipt ErasureReceipt `json:"receipt"`
+}
+
 /////////////////////////////////////////////////////////////////////////
 //                               Internal                              //
 /////////////////////////////////////////////////////////////////////////
@@ -95,15 +213,37 @@ type GetPermissionsResponse struct {
 // request is a catch-all for request structs. By using `omitempty` we can marshal to the correct
 // JSON structure by only setting the necessary fields.
 type request struct {
-	Scopes         []string `json:"scopes,omitempty"`
-	UserID         string   `json:"user_id,omitempty"`
-	GroupID        string   `json:"group_id,omitempty"`
-	Target         string   `json:"target,omitempty"`
-	ObjectID       string   `json:"object_id,omitempty"`
-	Plaintext      []byte   `json:"plaintext,omitempty"`
-	Ciphertext     []byte   `json:"ciphertext,omitempty"`
-	AssociatedData []byte   `json:"associated_data,omitempty"`
-	Password       string   `json:"password,omitempty"`
+	Scopes         []string        `json:"scopes,omitempty"`
+	UserID         string          `json:"user_id,omitempty"`
+	GroupID        string          `json:"group_id,omitempty"`
+	Target         string          `json:"target,omitempty"`
+	ObjectID       string          `json:"object_id,omitempty"`
+	KeyID          string          `json:"key_id,omitempty"`
+	Plaintext      []byte          `json:"plaintext,omitempty"`
+	Ciphertext     []byte          `json:"ciphertext,omitempty"`
+	AssociatedData []byte          `json:"associated_data,omitempty"`
+	Password       string          `json:"password,omitempty"`
+	Rekey          bool            `json:"rekey,omitempty"`
+	WrappedDataKey []byte          `json:"wrapped_data_key,omitempty"`
+	Message        []byte          `json:"message,omitempty"`
+	Tag            []byte          `json:"tag,omitempty"`
+	Signature      []byte          `json:"signature,omitempty"`
+	Format         PublicKeyFormat `json:"format,omitempty"`
+	WrappedKey     []byte          `json:"wrapped_key,omitempty"`
+	Token          string          `json:"token,omitempty"`
+	Alphabet       string          `json:"alphabet,omitempty"`
+	Length         uint32          `json:"length,omitempty"`
+	Unique         bool            `json:"unique,omitempty"`
+}
+
+// fpeRequest is the request struct of the format-preserving encryption endpoints, which take the
+// plaintext as a string rather than as bytes.
+type fpeRequest struct {
+	ObjectID  string `json:"object_id,omitempty"`
+	Plaintext string `json:"plaintext,omitempty"`
+	Token     string `json:"token,omitempty"`
+	Alphabet  string `json:"alphabet,omitempty"`
+	Tweak     []byte `json:"tweak,omitempty"`
 }
 
 type accessToken struct {
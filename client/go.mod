@@ -5,6 +5,7 @@ go 1.17
 require (
 	github.com/fullstorydev/grpcurl v1.8.5
 	github.com/jhump/protoreflect v1.10.1
+	golang.org/x/crypto v0.0.0-20211117183948-ae814b36b871
 	google.golang.org/grpc v1.42.0
 )
 
@@ -0,0 +1,104 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+const ingestVersion = 1
+
+// Info string of the key derivation of ingest ciphertexts
+var ingestInfo = []byte("Encryptonize hybrid encryption v1")
+
+// Associated data of imported keys
+var importKeyAAD = []byte("import key")
+
+// Size of imported keys in bytes
+const importKeySize = 32
+
+// IngestEncrypt encrypts the `plaintext` and tags both `plaintext` and `associatedData` to the public
+// key of an ingest key. It does not need a connection to the Encryptonize service, so data can be
+// encrypted by producers without credentials. The ciphertext can be converted into an object with
+// `Ingest` by a user with access to the ingest key.
+//
+// An ephemeral X25519 key is agreed with the public key, and the shared secret is expanded with
+// HKDF-SHA256 into an AES-256-GCM key. The ciphertext has the format
+//
+//	version (1) || ephemeral public key (32) || nonce (12) || ciphertext || tag (16)
+//
+// The version and ephemeral public key are authenticated along with the associated data.
+func IngestEncrypt(publicKey, plaintext, associatedData []byte) ([]byte, error) {
+	ephemeralPrivateKey := make([]byte, curve25519.ScalarSize)
+	if _, err := rand.Read(ephemeralPrivateKey); err != nil {
+		return nil, err
+	}
+	ephemeralPublicKey, err := curve25519.X25519(ephemeralPrivateKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	sharedSecret, err := curve25519.X25519(ephemeralPrivateKey, publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 0, 2*curve25519.PointSize)
+	salt = append(salt, ephemeralPublicKey...)
+	salt = append(salt, publicKey...)
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, sharedSecret, salt, ingestInfo), key); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	ciphertext := make([]byte, 0, 1+len(ephemeralPublicKey)+len(nonce)+len(plaintext)+aead.Overhead())
+	ciphertext = append(ciphertext, ingestVersion)
+	ciphertext = append(ciphertext, ephemeralPublicKey...)
+	header := ciphertext
+	ciphertext = append(ciphertext, nonce...)
+
+	return aead.Seal(ciphertext, nonce, plaintext, localAAD(header, associatedData)), nil
+}
+
+// WrapImportKey encrypts a 32 byte `key` to the public key of an ingest key, such that it can be
+// imported with `ImportKey`. Like `IngestEncrypt` it does not need a connection to the Encryptonize
+// service, so the key can be wrapped where it is generated.
+func WrapImportKey(publicKey, key []byte) ([]byte, error) {
+	if len(key) != importKeySize {
+		return nil, fmt.Errorf("invalid key length: want %d, got %d", importKeySize, len(key))
+	}
+	return IngestEncrypt(publicKey, key, importKeyAAD)
+}
@@ -0,0 +1,199 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Locally encrypted containers have the following format:
+//
+//	| magic (1) | version (1) | object ID (16) | wrapped key length (4) | wrapped key | nonce (12) | ciphertext and tag |
+//
+// The wrapped key length is big endian. The data is encrypted with AES-256-GCM under the data key,
+// and everything preceding the nonce is authenticated together with the associated data.
+const (
+	localMagic               = 0xEE
+	localVersion             = 1
+	localObjectIDLength      = 16
+	localHeaderLength        = 2 + localObjectIDLength + 4
+	localNonceLength         = 12
+	localTagLength           = 16
+	localMaxWrappedKeyLength = 1024
+)
+
+// ErrInvalidContainer is returned by `DecryptLocal` when the ciphertext is not a valid locally
+// encrypted container.
+var ErrInvalidContainer = errors.New("invalid local ciphertext container")
+
+// EncryptLocal encrypts the `plaintext` and tags both `plaintext` and `associatedData` under a new
+// data key without sending the data to the Encryptonize service. The returned ciphertext contains the
+// wrapped data key and the Object ID needed to decrypt it. Access to the ciphertext is managed through
+// the permissions of the Object ID.
+func (c *Client) EncryptLocal(plaintext, associatedData []byte) (*EncryptResponse, error) {
+	dataKeyResponse, err := c.GenerateDataKey()
+	if err != nil {
+		return nil, err
+	}
+	defer zero(dataKeyResponse.DataKey)
+
+	ciphertext, err := sealLocal(dataKeyResponse, plaintext, associatedData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EncryptResponse{
+		Ciphertext:     ciphertext,
+		AssociatedData: associatedData,
+		ObjectID:       dataKeyResponse.ObjectID,
+	}, nil
+}
+
+// DecryptLocal decrypts a `ciphertext` produced by `EncryptLocal` and verifies the integrity of the
+// `ciphertext` and `associatedData`. The data key is unwrapped by the Encryptonize service, which
+// requires the caller to have access to the object, but the data is decrypted locally.
+func (c *Client) DecryptLocal(ciphertext, associatedData []byte) (*DecryptResponse, error) {
+	objectID, wrappedDataKey, err := parseLocal(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKeyResponse, err := c.DecryptDataKey(objectID, wrappedDataKey)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(dataKeyResponse.DataKey)
+
+	plaintext, err := openLocal(dataKeyResponse.DataKey, ciphertext, associatedData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DecryptResponse{
+		Plaintext:      plaintext,
+		AssociatedData: associatedData,
+	}, nil
+}
+
+// sealLocal encrypts `plaintext` under the data key and returns the resulting container
+func sealLocal(dataKey *GenerateDataKeyResponse, plaintext, associatedData []byte) ([]byte, error) {
+	objectID, err := encodeObjectID(dataKey.ObjectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(dataKey.WrappedDataKey) > localMaxWrappedKeyLength {
+		return nil, fmt.Errorf("wrapped data key too long: %d bytes", len(dataKey.WrappedDataKey))
+	}
+
+	aead, err := newLocalAEAD(dataKey.DataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	container := make([]byte, 0, localHeaderLength+len(dataKey.WrappedDataKey)+localNonceLength+len(plaintext)+localTagLength)
+	container = append(container, localMagic, localVersion)
+	container = append(container, objectID...)
+	wrappedKeyLength := make([]byte, 4)
+	binary.BigEndian.PutUint32(wrappedKeyLength, uint32(len(dataKey.WrappedDataKey)))
+	container = append(container, wrappedKeyLength...)
+	container = append(container, dataKey.WrappedDataKey...)
+	header := container
+
+	nonce := make([]byte, localNonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	container = append(container, nonce...)
+
+	return aead.Seal(container, nonce, plaintext, localAAD(header, associatedData)), nil
+}
+
+// parseLocal extracts the Object ID and the wrapped data key from a container
+func parseLocal(container []byte) (string, []byte, error) {
+	if len(container) < localHeaderLength || container[0] != localMagic || container[1] != localVersion {
+		return "", nil, ErrInvalidContainer
+	}
+
+	wrappedKeyLength := binary.BigEndian.Uint32(container[2+localObjectIDLength : localHeaderLength])
+	if wrappedKeyLength > localMaxWrappedKeyLength ||
+		len(container) < localHeaderLength+int(wrappedKeyLength)+localNonceLength+localTagLength {
+		return "", nil, ErrInvalidContainer
+	}
+
+	objectID := decodeObjectID(container[2 : 2+localObjectIDLength])
+	wrappedDataKey := container[localHeaderLength : localHeaderLength+int(wrappedKeyLength)]
+	return objectID, wrappedDataKey, nil
+}
+
+// openLocal decrypts a container that has been checked with `parseLocal`
+func openLocal(dataKey, container, associatedData []byte) ([]byte, error) {
+	aead, err := newLocalAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	headerLength := localHeaderLength + int(binary.BigEndian.Uint32(container[2+localObjectIDLength:localHeaderLength]))
+	header := container[:headerLength]
+	nonce := container[headerLength : headerLength+localNonceLength]
+	ciphertext := container[headerLength+localNonceLength:]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, localAAD(header, associatedData))
+	if err != nil {
+		return nil, ErrInvalidContainer
+	}
+	return plaintext, nil
+}
+
+func newLocalAEAD(dataKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// localAAD binds the container header to the caller's associated data
+func localAAD(header, associatedData []byte) []byte {
+	aad := make([]byte, 0, len(header)+len(associatedData))
+	aad = append(aad, header...)
+	return append(aad, associatedData...)
+}
+
+// encodeObjectID converts a textual UUID to its 16 byte form
+func encodeObjectID(objectID string) ([]byte, error) {
+	decoded, err := hex.DecodeString(strings.ReplaceAll(objectID, "-", ""))
+	if err != nil || len(decoded) != localObjectIDLength {
+		return nil, fmt.Errorf("invalid object ID: %q", objectID)
+	}
+	return decoded, nil
+}
+
+// decodeObjectID converts a 16 byte UUID to its textual form
+func decodeObjectID(objectID []byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", objectID[0:4], objectID[4:6], objectID[6:8], objectID[8:10], objectID[10:16])
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
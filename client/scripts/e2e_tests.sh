@@ -24,6 +24,8 @@
 # - E2E_TEST_UID       : UID of a user with USERMANAGEMENT scope
 # - E2E_TEST_PASS      : Password of the above mentioned user
 # - E2E_TEST_HTTPS     : Set to "true" if testing an HTTPS endpoint
+# - E2E_TEST_RECEIPT_PUBLIC_KEY : Public key of the receipt signing key, used to verify erasure
+#                                 receipts. Verification is skipped if not set.
 
 set -euo pipefail
 
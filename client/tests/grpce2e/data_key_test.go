@@ -0,0 +1,119 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build encryption
+// +build encryption
+
+package grpce2e
+
+import (
+	"testing"
+
+	"bytes"
+	"context"
+
+	coreclient "github.com/cyber-crypt-com/encryptonize-core/client"
+)
+
+func TestGenerateAndDecryptDataKey(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	generateResponse, err := client.GenerateDataKey()
+	failOnError("GenerateDataKey operation failed", err, t)
+
+	if len(generateResponse.DataKey) != 32 {
+		t.Fatalf("Expected 32 byte data key but got %d bytes", len(generateResponse.DataKey))
+	}
+
+	decryptResponse, err := client.DecryptDataKey(generateResponse.ObjectID, generateResponse.WrappedDataKey)
+	failOnError("DecryptDataKey operation failed", err, t)
+
+	if !bytes.Equal(decryptResponse.DataKey, generateResponse.DataKey) {
+		t.Fatalf("Expected data key %v but got %v", generateResponse.DataKey, decryptResponse.DataKey)
+	}
+}
+
+// Test that a data key can only be decrypted by users with access to its object
+func TestDecryptDataKeyWithoutPermissions(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	createUserResponse, err := client.CreateUser(protoUserScopes)
+	failOnError("Create user request failed", err, t)
+
+	generateResponse, err := client.GenerateDataKey()
+	failOnError("GenerateDataKey operation failed", err, t)
+
+	err = client.LoginUser(createUserResponse.UserID, createUserResponse.Password)
+	failOnError("Could not log in user", err, t)
+
+	_, err = client.DecryptDataKey(generateResponse.ObjectID, generateResponse.WrappedDataKey)
+	failOnSuccess("Unauthorized user should not be able to decrypt data key", err, t)
+}
+
+// Test that locally encrypted data can be shared through the permissions of its object
+func TestEncryptLocalGroupPermissions(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	createUserResponse, err := client.CreateUser(protoUserScopes)
+	failOnError("Create user request failed", err, t)
+	uid2 := createUserResponse.UserID
+	pwd2 := createUserResponse.Password
+
+	createGroupResponse, err := client.CreateGroup(protoUserScopes)
+	failOnError("Create group request failed", err, t)
+	gid := createGroupResponse.GroupID
+
+	err = client.AddUserToGroup(uid2, gid)
+	failOnError("Add user to group request failed", err, t)
+
+	plaintext := []byte("foo")
+	associatedData := []byte("bar")
+	encryptResponse, err := client.EncryptLocal(plaintext, associatedData)
+	failOnError("EncryptLocal operation failed", err, t)
+
+	// Without permission the data key cannot be unwrapped
+	err = client.LoginUser(uid2, pwd2)
+	failOnError("Could not log in user", err, t)
+	_, err = client.DecryptLocal(encryptResponse.Ciphertext, associatedData)
+	failOnSuccess("Unauthorized user should not be able to decrypt object", err, t)
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+	err = client.AddPermission(encryptResponse.ObjectID, gid)
+	failOnError("Add permission request failed", err, t)
+
+	err = client.LoginUser(uid2, pwd2)
+	failOnError("Could not log in user", err, t)
+	decryptResponse, err := client.DecryptLocal(encryptResponse.Ciphertext, associatedData)
+	failOnError("DecryptLocal operation failed", err, t)
+
+	if !bytes.Equal(decryptResponse.Plaintext, plaintext) {
+		t.Fatalf("Expected plaintext %v but got %v", plaintext, decryptResponse.Plaintext)
+	}
+}
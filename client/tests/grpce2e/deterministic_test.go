@@ -0,0 +1,135 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//go:build encryption
+// +build encryption
+
+package grpce2e
+
+import (
+	"testing"
+
+	"bytes"
+	"context"
+
+	coreclient "github.com/cyber-crypt-com/encryptonize-core/client"
+)
+
+// Test that deterministic encryption returns equal ciphertexts for equal plaintexts
+func TestEncryptDeterministic(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	createDeterministicKeyResponse, err := client.CreateDeterministicKey()
+	failOnError("CreateDeterministicKey operation failed", err, t)
+
+	plaintext := []byte("foo")
+	associatedData := []byte("bar")
+	first, err := client.EncryptDeterministic(createDeterministicKeyResponse.ObjectID, plaintext, associatedData)
+	failOnError("EncryptDeterministic operation failed", err, t)
+
+	second, err := client.EncryptDeterministic(createDeterministicKeyResponse.ObjectID, plaintext, associatedData)
+	failOnError("EncryptDeterministic operation failed", err, t)
+
+	if !bytes.Equal(first.Ciphertext, second.Ciphertext) {
+		t.Fatalf("Expected equal ciphertexts but got %v and %v", first.Ciphertext, second.Ciphertext)
+	}
+
+	decryptResponse, err := client.DecryptDeterministic(first.ObjectID, first.Ciphertext, associatedData)
+	failOnError("DecryptDeterministic operation failed", err, t)
+
+	if !bytes.Equal(decryptResponse.Plaintext, plaintext) {
+		t.Fatalf("Expected plaintext %v but got %v", plaintext, decryptResponse.Plaintext)
+	}
+
+	// Deterministic ciphertexts are not accepted by the randomized path
+	_, err = client.Decrypt(first.ObjectID, first.Ciphertext, associatedData)
+	failOnSuccess("Decrypt should not accept deterministic ciphertexts", err, t)
+}
+
+// Test that deterministic encryption requires the DETERMINISTIC scope
+func TestEncryptDeterministicWithoutScope(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	createDeterministicKeyResponse, err := client.CreateDeterministicKey()
+	failOnError("CreateDeterministicKey operation failed", err, t)
+	oid := createDeterministicKeyResponse.ObjectID
+
+	scopes := []coreclient.Scope{coreclient.ScopeRead, coreclient.ScopeCreate}
+	createUserResponse, err := client.CreateUser(scopes)
+	failOnError("Create user request failed", err, t)
+
+	err = client.AddPermission(oid, createUserResponse.UserID)
+	failOnError("Add permission request failed", err, t)
+
+	err = client.LoginUser(createUserResponse.UserID, createUserResponse.Password)
+	failOnError("Could not log in user", err, t)
+
+	_, err = client.CreateDeterministicKey()
+	failOnSuccess("User without DETERMINISTIC scope should not be able to create deterministic keys", err, t)
+
+	_, err = client.EncryptDeterministic(oid, []byte("foo"), []byte("bar"))
+	failOnSuccess("User without DETERMINISTIC scope should not be able to encrypt deterministically", err, t)
+}
+
+// Test that members of a group share its deterministic key
+func TestEncryptDeterministicGroup(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	createUserResponse, err := client.CreateUser(protoUserScopes)
+	failOnError("Create user request failed", err, t)
+
+	createGroupResponse, err := client.CreateGroup(protoUserScopes)
+	failOnError("Create group request failed", err, t)
+	gid := createGroupResponse.GroupID
+
+	err = client.AddUserToGroup(uid, gid)
+	failOnError("Add user to group request failed", err, t)
+	err = client.AddUserToGroup(createUserResponse.UserID, gid)
+	failOnError("Add user to group request failed", err, t)
+
+	plaintext := []byte("foo")
+	createDeterministicKeyResponse, err := client.CreateGroupDeterministicKey(gid)
+	failOnError("CreateGroupDeterministicKey operation failed", err, t)
+	first, err := client.EncryptDeterministic(createDeterministicKeyResponse.ObjectID, plaintext, nil)
+	failOnError("EncryptDeterministic operation failed", err, t)
+
+	err = client.LoginUser(createUserResponse.UserID, createUserResponse.Password)
+	failOnError("Could not log in user", err, t)
+
+	otherCreateDeterministicKeyResponse, err := client.CreateGroupDeterministicKey(gid)
+	failOnError("CreateGroupDeterministicKey operation failed", err, t)
+	if otherCreateDeterministicKeyResponse.ObjectID != createDeterministicKeyResponse.ObjectID {
+		t.Fatalf("Expected object ID %s but got %s", createDeterministicKeyResponse.ObjectID, otherCreateDeterministicKeyResponse.ObjectID)
+	}
+
+	second, err := client.EncryptDeterministic(otherCreateDeterministicKeyResponse.ObjectID, plaintext, nil)
+	failOnError("EncryptDeterministic operation failed", err, t)
+	if !bytes.Equal(first.Ciphertext, second.Ciphertext) {
+		t.Fatalf("Expected equal ciphertexts but got %v and %v", first.Ciphertext, second.Ciphertext)
+	}
+}
@@ -39,4 +39,10 @@ func TestEncDisabled(t *testing.T) {
 
 	_, err = client.Encrypt(plaintext, associatedData)
 	failOnSuccess("Encrypt operation should have failed", err, t)
+
+	_, err = client.GenerateDataKey()
+	failOnSuccess("GenerateDataKey operation should have failed", err, t)
+
+	_, err = client.CreateIngestKey()
+	failOnSuccess("CreateIngestKey operation should have failed", err, t)
 }
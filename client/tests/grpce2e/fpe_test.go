@@ -0,0 +1,96 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package grpce2e
+
+import (
+	"testing"
+
+	"context"
+
+	coreclient "github.com/cyber-crypt-com/encryptonize-core/client"
+)
+
+// Test that tokens preserve the format of the plaintext and can be detokenized
+func TestTokenizeAndDetokenizeFPE(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	createFPEKeyResponse, err := client.CreateFPEKey()
+	failOnError("CreateFPEKey operation failed", err, t)
+	oid := createFPEKeyResponse.ObjectID
+
+	alphabet := "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	plaintext := "DK1234567890"
+	tokenizeResponse, err := client.TokenizeFPE(oid, plaintext, alphabet, nil)
+	failOnError("TokenizeFPE operation failed", err, t)
+	if len(tokenizeResponse.Token) != len(plaintext) {
+		t.Fatalf("Token %s does not have the length of the plaintext", tokenizeResponse.Token)
+	}
+
+	detokenizeResponse, err := client.DetokenizeFPE(oid, tokenizeResponse.Token, alphabet, nil)
+	failOnError("DetokenizeFPE operation failed", err, t)
+	if detokenizeResponse.Plaintext != plaintext {
+		t.Fatalf("Expected plaintext %s but got %s", plaintext, detokenizeResponse.Plaintext)
+	}
+
+	_, err = client.TokenizeFPE(oid, "not in alphabet", alphabet, nil)
+	failOnSuccess("Plaintext outside the alphabet should be rejected", err, t)
+}
+
+// Test that only users with access to the key can detokenize
+func TestFPEPermissions(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	createUserResponse, err := client.CreateUser(protoUserScopes)
+	failOnError("Create user request failed", err, t)
+	uid2 := createUserResponse.UserID
+	pwd2 := createUserResponse.Password
+
+	createFPEKeyResponse, err := client.CreateFPEKey()
+	failOnError("CreateFPEKey operation failed", err, t)
+	oid := createFPEKeyResponse.ObjectID
+
+	plaintext := "123456789"
+	tokenizeResponse, err := client.TokenizeFPE(oid, plaintext, "", nil)
+	failOnError("TokenizeFPE operation failed", err, t)
+
+	err = client.LoginUser(uid2, pwd2)
+	failOnError("Could not log in user", err, t)
+	_, err = client.DetokenizeFPE(oid, tokenizeResponse.Token, "", nil)
+	failOnSuccess("Unauthorized user should not be able to detokenize", err, t)
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+	err = client.AddPermission(oid, uid2)
+	failOnError("Add permission request failed", err, t)
+
+	err = client.LoginUser(uid2, pwd2)
+	failOnError("Could not log in user", err, t)
+	detokenizeResponse, err := client.DetokenizeFPE(oid, tokenizeResponse.Token, "", nil)
+	failOnError("DetokenizeFPE operation failed", err, t)
+	if detokenizeResponse.Plaintext != plaintext {
+		t.Fatalf("Expected plaintext %s but got %s", plaintext, detokenizeResponse.Plaintext)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//go:build encryption
+// +build encryption
+
+package grpce2e
+
+import (
+	"testing"
+
+	"bytes"
+	"context"
+
+	coreclient "github.com/cyber-crypt-com/encryptonize-core/client"
+)
+
+// Test that data can be encrypted under an imported key
+func TestImportKey(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	createIngestKeyResponse, err := client.CreateIngestKey()
+	failOnError("CreateIngestKey operation failed", err, t)
+
+	key := []byte("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA")
+	wrappedKey, err := coreclient.WrapImportKey(createIngestKeyResponse.PublicKey, key)
+	failOnError("WrapImportKey failed", err, t)
+
+	importKeyResponse, err := client.ImportKey(createIngestKeyResponse.ObjectID, wrappedKey)
+	failOnError("ImportKey operation failed", err, t)
+
+	plaintext := []byte("foo")
+	associatedData := []byte("bar")
+	encryptResponse, err := client.EncryptWithKey(importKeyResponse.ObjectID, plaintext, associatedData)
+	failOnError("EncryptWithKey operation failed", err, t)
+
+	if encryptResponse.ObjectID != importKeyResponse.ObjectID {
+		t.Fatalf("Expected object ID %s but got %s", importKeyResponse.ObjectID, encryptResponse.ObjectID)
+	}
+
+	decryptResponse, err := client.Decrypt(encryptResponse.ObjectID, encryptResponse.Ciphertext, associatedData)
+	failOnError("Decrypt operation failed", err, t)
+
+	if !bytes.Equal(decryptResponse.Plaintext, plaintext) {
+		t.Fatalf("Expected plaintext %v but got %v", plaintext, decryptResponse.Plaintext)
+	}
+
+	// Encrypting under the key into a new object
+	encryptResponse, err = client.EncryptUnderKey(importKeyResponse.ObjectID, plaintext, associatedData)
+	failOnError("EncryptUnderKey operation failed", err, t)
+
+	if encryptResponse.ObjectID == importKeyResponse.ObjectID {
+		t.Fatalf("Expected a new object ID but got the ID of the imported key")
+	}
+
+	decryptResponse, err = client.Decrypt(encryptResponse.ObjectID, encryptResponse.Ciphertext, associatedData)
+	failOnError("Decrypt operation failed", err, t)
+
+	if !bytes.Equal(decryptResponse.Plaintext, plaintext) {
+		t.Fatalf("Expected plaintext %v but got %v", plaintext, decryptResponse.Plaintext)
+	}
+}
+
+// Test that only users with access to the imported key can encrypt under it
+func TestImportKeyWithoutPermissions(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	createUserResponse, err := client.CreateUser(protoUserScopes)
+	failOnError("Create user request failed", err, t)
+
+	createIngestKeyResponse, err := client.CreateIngestKey()
+	failOnError("CreateIngestKey operation failed", err, t)
+
+	wrappedKey, err := coreclient.WrapImportKey(createIngestKeyResponse.PublicKey, []byte("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"))
+	failOnError("WrapImportKey failed", err, t)
+
+	importKeyResponse, err := client.ImportKey(createIngestKeyResponse.ObjectID, wrappedKey)
+	failOnError("ImportKey operation failed", err, t)
+
+	err = client.LoginUser(createUserResponse.UserID, createUserResponse.Password)
+	failOnError("Could not log in user", err, t)
+
+	_, err = client.ImportKey(createIngestKeyResponse.ObjectID, wrappedKey)
+	failOnSuccess("Unauthorized user should not be able to import keys", err, t)
+
+	_, err = client.EncryptWithKey(importKeyResponse.ObjectID, []byte("foo"), []byte("bar"))
+	failOnSuccess("Unauthorized user should not be able to encrypt under imported key", err, t)
+
+	_, err = client.EncryptUnderKey(importKeyResponse.ObjectID, []byte("foo"), []byte("bar"))
+	failOnSuccess("Unauthorized user should not be able to encrypt under imported key", err, t)
+}
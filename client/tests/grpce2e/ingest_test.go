@@ -0,0 +1,84 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build encryption
+// +build encryption
+
+package grpce2e
+
+import (
+	"testing"
+
+	"bytes"
+	"context"
+
+	coreclient "github.com/cyber-crypt-com/encryptonize-core/client"
+)
+
+// Test that data encrypted without credentials can be ingested as an object
+func TestIngestEncrypted(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	createIngestKeyResponse, err := client.CreateIngestKey()
+	failOnError("CreateIngestKey operation failed", err, t)
+
+	publicKeyResponse, err := client.GetIngestPublicKey(createIngestKeyResponse.ObjectID)
+	failOnError("GetIngestPublicKey operation failed", err, t)
+
+	plaintext := []byte("foo")
+	associatedData := []byte("bar")
+	ciphertext, err := coreclient.IngestEncrypt(publicKeyResponse.PublicKey, plaintext, associatedData)
+	failOnError("IngestEncrypt failed", err, t)
+
+	ingestResponse, err := client.Ingest(createIngestKeyResponse.ObjectID, ciphertext, associatedData)
+	failOnError("Ingest operation failed", err, t)
+
+	decryptResponse, err := client.Decrypt(ingestResponse.ObjectID, ingestResponse.Ciphertext, associatedData)
+	failOnError("Decrypt operation failed", err, t)
+
+	if !bytes.Equal(decryptResponse.Plaintext, plaintext) {
+		t.Fatalf("Expected plaintext %v but got %v", plaintext, decryptResponse.Plaintext)
+	}
+}
+
+// Test that only users with access to the ingest key can ingest data
+func TestIngestWithoutPermissions(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	createUserResponse, err := client.CreateUser(protoUserScopes)
+	failOnError("Create user request failed", err, t)
+
+	createIngestKeyResponse, err := client.CreateIngestKey()
+	failOnError("CreateIngestKey operation failed", err, t)
+
+	associatedData := []byte("bar")
+	ciphertext, err := coreclient.IngestEncrypt(createIngestKeyResponse.PublicKey, []byte("foo"), associatedData)
+	failOnError("IngestEncrypt failed", err, t)
+
+	err = client.LoginUser(createUserResponse.UserID, createUserResponse.Password)
+	failOnError("Could not log in user", err, t)
+
+	_, err = client.Ingest(createIngestKeyResponse.ObjectID, ciphertext, associatedData)
+	failOnSuccess("Unauthorized user should not be able to ingest data", err, t)
+}
@@ -35,6 +35,7 @@ var protoUserScopes = []coreclient.Scope{
 	coreclient.ScopeUserManagement,
 	coreclient.ScopeUpdate,
 	coreclient.ScopeDelete,
+	coreclient.ScopeDeterministic,
 }
 var certPath = ""
 
@@ -0,0 +1,93 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpce2e
+
+import (
+	"testing"
+
+	"context"
+
+	coreclient "github.com/cyber-crypt-com/encryptonize-core/client"
+)
+
+func TestTagAndVerify(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	createMACKeyResponse, err := client.CreateMACKey()
+	failOnError("CreateMACKey operation failed", err, t)
+	oid := createMACKeyResponse.ObjectID
+
+	message := []byte("foo")
+	tagResponse, err := client.Tag(oid, message)
+	failOnError("Tag operation failed", err, t)
+
+	verifyResponse, err := client.Verify(oid, message, tagResponse.Tag)
+	failOnError("Verify operation failed", err, t)
+	if !verifyResponse.Valid {
+		t.Fatalf("Valid tag was rejected")
+	}
+
+	verifyResponse, err = client.Verify(oid, []byte("bar"), tagResponse.Tag)
+	failOnError("Verify operation failed", err, t)
+	if verifyResponse.Valid {
+		t.Fatalf("Tag of another message was accepted")
+	}
+}
+
+// Test that a MAC key can be shared through the permissions of its object
+func TestShareMACKey(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	createUserResponse, err := client.CreateUser(protoUserScopes)
+	failOnError("Create user request failed", err, t)
+	uid2 := createUserResponse.UserID
+	pwd2 := createUserResponse.Password
+
+	createMACKeyResponse, err := client.CreateMACKey()
+	failOnError("CreateMACKey operation failed", err, t)
+	oid := createMACKeyResponse.ObjectID
+
+	message := []byte("foo")
+	tagResponse, err := client.Tag(oid, message)
+	failOnError("Tag operation failed", err, t)
+
+	err = client.LoginUser(uid2, pwd2)
+	failOnError("Could not log in user", err, t)
+	_, err = client.Verify(oid, message, tagResponse.Tag)
+	failOnSuccess("Unauthorized user should not be able to verify tag", err, t)
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+	err = client.AddPermission(oid, uid2)
+	failOnError("Add permission request failed", err, t)
+
+	err = client.LoginUser(uid2, pwd2)
+	failOnError("Could not log in user", err, t)
+	verifyResponse, err := client.Verify(oid, message, tagResponse.Tag)
+	failOnError("Verify operation failed", err, t)
+	if !verifyResponse.Valid {
+		t.Fatalf("Valid tag was rejected")
+	}
+}
@@ -279,3 +279,43 @@ func TestAddPermissionNoTargetUser(t *testing.T) {
 	err = client.AddPermission(oid, nonExistingUser)
 	failOnSuccess("Shouldn't able to add user that does not exist!", err, t)
 }
+
+// Test that removing a permission can re-key the object
+func TestRemovePermissionAndRekey(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	plaintext := []byte("foo")
+	storeResponse, err := client.Store(plaintext, []byte("bar"))
+	failOnError("Store operation failed", err, t)
+	oid := storeResponse.ObjectID
+
+	createUserResponse, err := client.CreateUser(protoUserScopes)
+	failOnError("Create user request failed", err, t)
+	uid2 := createUserResponse.UserID
+	pwd2 := createUserResponse.Password
+
+	err = client.AddPermission(oid, uid2)
+	failOnError("Add permission request failed", err, t)
+
+	err = client.RemovePermissionAndRekey(oid, uid2)
+	failOnError("Could not remove permissions", err, t)
+
+	// Check that the object can still be retrieved by the owner
+	retrieveResponse, err := client.Retrieve(oid)
+	failOnError("Retrieve operation failed", err, t)
+	if !reflect.DeepEqual(retrieveResponse.Plaintext, plaintext) {
+		t.Fatalf("Expected plaintext %v but got %v", plaintext, retrieveResponse.Plaintext)
+	}
+
+	// Check that user 2 doesn't have permissions
+	err = client.LoginUser(uid2, pwd2)
+	failOnError("Could not log in user", err, t)
+
+	_, err = client.Retrieve(oid)
+	failOnSuccess("Unauthorized user should not be able to access object", err, t)
+}
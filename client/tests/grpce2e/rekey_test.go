@@ -0,0 +1,84 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build storage
+// +build storage
+
+package grpce2e
+
+import (
+	"testing"
+
+	"bytes"
+	"context"
+
+	coreclient "github.com/cyber-crypt-com/encryptonize-core/client"
+)
+
+// Test that we can store an object, re-key it and retrieve it later
+func TestStoreAndRekey(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	plaintext := []byte("foo")
+	associatedData := []byte("ChunkID")
+
+	storeResponse, err := client.Store(plaintext, associatedData)
+	failOnError("Store operation failed", err, t)
+	oid := storeResponse.ObjectID
+
+	err = client.Rekey(oid)
+	failOnError("Rekey operation failed", err, t)
+
+	// Re-keying again must also work
+	err = client.Rekey(oid)
+	failOnError("Rekey operation failed", err, t)
+
+	retrieveResponse, err := client.Retrieve(oid)
+	failOnError("Retrieve operation failed", err, t)
+
+	if !bytes.Equal(retrieveResponse.Plaintext, plaintext) {
+		t.Fatalf("Expected plaintext %v but got %v", plaintext, retrieveResponse.Plaintext)
+	}
+
+	if !bytes.Equal(retrieveResponse.AssociatedData, associatedData) {
+		t.Fatalf("Expected associated data %v but got %v", associatedData, retrieveResponse.AssociatedData)
+	}
+}
+
+// Test that an object can't be re-keyed by a user without access
+func TestRekeyUnauthorized(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	storeResponse, err := client.Store([]byte("foo"), []byte("ChunkID"))
+	failOnError("Store operation failed", err, t)
+
+	createUserResponse, err := client.CreateUser(protoUserScopes)
+	failOnError("Create user request failed", err, t)
+
+	err = client.LoginUser(createUserResponse.UserID, createUserResponse.Password)
+	failOnError("Could not log in user", err, t)
+
+	err = client.Rekey(storeResponse.ObjectID)
+	failOnSuccess("Rekey by user without access should have failed", err, t)
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build storage
+// +build storage
+
+package grpce2e
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"context"
+
+	coreclient "github.com/cyber-crypt-com/encryptonize-core/client"
+)
+
+// Test that a shredded object can no longer be retrieved
+func TestStoreShredRetrieve(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	storeResponse, err := client.Store([]byte("foo"), []byte("bar"))
+	failOnError("Store operation failed", err, t)
+	oid := storeResponse.ObjectID
+
+	shredResponse, err := client.Shred(oid)
+	failOnError("Shred operation failed", err, t)
+
+	if shredResponse.Receipt.ObjectID != oid {
+		t.Fatalf("Expected receipt for %v but got %v", oid, shredResponse.Receipt.ObjectID)
+	}
+	if len(shredResponse.Receipt.Digest) != 32 {
+		t.Fatalf("Expected 32 byte digest but got %v", shredResponse.Receipt.Digest)
+	}
+
+	receiptResponse, err := client.GetErasureReceipt(oid)
+	failOnError("GetErasureReceipt operation failed", err, t)
+	if !reflect.DeepEqual(receiptResponse.Receipt, shredResponse.Receipt) {
+		t.Fatalf("Fetched receipt %v doesn't match %v", receiptResponse.Receipt, shredResponse.Receipt)
+	}
+
+	verifyErasureReceipt(receiptResponse.Receipt, t)
+
+	_, err = client.Retrieve(oid)
+	failOnSuccess("Object should not be retrievable after shredding", err, t)
+
+	_, err = client.Shred(oid)
+	failOnSuccess("Object should not be shreddable twice", err, t)
+}
+
+// verifyErasureReceipt checks the receipt signature against the pinned public key of the receipt
+// signing key, as printed by `encryption-service receipt-signing-key`. Verification is skipped if
+// E2E_TEST_RECEIPT_PUBLIC_KEY is not set.
+func verifyErasureReceipt(receipt coreclient.ErasureReceipt, t *testing.T) {
+	encodedKey, ok := os.LookupEnv("E2E_TEST_RECEIPT_PUBLIC_KEY")
+	if !ok {
+		t.Log("E2E_TEST_RECEIPT_PUBLIC_KEY is not set, skipping receipt signature verification")
+		return
+	}
+	derKey, err := base64.StdEncoding.DecodeString(encodedKey)
+	failOnError("Could not decode receipt public key", err, t)
+	publicKey, err := x509.ParsePKIXPublicKey(derKey)
+	failOnError("Could not parse receipt public key", err, t)
+
+	// The receipt is signed over "erasure receipt" || key ID || object ID || time of shredding || digest
+	var keyID [4]byte
+	binary.BigEndian.PutUint32(keyID[:], receipt.KeyID)
+	objectID, err := hex.DecodeString(strings.ReplaceAll(receipt.ObjectID, "-", ""))
+	failOnError("Could not parse object ID", err, t)
+	var shreddedAt [8]byte
+	binary.BigEndian.PutUint64(shreddedAt[:], uint64(receipt.ShreddedAt.UnixNano()))
+	signed := append([]byte("erasure receipt"), keyID[:]...)
+	signed = append(signed, objectID...)
+	signed = append(signed, shreddedAt[:]...)
+	signed = append(signed, receipt.Digest...)
+	if !ed25519.Verify(publicKey.(ed25519.PublicKey), signed, receipt.Signature) {
+		t.Fatal("Erasure receipt signature invalid")
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpce2e
+
+import (
+	"testing"
+
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+
+	coreclient "github.com/cyber-crypt-com/encryptonize-core/client"
+)
+
+// Test that signatures can be verified with the exported public key
+func TestSignAndVerify(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	createSigningKeyResponse, err := client.CreateSigningKey()
+	failOnError("CreateSigningKey operation failed", err, t)
+	oid := createSigningKeyResponse.ObjectID
+
+	message := []byte("foo")
+	signResponse, err := client.Sign(oid, message)
+	failOnError("Sign operation failed", err, t)
+
+	verifyResponse, err := client.VerifySignature(oid, message, signResponse.Signature)
+	failOnError("VerifySignature operation failed", err, t)
+	if !verifyResponse.Valid {
+		t.Fatalf("Valid signature was rejected")
+	}
+
+	publicKeyResponse, err := client.GetPublicKey(oid, coreclient.PublicKeyFormatPEM)
+	failOnError("GetPublicKey operation failed", err, t)
+
+	block, _ := pem.Decode([]byte(publicKeyResponse.PublicKey))
+	if block == nil {
+		t.Fatalf("Public key is not PEM encoded: %s", publicKeyResponse.PublicKey)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	failOnError("Could not parse public key", err, t)
+
+	ed25519PublicKey, ok := publicKey.(ed25519.PublicKey)
+	if !ok {
+		t.Fatalf("Expected Ed25519 public key but got %T", publicKey)
+	}
+	if !ed25519.Verify(ed25519PublicKey, message, signResponse.Signature) {
+		t.Fatalf("Signature does not verify under the exported public key")
+	}
+}
+
+// Test that only members of a group with access to the signing key can sign
+func TestSignGroupPermissions(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	createUserResponse, err := client.CreateUser(protoUserScopes)
+	failOnError("Create user request failed", err, t)
+	uid2 := createUserResponse.UserID
+	pwd2 := createUserResponse.Password
+
+	createGroupResponse, err := client.CreateGroup(protoUserScopes)
+	failOnError("Create group request failed", err, t)
+	gid := createGroupResponse.GroupID
+
+	err = client.AddUserToGroup(uid2, gid)
+	failOnError("Add user to group request failed", err, t)
+
+	createSigningKeyResponse, err := client.CreateSigningKey()
+	failOnError("CreateSigningKey operation failed", err, t)
+	oid := createSigningKeyResponse.ObjectID
+
+	message := []byte("foo")
+
+	err = client.LoginUser(uid2, pwd2)
+	failOnError("Could not log in user", err, t)
+	_, err = client.Sign(oid, message)
+	failOnSuccess("Unauthorized user should not be able to sign", err, t)
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+	err = client.AddPermission(oid, gid)
+	failOnError("Add permission request failed", err, t)
+
+	err = client.LoginUser(uid2, pwd2)
+	failOnError("Could not log in user", err, t)
+	_, err = client.Sign(oid, message)
+	failOnError("Sign operation failed", err, t)
+}
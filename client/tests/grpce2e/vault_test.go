@@ -0,0 +1,107 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package grpce2e
+
+import (
+	"testing"
+
+	"context"
+
+	coreclient "github.com/cyber-crypt-com/encryptonize-core/client"
+)
+
+// Test that values can be tokenized and resolved, and that unique tokens are reused
+func TestTokenizeAndDetokenize(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	createNamespaceResponse, err := client.CreateNamespace()
+	failOnError("CreateNamespace operation failed", err, t)
+	oid := createNamespaceResponse.ObjectID
+
+	plaintext := []byte("4111111111111111")
+	tokenizeResponse, err := client.Tokenize(oid, plaintext, "0123456789", 16, true)
+	failOnError("Tokenize operation failed", err, t)
+	if len(tokenizeResponse.Token) != 16 {
+		t.Fatalf("Token %s does not have the requested length", tokenizeResponse.Token)
+	}
+
+	detokenizeResponse, err := client.Detokenize(oid, tokenizeResponse.Token)
+	failOnError("Detokenize operation failed", err, t)
+	if string(detokenizeResponse.Plaintext) != string(plaintext) {
+		t.Fatalf("Expected plaintext %s but got %s", plaintext, detokenizeResponse.Plaintext)
+	}
+
+	uniqueResponse, err := client.Tokenize(oid, plaintext, "0123456789", 16, true)
+	failOnError("Tokenize operation failed", err, t)
+	if uniqueResponse.Token != tokenizeResponse.Token {
+		t.Fatalf("Unique tokenization gave different tokens %s and %s", tokenizeResponse.Token, uniqueResponse.Token)
+	}
+
+	otherResponse, err := client.Tokenize(oid, plaintext, "0123456789", 16, false)
+	failOnError("Tokenize operation failed", err, t)
+	if otherResponse.Token == tokenizeResponse.Token {
+		t.Fatalf("Non-unique tokenization reused the token %s", tokenizeResponse.Token)
+	}
+
+	_, err = client.Detokenize(oid, "0000000000000000")
+	failOnSuccess("Unknown token should not be resolved", err, t)
+}
+
+// Test that only users with access to the namespace can detokenize
+func TestTokenizePermissions(t *testing.T) {
+	client, err := coreclient.NewClient(context.Background(), endpoint, certPath)
+	failOnError("Could not create client", err, t)
+	defer client.Close()
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+
+	createUserResponse, err := client.CreateUser(protoUserScopes)
+	failOnError("Create user request failed", err, t)
+	uid2 := createUserResponse.UserID
+	pwd2 := createUserResponse.Password
+
+	createNamespaceResponse, err := client.CreateNamespace()
+	failOnError("CreateNamespace operation failed", err, t)
+	oid := createNamespaceResponse.ObjectID
+
+	plaintext := []byte("foo")
+	tokenizeResponse, err := client.Tokenize(oid, plaintext, "", 0, false)
+	failOnError("Tokenize operation failed", err, t)
+
+	err = client.LoginUser(uid2, pwd2)
+	failOnError("Could not log in user", err, t)
+	_, err = client.Detokenize(oid, tokenizeResponse.Token)
+	failOnSuccess("Unauthorized user should not be able to detokenize", err, t)
+
+	err = client.LoginUser(uid, pwd)
+	failOnError("Could not log in user", err, t)
+	err = client.AddPermission(oid, uid2)
+	failOnError("Add permission request failed", err, t)
+
+	err = client.LoginUser(uid2, pwd2)
+	failOnError("Could not log in user", err, t)
+	detokenizeResponse, err := client.Detokenize(oid, tokenizeResponse.Token)
+	failOnError("Detokenize operation failed", err, t)
+	if string(detokenizeResponse.Plaintext) != string(plaintext) {
+		t.Fatalf("Expected plaintext %s but got %s", plaintext, detokenizeResponse.Plaintext)
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build pkcs11
+// +build pkcs11
+
+package buildtags
+
+import (
+	"context"
+
+	"encryption-service/config"
+	"encryption-service/impl/crypt"
+	"encryption-service/interfaces"
+	log "encryption-service/logger"
+)
+
+func SetupKEKWrapper(ctx context.Context, keys config.Keys) (interfaces.KeyWrapperInterface, error) {
+	if keys.KEKProvider != config.KEKProviderPKCS11 {
+		log.Info(ctx, "Setup KEK wrapper")
+		return crypt.NewKWP(keys.KEK)
+	}
+
+	log.Info(ctx, "Setup KEK wrapper PKCS#11")
+	return crypt.NewPKCS11KeyWrapper(keys.PKCS11.Module, keys.PKCS11.TokenLabel, keys.PKCS11.Pin, keys.PKCS11.KeyLabel, keys.PKCS11.MaxSessions)
+}
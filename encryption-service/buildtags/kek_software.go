@@ -0,0 +1,37 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !pkcs11
+// +build !pkcs11
+
+package buildtags
+
+import (
+	"context"
+	"errors"
+
+	"encryption-service/config"
+	"encryption-service/impl/crypt"
+	"encryption-service/interfaces"
+	log "encryption-service/logger"
+)
+
+func SetupKEKWrapper(ctx context.Context, keys config.Keys) (interfaces.KeyWrapperInterface, error) {
+	if keys.KEKProvider == config.KEKProviderPKCS11 {
+		return nil, errors.New("built without PKCS#11 support, rebuild with the pkcs11 build tag")
+	}
+
+	log.Info(ctx, "Setup KEK wrapper")
+	return crypt.NewKWP(keys.KEK)
+}
@@ -14,13 +14,80 @@
 package common
 
 import (
+	"encoding/binary"
+	"errors"
+
 	"github.com/gofrs/uuid"
 )
 
+// InitialDataVersion is the data version of newly created objects. Objects created before the data
+// version was introduced have data version 0.
+const InitialDataVersion = 1
+
+// DefaultMaxEncryptionsPerKey is the default number of encryptions allowed under an object key. It
+// is the limit on invocations of AES-GCM with random nonces given in NIST SP 800-38D.
+const DefaultMaxEncryptionsPerKey uint64 = 1 << 32
+
+// ErrEncryptionLimitReached is returned when a key may not be used for further encryptions
+var ErrEncryptionLimitReached = errors.New("encryption limit of key reached")
+
+// ErrImportedKeyDeleted is returned when the imported key an object is encrypted under has been
+// deleted or shredded
+var ErrImportedKeyDeleted = errors.New("imported key has been deleted")
+
+// MaxEncryptionsPerKey returns the configured number of encryptions allowed under an object key, or
+// DefaultMaxEncryptionsPerKey if it is not configured
+func MaxEncryptionsPerKey(configured uint64) uint64 {
+	if configured == 0 {
+		return DefaultMaxEncryptionsPerKey
+	}
+	return configured
+}
+
+// KeyType records what the key of an access object is used for. A key must only be used by the
+// operations of its own type.
+type KeyType uint32
+
+const (
+	// KeyTypeUnspecified is the key type of objects created before key types were recorded. All of
+	// them are data keys.
+	KeyTypeUnspecified KeyType = iota
+	// KeyTypeData keys encrypt object data in the Storage and Enc services
+	KeyTypeData
+	// KeyTypeDataKey keys wrap data keys generated by `GenerateDataKey`
+	KeyTypeDataKey
+	// KeyTypeIngest keys are X25519 private keys used to ingest data and import keys
+	KeyTypeIngest
+	// KeyTypeImported keys are data keys imported with `ImportKey`
+	KeyTypeImported
+	// KeyTypeDeterministic keys are AES-SIV keys used for deterministic encryption
+	KeyTypeDeterministic
+	// KeyTypeMAC keys are HMAC keys
+	KeyTypeMAC
+	// KeyTypeSigning keys are Ed25519 private key seeds
+	KeyTypeSigning
+	// KeyTypeFPE keys are FF1 keys used for format preserving encryption
+	KeyTypeFPE
+	// KeyTypeTokenization keys are the keys of tokenization namespaces
+	KeyTypeTokenization
+)
+
 type AccessObject struct {
 	GroupIDs map[uuid.UUID]bool
 	Woek     []byte
+	KeyType  KeyType
 	Version  uint64
+	// DataVersion is incremented whenever the object data is replaced and is bound to the data
+	// ciphertext together with the object ID
+	DataVersion uint64
+	// Reencryptions counts how often the object data has been encrypted under the WOEK after the
+	// encryption that created it. It is reset whenever the object is re-keyed. Imported keys are not
+	// created by an encryption, so for them it counts every encryption under the key.
+	Reencryptions uint64
+	// KeyID is the object ID of the imported key the object data is encrypted under, if any. The key
+	// is only held by the access object of the imported key, which also counts the encryptions under
+	// it.
+	KeyID uuid.UUID
 }
 
 type ProtectedAccessObject struct {
@@ -29,13 +96,15 @@ type ProtectedAccessObject struct {
 	WrappedKey   []byte
 }
 
-// AccessObject instantiates a new Access Object with given groupID and WOEK.
-// A new object starts with Version: 0
-func NewAccessObject(groupID uuid.UUID, woek []byte) *AccessObject {
+// AccessObject instantiates a new Access Object with given groupID, key type and WOEK.
+// A new object starts with Version: 0 and DataVersion: InitialDataVersion
+func NewAccessObject(groupID uuid.UUID, keyType KeyType, woek []byte) *AccessObject {
 	return &AccessObject{
-		GroupIDs: map[uuid.UUID]bool{groupID: true},
-		Woek:     woek,
-		Version:  0,
+		GroupIDs:    map[uuid.UUID]bool{groupID: true},
+		Woek:        woek,
+		KeyType:     keyType,
+		Version:     0,
+		DataVersion: InitialDataVersion,
 	}
 }
 
@@ -64,3 +133,37 @@ func (a *AccessObject) GetGroups() map[uuid.UUID]bool {
 func (a *AccessObject) GetWOEK() []byte {
 	return a.Woek
 }
+
+// CountEncryption records an encryption under the key of the Access Object. Fails with
+// ErrEncryptionLimitReached if `limit` encryptions have already been counted.
+func (a *AccessObject) CountEncryption(limit uint64) error {
+	if a.Reencryptions >= limit {
+		return ErrEncryptionLimitReached
+	}
+	a.Reencryptions++
+	return nil
+}
+
+// HasKeyType returns whether the key of the Access Object may be used as a key of the given type.
+// Objects without a recorded key type are data keys.
+func (a *AccessObject) HasKeyType(keyType KeyType) bool {
+	if a.KeyType == KeyTypeUnspecified {
+		return keyType == KeyTypeData
+	}
+	return a.KeyType == keyType
+}
+
+// DataAAD returns the associated data used when encrypting the data of an object. It binds the
+// object ID and data version to the user provided associated data, such that ciphertexts can neither
+// be moved between objects nor replayed from an earlier version of the same object. Legacy objects
+// (data version 0) use the user provided associated data as is.
+func DataAAD(objectID uuid.UUID, dataVersion uint64, aad []byte) []byte {
+	if dataVersion == 0 {
+		return aad
+	}
+
+	bound := make([]byte, uuid.Size+8, uuid.Size+8+len(aad))
+	copy(bound, objectID.Bytes())
+	binary.BigEndian.PutUint64(bound[uuid.Size:], dataVersion)
+	return append(bound, aad...)
+}
@@ -14,6 +14,9 @@
 package common
 
 import (
+	"bytes"
+	"encoding/hex"
+	"errors"
 	"reflect"
 	"testing"
 
@@ -79,14 +82,16 @@ func TestNew(t *testing.T) {
 	groupID := uuid.Must(uuid.NewV4())
 	woek := []byte{1, 2, 3, 4}
 
-	accessObject := NewAccessObject(groupID, woek)
+	accessObject := NewAccessObject(groupID, KeyTypeMAC, woek)
 
 	expected := &AccessObject{
 		GroupIDs: map[uuid.UUID]bool{
 			groupID: true,
 		},
-		Woek:    woek,
-		Version: 0,
+		Woek:        woek,
+		KeyType:     KeyTypeMAC,
+		Version:     0,
+		DataVersion: InitialDataVersion,
 	}
 
 	if !reflect.DeepEqual(expected, accessObject) {
@@ -94,6 +99,40 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestCountEncryption(t *testing.T) {
+	accessObject := &AccessObject{}
+	for i := 0; i < 3; i++ {
+		if err := accessObject.CountEncryption(3); err != nil {
+			t.Fatalf("Encryption %d rejected: %v", i, err)
+		}
+	}
+	if err := accessObject.CountEncryption(3); !errors.Is(err, ErrEncryptionLimitReached) {
+		t.Fatalf("Expected ErrEncryptionLimitReached, got %v", err)
+	}
+	if accessObject.Reencryptions != 3 {
+		t.Fatalf("Expected 3 encryptions, got %d", accessObject.Reencryptions)
+	}
+}
+
+func TestHasKeyType(t *testing.T) {
+	signingKey := &AccessObject{KeyType: KeyTypeSigning}
+	if !signingKey.HasKeyType(KeyTypeSigning) {
+		t.Error("Signing key rejected as signing key")
+	}
+	if signingKey.HasKeyType(KeyTypeData) || signingKey.HasKeyType(KeyTypeMAC) {
+		t.Error("Signing key accepted as other key type")
+	}
+
+	// Objects created before key types were recorded are data keys
+	legacy := &AccessObject{}
+	if !legacy.HasKeyType(KeyTypeData) {
+		t.Error("Legacy object rejected as data key")
+	}
+	if legacy.HasKeyType(KeyTypeSigning) || legacy.HasKeyType(KeyTypeUnspecified) {
+		t.Error("Legacy object accepted as other key type")
+	}
+}
+
 //nolint: gosec
 func TestRemoveGroup(t *testing.T) {
 	for groupID := range accessObject.GroupIDs {
@@ -104,3 +143,18 @@ func TestRemoveGroup(t *testing.T) {
 		}
 	}
 }
+
+func TestDataAAD(t *testing.T) {
+	objectID := uuid.Must(uuid.FromString("10000000-0000-0000-0000-000000000000"))
+	aad := []byte{0xaa, 0xbb}
+
+	expected, _ := hex.DecodeString("100000000000000000000000000000000000000000000002aabb")
+	if bound := DataAAD(objectID, 2, aad); !bytes.Equal(expected, bound) {
+		t.Errorf("DataAAD mismatch: %x != %x", expected, bound)
+	}
+
+	// Legacy objects use the associated data as is
+	if bound := DataAAD(objectID, 0, aad); !bytes.Equal(aad, bound) {
+		t.Errorf("DataAAD mismatch for legacy object: %x != %x", aad, bound)
+	}
+}
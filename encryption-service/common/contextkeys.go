@@ -24,4 +24,8 @@ const (
 	ObjectIDCtxKey
 	TargetIDCtxKey
 	AccessObjectCtxKey
+	// Access object of the key referenced by a request creating a new object
+	KeyAccessObjectCtxKey
+	// Erasure receipt of a shredded object, which has no access object
+	ErasureReceiptCtxKey
 )
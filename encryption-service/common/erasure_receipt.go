@@ -0,0 +1,91 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// ErasureReceipt records that the access object of an object, and with it the key of the object,
+// was destroyed
+type ErasureReceipt struct {
+	ObjectID   uuid.UUID
+	ShreddedAt time.Time
+	// Digest commits to the destroyed access object, see `AccessObjectDigest`
+	Digest []byte
+	// KeyID identifies the receipt signing key that produced `Signature`
+	KeyID uint32
+	// Signature of `SignedData` by the receipt signing key of the service
+	Signature []byte
+	// GroupIDs are the groups that had access to the object when it was shredded. They are used to
+	// authorize access to the receipt and are not covered by the signature.
+	GroupIDs map[uuid.UUID]bool
+}
+
+type ProtectedErasureReceipt struct {
+	ObjectID   uuid.UUID
+	Receipt    []byte
+	WrappedKey []byte
+}
+
+// NewErasureReceipt creates a receipt for the destruction of a protected access object, which gave
+// the groups `groupIDs` access to the object
+func NewErasureReceipt(protected *ProtectedAccessObject, groupIDs map[uuid.UUID]bool, shreddedAt time.Time) *ErasureReceipt {
+	return &ErasureReceipt{
+		ObjectID:   protected.ObjectID,
+		ShreddedAt: shreddedAt,
+		Digest:     AccessObjectDigest(protected),
+		GroupIDs:   groupIDs,
+	}
+}
+
+// SignedData returns the data covered by the receipt signature: the string "erasure receipt",
+// followed by the key ID as a 4 byte big endian integer, the object ID, the time of shredding in
+// nanoseconds since the Unix epoch as an 8 byte big endian integer, and the digest.
+func (e *ErasureReceipt) SignedData() []byte {
+	var keyID [4]byte
+	binary.BigEndian.PutUint32(keyID[:], e.KeyID)
+	data := append([]byte("erasure receipt"), keyID[:]...)
+	data = append(data, e.ObjectID.Bytes()...)
+	var shreddedAt [8]byte
+	binary.BigEndian.PutUint64(shreddedAt[:], uint64(e.ShreddedAt.UnixNano()))
+	data = append(data, shreddedAt[:]...)
+	return append(data, e.Digest...)
+}
+
+// AccessObjectDigest returns the SHA-256 hash of the object ID, followed by the length prefixed
+// access object ciphertext and wrapped key as stored in the Auth Storage. Lengths are encoded as 8
+// byte big endian integers. The digest can be compared against a backup of the Auth Storage to
+// verify which access object was destroyed.
+func AccessObjectDigest(protected *ProtectedAccessObject) []byte {
+	hash := sha256.New()
+	hash.Write(protected.ObjectID.Bytes())
+	for _, field := range [][]byte{protected.AccessObject, protected.WrappedKey} {
+		var length [8]byte
+		binary.BigEndian.PutUint64(length[:], uint64(len(field)))
+		hash.Write(length[:])
+		hash.Write(field)
+	}
+	return hash.Sum(nil)
+}
+
+// ErasureReceiptAAD returns the associated data used when encrypting the erasure receipt of an
+// object. It differs from the associated data of access objects, which are protected by the same key.
+func ErasureReceiptAAD(objectID uuid.UUID) []byte {
+	return append([]byte("erasure receipt"), objectID.Bytes()...)
+}
@@ -0,0 +1,223 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Schema versions of the records written by this version of the service. Records with a newer schema
+// version are rejected.
+const (
+	userRecordSchemaVersion         = 1
+	groupRecordSchemaVersion        = 1
+	accessObjectRecordSchemaVersion = 1
+	erasureReceiptSchemaVersion     = 1
+	// AccessTokenRecordSchemaVersion is exported as access tokens are implemented outside this package
+	AccessTokenRecordSchemaVersion = 1
+)
+
+// MarshalRecord serializes the user data as a UserRecord
+func (u *UserData) MarshalRecord() ([]byte, error) {
+	return proto.Marshal(&UserRecord{
+		SchemaVersion:  userRecordSchemaVersion,
+		HashedPassword: u.HashedPassword,
+		Salt:           u.Salt,
+		GroupIds:       marshalUUIDSet(u.GroupIDs),
+	})
+}
+
+// UnmarshalRecord deserializes user data from a UserRecord
+func (u *UserData) UnmarshalRecord(data []byte) error {
+	record := &UserRecord{}
+	if err := UnmarshalVersionedRecord(data, record, record.GetSchemaVersion, userRecordSchemaVersion); err != nil {
+		return err
+	}
+
+	groupIDs, err := unmarshalUUIDSet(record.GroupIds)
+	if err != nil {
+		return err
+	}
+
+	*u = UserData{
+		HashedPassword: record.HashedPassword,
+		Salt:           record.Salt,
+		GroupIDs:       groupIDs,
+	}
+	return nil
+}
+
+// MarshalRecord serializes the group data as a GroupRecord
+func (g *GroupData) MarshalRecord() ([]byte, error) {
+	return proto.Marshal(&GroupRecord{
+		SchemaVersion: groupRecordSchemaVersion,
+		Scopes:        uint64(g.Scopes),
+	})
+}
+
+// UnmarshalRecord deserializes group data from a GroupRecord
+func (g *GroupData) UnmarshalRecord(data []byte) error {
+	record := &GroupRecord{}
+	if err := UnmarshalVersionedRecord(data, record, record.GetSchemaVersion, groupRecordSchemaVersion); err != nil {
+		return err
+	}
+
+	*g = GroupData{
+		Scopes: ScopeType(record.Scopes),
+	}
+	return nil
+}
+
+// MarshalRecord serializes the access object as an AccessObjectRecord
+func (a *AccessObject) MarshalRecord() ([]byte, error) {
+	return proto.Marshal(&AccessObjectRecord{
+		SchemaVersion: accessObjectRecordSchemaVersion,
+		GroupIds:      marshalUUIDSet(a.GroupIDs),
+		Woek:          a.Woek,
+		KeyType:       uint32(a.KeyType),
+		Version:       a.Version,
+		DataVersion:   a.DataVersion,
+		Reencryptions: a.Reencryptions,
+		KeyId:         marshalOptionalUUID(a.KeyID),
+	})
+}
+
+// UnmarshalRecord deserializes an access object from an AccessObjectRecord
+func (a *AccessObject) UnmarshalRecord(data []byte) error {
+	record := &AccessObjectRecord{}
+	if err := UnmarshalVersionedRecord(data, record, record.GetSchemaVersion, accessObjectRecordSchemaVersion); err != nil {
+		return err
+	}
+
+	groupIDs, err := unmarshalUUIDSet(record.GroupIds)
+	if err != nil {
+		return err
+	}
+
+	keyID, err := unmarshalOptionalUUID(record.KeyId)
+	if err != nil {
+		return err
+	}
+
+	*a = AccessObject{
+		GroupIDs:      groupIDs,
+		Woek:          record.Woek,
+		KeyType:       KeyType(record.KeyType),
+		Version:       record.Version,
+		DataVersion:   record.DataVersion,
+		Reencryptions: record.Reencryptions,
+		KeyID:         keyID,
+	}
+	return nil
+}
+
+// MarshalRecord serializes the erasure receipt as an ErasureReceiptRecord
+func (e *ErasureReceipt) MarshalRecord() ([]byte, error) {
+	return proto.Marshal(&ErasureReceiptRecord{
+		SchemaVersion: erasureReceiptSchemaVersion,
+		ObjectId:      e.ObjectID.Bytes(),
+		ShreddedAt:    timestamppb.New(e.ShreddedAt),
+		Digest:        e.Digest,
+		Signature:     e.Signature,
+		KeyId:         e.KeyID,
+		GroupIds:      marshalUUIDSet(e.GroupIDs),
+	})
+}
+
+// UnmarshalRecord deserializes an erasure receipt from an ErasureReceiptRecord
+func (e *ErasureReceipt) UnmarshalRecord(data []byte) error {
+	record := &ErasureReceiptRecord{}
+	if err := UnmarshalVersionedRecord(data, record, record.GetSchemaVersion, erasureReceiptSchemaVersion); err != nil {
+		return err
+	}
+
+	objectID, err := uuid.FromBytes(record.ObjectId)
+	if err != nil {
+		return err
+	}
+
+	groupIDs, err := unmarshalUUIDSet(record.GroupIds)
+	if err != nil {
+		return err
+	}
+
+	*e = ErasureReceipt{
+		ObjectID:   objectID,
+		ShreddedAt: record.ShreddedAt.AsTime().Local(),
+		Digest:     record.Digest,
+		KeyID:      record.KeyId,
+		Signature:  record.Signature,
+		GroupIDs:   groupIDs,
+	}
+	return nil
+}
+
+// UnmarshalVersionedRecord deserializes a protobuf record and checks that its schema version, as
+// returned by `schemaVersion`, is supported
+func UnmarshalVersionedRecord(data []byte, record proto.Message, schemaVersion func() uint32, supportedVersion uint32) error {
+	if err := proto.Unmarshal(data, record); err != nil {
+		return err
+	}
+
+	version := schemaVersion()
+	if version == 0 || version > supportedVersion {
+		return fmt.Errorf("unsupported schema version %d of %s", version, record.ProtoReflect().Descriptor().Name())
+	}
+	return nil
+}
+
+// marshalUUIDSet serializes a set of UUIDs in ascending order
+func marshalUUIDSet(set map[uuid.UUID]bool) [][]byte {
+	ids := make([][]byte, 0, len(set))
+	for id := range set {
+		ids = append(ids, id.Bytes())
+	}
+	sort.Slice(ids, func(i, j int) bool { return bytes.Compare(ids[i], ids[j]) < 0 })
+	return ids
+}
+
+// unmarshalUUIDSet deserializes a set of UUIDs
+func unmarshalUUIDSet(ids [][]byte) (map[uuid.UUID]bool, error) {
+	set := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		parsed, err := uuid.FromBytes(id)
+		if err != nil {
+			return nil, err
+		}
+		set[parsed] = true
+	}
+	return set, nil
+}
+
+// marshalOptionalUUID serializes a UUID that is unset if nil
+func marshalOptionalUUID(id uuid.UUID) []byte {
+	if id == uuid.Nil {
+		return nil
+	}
+	return id.Bytes()
+}
+
+// unmarshalOptionalUUID deserializes a UUID serialized by marshalOptionalUUID
+func unmarshalOptionalUUID(id []byte) (uuid.UUID, error) {
+	if len(id) == 0 {
+		return uuid.Nil, nil
+	}
+	return uuid.FromBytes(id)
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package common
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestRecordRoundTrip(t *testing.T) {
+	groupID := uuid.Must(uuid.FromString("10000000-0000-0000-0000-000000000000"))
+	otherGroupID := uuid.Must(uuid.FromString("20000000-0000-0000-0000-000000000000"))
+
+	records := map[string]struct {
+		record  interface{ MarshalRecord() ([]byte, error) }
+		decoded interface{ UnmarshalRecord([]byte) error }
+	}{
+		"user": {
+			&UserData{
+				HashedPassword: []byte("hash"),
+				Salt:           []byte("salt"),
+				GroupIDs:       map[uuid.UUID]bool{groupID: true, otherGroupID: true},
+			},
+			&UserData{},
+		},
+		"group": {
+			&GroupData{Scopes: ScopeRead | ScopeUpdate},
+			&GroupData{},
+		},
+		"access object": {
+			&AccessObject{
+				GroupIDs:      map[uuid.UUID]bool{groupID: true},
+				Woek:          []byte("woek"),
+				KeyType:       KeyTypeSigning,
+				Version:       3,
+				DataVersion:   2,
+				Reencryptions: 7,
+				KeyID:         otherGroupID,
+			},
+			&AccessObject{},
+		},
+		"erasure receipt": {
+			&ErasureReceipt{
+				ObjectID:   otherGroupID,
+				ShreddedAt: time.Unix(1600000000, 500),
+				Digest:     []byte("digest"),
+				KeyID:      4,
+				Signature:  []byte("signature"),
+				GroupIDs:   map[uuid.UUID]bool{groupID: true, otherGroupID: true},
+			},
+			&ErasureReceipt{},
+		},
+	}
+
+	for name, test := range records {
+		t.Run(name, func(t *testing.T) {
+			data, err := test.record.MarshalRecord()
+			if err != nil {
+				t.Fatalf("MarshalRecord failed: %v", err)
+			}
+			if err := test.decoded.UnmarshalRecord(data); err != nil {
+				t.Fatalf("UnmarshalRecord failed: %v", err)
+			}
+			if !reflect.DeepEqual(test.record, test.decoded) {
+				t.Fatalf("Decoded record doesn't match: %v != %v", test.record, test.decoded)
+			}
+		})
+	}
+}
+
+func TestRecordEncoding(t *testing.T) {
+	// The encoding must stay stable, as records are read by other tools
+	groupData := &GroupData{Scopes: ScopeRead | ScopeCreate}
+	data, err := groupData.MarshalRecord()
+	if err != nil {
+		t.Fatalf("MarshalRecord failed: %v", err)
+	}
+	if hex.EncodeToString(data) != "08011003" {
+		t.Fatalf("Unexpected encoding: %x", data)
+	}
+}
+
+func TestRecordUnsupportedSchemaVersion(t *testing.T) {
+	for _, version := range []uint32{0, groupRecordSchemaVersion + 1} {
+		data, err := proto.Marshal(&GroupRecord{SchemaVersion: version, Scopes: uint64(ScopeRead)})
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+
+		if err := (&GroupData{}).UnmarshalRecord(data); err == nil {
+			t.Errorf("UnmarshalRecord accepted schema version %d", version)
+		}
+	}
+}
+
+func TestRecordInvalidUUID(t *testing.T) {
+	data, err := proto.Marshal(&AccessObjectRecord{
+		SchemaVersion: accessObjectRecordSchemaVersion,
+		GroupIds:      [][]byte{[]byte("not a uuid")},
+	})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	if err := (&AccessObject{}).UnmarshalRecord(data); err == nil {
+		t.Error("UnmarshalRecord accepted invalid group ID")
+	}
+}
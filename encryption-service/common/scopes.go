@@ -30,6 +30,7 @@ const (
 	ScopeIndex
 	ScopeObjectPermissions
 	ScopeUserManagement
+	ScopeDeterministic
 	ScopeEnd
 )
 
@@ -38,6 +39,10 @@ const baseStoragePath string = "/storage.Encryptonize/"
 const baseAuthPath string = "/authn.Encryptonize/"
 const baseAuthzPath string = "/authz.Encryptonize/"
 const baseEncPath string = "/enc.Encryptonize/"
+const baseMACPath string = "/mac.Encryptonize/"
+const baseSignPath string = "/sign.Encryptonize/"
+const baseFPEPath string = "/fpe.Encryptonize/"
+const baseVaultPath string = "/vault.Encryptonize/"
 
 var MethodScopeMap = map[string]ScopeType{
 	baseAuthPath + "CreateUser":          ScopeUserManagement,
@@ -48,13 +53,41 @@ var MethodScopeMap = map[string]ScopeType{
 	baseAuthzPath + "GetPermissions":     ScopeIndex,
 	baseAuthzPath + "AddPermission":      ScopeObjectPermissions,
 	baseAuthzPath + "RemovePermission":   ScopeObjectPermissions,
+	baseAuthzPath + "Shred":              ScopeDelete,
+	baseAuthzPath + "GetErasureReceipt":  ScopeDelete,
 	baseStoragePath + "Store":            ScopeCreate,
 	baseStoragePath + "Update":           ScopeUpdate,
 	baseStoragePath + "Retrieve":         ScopeRead,
 	baseStoragePath + "Delete":           ScopeDelete,
+	baseStoragePath + "Rekey":            ScopeUpdate,
 	baseEncPath + "Encrypt":              ScopeCreate,
 	baseEncPath + "Decrypt":              ScopeRead,
+	baseEncPath + "GenerateDataKey":      ScopeCreate,
+	baseEncPath + "DecryptDataKey":       ScopeRead,
+	baseEncPath + "CreateIngestKey":      ScopeCreate,
+	baseEncPath + "GetIngestPublicKey":   ScopeRead,
+	baseEncPath + "Ingest":               ScopeCreate,
+	baseEncPath + "ImportKey":            ScopeCreate,
+	baseEncPath + "EncryptWithKey":       ScopeUpdate,
+	baseMACPath + "CreateKey":            ScopeCreate,
+	baseMACPath + "Tag":                  ScopeCreate,
+	baseMACPath + "Verify":               ScopeRead,
+	baseSignPath + "CreateSigningKey":    ScopeCreate,
+	baseSignPath + "Sign":                ScopeCreate,
+	baseSignPath + "Verify":              ScopeRead,
+	baseSignPath + "GetPublicKey":        ScopeRead,
+	baseFPEPath + "CreateKey":            ScopeCreate,
+	baseFPEPath + "Tokenize":             ScopeCreate,
+	baseFPEPath + "Detokenize":           ScopeRead,
+	baseVaultPath + "CreateNamespace":    ScopeCreate,
+	baseVaultPath + "Tokenize":           ScopeCreate,
+	baseVaultPath + "Detokenize":         ScopeRead,
 	baseAppPath + "Version":              ScopeNone,
+
+	// Deterministic encryption leaks equality of plaintexts, so it requires an explicit scope
+	baseEncPath + "CreateDeterministicKey": ScopeCreate | ScopeDeterministic,
+	baseEncPath + "EncryptDeterministic":   ScopeDeterministic,
+	baseEncPath + "DecryptDeterministic":   ScopeRead,
 }
 
 // IsValid checks if the given scope is one of the defined scopes
@@ -94,6 +127,8 @@ func MapScopesToScopeType(protoScopes []Scope) (ScopeType, error) {
 			scopes |= ScopeObjectPermissions
 		case Scope_USERMANAGEMENT:
 			scopes |= ScopeUserManagement
+		case Scope_DETERMINISTIC:
+			scopes |= ScopeDeterministic
 		default:
 			return 0, fmt.Errorf("Invalid scope %v", scopes)
 		}
@@ -120,6 +155,8 @@ func MapStringToScopes(scopesString string) ([]Scope, error) {
 			scopes = append(scopes, Scope_OBJECTPERMISSIONS)
 		case "m":
 			scopes = append(scopes, Scope_USERMANAGEMENT)
+		case "e":
+			scopes = append(scopes, Scope_DETERMINISTIC)
 		default:
 			return nil, fmt.Errorf("Invalid scope %v", string(scope))
 		}
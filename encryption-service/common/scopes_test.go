@@ -25,6 +25,7 @@ func TestIsValid(t *testing.T) {
 		ScopeIndex,
 		ScopeObjectPermissions,
 		ScopeUserManagement,
+		ScopeDeterministic,
 	}
 
 	for _, scope := range validScopes {
@@ -42,7 +43,7 @@ func TestIsNotValid(t *testing.T) {
 }
 
 func TestMapScopes(t *testing.T) {
-	scopesString := "rcudiom"
+	scopesString := "rcudiome"
 
 	protoScopes, err := MapStringToScopes(scopesString)
 	if err != nil {
@@ -73,6 +74,9 @@ func TestMapScopes(t *testing.T) {
 	if !scopes.HasScopes(ScopeUserManagement) {
 		t.Error("Expected HasScopes to have ScopeUserManagement")
 	}
+	if !scopes.HasScopes(ScopeDeterministic) {
+		t.Error("Expected HasScopes to have ScopeDeterministic")
+	}
 }
 
 func TestMapMissingScope(t *testing.T) {
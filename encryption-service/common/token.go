@@ -0,0 +1,36 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package common
+
+import (
+	"github.com/gofrs/uuid"
+)
+
+// ProtectedToken is a surrogate token of a tokenization namespace together with the encrypted value
+// it stands for
+type ProtectedToken struct {
+	Namespace uuid.UUID
+	Token     string
+	// ValueTag is a MAC of the value under a key of the namespace. It is only set for tokens that are
+	// unique for their value, and is used to find the existing token of a value.
+	ValueTag []byte
+	Value    []byte
+}
+
+// TokenAAD returns the associated data used when encrypting the value of a token. It binds the value
+// to the token and its namespace.
+func TokenAAD(namespace uuid.UUID, token string) []byte {
+	aad := append([]byte("token"), namespace.Bytes()...)
+	return append(aad, token...)
+}
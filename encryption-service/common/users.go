@@ -20,9 +20,11 @@ import (
 )
 
 type UserData struct {
+	// Self-describing password hash, or a raw PBKDF2 hash for users created before Argon2id
 	HashedPassword []byte
-	Salt           []byte
-	GroupIDs       map[uuid.UUID]bool
+	// Salt of a raw PBKDF2 hash, unused for self-describing hashes
+	Salt     []byte
+	GroupIDs map[uuid.UUID]bool
 }
 
 type ProtectedUserData struct {
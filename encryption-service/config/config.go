@@ -16,10 +16,14 @@ package config
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 
 	"github.com/knadh/koanf"
@@ -28,6 +32,7 @@ import (
 	"github.com/knadh/koanf/parsers/yaml"
 	"github.com/knadh/koanf/providers/env"
 	"github.com/knadh/koanf/providers/file"
+	"golang.org/x/crypto/hkdf"
 
 	log "encryption-service/logger"
 )
@@ -37,23 +42,112 @@ type Config struct {
 	AuthStorage   AuthStorage   `koanf:"authstorage"`
 	ObjectStorage ObjectStorage `koanf:"objectstorage"`
 	Features      Features      `koanf:"features"`
+	Crypto        Crypto        `koanf:"crypto"`
 }
 
 type Keys struct {
+	// Optional path to a sealed key file. If set, all other keys are read from the key file once it
+	// is unsealed and must not be set here.
+	SealedKeyFile string `koanf:"sealedkeyfile" json:"-"`
+
+	// Optional path to a key file protected by a passphrase. If set, all other keys are read from
+	// the key file and must not be set here. The passphrase is read from `PassphraseFile` or from
+	// the file descriptor `PassphraseFD`.
+	KeyFile        string `koanf:"keyfile" json:"-"`
+	PassphraseFile string `koanf:"passphrasefile" json:"-"`
+	PassphraseFD   int    `koanf:"passphrasefd" json:"-"`
+
+	// Optional master key. If set, all other keys are derived from it and must not be set
+	// explicitly.
+	Master []byte `koanf:"master"`
+
+	// Key ID of the master key. Used as key ID of all derived keys.
+	MasterID uint32 `koanf:"masterid"`
+
+	// Previous master keys indexed by key ID. Retired keys are derived from these.
+	RetiredMasters map[uint32][]byte `koanf:"retiredmasters"`
+
 	// Used for key wrapping
 	KEK []byte `koanf:"kek"`
 
+	// Where the KEK is kept: "software" (default) uses `kek`, "pkcs11" uses a key in a PKCS#11
+	// token configured in `pkcs11`. In that case `kek` must not be set.
+	KEKProvider string `koanf:"kekprovider"`
+
+	// PKCS#11 token holding the KEK
+	PKCS11 PKCS11 `koanf:"pkcs11"`
+
+	// Key ID of the KEK. Must be changed whenever the KEK is rotated.
+	KEKID uint32 `koanf:"kekid"`
+
+	// Previous KEKs indexed by key ID. Needed to unwrap keys until `rotate-kek` has been run.
+	RetiredKEKs map[uint32][]byte `koanf:"retiredkeks"`
+
 	// Used for access object encryption
 	AEK []byte `koanf:"aek"`
 
+	// Key ID of the AEK. Must be changed whenever the AEK is rotated.
+	AEKID uint32 `koanf:"aekid"`
+
+	// Previous AEKs indexed by key ID. Needed until `rotate-auth-keys` has been run.
+	RetiredAEKs map[uint32][]byte `koanf:"retiredaeks"`
+
 	// Used for token encryption
 	TEK []byte `koanf:"tek"`
 
+	// Key ID of the TEK. Must be changed whenever the TEK is rotated.
+	TEKID uint32 `koanf:"tekid"`
+
+	// Previous TEKs indexed by key ID. Needed until all tokens issued under them have expired.
+	RetiredTEKs map[uint32][]byte `koanf:"retiredteks"`
+
 	// Used for confidential user data encryption
 	UEK []byte `koanf:"uek"`
 
+	// Key ID of the UEK. Must be changed whenever the UEK is rotated.
+	UEKID uint32 `koanf:"uekid"`
+
+	// Previous UEKs indexed by key ID. Needed until `rotate-auth-keys` has been run.
+	RetiredUEKs map[uint32][]byte `koanf:"retiredueks"`
+
 	// Used for confidential group data encryption
 	GEK []byte `koanf:"gek"`
+
+	// Key ID of the GEK. Must be changed whenever the GEK is rotated.
+	GEKID uint32 `koanf:"gekid"`
+
+	// Previous GEKs indexed by key ID. Needed until `rotate-auth-keys` has been run.
+	RetiredGEKs map[uint32][]byte `koanf:"retiredgeks"`
+
+	// Ed25519 private key seed used to sign erasure receipts. It is independent of the other keys,
+	// such that verifiers can pin its public key.
+	ReceiptSigningKey []byte `koanf:"receiptsigningkey"`
+
+	// Key ID of the receipt signing key, recorded in every erasure receipt. Must be changed whenever
+	// the receipt signing key is changed.
+	ReceiptSigningKeyID uint32 `koanf:"receiptsigningkeyid"`
+}
+
+const (
+	KEKProviderSoftware = "software"
+	KEKProviderPKCS11   = "pkcs11"
+)
+
+type PKCS11 struct {
+	// Path to the PKCS#11 module (shared library) of the token
+	Module string `koanf:"module"`
+
+	// Label of the token holding the KEK
+	TokenLabel string `koanf:"tokenlabel"`
+
+	// User PIN of the token
+	Pin string `koanf:"pin"`
+
+	// Label of the AES key in the token used as KEK
+	KeyLabel string `koanf:"keylabel"`
+
+	// Maximum number of sessions opened on the token. Defaults to 16 if zero.
+	MaxSessions int `koanf:"maxsessions"`
 }
 
 type AuthStorage struct {
@@ -100,6 +194,22 @@ type Features struct {
 	StorageService    bool `koanf:"storageservice"`
 }
 
+// Supported ciphers
+const (
+	CipherAES256GCM         = "aes-256-gcm"
+	CipherXChaCha20Poly1305 = "xchacha20-poly1305"
+)
+
+type Crypto struct {
+	// Cipher used to encrypt data: "aes-256-gcm" (default) or "xchacha20-poly1305". Data encrypted
+	// with either cipher can always be decrypted.
+	Cipher string `koanf:"cipher"`
+	// Number of encryptions under an object key after which updating the object rolls it over to a
+	// new key, and after which an imported key can no longer be used for encryption. Defaults to 2^32
+	// if zero.
+	MaxEncryptionsPerKey uint64 `koanf:"maxencryptionsperkey"`
+}
+
 func ParseConfig() (*Config, error) {
 	config := Config{}
 	err := LoadConfig(&config)
@@ -155,7 +265,23 @@ func LoadConfig(config interface{}) error {
 
 func (c *Config) ParseConfig() error {
 	// Process subconfigurations
-	if err := c.Keys.ParseConfig(); err != nil {
+	if err := c.Crypto.ParseConfig(); err != nil {
+		return err
+	}
+	if c.Keys.SealedKeyFile != "" {
+		// Keys are parsed once they are unsealed
+		if !reflect.DeepEqual(c.Keys, Keys{SealedKeyFile: c.Keys.SealedKeyFile}) {
+			return errors.New("keys must not be set when using a sealed key file")
+		}
+		return nil
+	}
+	if c.Keys.KeyFile != "" {
+		keys, err := c.Keys.readKeyFile()
+		if err != nil {
+			return err
+		}
+		c.Keys = *keys
+	} else if err := c.Keys.ParseConfig(); err != nil {
 		return err
 	}
 	c.Keys.CheckInsecure()
@@ -163,15 +289,97 @@ func (c *Config) ParseConfig() error {
 	return nil
 }
 
+// ParseConfig checks that the configured cipher is supported
+func (c *Crypto) ParseConfig() error {
+	switch c.Cipher {
+	case "", CipherAES256GCM, CipherXChaCha20Poly1305:
+		return nil
+	default:
+		return fmt.Errorf("unsupported cipher %v", c.Cipher)
+	}
+}
+
+// readKeyFile reads the keys from a key file protected by a passphrase
+func (k *Keys) readKeyFile() (*Keys, error) {
+	if !reflect.DeepEqual(*k, Keys{KeyFile: k.KeyFile, PassphraseFile: k.PassphraseFile, PassphraseFD: k.PassphraseFD}) {
+		return nil, errors.New("keys must not be set when using a key file")
+	}
+
+	passphrase, err := k.ReadPassphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	keyFile, err := ReadKeyFile(k.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return keyFile.OpenWithPassphrase(passphrase)
+}
+
+// ReadPassphrase reads the key file passphrase from the configured file or file descriptor. A
+// trailing newline is removed.
+func (k *Keys) ReadPassphrase() ([]byte, error) {
+	var passphrase []byte
+	var err error
+	switch {
+	case k.PassphraseFD != 0 && k.PassphraseFile != "":
+		return nil, errors.New("only one of passphrase file and passphrase file descriptor can be set")
+	case k.PassphraseFD != 0:
+		file := os.NewFile(uintptr(k.PassphraseFD), "passphrase")
+		if file == nil {
+			return nil, fmt.Errorf("invalid passphrase file descriptor %d", k.PassphraseFD)
+		}
+		defer file.Close()
+		passphrase, err = io.ReadAll(file)
+	case k.PassphraseFile != "":
+		passphrase, err = os.ReadFile(k.PassphraseFile)
+	default:
+		return nil, errors.New("passphrase file or passphrase file descriptor required")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	passphrase = []byte(strings.TrimRight(string(passphrase), "\r\n"))
+	if len(passphrase) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+
+	return passphrase, nil
+}
+
 // Converts keys as hex string values to bytes
 func (k *Keys) ParseConfig() error {
+	if len(k.Master) > 0 {
+		if err := k.deriveKeys(); err != nil {
+			return err
+		}
+	}
+
 	var err error
-	k.KEK, err = hex.DecodeString(string(k.KEK))
-	if err != nil {
-		return errors.New("KEK couldn't be parsed (decode hex)")
+	switch k.KEKProvider {
+	case "", KEKProviderSoftware:
+		k.KEK, err = hex.DecodeString(string(k.KEK))
+		if err != nil {
+			return errors.New("KEK couldn't be parsed (decode hex)")
+		}
+		if len(k.KEK) != 32 {
+			return errors.New("KEK must be 32 bytes (64 hex digits) long")
+		}
+	case KEKProviderPKCS11:
+		if len(k.KEK) > 0 {
+			return errors.New("KEK must not be set when using a PKCS#11 token")
+		}
+		if k.PKCS11.Module == "" || k.PKCS11.TokenLabel == "" || k.PKCS11.KeyLabel == "" {
+			return errors.New("PKCS#11 module, token label and key label must be set")
+		}
+	default:
+		return fmt.Errorf("unknown KEK provider %v", k.KEKProvider)
 	}
-	if len(k.KEK) != 32 {
-		return errors.New("KEK must be 32 bytes (64 hex digits) long")
+	if err := parseRetiredKeys("KEK", k.KEKID, k.RetiredKEKs); err != nil {
+		return err
 	}
 
 	k.AEK, err = hex.DecodeString(string(k.AEK))
@@ -181,6 +389,9 @@ func (k *Keys) ParseConfig() error {
 	if len(k.AEK) != 32 {
 		return errors.New("AEK must be 32 bytes (64 hex digits) long")
 	}
+	if err := parseRetiredKeys("AEK", k.AEKID, k.RetiredAEKs); err != nil {
+		return err
+	}
 
 	k.TEK, err = hex.DecodeString(string(k.TEK))
 	if err != nil {
@@ -189,6 +400,9 @@ func (k *Keys) ParseConfig() error {
 	if len(k.TEK) != 32 {
 		return errors.New("TEK must be 32 bytes (64 hex digits) long")
 	}
+	if err := parseRetiredKeys("TEK", k.TEKID, k.RetiredTEKs); err != nil {
+		return err
+	}
 
 	k.UEK, err = hex.DecodeString(string(k.UEK))
 	if err != nil {
@@ -197,6 +411,9 @@ func (k *Keys) ParseConfig() error {
 	if len(k.UEK) != 32 {
 		return errors.New("UEK must be 32 bytes (64 hex digits) long")
 	}
+	if err := parseRetiredKeys("UEK", k.UEKID, k.RetiredUEKs); err != nil {
+		return err
+	}
 
 	k.GEK, err = hex.DecodeString(string(k.GEK))
 	if err != nil {
@@ -205,10 +422,157 @@ func (k *Keys) ParseConfig() error {
 	if len(k.GEK) != 32 {
 		return errors.New("GEK must be 32 bytes (64 hex digits) long")
 	}
+	if err := parseRetiredKeys("GEK", k.GEKID, k.RetiredGEKs); err != nil {
+		return err
+	}
+
+	k.ReceiptSigningKey, err = hex.DecodeString(string(k.ReceiptSigningKey))
+	if err != nil {
+		return errors.New("receipt signing key couldn't be parsed (decode hex)")
+	}
+	if len(k.ReceiptSigningKey) != 32 {
+		return errors.New("receipt signing key must be 32 bytes (64 hex digits) long")
+	}
 
 	return nil
 }
 
+// Labels used to derive the individual keys from the master key
+var masterKeyLabels = map[string]string{
+	"KEK": "encryptonize KEK",
+	"AEK": "encryptonize AEK",
+	"TEK": "encryptonize TEK",
+	"UEK": "encryptonize UEK",
+	"GEK": "encryptonize GEK",
+	// The receipt signing key is an Ed25519 private key seed
+	"receipt signing key": "encryptonize receipt signing key",
+}
+
+// deriveKeys derives all keys from the master key using HKDF-SHA256 with a separate label per key.
+// The derived keys are hex encoded, so they are parsed like explicitly configured keys.
+func (k *Keys) deriveKeys() error {
+	if len(k.KEK) > 0 || len(k.AEK) > 0 || len(k.TEK) > 0 || len(k.UEK) > 0 || len(k.GEK) > 0 || len(k.ReceiptSigningKey) > 0 {
+		return errors.New("master key and explicit keys cannot both be set")
+	}
+	if len(k.RetiredKEKs) > 0 || len(k.RetiredAEKs) > 0 || len(k.RetiredTEKs) > 0 || len(k.RetiredUEKs) > 0 || len(k.RetiredGEKs) > 0 {
+		return errors.New("master key and explicit retired keys cannot both be set")
+	}
+
+	var err error
+	k.Master, err = hex.DecodeString(string(k.Master))
+	if err != nil {
+		return errors.New("master key couldn't be parsed (decode hex)")
+	}
+	if len(k.Master) != 32 {
+		return errors.New("master key must be 32 bytes (64 hex digits) long")
+	}
+	if err := parseRetiredKeys("master key", k.MasterID, k.RetiredMasters); err != nil {
+		return err
+	}
+
+	keys := map[string]*[]byte{
+		"KEK": &k.KEK, "AEK": &k.AEK, "TEK": &k.TEK, "UEK": &k.UEK, "GEK": &k.GEK, "receipt signing key": &k.ReceiptSigningKey,
+	}
+	if k.KEKProvider == KEKProviderPKCS11 {
+		// The KEK is kept in the PKCS#11 token
+		delete(keys, "KEK")
+	}
+	for name, key := range keys {
+		derived, err := deriveKey(k.Master, masterKeyLabels[name])
+		if err != nil {
+			return err
+		}
+		*key = []byte(hex.EncodeToString(derived))
+	}
+	k.KEKID, k.AEKID, k.TEKID, k.UEKID, k.GEKID = k.MasterID, k.MasterID, k.MasterID, k.MasterID, k.MasterID
+	k.ReceiptSigningKeyID = k.MasterID
+
+	if len(k.RetiredMasters) > 0 {
+		retired := map[string]*map[uint32][]byte{
+			"KEK": &k.RetiredKEKs, "AEK": &k.RetiredAEKs, "TEK": &k.RetiredTEKs, "UEK": &k.RetiredUEKs, "GEK": &k.RetiredGEKs,
+		}
+		if k.KEKProvider == KEKProviderPKCS11 {
+			// Software KEKs must not stay active next to the KEK in the PKCS#11 token
+			delete(retired, "KEK")
+		}
+		for name, keys := range retired {
+			*keys = make(map[uint32][]byte, len(k.RetiredMasters))
+			for keyID, master := range k.RetiredMasters {
+				derived, err := deriveKey(master, masterKeyLabels[name])
+				if err != nil {
+					return err
+				}
+				(*keys)[keyID] = []byte(hex.EncodeToString(derived))
+			}
+		}
+	}
+
+	return nil
+}
+
+// deriveKey derives a 32 byte key from a master key and a label
+func deriveKey(master []byte, label string) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, master, nil, []byte(label)), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// parseRetiredKeys converts retired keys as hex string values to bytes
+func parseRetiredKeys(name string, currentID uint32, keys map[uint32][]byte) error {
+	for keyID, key := range keys {
+		if keyID == currentID {
+			return fmt.Errorf("retired %s %d has the same ID as the current %s", name, keyID, name)
+		}
+		key, err := hex.DecodeString(string(key))
+		if err != nil {
+			return fmt.Errorf("retired %s %d couldn't be parsed (decode hex)", name, keyID)
+		}
+		if len(key) != 32 {
+			return fmt.Errorf("retired %s %d must be 32 bytes (64 hex digits) long", name, keyID)
+		}
+		keys[keyID] = key
+	}
+
+	return nil
+}
+
+// keyRing returns the current and all retired keys indexed by key ID
+func keyRing(current []byte, currentID uint32, retired map[uint32][]byte) map[uint32][]byte {
+	keys := make(map[uint32][]byte, len(retired)+1)
+	for keyID, key := range retired {
+		keys[keyID] = key
+	}
+	keys[currentID] = current
+	return keys
+}
+
+// KEKs returns the current and all retired KEKs indexed by key ID
+func (k *Keys) KEKs() map[uint32][]byte {
+	return keyRing(k.KEK, k.KEKID, k.RetiredKEKs)
+}
+
+// AEKs returns the current and all retired AEKs indexed by key ID
+func (k *Keys) AEKs() map[uint32][]byte {
+	return keyRing(k.AEK, k.AEKID, k.RetiredAEKs)
+}
+
+// TEKs returns the current and all retired TEKs indexed by key ID
+func (k *Keys) TEKs() map[uint32][]byte {
+	return keyRing(k.TEK, k.TEKID, k.RetiredTEKs)
+}
+
+// UEKs returns the current and all retired UEKs indexed by key ID
+func (k *Keys) UEKs() map[uint32][]byte {
+	return keyRing(k.UEK, k.UEKID, k.RetiredUEKs)
+}
+
+// GEKs returns the current and all retired GEKs indexed by key ID
+func (k *Keys) GEKs() map[uint32][]byte {
+	return keyRing(k.GEK, k.GEKID, k.RetiredGEKs)
+}
+
 const stopSign = `
             uuuuuuuuuuuuuuuuuuuu
           u* uuuuuuuuuuuuuuuuuu *u
@@ -244,20 +608,38 @@ func (k *Keys) CheckInsecure() {
 			log.Warn(ctx, line)
 		}
 	} else {
-		if hex.EncodeToString(k.KEK) == "0000000000000000000000000000000000000000000000000000000000000000" {
-			log.Fatal(ctx, errors.New(""), "Test KEK used outside of INSECURE testing mode")
+		for _, master := range keyRing(k.Master, k.MasterID, k.RetiredMasters) {
+			if hex.EncodeToString(master) == "0000000000000000000000000000000000000000000000000000000000000005" {
+				log.Fatal(ctx, errors.New(""), "Test master key used outside of INSECURE testing mode")
+			}
+		}
+		for _, kek := range k.KEKs() {
+			if hex.EncodeToString(kek) == "0000000000000000000000000000000000000000000000000000000000000000" {
+				log.Fatal(ctx, errors.New(""), "Test KEK used outside of INSECURE testing mode")
+			}
+		}
+		for _, key := range k.AEKs() {
+			if hex.EncodeToString(key) == "0000000000000000000000000000000000000000000000000000000000000001" {
+				log.Fatal(ctx, errors.New(""), "Test AEK used outside of INSECURE testing mode")
+			}
 		}
-		if hex.EncodeToString(k.AEK) == "0000000000000000000000000000000000000000000000000000000000000001" {
-			log.Fatal(ctx, errors.New(""), "Test AEK used outside of INSECURE testing mode")
+		for _, key := range k.TEKs() {
+			if hex.EncodeToString(key) == "0000000000000000000000000000000000000000000000000000000000000002" {
+				log.Fatal(ctx, errors.New(""), "Test TEK used outside of INSECURE testing mode")
+			}
 		}
-		if hex.EncodeToString(k.TEK) == "0000000000000000000000000000000000000000000000000000000000000002" {
-			log.Fatal(ctx, errors.New(""), "Test TEK used outside of INSECURE testing mode")
+		for _, key := range k.UEKs() {
+			if hex.EncodeToString(key) == "0000000000000000000000000000000000000000000000000000000000000003" {
+				log.Fatal(ctx, errors.New(""), "Test UEK used outside of INSECURE testing mode")
+			}
 		}
-		if hex.EncodeToString(k.UEK) == "0000000000000000000000000000000000000000000000000000000000000003" {
-			log.Fatal(ctx, errors.New(""), "Test UEK used outside of INSECURE testing mode")
+		for _, key := range k.GEKs() {
+			if hex.EncodeToString(key) == "0000000000000000000000000000000000000000000000000000000000000004" {
+				log.Fatal(ctx, errors.New(""), "Test GEK used outside of INSECURE testing mode")
+			}
 		}
-		if hex.EncodeToString(k.GEK) == "0000000000000000000000000000000000000000000000000000000000000004" {
-			log.Fatal(ctx, errors.New(""), "Test GEK used outside of INSECURE testing mode")
+		if hex.EncodeToString(k.ReceiptSigningKey) == "0000000000000000000000000000000000000000000000000000000000000006" {
+			log.Fatal(ctx, errors.New(""), "Test receipt signing key used outside of INSECURE testing mode")
 		}
 	}
 }
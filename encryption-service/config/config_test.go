@@ -1,6 +1,8 @@
 package config
 
 import (
+	"bytes"
+	"encoding/hex"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -14,6 +16,7 @@ aek = "0202020202020202020202020202020202020202020202020202020202020202"
 tek = "0303030303030303030303030303030303030303030303030303030303030303"
 uek = "0404040404040404040404040404040404040404040404040404040404040404"
 gek = "0505050505050505050505050505050505050505050505050505050505050505"
+receiptsigningkey = "0707070707070707070707070707070707070707070707070707070707070707"
 
 [authstorage]
 username = "authstorage.username"
@@ -39,6 +42,7 @@ keys:
   tek: "0303030303030303030303030303030303030303030303030303030303030303"
   uek: "0404040404040404040404040404040404040404040404040404040404040404"
   gek: "0505050505050505050505050505050505050505050505050505050505050505"
+  receiptsigningkey: "0707070707070707070707070707070707070707070707070707070707070707"
 
 authstorage:
   username: "authstorage.username"
@@ -64,7 +68,8 @@ var testConfigJSON = `
 		"aek": "0202020202020202020202020202020202020202020202020202020202020202",
 		"tek": "0303030303030303030303030303030303030303030303030303030303030303",
 		"uek": "0404040404040404040404040404040404040404040404040404040404040404",
-		"gek": "0505050505050505050505050505050505050505050505050505050505050505"
+		"gek": "0505050505050505050505050505050505050505050505050505050505050505",
+		"receiptsigningkey": "0707070707070707070707070707070707070707070707070707070707070707"
 	},
 	"authstorage": {
 		"username": "authstorage.username",
@@ -87,11 +92,12 @@ var testConfigJSON = `
 
 var testConfig = Config{
 	Keys: Keys{
-		KEK: []byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
-		AEK: []byte{2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2},
-		TEK: []byte{3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3},
-		UEK: []byte{4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4},
-		GEK: []byte{5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5},
+		KEK:               []byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+		AEK:               []byte{2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2},
+		TEK:               []byte{3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3},
+		UEK:               []byte{4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4},
+		GEK:               []byte{5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5},
+		ReceiptSigningKey: []byte{7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7},
 	},
 	AuthStorage: AuthStorage{
 		Username:    "authstorage.username",
@@ -230,11 +236,12 @@ func TestReadEnv(t *testing.T) {
 
 func TestParseKeys(t *testing.T) {
 	testKeys := Keys{
-		KEK: []byte("0101010101010101010101010101010101010101010101010101010101010101"),
-		AEK: []byte("0202020202020202020202020202020202020202020202020202020202020202"),
-		TEK: []byte("0303030303030303030303030303030303030303030303030303030303030303"),
-		UEK: []byte("0404040404040404040404040404040404040404040404040404040404040404"),
-		GEK: []byte("0505050505050505050505050505050505050505050505050505050505050505"),
+		KEK:               []byte("0101010101010101010101010101010101010101010101010101010101010101"),
+		AEK:               []byte("0202020202020202020202020202020202020202020202020202020202020202"),
+		TEK:               []byte("0303030303030303030303030303030303030303030303030303030303030303"),
+		UEK:               []byte("0404040404040404040404040404040404040404040404040404040404040404"),
+		GEK:               []byte("0505050505050505050505050505050505050505050505050505050505050505"),
+		ReceiptSigningKey: []byte("0707070707070707070707070707070707070707070707070707070707070707"),
 	}
 	keys := testKeys
 
@@ -269,6 +276,12 @@ func TestParseKeys(t *testing.T) {
 	}
 	keys = testKeys
 
+	keys.ReceiptSigningKey = []byte("totally not hex")
+	if err := keys.ParseConfig(); err == nil {
+		t.Error("Expected ParseConfig to fail (receipt signing key)")
+	}
+	keys = testKeys
+
 	// Test wrong length
 	keys.KEK = []byte("deadbeef")
 	if err := keys.ParseConfig(); err == nil {
@@ -299,4 +312,258 @@ func TestParseKeys(t *testing.T) {
 		t.Error("Expected ParseConfig to fail (GEK)")
 	}
 	keys = testKeys
+
+	keys.ReceiptSigningKey = []byte("deadbeef")
+	if err := keys.ParseConfig(); err == nil {
+		t.Error("Expected ParseConfig to fail (receipt signing key)")
+	}
+	keys = testKeys
+}
+
+func TestReadRetiredKEKs(t *testing.T) {
+	tmpdir := t.TempDir()
+	configPath := filepath.Join(tmpdir, "config.toml")
+	configTOML := testConfigTOML + `
+[keys.retiredkeks]
+1 = "0606060606060606060606060606060606060606060606060606060606060606"
+`
+	if err := os.WriteFile(configPath, []byte(configTOML), 0444); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	if err := os.Setenv("ECTNZ_CONFIGFILE", configPath); err != nil {
+		t.Fatalf("Failed to set env: %v", err)
+	}
+	if err := os.Setenv("ECTNZ_KEYS_KEKID", "3"); err != nil {
+		t.Fatalf("Failed to set env: %v", err)
+	}
+	defer os.Unsetenv("ECTNZ_KEYS_KEKID")
+	if err := os.Setenv("ECTNZ_KEYS_RETIREDKEKS_2", "0707070707070707070707070707070707070707070707070707070707070707"); err != nil {
+		t.Fatalf("Failed to set env: %v", err)
+	}
+	defer os.Unsetenv("ECTNZ_KEYS_RETIREDKEKS_2")
+
+	parsedConfig, err := ParseConfig()
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	expected := map[uint32][]byte{
+		1: {6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6},
+		2: {7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7},
+		3: testConfig.Keys.KEK,
+	}
+	if parsedConfig.Keys.KEKID != 3 {
+		t.Fatalf("Wrong KEK ID: %v", parsedConfig.Keys.KEKID)
+	}
+	if !reflect.DeepEqual(expected, parsedConfig.Keys.KEKs()) {
+		t.Fatalf("%v != %v", expected, parsedConfig.Keys.KEKs())
+	}
+}
+
+func TestParseRetiredKeys(t *testing.T) {
+	newKeys := func() Keys {
+		return Keys{
+			KEK:               []byte("0101010101010101010101010101010101010101010101010101010101010101"),
+			AEK:               []byte("0202020202020202020202020202020202020202020202020202020202020202"),
+			TEK:               []byte("0303030303030303030303030303030303030303030303030303030303030303"),
+			UEK:               []byte("0404040404040404040404040404040404040404040404040404040404040404"),
+			GEK:               []byte("0505050505050505050505050505050505050505050505050505050505050505"),
+			ReceiptSigningKey: []byte("0707070707070707070707070707070707070707070707070707070707070707"),
+		}
+	}
+	setRetired := map[string]func(k *Keys, currentID uint32, retired []byte){
+		"KEK": func(k *Keys, currentID uint32, retired []byte) {
+			k.KEKID, k.RetiredKEKs = currentID, map[uint32][]byte{0: retired}
+		},
+		"AEK": func(k *Keys, currentID uint32, retired []byte) {
+			k.AEKID, k.RetiredAEKs = currentID, map[uint32][]byte{0: retired}
+		},
+		"TEK": func(k *Keys, currentID uint32, retired []byte) {
+			k.TEKID, k.RetiredTEKs = currentID, map[uint32][]byte{0: retired}
+		},
+		"UEK": func(k *Keys, currentID uint32, retired []byte) {
+			k.UEKID, k.RetiredUEKs = currentID, map[uint32][]byte{0: retired}
+		},
+		"GEK": func(k *Keys, currentID uint32, retired []byte) {
+			k.GEKID, k.RetiredGEKs = currentID, map[uint32][]byte{0: retired}
+		},
+	}
+
+	for name, set := range setRetired {
+		keys := newKeys()
+		set(&keys, 1, []byte("0606060606060606060606060606060606060606060606060606060606060606"))
+		if err := keys.ParseConfig(); err != nil {
+			t.Fatalf("ParseConfig failed (%v): %v", name, err)
+		}
+
+		keys = newKeys()
+		set(&keys, 1, []byte("totally not hex"))
+		if err := keys.ParseConfig(); err == nil {
+			t.Errorf("Expected ParseConfig to fail (retired %v)", name)
+		}
+
+		keys = newKeys()
+		set(&keys, 1, []byte("deadbeef"))
+		if err := keys.ParseConfig(); err == nil {
+			t.Errorf("Expected ParseConfig to fail (retired %v)", name)
+		}
+
+		// Retired key ID must differ from the current key ID
+		keys = newKeys()
+		set(&keys, 0, []byte("0606060606060606060606060606060606060606060606060606060606060606"))
+		if err := keys.ParseConfig(); err == nil {
+			t.Errorf("Expected ParseConfig to fail (%v ID)", name)
+		}
+	}
+}
+
+func TestParseMasterKey(t *testing.T) {
+	keys := Keys{
+		Master:         []byte("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"),
+		MasterID:       2,
+		RetiredMasters: map[uint32][]byte{1: []byte("0606060606060606060606060606060606060606060606060606060606060606")},
+	}
+	if err := keys.ParseConfig(); err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+
+	// Known answers for HKDF-SHA256 with the key labels
+	expected := map[string][]byte{
+		"75ca93689e236dad1def0a4e8ac13f363680040e6e3dde517a6092059405ed59": keys.KEK,
+		"d679b667198f5b25358d0896b0f357959c63a1aba28fb15589245556789c74eb": keys.AEK,
+		"e1989e7e0b966dc945b92841457ddc27ad7167fa2ed81df9d7d46c481d362942": keys.TEK,
+		"cf83e105d158b3896d6affb54e8b0c100a6c47c4f79c7db4c94d60c9d502c1ee": keys.UEK,
+		"ae63d786fe7dd7ee83c0f566d690ec2098ae7b6e6cacd70ab71c4ab91e93336d": keys.GEK,
+		"ebf1d45174bb5cbc3d2d54245244fe5c9b5f4dbf647232f09e444e9bd19480f9": keys.ReceiptSigningKey,
+	}
+	for expectedKey, key := range expected {
+		if hex.EncodeToString(key) != expectedKey {
+			t.Errorf("Derived key %x != %v", key, expectedKey)
+		}
+	}
+
+	if keys.KEKID != 2 || keys.AEKID != 2 || keys.TEKID != 2 || keys.UEKID != 2 || keys.GEKID != 2 || keys.ReceiptSigningKeyID != 2 {
+		t.Error("Derived keys must use the master key ID")
+	}
+
+	// Retired keys are derived from the retired master keys
+	for _, retired := range []map[uint32][]byte{keys.RetiredKEKs, keys.RetiredAEKs, keys.RetiredTEKs, keys.RetiredUEKs, keys.RetiredGEKs} {
+		if len(retired) != 1 || len(retired[1]) != 32 {
+			t.Fatalf("Retired key not derived: %v", retired)
+		}
+	}
+	if bytes.Equal(keys.RetiredKEKs[1], keys.KEK) || bytes.Equal(keys.RetiredKEKs[1], keys.RetiredAEKs[1]) {
+		t.Error("Retired keys derived incorrectly")
+	}
+}
+
+func TestParseMasterKeyInvalid(t *testing.T) {
+	master := []byte("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+
+	keys := Keys{Master: []byte("totally not hex")}
+	if err := keys.ParseConfig(); err == nil {
+		t.Error("Expected ParseConfig to fail (master key)")
+	}
+
+	keys = Keys{Master: []byte("deadbeef")}
+	if err := keys.ParseConfig(); err == nil {
+		t.Error("Expected ParseConfig to fail (master key)")
+	}
+
+	// Master key and explicit keys are mutually exclusive
+	keys = Keys{Master: master, KEK: []byte("0101010101010101010101010101010101010101010101010101010101010101")}
+	if err := keys.ParseConfig(); err == nil {
+		t.Error("Expected ParseConfig to fail (master key and KEK)")
+	}
+
+	keys = Keys{Master: master, RetiredGEKs: map[uint32][]byte{1: []byte("0101010101010101010101010101010101010101010101010101010101010101")}}
+	if err := keys.ParseConfig(); err == nil {
+		t.Error("Expected ParseConfig to fail (master key and retired GEK)")
+	}
+}
+
+func TestParseKEKProvider(t *testing.T) {
+	newKeys := func() Keys {
+		return Keys{
+			KEKProvider: KEKProviderPKCS11,
+			PKCS11: PKCS11{
+				Module:     "/usr/lib/softhsm/libsofthsm2.so",
+				TokenLabel: "token",
+				Pin:        "1234",
+				KeyLabel:   "kek",
+			},
+			AEK:               []byte("0202020202020202020202020202020202020202020202020202020202020202"),
+			TEK:               []byte("0303030303030303030303030303030303030303030303030303030303030303"),
+			UEK:               []byte("0404040404040404040404040404040404040404040404040404040404040404"),
+			GEK:               []byte("0505050505050505050505050505050505050505050505050505050505050505"),
+			ReceiptSigningKey: []byte("0707070707070707070707070707070707070707070707070707070707070707"),
+		}
+	}
+
+	keys := newKeys()
+	if err := keys.ParseConfig(); err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+
+	// The KEK is kept in the token
+	keys = newKeys()
+	keys.KEK = []byte("0101010101010101010101010101010101010101010101010101010101010101")
+	if err := keys.ParseConfig(); err == nil {
+		t.Error("Expected ParseConfig to fail (KEK)")
+	}
+
+	keys = newKeys()
+	keys.PKCS11.KeyLabel = ""
+	if err := keys.ParseConfig(); err == nil {
+		t.Error("Expected ParseConfig to fail (key label)")
+	}
+
+	keys = newKeys()
+	keys.KEKProvider = "foo"
+	if err := keys.ParseConfig(); err == nil {
+		t.Error("Expected ParseConfig to fail (KEK provider)")
+	}
+
+	// Only the non-KEK keys are derived from the master key
+	keys = Keys{
+		KEKProvider: KEKProviderPKCS11,
+		PKCS11:      newKeys().PKCS11,
+		Master:      []byte("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"),
+	}
+	if err := keys.ParseConfig(); err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+	if len(keys.KEK) != 0 || len(keys.AEK) != 32 {
+		t.Error("Wrong keys derived")
+	}
+
+	// Neither are the retired KEKs derived from the retired master keys
+	keys = Keys{
+		KEKProvider:    KEKProviderPKCS11,
+		PKCS11:         newKeys().PKCS11,
+		Master:         []byte("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"),
+		MasterID:       2,
+		RetiredMasters: map[uint32][]byte{1: []byte("0606060606060606060606060606060606060606060606060606060606060606")},
+	}
+	if err := keys.ParseConfig(); err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+	if len(keys.RetiredKEKs) != 0 || len(keys.RetiredAEKs[1]) != 32 {
+		t.Errorf("Wrong retired keys derived: KEKs %v, AEKs %v", keys.RetiredKEKs, keys.RetiredAEKs)
+	}
+}
+
+func TestParseCipher(t *testing.T) {
+	for _, cipher := range []string{"", CipherAES256GCM, CipherXChaCha20Poly1305} {
+		crypto := Crypto{Cipher: cipher}
+		if err := crypto.ParseConfig(); err != nil {
+			t.Errorf("ParseConfig failed (%v): %v", cipher, err)
+		}
+	}
+
+	crypto := Crypto{Cipher: "rot13"}
+	if err := crypto.ParseConfig(); err == nil {
+		t.Error("Expected ParseConfig to fail (cipher)")
+	}
 }
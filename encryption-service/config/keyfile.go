@@ -0,0 +1,338 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/argon2"
+
+	"encryption-service/impl/crypt"
+)
+
+const keyFileVersion = 1
+
+// Default Argon2id parameters for passphrase protected key files (RFC 9106, second recommended
+// option)
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+	argon2SaltLen = 16
+)
+
+// KeyFile holds the keys configuration encrypted under a key file key. The key file key is either
+// split into Shamir shares, of which `Threshold` are needed to unseal the keys, or derived from a
+// passphrase with Argon2id.
+type KeyFile struct {
+	Version   int `json:"version"`
+	Shares    int `json:"shares,omitempty"`
+	Threshold int `json:"threshold,omitempty"`
+
+	// Parameters used to derive the key file key from a passphrase
+	KDF *Argon2Params `json:"kdf,omitempty"`
+
+	// The encrypted keys configuration
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Argon2Params are the Argon2id parameters of a passphrase protected key file
+type Argon2Params struct {
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+	Salt    []byte `json:"salt"`
+}
+
+// newArgon2Params creates Argon2id parameters with the default cost and a random salt
+func newArgon2Params() (*Argon2Params, error) {
+	salt, err := crypt.Random(argon2SaltLen)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Argon2Params{
+		Time:    argon2Time,
+		Memory:  argon2Memory,
+		Threads: argon2Threads,
+		Salt:    salt,
+	}, nil
+}
+
+// deriveKey derives the key file key from a passphrase
+func (p *Argon2Params) deriveKey(passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	if p.Time == 0 || p.Memory == 0 || p.Threads == 0 || len(p.Salt) < argon2SaltLen {
+		return nil, errors.New("invalid key file KDF parameters")
+	}
+
+	return argon2.IDKey(passphrase, p.Salt, p.Time, p.Memory, p.Threads, 32), nil
+}
+
+// marshalKeys serializes a keys configuration for a key file. The keys must not be parsed yet, i.e.
+// they must be hex encoded as in the configuration.
+func marshalKeys(keys Keys) ([]byte, error) {
+	plaintext, err := json.Marshal(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the keys can be used
+	if _, err := unmarshalKeys(plaintext); err != nil {
+		return nil, err
+	}
+
+	return plaintext, nil
+}
+
+// unmarshalKeys deserializes and parses a keys configuration from a key file
+func unmarshalKeys(plaintext []byte) (*Keys, error) {
+	keys := &Keys{}
+	if err := json.Unmarshal(plaintext, keys); err != nil {
+		return nil, err
+	}
+	if err := keys.ParseConfig(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// SealKeys encrypts a keys configuration under a new unseal key, which is split into `shares` Shamir
+// shares. The keys must not be parsed yet, i.e. they must be hex encoded as in the configuration.
+func SealKeys(keys Keys, shares, threshold int) (*KeyFile, [][]byte, error) {
+	plaintext, err := marshalKeys(keys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unsealKey, err := crypt.Random(32)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unsealShares, err := crypt.ShamirSplit(unsealKey, shares, threshold)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyFile := &KeyFile{
+		Version:   keyFileVersion,
+		Shares:    shares,
+		Threshold: threshold,
+	}
+	if err := keyFile.encrypt(plaintext, unsealKey); err != nil {
+		return nil, nil, err
+	}
+
+	return keyFile, unsealShares, nil
+}
+
+// EncryptKeys encrypts a keys configuration under a key derived from `passphrase`. The keys must not
+// be parsed yet, i.e. they must be hex encoded as in the configuration.
+func EncryptKeys(keys Keys, passphrase []byte) (*KeyFile, error) {
+	plaintext, err := marshalKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPassphraseKeyFile(plaintext, passphrase)
+}
+
+// newPassphraseKeyFile encrypts a serialized keys configuration under a key derived from
+// `passphrase` with fresh KDF parameters
+func newPassphraseKeyFile(plaintext, passphrase []byte) (*KeyFile, error) {
+	kdf, err := newArgon2Params()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := kdf.deriveKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	keyFile := &KeyFile{
+		Version: keyFileVersion,
+		KDF:     kdf,
+	}
+	if err := keyFile.encrypt(plaintext, key); err != nil {
+		return nil, err
+	}
+
+	return keyFile, nil
+}
+
+// ReadKeyFile reads a key file from disk
+func ReadKeyFile(path string) (*KeyFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keyFile := &KeyFile{}
+	if err := json.Unmarshal(data, keyFile); err != nil {
+		return nil, err
+	}
+	if keyFile.Version != keyFileVersion {
+		return nil, fmt.Errorf("unsupported key file version %d", keyFile.Version)
+	}
+	if (keyFile.KDF == nil) == (keyFile.Threshold == 0) {
+		return nil, errors.New("key file must either be sealed or protected by a passphrase")
+	}
+
+	return keyFile, nil
+}
+
+// Write writes a key file to disk. Existing files are not overwritten.
+func (f *KeyFile) Write(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Replace atomically replaces an existing key file on disk
+func (f *KeyFile) Replace(path string) error {
+	tmpPath := path + ".new"
+	if err := f.Write(tmpPath); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// Unseal combines the unseal shares and decrypts the keys
+func (f *KeyFile) Unseal(shares [][]byte) (*Keys, error) {
+	if f.Threshold == 0 {
+		return nil, errors.New("key file is not sealed")
+	}
+	if len(shares) < f.Threshold {
+		return nil, fmt.Errorf("%d of %d unseal shares provided", len(shares), f.Threshold)
+	}
+
+	unsealKey, err := crypt.ShamirCombine(shares)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.Open(unsealKey)
+}
+
+// Open decrypts and parses the keys with the unseal key
+func (f *KeyFile) Open(unsealKey []byte) (*Keys, error) {
+	plaintext, err := f.decrypt(unsealKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalKeys(plaintext)
+}
+
+// OpenWithPassphrase decrypts and parses the keys with a key derived from `passphrase`
+func (f *KeyFile) OpenWithPassphrase(passphrase []byte) (*Keys, error) {
+	plaintext, err := f.decryptWithPassphrase(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalKeys(plaintext)
+}
+
+// ChangePassphrase re-encrypts the keys under a new passphrase. The key file key is derived with
+// fresh KDF parameters using the current default cost.
+func (f *KeyFile) ChangePassphrase(passphrase, newPassphrase []byte) (*KeyFile, error) {
+	plaintext, err := f.decryptWithPassphrase(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPassphraseKeyFile(plaintext, newPassphrase)
+}
+
+func (f *KeyFile) decryptWithPassphrase(passphrase []byte) ([]byte, error) {
+	if f.KDF == nil {
+		return nil, errors.New("key file is not protected by a passphrase")
+	}
+
+	key, err := f.KDF.deriveKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := f.decrypt(key)
+	if err != nil {
+		return nil, errors.New("invalid passphrase")
+	}
+
+	return plaintext, nil
+}
+
+func (f *KeyFile) encrypt(plaintext, key []byte) error {
+	crypter := &crypt.AESCrypter{}
+	ciphertext, err := crypter.Encrypt(plaintext, f.aad(), key)
+	if err != nil {
+		return err
+	}
+
+	f.Ciphertext = ciphertext
+	return nil
+}
+
+func (f *KeyFile) decrypt(key []byte) ([]byte, error) {
+	if len(key) != 32 {
+		return nil, errors.New("invalid unseal key")
+	}
+
+	// Decrypt works in place, so don't touch the key file
+	ciphertext := append([]byte{}, f.Ciphertext...)
+
+	crypter := &crypt.AESCrypter{}
+	plaintext, err := crypter.Decrypt(ciphertext, f.aad(), key)
+	if err != nil {
+		return nil, errors.New("invalid unseal key")
+	}
+
+	return plaintext, nil
+}
+
+// aad binds the key file parameters to the ciphertext
+func (f *KeyFile) aad() []byte {
+	if f.KDF != nil {
+		return []byte(fmt.Sprintf("encryptonize key file v%d argon2id t=%d m=%d p=%d salt=%x",
+			f.Version, f.KDF.Time, f.KDF.Memory, f.KDF.Threads, f.KDF.Salt))
+	}
+	return []byte(fmt.Sprintf("encryptonize key file v%d %d/%d", f.Version, f.Threshold, f.Shares))
+}
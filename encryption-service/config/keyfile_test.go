@@ -0,0 +1,239 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var testSealKeys = Keys{
+	KEK:               []byte("0101010101010101010101010101010101010101010101010101010101010101"),
+	AEK:               []byte("0202020202020202020202020202020202020202020202020202020202020202"),
+	TEK:               []byte("0303030303030303030303030303030303030303030303030303030303030303"),
+	UEK:               []byte("0404040404040404040404040404040404040404040404040404040404040404"),
+	GEK:               []byte("0505050505050505050505050505050505050505050505050505050505050505"),
+	ReceiptSigningKey: []byte("0707070707070707070707070707070707070707070707070707070707070707"),
+}
+
+func TestSealUnseal(t *testing.T) {
+	keyFile, shares, err := SealKeys(testSealKeys, 5, 3)
+	if err != nil {
+		t.Fatalf("SealKeys failed: %v", err)
+	}
+	if len(shares) != 5 {
+		t.Fatalf("Expected 5 shares, got %d", len(shares))
+	}
+
+	path := filepath.Join(t.TempDir(), "keys.sealed")
+	if err := keyFile.Write(path); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := keyFile.Write(path); err == nil {
+		t.Error("Expected Write to refuse overwriting the key file")
+	}
+
+	readKeyFile, err := ReadKeyFile(path)
+	if err != nil {
+		t.Fatalf("ReadKeyFile failed: %v", err)
+	}
+
+	keys, err := readKeyFile.Unseal([][]byte{shares[4], shares[0], shares[2]})
+	if err != nil {
+		t.Fatalf("Unseal failed: %v", err)
+	}
+	if !reflect.DeepEqual(testConfig.Keys, *keys) {
+		t.Fatalf("%v != %v", testConfig.Keys, keys)
+	}
+
+	// Unsealing twice must work, as Decrypt works in place
+	if _, err := readKeyFile.Unseal(shares[:3]); err != nil {
+		t.Fatalf("Unseal failed: %v", err)
+	}
+}
+
+func TestUnsealInvalid(t *testing.T) {
+	keyFile, shares, err := SealKeys(testSealKeys, 3, 2)
+	if err != nil {
+		t.Fatalf("SealKeys failed: %v", err)
+	}
+
+	if _, err := keyFile.Unseal(shares[:1]); err == nil {
+		t.Error("Expected Unseal to fail (too few shares)")
+	}
+
+	modified := append([]byte{}, shares[1]...)
+	modified[0] ^= 1
+	if _, err := keyFile.Unseal([][]byte{shares[0], modified}); err == nil {
+		t.Error("Expected Unseal to fail (modified share)")
+	}
+
+	_, otherShares, err := SealKeys(testSealKeys, 3, 2)
+	if err != nil {
+		t.Fatalf("SealKeys failed: %v", err)
+	}
+	if _, err := keyFile.Unseal(otherShares[:2]); err == nil {
+		t.Error("Expected Unseal to fail (shares of other key file)")
+	}
+
+	// The share parameters are bound to the ciphertext
+	keyFile.Threshold = 1
+	if _, err := keyFile.Unseal(shares[:2]); err == nil {
+		t.Error("Expected Unseal to fail (modified threshold)")
+	}
+}
+
+func TestSealInvalidKeys(t *testing.T) {
+	keys := testSealKeys
+	keys.KEK = []byte("deadbeef")
+	if _, _, err := SealKeys(keys, 3, 2); err == nil {
+		t.Error("Expected SealKeys to fail (invalid KEK)")
+	}
+}
+
+func TestParseSealedKeyFile(t *testing.T) {
+	config := Config{Keys: Keys{SealedKeyFile: "keys.sealed"}}
+	if err := config.ParseConfig(); err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+
+	// Keys must be kept in the key file
+	config = Config{Keys: testSealKeys}
+	config.Keys.SealedKeyFile = "keys.sealed"
+	if err := config.ParseConfig(); err == nil {
+		t.Error("Expected ParseConfig to fail (keys set)")
+	}
+}
+
+func TestEncryptKeys(t *testing.T) {
+	keyFile, err := EncryptKeys(testSealKeys, []byte("passphrase"))
+	if err != nil {
+		t.Fatalf("EncryptKeys failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keys.enc")
+	if err := keyFile.Write(path); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	readKeyFile, err := ReadKeyFile(path)
+	if err != nil {
+		t.Fatalf("ReadKeyFile failed: %v", err)
+	}
+
+	keys, err := readKeyFile.OpenWithPassphrase([]byte("passphrase"))
+	if err != nil {
+		t.Fatalf("OpenWithPassphrase failed: %v", err)
+	}
+	if !reflect.DeepEqual(testConfig.Keys, *keys) {
+		t.Fatalf("%v != %v", testConfig.Keys, keys)
+	}
+
+	if _, err := readKeyFile.OpenWithPassphrase([]byte("wrong passphrase")); err == nil {
+		t.Error("Expected OpenWithPassphrase to fail (wrong passphrase)")
+	}
+	if _, err := readKeyFile.Unseal(nil); err == nil {
+		t.Error("Expected Unseal to fail (not sealed)")
+	}
+
+	// The KDF parameters are bound to the ciphertext
+	readKeyFile.KDF.Time++
+	if _, err := readKeyFile.OpenWithPassphrase([]byte("passphrase")); err == nil {
+		t.Error("Expected OpenWithPassphrase to fail (modified KDF parameters)")
+	}
+}
+
+func TestChangePassphrase(t *testing.T) {
+	keyFile, err := EncryptKeys(testSealKeys, []byte("passphrase"))
+	if err != nil {
+		t.Fatalf("EncryptKeys failed: %v", err)
+	}
+
+	if _, err := keyFile.ChangePassphrase([]byte("wrong passphrase"), []byte("new passphrase")); err == nil {
+		t.Error("Expected ChangePassphrase to fail (wrong passphrase)")
+	}
+
+	newKeyFile, err := keyFile.ChangePassphrase([]byte("passphrase"), []byte("new passphrase"))
+	if err != nil {
+		t.Fatalf("ChangePassphrase failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keys.enc")
+	if err := keyFile.Write(path); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := newKeyFile.Replace(path); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+	readKeyFile, err := ReadKeyFile(path)
+	if err != nil {
+		t.Fatalf("ReadKeyFile failed: %v", err)
+	}
+
+	if _, err := readKeyFile.OpenWithPassphrase([]byte("passphrase")); err == nil {
+		t.Error("Expected OpenWithPassphrase to fail (old passphrase)")
+	}
+	keys, err := readKeyFile.OpenWithPassphrase([]byte("new passphrase"))
+	if err != nil {
+		t.Fatalf("OpenWithPassphrase failed: %v", err)
+	}
+	if !reflect.DeepEqual(testConfig.Keys, *keys) {
+		t.Fatalf("%v != %v", testConfig.Keys, keys)
+	}
+}
+
+func TestParseKeyFile(t *testing.T) {
+	tmpdir := t.TempDir()
+	keyFilePath := filepath.Join(tmpdir, "keys.enc")
+	passphrasePath := filepath.Join(tmpdir, "passphrase")
+
+	keyFile, err := EncryptKeys(testSealKeys, []byte("passphrase"))
+	if err != nil {
+		t.Fatalf("EncryptKeys failed: %v", err)
+	}
+	if err := keyFile.Write(keyFilePath); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := os.WriteFile(passphrasePath, []byte("passphrase\n"), 0400); err != nil {
+		t.Fatalf("Failed to write passphrase file: %v", err)
+	}
+
+	config := Config{Keys: Keys{KeyFile: keyFilePath, PassphraseFile: passphrasePath}}
+	if err := config.ParseConfig(); err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+	if !reflect.DeepEqual(testConfig.Keys, config.Keys) {
+		t.Fatalf("%v != %v", testConfig.Keys, config.Keys)
+	}
+
+	// Passphrase from a file descriptor
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe failed: %v", err)
+	}
+	if _, err := writer.Write([]byte("passphrase")); err != nil {
+		t.Fatalf("Failed to write passphrase: %v", err)
+	}
+	writer.Close()
+
+	config = Config{Keys: Keys{KeyFile: keyFilePath, PassphraseFD: int(reader.Fd())}}
+	if err := config.ParseConfig(); err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+
+	// Keys must be kept in the key file
+	config = Config{Keys: testSealKeys}
+	config.Keys.KeyFile, config.Keys.PassphraseFile = keyFilePath, passphrasePath
+	if err := config.ParseConfig(); err == nil {
+		t.Error("Expected ParseConfig to fail (keys set)")
+	}
+
+	config = Config{Keys: Keys{KeyFile: keyFilePath}}
+	if err := config.ParseConfig(); err == nil {
+		t.Error("Expected ParseConfig to fail (passphrase missing)")
+	}
+
+	config = Config{Keys: Keys{KeyFile: keyFilePath, SealedKeyFile: keyFilePath, PassphraseFile: passphrasePath}}
+	if err := config.ParseConfig(); err == nil {
+		t.Error("Expected ParseConfig to fail (sealed key file set)")
+	}
+}
@@ -18,3 +18,18 @@ CREATE TABLE IF NOT EXISTS access_objects  (
     data BYTEA NOT NULL,
     key BYTEA NOT NULL
 );
+
+CREATE TABLE IF NOT EXISTS erasure_receipts  (
+    id UUID PRIMARY KEY,
+    data BYTEA NOT NULL,
+    key BYTEA NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS tokens  (
+    namespace UUID NOT NULL,
+    token TEXT NOT NULL,
+    value_tag BYTEA,
+    data BYTEA NOT NULL,
+    PRIMARY KEY (namespace, token),
+    UNIQUE (namespace, value_tag)
+);
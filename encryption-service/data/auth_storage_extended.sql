@@ -3,3 +3,5 @@
 -- Enable audit logs
 ALTER TABLE users EXPERIMENTAL_AUDIT SET READ WRITE;
 ALTER TABLE access_objects EXPERIMENTAL_AUDIT SET READ WRITE;
+ALTER TABLE erasure_receipts EXPERIMENTAL_AUDIT SET READ WRITE;
+ALTER TABLE tokens EXPERIMENTAL_AUDIT SET READ WRITE;
@@ -6,9 +6,11 @@ require (
 	github.com/aws/aws-sdk-go v1.42.16
 	github.com/gofrs/uuid v4.2.0+incompatible
 	github.com/grpc-ecosystem/go-grpc-middleware v1.3.0
+	github.com/jackc/pgconn v1.10.1
 	github.com/jackc/pgtype v1.9.1
 	github.com/jackc/pgx/v4 v4.14.1
 	github.com/knadh/koanf v1.3.3
+	github.com/miekg/pkcs11 v1.1.1
 	github.com/sirupsen/logrus v1.8.1
 	github.com/sony/gobreaker v0.5.0
 	go.etcd.io/bbolt v1.3.6
@@ -21,7 +23,6 @@ require (
 	github.com/fsnotify/fsnotify v1.5.1 // indirect
 	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/jackc/chunkreader/v2 v2.0.1 // indirect
-	github.com/jackc/pgconn v1.10.1 // indirect
 	github.com/jackc/pgio v1.0.0 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgproto3/v2 v2.2.0 // indirect
@@ -42,14 +42,18 @@ func (ua *UserAuthenticator) newUserData() (*common.ProtectedUserData, string, e
 	}
 
 	// user password creation
-	pwd, salt, err := crypt.GenerateUserPassword()
+	pwd, err := crypt.GenerateUserPassword()
+	if err != nil {
+		return nil, "", err
+	}
+
+	hashedPassword, err := crypt.HashPassword(pwd)
 	if err != nil {
 		return nil, "", err
 	}
 
 	userData := &common.UserData{
-		HashedPassword: crypt.HashPassword(pwd, salt),
-		Salt:           salt,
+		HashedPassword: hashedPassword,
 		GroupIDs:       map[uuid.UUID]bool{},
 	}
 
@@ -128,7 +132,7 @@ func (ua *UserAuthenticator) GetUserData(ctx context.Context, userID uuid.UUID)
 	return userData, nil
 }
 
-// LoginUser logs in a user
+// LoginUser logs in a user, upgrading the stored password hash if it uses outdated parameters
 func (ua *UserAuthenticator) LoginUser(ctx context.Context, userID uuid.UUID, providedPassword string) (string, error) {
 	// Fetch user data and check the provided credentials
 	userData, err := ua.GetUserData(ctx, userID)
@@ -140,6 +144,20 @@ func (ua *UserAuthenticator) LoginUser(ctx context.Context, userID uuid.UUID, pr
 		return "", errors.New("Incorrect password")
 	}
 
+	// Upgrade outdated password hashes while the password is at hand. The caller must commit the
+	// transaction for the new hash to be stored.
+	if crypt.PasswordNeedsRehash(userData.HashedPassword, userData.Salt) {
+		userData.HashedPassword, err = crypt.HashPassword(providedPassword)
+		if err != nil {
+			return "", err
+		}
+		userData.Salt = nil
+
+		if err := ua.UpdateUser(ctx, userID, userData); err != nil {
+			return "", err
+		}
+	}
+
 	// Fetch the user's groups and extract scopes
 	groupDataBatch, err := ua.GetGroupDataBatch(ctx, userData.GetGroupIDs())
 	if err != nil {
@@ -21,6 +21,8 @@ import (
 	"reflect"
 
 	"github.com/gofrs/uuid"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/sha3"
 
 	"encryption-service/common"
 	"encryption-service/impl/authstorage"
@@ -170,11 +172,12 @@ func TestLoginUser(t *testing.T) {
 	}
 
 	password := "Password"
-	salt := []byte("Salt")
-	hashedPassword := crypt.HashPassword(password, salt)
+	hashedPassword, err := crypt.HashPassword(password)
+	if err != nil {
+		t.Fatalf("HashPassword errored: %s", err)
+	}
 	var userData = &common.UserData{
 		HashedPassword: hashedPassword,
-		Salt:           salt,
 		GroupIDs: map[uuid.UUID]bool{
 			uuid.FromStringOrNil("10000000-0000-0000-0000-000000000000"): true,
 		},
@@ -234,6 +237,71 @@ func TestLoginUser(t *testing.T) {
 	}
 }
 
+func TestLoginUserRehash(t *testing.T) {
+	userAuthenticator, err := SetupUA()
+	if err != nil {
+		t.Fatalf("LoginUser errored: %s", err)
+	}
+
+	password := "Password"
+	outdatedParams := crypt.DefaultPasswordHashParams
+	outdatedParams.Time--
+	outdatedHash, err := crypt.HashPasswordWithParams(password, outdatedParams)
+	if err != nil {
+		t.Fatalf("HashPasswordWithParams errored: %s", err)
+	}
+	legacySalt := []byte("Salt")
+
+	tests := map[string]*common.UserData{
+		"outdated parameters": {HashedPassword: outdatedHash},
+		"legacy PBKDF2":       {HashedPassword: pbkdf2.Key([]byte(password), legacySalt, 10000, 32, sha3.New256), Salt: legacySalt},
+	}
+
+	for name, userData := range tests {
+		t.Run(name, func(t *testing.T) {
+			wrappedKey, ciphertext, err := userAuthenticator.UserCryptor.EncodeAndEncrypt(userData, userID.Bytes())
+			if err != nil {
+				t.Fatalf("EncodeAndEncrypt errored: %s", err)
+			}
+			protected := &common.ProtectedUserData{
+				UserID:     userID,
+				UserData:   ciphertext,
+				WrappedKey: wrappedKey,
+			}
+
+			authStoreTx := &authstorage.AuthStoreTxMock{
+				GetUserDataFunc: func(ctx context.Context, userID uuid.UUID) (*common.ProtectedUserData, error) {
+					return protected, nil
+				},
+				UpdateUserFunc: func(ctx context.Context, updated *common.ProtectedUserData) error {
+					protected = updated
+					return nil
+				},
+				GetGroupDataBatchFunc: func(ctx context.Context, groupIDs []uuid.UUID) ([]common.ProtectedGroupData, error) {
+					return nil, nil
+				},
+			}
+			ctx := context.WithValue(context.Background(), common.AuthStorageTxCtxKey, authStoreTx)
+
+			_, err = userAuthenticator.LoginUser(ctx, userID, password)
+			failOnError("Expected LoginUser to succeed", err, t)
+
+			updated, err := userAuthenticator.GetUserData(ctx, userID)
+			failOnError("Expected GetUserData to succeed", err, t)
+			if crypt.PasswordNeedsRehash(updated.HashedPassword, updated.Salt) {
+				t.Fatalf("Password hash was not upgraded: %s", updated.HashedPassword)
+			}
+			if updated.Salt != nil {
+				t.Fatalf("Legacy salt was not removed")
+			}
+
+			// The upgraded hash must still be accepted
+			_, err = userAuthenticator.LoginUser(ctx, userID, password)
+			failOnError("Expected LoginUser to succeed after upgrade", err, t)
+		})
+	}
+}
+
 func TestLoginUserWrongPassword(t *testing.T) {
 	userAuthenticator, err := SetupUA()
 	if err != nil {
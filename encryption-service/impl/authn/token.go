@@ -21,6 +21,8 @@ import (
 	"time"
 
 	"github.com/gofrs/uuid"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"encryption-service/common"
 	"encryption-service/interfaces"
@@ -64,8 +66,41 @@ func (at *AccessToken) IsValid() bool {
 	return time.Now().Before(at.ExpiryTime)
 }
 
+// MarshalRecord serializes the access token as an AccessTokenRecord
+func (at *AccessToken) MarshalRecord() ([]byte, error) {
+	return proto.Marshal(&common.AccessTokenRecord{
+		SchemaVersion: common.AccessTokenRecordSchemaVersion,
+		UserId:        at.UserID.Bytes(),
+		Scopes:        uint64(at.Scopes),
+		ExpiryTime:    timestamppb.New(at.ExpiryTime),
+	})
+}
+
+// UnmarshalRecord deserializes an access token from an AccessTokenRecord
+func (at *AccessToken) UnmarshalRecord(data []byte) error {
+	record := &common.AccessTokenRecord{}
+	if err := common.UnmarshalVersionedRecord(data, record, record.GetSchemaVersion, common.AccessTokenRecordSchemaVersion); err != nil {
+		return err
+	}
+
+	userID, err := uuid.FromBytes(record.UserId)
+	if err != nil {
+		return err
+	}
+	if err := record.ExpiryTime.CheckValid(); err != nil {
+		return err
+	}
+
+	*at = AccessToken{
+		UserID:     userID,
+		Scopes:     common.ScopeType(record.Scopes),
+		ExpiryTime: record.ExpiryTime.AsTime().Local(),
+	}
+	return nil
+}
+
 // SerializeAccessToken encrypts and serializes an access token with a CryptorInterface
-// Format (only used internally): base64_url(wrapped_key).base64_url(gob(enc(AccessToken)))
+// Format (only used internally): base64_url(wrapped_key).base64_url(enc(AccessTokenRecord))
 func (at *AccessToken) SerializeAccessToken(cryptor interfaces.CryptorInterface) (string, error) {
 	//TODO not sure about these checks
 	if at.Scopes.IsValid() != nil {
@@ -79,6 +79,42 @@ func TestSerializeParse(t *testing.T) {
 	}
 }
 
+func TestParseRetiredTEK(t *testing.T) {
+	oldTEK, err := crypt.Random(32)
+	if err != nil {
+		t.Fatalf("Random errored: %v", err)
+	}
+	newTEK, err := crypt.Random(32)
+	if err != nil {
+		t.Fatalf("Random errored: %v", err)
+	}
+
+	oldKeyRing, err := crypt.NewKWPKeyRing(1, map[uint32][]byte{1: oldTEK})
+	if err != nil {
+		t.Fatalf("NewKWPKeyRing errored: %v", err)
+	}
+	newKeyRing, err := crypt.NewKWPKeyRing(2, map[uint32][]byte{1: oldTEK, 2: newTEK})
+	if err != nil {
+		t.Fatalf("NewKWPKeyRing errored: %v", err)
+	}
+
+	// Tokens issued under the retired TEK remain valid after rotation
+	accessToken := NewAccessTokenDuration(userID, scope, time.Second*30)
+	token, err := accessToken.SerializeAccessToken(crypt.NewAESCryptorWithKeyWrap(oldKeyRing))
+	if err != nil {
+		t.Fatalf("SerializeAccessToken errored: %v", err)
+	}
+
+	parsedAccessToken, err := ParseAccessToken(crypt.NewAESCryptorWithKeyWrap(newKeyRing), token)
+	if err != nil {
+		t.Fatalf("ParseAccessToken errored: %v", err)
+	}
+
+	if !reflect.DeepEqual(accessToken, parsedAccessToken) {
+		t.Fatalf("accessToken doesn't match: %v != %v", accessToken, parsedAccessToken)
+	}
+}
+
 func TestParseExpiry(t *testing.T) {
 	userID := uuid.Must(uuid.NewV4())
 	kek, err := crypt.Random(32)
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgtype"
 	pgtypeuuid "github.com/jackc/pgtype/ext/gofrs-uuid"
 	"github.com/jackc/pgx/v4"
@@ -54,6 +55,9 @@ func initCircuitBreaker() *gobreaker.CircuitBreaker {
 
 var cb *gobreaker.CircuitBreaker = initCircuitBreaker()
 
+// SQLSTATE returned by Postgres and CockroachDB when a UNIQUE constraint is violated
+const uniqueViolationCode = "23505"
+
 // Implementation of the AuthStoreInterface
 type AuthStore struct {
 	Pool *pgxpool.Pool
@@ -220,6 +224,11 @@ func (storeTx *AuthStoreTx) GetUserData(ctx context.Context, userID uuid.UUID) (
 	return protected, nil
 }
 
+// ListUserIDs lists up to `limit` IDs of users that are not removed and greater than `after` in ascending order
+func (storeTx *AuthStoreTx) ListUserIDs(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return storeTx.listIDs(ctx, "SELECT id FROM users WHERE id > $1 AND deleted_at IS NULL ORDER BY id LIMIT $2", after, limit)
+}
+
 // GroupExists checks if a group exists in the auth store
 func (storeTx *AuthStoreTx) GroupExists(ctx context.Context, groupID uuid.UUID) (bool, error) {
 	var fetchedID []byte
@@ -242,6 +251,23 @@ func (storeTx *AuthStoreTx) InsertGroup(ctx context.Context, protected *common.P
 	return err
 }
 
+// UpdateGroup updates an existing group's data
+func (storeTx *AuthStoreTx) UpdateGroup(ctx context.Context, protected *common.ProtectedGroupData) error {
+	res, err := storeTx.Tx.Exec(ctx, storeTx.NewQuery("UPDATE groups SET data = $1, key = $2 WHERE id = $3"), protected.GroupData, protected.WrappedKey, protected.GroupID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() < 1 {
+		return interfaces.ErrNotFound
+	}
+	return nil
+}
+
+// ListGroupIDs lists up to `limit` group IDs greater than `after` in ascending order
+func (storeTx *AuthStoreTx) ListGroupIDs(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return storeTx.listIDs(ctx, "SELECT id FROM groups WHERE id > $1 ORDER BY id LIMIT $2", after, limit)
+}
+
 // Get one or more groups' confidential data
 func (storeTx *AuthStoreTx) GetGroupDataBatch(ctx context.Context, groupIDs []uuid.UUID) ([]common.ProtectedGroupData, error) {
 	rows, err := storeTx.Tx.Query(ctx, storeTx.NewQuery("SELECT data, key, id FROM groups WHERE id = any($1)"), groupIDs)
@@ -287,6 +313,10 @@ func (storeTx *AuthStoreTx) GetAccessObject(ctx context.Context, objectID uuid.U
 // InsertAcccessObject inserts an Access Object (Object ID, data, tag)
 func (storeTx *AuthStoreTx) InsertAcccessObject(ctx context.Context, protected *common.ProtectedAccessObject) error {
 	_, err := storeTx.Tx.Exec(ctx, storeTx.NewQuery("INSERT INTO access_objects (id, data, key) VALUES ($1, $2, $3)"), protected.ObjectID, protected.AccessObject, protected.WrappedKey)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return fmt.Errorf("%w: %v", interfaces.ErrAlreadyExists, err)
+	}
 	return err
 }
 
@@ -312,3 +342,115 @@ func (storeTx *AuthStoreTx) DeleteAccessObject(ctx context.Context, objectID uui
 	_, err = storeTx.Tx.Exec(ctx, storeTx.NewQuery("DELETE FROM access_objects WHERE id = $1"), objectID)
 	return err
 }
+
+// ListAccessObjectIDs lists up to `limit` Object IDs greater than `after` in ascending order
+func (storeTx *AuthStoreTx) ListAccessObjectIDs(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return storeTx.listIDs(ctx, "SELECT id FROM access_objects WHERE id > $1 ORDER BY id LIMIT $2", after, limit)
+}
+
+// GetErasureReceipt fetches data, key of the erasure receipt of a shredded object
+func (storeTx *AuthStoreTx) GetErasureReceipt(ctx context.Context, objectID uuid.UUID) (*common.ProtectedErasureReceipt, error) {
+	protected := &common.ProtectedErasureReceipt{ObjectID: objectID}
+
+	row := storeTx.Tx.QueryRow(ctx, storeTx.NewQuery("SELECT data, key FROM erasure_receipts WHERE id = $1"), objectID)
+	err := row.Scan(&protected.Receipt, &protected.WrappedKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, interfaces.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return protected, nil
+}
+
+// InsertErasureReceipt inserts an erasure receipt (Object ID, data, key)
+func (storeTx *AuthStoreTx) InsertErasureReceipt(ctx context.Context, protected *common.ProtectedErasureReceipt) error {
+	_, err := storeTx.Tx.Exec(ctx, storeTx.NewQuery("INSERT INTO erasure_receipts (id, data, key) VALUES ($1, $2, $3)"), protected.ObjectID, protected.Receipt, protected.WrappedKey)
+	return err
+}
+
+// UpdateErasureReceipt updates the erasure receipt with Object ID and sets data, key
+func (storeTx *AuthStoreTx) UpdateErasureReceipt(ctx context.Context, protected *common.ProtectedErasureReceipt) error {
+	res, err := storeTx.Tx.Exec(ctx, storeTx.NewQuery("UPDATE erasure_receipts SET data = $1, key = $2 WHERE id = $3"), protected.Receipt, protected.WrappedKey, protected.ObjectID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() < 1 {
+		return interfaces.ErrNotFound
+	}
+	return err
+}
+
+// ListErasureReceiptIDs lists up to `limit` Object IDs of shredded objects greater than `after` in
+// ascending order
+func (storeTx *AuthStoreTx) ListErasureReceiptIDs(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return storeTx.listIDs(ctx, "SELECT id FROM erasure_receipts WHERE id > $1 ORDER BY id LIMIT $2", after, limit)
+}
+
+// GetToken fetches value tag, data of a token in a namespace
+func (storeTx *AuthStoreTx) GetToken(ctx context.Context, namespace uuid.UUID, token string) (*common.ProtectedToken, error) {
+	protected := &common.ProtectedToken{Namespace: namespace, Token: token}
+
+	row := storeTx.Tx.QueryRow(ctx, storeTx.NewQuery("SELECT value_tag, data FROM tokens WHERE namespace = $1 AND token = $2"), namespace, token)
+	err := row.Scan(&protected.ValueTag, &protected.Value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, interfaces.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return protected, nil
+}
+
+// GetTokenByValueTag fetches token, data of the token in a namespace with the given value tag
+func (storeTx *AuthStoreTx) GetTokenByValueTag(ctx context.Context, namespace uuid.UUID, valueTag []byte) (*common.ProtectedToken, error) {
+	protected := &common.ProtectedToken{Namespace: namespace, ValueTag: valueTag}
+
+	row := storeTx.Tx.QueryRow(ctx, storeTx.NewQuery("SELECT token, data FROM tokens WHERE namespace = $1 AND value_tag = $2"), namespace, valueTag)
+	err := row.Scan(&protected.Token, &protected.Value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, interfaces.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return protected, nil
+}
+
+// InsertToken inserts a token (namespace, token, value tag, data)
+func (storeTx *AuthStoreTx) InsertToken(ctx context.Context, protected *common.ProtectedToken) error {
+	_, err := storeTx.Tx.Exec(ctx, storeTx.NewQuery("INSERT INTO tokens (namespace, token, value_tag, data) VALUES ($1, $2, $3, $4)"), protected.Namespace, protected.Token, protected.ValueTag, protected.Value)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return fmt.Errorf("%w: %v", interfaces.ErrAlreadyExists, err)
+	}
+	return err
+}
+
+// listIDs runs a query selecting IDs following `after` and collects the results
+func (storeTx *AuthStoreTx) listIDs(ctx context.Context, query string, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	rows, err := storeTx.Tx.Query(ctx, storeTx.NewQuery(query), after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]uuid.UUID, 0, limit)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
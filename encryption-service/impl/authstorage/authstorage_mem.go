@@ -18,6 +18,7 @@ import (
 	"context"
 	"encoding/gob"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/gofrs/uuid"
@@ -33,6 +34,9 @@ type MemoryAuthStore struct {
 	userBucket         []byte
 	groupBucket        []byte
 	accessObjectBucket []byte
+	receiptBucket      []byte
+	tokenBucket        []byte
+	valueTagBucket     []byte
 }
 
 func NewMemoryAuthStore(dbFilePath string) (*MemoryAuthStore, error) {
@@ -44,6 +48,9 @@ func NewMemoryAuthStore(dbFilePath string) (*MemoryAuthStore, error) {
 	userBucket := []byte("user")
 	groupBucket := []byte("group")
 	accessObjectBucket := []byte("access_object")
+	receiptBucket := []byte("erasure_receipt")
+	tokenBucket := []byte("token")
+	valueTagBucket := []byte("token_value_tag")
 
 	err = db.Update(func(tx *bolt.Tx) error {
 		_, err := tx.CreateBucketIfNotExists(userBucket)
@@ -58,13 +65,25 @@ func NewMemoryAuthStore(dbFilePath string) (*MemoryAuthStore, error) {
 		if err != nil {
 			return err
 		}
+		_, err = tx.CreateBucketIfNotExists(receiptBucket)
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists(tokenBucket)
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists(valueTagBucket)
+		if err != nil {
+			return err
+		}
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &MemoryAuthStore{db, userBucket, groupBucket, accessObjectBucket}, nil
+	return &MemoryAuthStore{db, userBucket, groupBucket, accessObjectBucket, receiptBucket, tokenBucket, valueTagBucket}, nil
 }
 
 func (store *MemoryAuthStore) Close() {
@@ -76,6 +95,9 @@ type MemoryAuthStoreTx struct {
 	UserBucket         []byte
 	GroupBucket        []byte
 	AccessObjectBucket []byte
+	ReceiptBucket      []byte
+	TokenBucket        []byte
+	ValueTagBucket     []byte
 }
 
 func (store *MemoryAuthStore) NewTransaction(ctx context.Context) (interfaces.AuthStoreTxInterface, error) {
@@ -84,7 +106,7 @@ func (store *MemoryAuthStore) NewTransaction(ctx context.Context) (interfaces.Au
 		return nil, err
 	}
 
-	return &MemoryAuthStoreTx{tx, store.userBucket, store.groupBucket, store.accessObjectBucket}, nil
+	return &MemoryAuthStoreTx{tx, store.userBucket, store.groupBucket, store.accessObjectBucket, store.receiptBucket, store.tokenBucket, store.valueTagBucket}, nil
 }
 
 func (storeTx *MemoryAuthStoreTx) Commit(ctx context.Context) error {
@@ -162,6 +184,17 @@ func (storeTx *MemoryAuthStoreTx) RemoveUser(ctx context.Context, userID uuid.UU
 	return b.Put(userID.Bytes(), userBuffer.Bytes())
 }
 
+func (storeTx *MemoryAuthStoreTx) ListUserIDs(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return storeTx.listIDs(storeTx.UserBucket, after, limit, func(value []byte) (bool, error) {
+		userData := &common.ProtectedUserData{}
+		dec := gob.NewDecoder(bytes.NewReader(value))
+		if err := dec.Decode(userData); err != nil {
+			return false, err
+		}
+		return userData.DeletedAt != nil, nil
+	})
+}
+
 func (storeTx *MemoryAuthStoreTx) GroupExists(ctx context.Context, groupID uuid.UUID) (bool, error) {
 	groupDataBatch, err := storeTx.GetGroupDataBatch(ctx, []uuid.UUID{groupID})
 	if err != nil {
@@ -183,6 +216,21 @@ func (storeTx *MemoryAuthStoreTx) InsertGroup(ctx context.Context, protected *co
 	return b.Put(protected.GroupID.Bytes(), groupBuffer.Bytes())
 }
 
+func (storeTx *MemoryAuthStoreTx) UpdateGroup(ctx context.Context, protected *common.ProtectedGroupData) error {
+	exists, err := storeTx.GroupExists(ctx, protected.GroupID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return interfaces.ErrNotFound
+	}
+	return storeTx.InsertGroup(ctx, protected)
+}
+
+func (storeTx *MemoryAuthStoreTx) ListGroupIDs(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return storeTx.listIDs(storeTx.GroupBucket, after, limit, nil)
+}
+
 func (storeTx *MemoryAuthStoreTx) GetGroupDataBatch(ctx context.Context, groupIDs []uuid.UUID) ([]common.ProtectedGroupData, error) {
 	b := storeTx.Tx.Bucket(storeTx.GroupBucket)
 
@@ -226,6 +274,20 @@ func (storeTx *MemoryAuthStoreTx) GetAccessObject(ctx context.Context, objectID
 }
 
 func (storeTx *MemoryAuthStoreTx) InsertAcccessObject(ctx context.Context, protected *common.ProtectedAccessObject) error {
+	b := storeTx.Tx.Bucket(storeTx.AccessObjectBucket)
+	if b.Get(protected.ObjectID.Bytes()) != nil {
+		return fmt.Errorf("access object %w", interfaces.ErrAlreadyExists)
+	}
+
+	return storeTx.putAccessObject(protected)
+}
+
+func (storeTx *MemoryAuthStoreTx) UpdateAccessObject(ctx context.Context, accessObject *common.ProtectedAccessObject) error {
+	return storeTx.putAccessObject(accessObject)
+}
+
+// putAccessObject stores an access object, replacing any existing one with the same ID
+func (storeTx *MemoryAuthStoreTx) putAccessObject(protected *common.ProtectedAccessObject) error {
 	var objectBuffer bytes.Buffer
 	enc := gob.NewEncoder(&objectBuffer)
 	err := enc.Encode(protected)
@@ -238,12 +300,141 @@ func (storeTx *MemoryAuthStoreTx) InsertAcccessObject(ctx context.Context, prote
 	return b.Put(protected.ObjectID.Bytes(), objectBuffer.Bytes())
 }
 
-func (storeTx *MemoryAuthStoreTx) UpdateAccessObject(ctx context.Context, accessObject *common.ProtectedAccessObject) error {
-	return storeTx.InsertAcccessObject(ctx, accessObject)
-}
-
 func (storeTx *MemoryAuthStoreTx) DeleteAccessObject(ctx context.Context, objectID uuid.UUID) error {
 	b := storeTx.Tx.Bucket(storeTx.AccessObjectBucket)
 
 	return b.Delete(objectID.Bytes())
 }
+
+func (storeTx *MemoryAuthStoreTx) ListAccessObjectIDs(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return storeTx.listIDs(storeTx.AccessObjectBucket, after, limit, nil)
+}
+
+func (storeTx *MemoryAuthStoreTx) GetErasureReceipt(ctx context.Context, objectID uuid.UUID) (*common.ProtectedErasureReceipt, error) {
+	b := storeTx.Tx.Bucket(storeTx.ReceiptBucket)
+
+	obj := b.Get(objectID.Bytes())
+	if obj == nil {
+		return nil, interfaces.ErrNotFound
+	}
+
+	receipt := &common.ProtectedErasureReceipt{}
+	dec := gob.NewDecoder(bytes.NewReader(obj))
+	err := dec.Decode(receipt)
+	if err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
+
+func (storeTx *MemoryAuthStoreTx) InsertErasureReceipt(ctx context.Context, protected *common.ProtectedErasureReceipt) error {
+	var receiptBuffer bytes.Buffer
+	enc := gob.NewEncoder(&receiptBuffer)
+	err := enc.Encode(protected)
+	if err != nil {
+		return err
+	}
+
+	b := storeTx.Tx.Bucket(storeTx.ReceiptBucket)
+
+	return b.Put(protected.ObjectID.Bytes(), receiptBuffer.Bytes())
+}
+
+func (storeTx *MemoryAuthStoreTx) UpdateErasureReceipt(ctx context.Context, protected *common.ProtectedErasureReceipt) error {
+	if _, err := storeTx.GetErasureReceipt(ctx, protected.ObjectID); err != nil {
+		return err
+	}
+	return storeTx.InsertErasureReceipt(ctx, protected)
+}
+
+func (storeTx *MemoryAuthStoreTx) ListErasureReceiptIDs(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return storeTx.listIDs(storeTx.ReceiptBucket, after, limit, nil)
+}
+
+func (storeTx *MemoryAuthStoreTx) GetToken(ctx context.Context, namespace uuid.UUID, token string) (*common.ProtectedToken, error) {
+	b := storeTx.Tx.Bucket(storeTx.TokenBucket)
+
+	obj := b.Get(append(namespace.Bytes(), token...))
+	if obj == nil {
+		return nil, interfaces.ErrNotFound
+	}
+
+	protected := &common.ProtectedToken{}
+	dec := gob.NewDecoder(bytes.NewReader(obj))
+	err := dec.Decode(protected)
+	if err != nil {
+		return nil, err
+	}
+
+	return protected, nil
+}
+
+func (storeTx *MemoryAuthStoreTx) GetTokenByValueTag(ctx context.Context, namespace uuid.UUID, valueTag []byte) (*common.ProtectedToken, error) {
+	b := storeTx.Tx.Bucket(storeTx.ValueTagBucket)
+
+	token := b.Get(append(namespace.Bytes(), valueTag...))
+	if token == nil {
+		return nil, interfaces.ErrNotFound
+	}
+
+	return storeTx.GetToken(ctx, namespace, string(token))
+}
+
+func (storeTx *MemoryAuthStoreTx) InsertToken(ctx context.Context, protected *common.ProtectedToken) error {
+	key := append(protected.Namespace.Bytes(), protected.Token...)
+	tokenBucket := storeTx.Tx.Bucket(storeTx.TokenBucket)
+	if tokenBucket.Get(key) != nil {
+		return fmt.Errorf("token %w", interfaces.ErrAlreadyExists)
+	}
+
+	var tokenBuffer bytes.Buffer
+	enc := gob.NewEncoder(&tokenBuffer)
+	err := enc.Encode(protected)
+	if err != nil {
+		return err
+	}
+
+	if protected.ValueTag != nil {
+		valueTagKey := append(protected.Namespace.Bytes(), protected.ValueTag...)
+		valueTagBucket := storeTx.Tx.Bucket(storeTx.ValueTagBucket)
+		if valueTagBucket.Get(valueTagKey) != nil {
+			return fmt.Errorf("value tag %w", interfaces.ErrAlreadyExists)
+		}
+		if err := valueTagBucket.Put(valueTagKey, []byte(protected.Token)); err != nil {
+			return err
+		}
+	}
+
+	return tokenBucket.Put(key, tokenBuffer.Bytes())
+}
+
+// listIDs collects up to `limit` keys of a bucket following `after`, skipping keys for which `skip`
+// returns true
+func (storeTx *MemoryAuthStoreTx) listIDs(bucket []byte, after uuid.UUID, limit int, skip func(value []byte) (bool, error)) ([]uuid.UUID, error) {
+	c := storeTx.Tx.Bucket(bucket).Cursor()
+
+	ids := make([]uuid.UUID, 0, limit)
+	for k, v := c.Seek(after.Bytes()); k != nil && len(ids) < limit; k, v = c.Next() {
+		id, err := uuid.FromBytes(k)
+		if err != nil {
+			return nil, err
+		}
+		if id == after {
+			continue
+		}
+		if skip != nil {
+			skipped, err := skip(v)
+			if err != nil {
+				return nil, err
+			}
+			if skipped {
+				continue
+			}
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
@@ -46,15 +46,28 @@ type AuthStoreTxMock struct {
 	UpdateUserFunc  func(ctx context.Context, protected *common.ProtectedUserData) error
 	GetUserDataFunc func(ctx context.Context, userID uuid.UUID) (*common.ProtectedUserData, error)
 	RemoveUserFunc  func(ctx context.Context, userID uuid.UUID) error
+	ListUserIDsFunc func(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error)
 
 	GroupExistsFunc       func(ctx context.Context, groupID uuid.UUID) (bool, error)
 	InsertGroupFunc       func(ctx context.Context, group *common.ProtectedGroupData) error
+	UpdateGroupFunc       func(ctx context.Context, group *common.ProtectedGroupData) error
+	ListGroupIDsFunc      func(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error)
 	GetGroupDataBatchFunc func(ctx context.Context, groupIDs []uuid.UUID) ([]common.ProtectedGroupData, error)
 
 	GetAccessObjectFunc     func(ctx context.Context, objectID uuid.UUID) (*common.ProtectedAccessObject, error)
 	InsertAcccessObjectFunc func(ctx context.Context, protected *common.ProtectedAccessObject) error
 	UpdateAccessObjectFunc  func(ctx context.Context, protected *common.ProtectedAccessObject) error
 	DeleteAccessObjectFunc  func(ctx context.Context, objectID uuid.UUID) error
+	ListAccessObjectIDsFunc func(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error)
+
+	GetErasureReceiptFunc     func(ctx context.Context, objectID uuid.UUID) (*common.ProtectedErasureReceipt, error)
+	InsertErasureReceiptFunc  func(ctx context.Context, protected *common.ProtectedErasureReceipt) error
+	UpdateErasureReceiptFunc  func(ctx context.Context, protected *common.ProtectedErasureReceipt) error
+	ListErasureReceiptIDsFunc func(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error)
+
+	GetTokenFunc           func(ctx context.Context, namespace uuid.UUID, token string) (*common.ProtectedToken, error)
+	GetTokenByValueTagFunc func(ctx context.Context, namespace uuid.UUID, valueTag []byte) (*common.ProtectedToken, error)
+	InsertTokenFunc        func(ctx context.Context, protected *common.ProtectedToken) error
 }
 
 func (db *AuthStoreTxMock) Commit(ctx context.Context) error {
@@ -80,6 +93,10 @@ func (db *AuthStoreTxMock) GetUserData(ctx context.Context, userID uuid.UUID) (*
 	return db.GetUserDataFunc(ctx, userID)
 }
 
+func (db *AuthStoreTxMock) ListUserIDs(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return db.ListUserIDsFunc(ctx, after, limit)
+}
+
 func (db *AuthStoreTxMock) GroupExists(ctx context.Context, groupID uuid.UUID) (bool, error) {
 	return db.GroupExistsFunc(ctx, groupID)
 }
@@ -88,6 +105,14 @@ func (db *AuthStoreTxMock) InsertGroup(ctx context.Context, protected *common.Pr
 	return db.InsertGroupFunc(ctx, protected)
 }
 
+func (db *AuthStoreTxMock) UpdateGroup(ctx context.Context, protected *common.ProtectedGroupData) error {
+	return db.UpdateGroupFunc(ctx, protected)
+}
+
+func (db *AuthStoreTxMock) ListGroupIDs(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return db.ListGroupIDsFunc(ctx, after, limit)
+}
+
 func (db *AuthStoreTxMock) GetGroupDataBatch(ctx context.Context, groupIDs []uuid.UUID) ([]common.ProtectedGroupData, error) {
 	return db.GetGroupDataBatchFunc(ctx, groupIDs)
 }
@@ -107,3 +132,35 @@ func (db *AuthStoreTxMock) UpdateAccessObject(ctx context.Context, protected *co
 func (db *AuthStoreTxMock) DeleteAccessObject(ctx context.Context, objectID uuid.UUID) error {
 	return db.DeleteAccessObjectFunc(ctx, objectID)
 }
+
+func (db *AuthStoreTxMock) ListAccessObjectIDs(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return db.ListAccessObjectIDsFunc(ctx, after, limit)
+}
+
+func (db *AuthStoreTxMock) GetErasureReceipt(ctx context.Context, objectID uuid.UUID) (*common.ProtectedErasureReceipt, error) {
+	return db.GetErasureReceiptFunc(ctx, objectID)
+}
+
+func (db *AuthStoreTxMock) InsertErasureReceipt(ctx context.Context, protected *common.ProtectedErasureReceipt) error {
+	return db.InsertErasureReceiptFunc(ctx, protected)
+}
+
+func (db *AuthStoreTxMock) UpdateErasureReceipt(ctx context.Context, protected *common.ProtectedErasureReceipt) error {
+	return db.UpdateErasureReceiptFunc(ctx, protected)
+}
+
+func (db *AuthStoreTxMock) ListErasureReceiptIDs(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return db.ListErasureReceiptIDsFunc(ctx, after, limit)
+}
+
+func (db *AuthStoreTxMock) GetToken(ctx context.Context, namespace uuid.UUID, token string) (*common.ProtectedToken, error) {
+	return db.GetTokenFunc(ctx, namespace, token)
+}
+
+func (db *AuthStoreTxMock) GetTokenByValueTag(ctx context.Context, namespace uuid.UUID, valueTag []byte) (*common.ProtectedToken, error) {
+	return db.GetTokenByValueTagFunc(ctx, namespace, valueTag)
+}
+
+func (db *AuthStoreTxMock) InsertToken(ctx context.Context, protected *common.ProtectedToken) error {
+	return db.InsertTokenFunc(ctx, protected)
+}
@@ -16,6 +16,8 @@ package authz
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/gofrs/uuid"
 
@@ -24,20 +26,38 @@ import (
 )
 
 var ErrAuthStoreTxCastFailed = errors.New("Could not typecast authstorage to authstorage.AuthStoreInterface")
+var ErrNoReceiptSigner = errors.New("no erasure receipt signer configured")
 
 // Authorizer encapsulates a MessageAuthenticator and a backing Auth Storage for reading and writing Access Objects
 type Authorizer struct {
 	AccessObjectCryptor interfaces.CryptorInterface
+	// Signs erasure receipts, such that they can be verified without access to the Auth Storage
+	ReceiptSigner interfaces.SignerInterface
+	// Key ID of the receipt signing key, recorded in each receipt
+	ReceiptSigningKeyID uint32
 }
 
 // CreateObject creates a new object with given parameters and inserts it into the Auth Store.
-func (a *Authorizer) CreateAccessObject(ctx context.Context, objectID, groupID uuid.UUID, woek []byte) error {
+func (a *Authorizer) CreateAccessObject(ctx context.Context, objectID, groupID uuid.UUID, keyType common.KeyType, woek []byte) error {
+	return a.insertAccessObject(ctx, objectID, common.NewAccessObject(groupID, keyType, woek))
+}
+
+// CreateAccessObjectWithKeyID creates a new data object encrypted under the imported key with ID
+// `keyID` and inserts it into the Auth Store. The object only refers to the key, such that deleting
+// the key makes the object data unreadable.
+func (a *Authorizer) CreateAccessObjectWithKeyID(ctx context.Context, objectID, groupID, keyID uuid.UUID) error {
+	accessObject := common.NewAccessObject(groupID, common.KeyTypeData, nil)
+	accessObject.KeyID = keyID
+	return a.insertAccessObject(ctx, objectID, accessObject)
+}
+
+// insertAccessObject encrypts a new access object and inserts it into the Auth Store
+func (a *Authorizer) insertAccessObject(ctx context.Context, objectID uuid.UUID, accessObject *common.AccessObject) error {
 	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
 	if !ok {
 		return ErrAuthStoreTxCastFailed
 	}
 
-	accessObject := common.NewAccessObject(groupID, woek)
 	wrappedKey, ciphertext, err := a.AccessObjectCryptor.EncodeAndEncrypt(accessObject, objectID.Bytes())
 	if err != nil {
 		return err
@@ -78,6 +98,28 @@ func (a *Authorizer) FetchAccessObject(ctx context.Context, objectID uuid.UUID)
 	return accessObject, nil
 }
 
+// FetchObjectKey returns the wrapped key the data of an object is encrypted under. For data encrypted
+// under an imported key this is the key held by the access object of the key.
+func (a *Authorizer) FetchObjectKey(ctx context.Context, accessObject *common.AccessObject) ([]byte, error) {
+	if accessObject.KeyID == uuid.Nil {
+		return accessObject.GetWOEK(), nil
+	}
+
+	keyAccessObject, err := a.FetchAccessObject(ctx, accessObject.KeyID)
+	if errors.Is(err, interfaces.ErrNotFound) {
+		return nil, common.ErrImportedKeyDeleted
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !keyAccessObject.HasKeyType(common.KeyTypeImported) {
+		return nil, fmt.Errorf("key %v of object is not an imported key", accessObject.KeyID)
+	}
+
+	return keyAccessObject.GetWOEK(), nil
+}
+
 // updatePermissions increments the Access Object's version and updates in the Auth Storage
 func (a *Authorizer) UpdateAccessObject(ctx context.Context, objectID uuid.UUID, accessObject common.AccessObject) error {
 	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
@@ -87,7 +129,7 @@ func (a *Authorizer) UpdateAccessObject(ctx context.Context, objectID uuid.UUID,
 
 	accessObject.Version++
 
-	wrappedKey, ciphertext, err := a.AccessObjectCryptor.EncodeAndEncrypt(accessObject, objectID.Bytes())
+	wrappedKey, ciphertext, err := a.AccessObjectCryptor.EncodeAndEncrypt(&accessObject, objectID.Bytes())
 	if err != nil {
 		return err
 	}
@@ -119,3 +161,86 @@ func (a *Authorizer) DeleteAccessObject(ctx context.Context, objectID uuid.UUID)
 
 	return nil
 }
+
+// ShredAccessObject destroys an Access Object, and with it the object key, and records an erasure
+// receipt in the Auth Storage
+func (a *Authorizer) ShredAccessObject(ctx context.Context, objectID uuid.UUID) (*common.ErasureReceipt, error) {
+	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
+	if !ok {
+		return nil, ErrAuthStoreTxCastFailed
+	}
+
+	protected, err := authStorageTx.GetAccessObject(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The former groups of the object are kept in the receipt to authorize access to it
+	accessObject := &common.AccessObject{}
+	err = a.AccessObjectCryptor.DecodeAndDecrypt(accessObject, protected.WrappedKey, protected.AccessObject, objectID.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := common.NewErasureReceipt(protected, accessObject.GroupIDs, time.Now())
+	if err := a.signErasureReceipt(receipt); err != nil {
+		return nil, err
+	}
+	wrappedKey, ciphertext, err := a.AccessObjectCryptor.EncodeAndEncrypt(receipt, common.ErasureReceiptAAD(objectID))
+	if err != nil {
+		return nil, err
+	}
+
+	err = authStorageTx.InsertErasureReceipt(ctx, &common.ProtectedErasureReceipt{
+		ObjectID:   objectID,
+		Receipt:    ciphertext,
+		WrappedKey: wrappedKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = authStorageTx.DeleteAccessObject(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
+
+// FetchErasureReceipt fetches the erasure receipt of a shredded object and decrypts it
+func (a *Authorizer) FetchErasureReceipt(ctx context.Context, objectID uuid.UUID) (*common.ErasureReceipt, error) {
+	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
+	if !ok {
+		return nil, ErrAuthStoreTxCastFailed
+	}
+
+	protected, err := authStorageTx.GetErasureReceipt(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := &common.ErasureReceipt{}
+	err = a.AccessObjectCryptor.DecodeAndDecrypt(receipt, protected.WrappedKey, protected.Receipt, common.ErasureReceiptAAD(objectID))
+	if err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
+
+// signErasureReceipt signs the receipt and records the ID of the signing key
+func (a *Authorizer) signErasureReceipt(receipt *common.ErasureReceipt) error {
+	if a.ReceiptSigner == nil {
+		return ErrNoReceiptSigner
+	}
+
+	receipt.KeyID = a.ReceiptSigningKeyID
+	signature, err := a.ReceiptSigner.Sign(receipt.SignedData())
+	if err != nil {
+		return err
+	}
+
+	receipt.Signature = signature
+	return nil
+}
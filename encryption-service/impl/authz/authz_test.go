@@ -15,6 +15,7 @@ package authz
 
 import (
 	"context"
+	"crypto/ed25519"
 	"errors"
 	"reflect"
 	"testing"
@@ -24,6 +25,7 @@ import (
 	"encryption-service/common"
 	"encryption-service/impl/authstorage"
 	"encryption-service/impl/crypt"
+	"encryption-service/interfaces"
 )
 
 var objectID = uuid.Must(uuid.FromString("20000000-0000-0000-0000-000000000000"))
@@ -34,12 +36,30 @@ var accessObject = &common.AccessObject{
 	GroupIDs: map[uuid.UUID]bool{
 		groupID: true,
 	},
-	Woek: woek,
+	Woek:        woek,
+	KeyType:     common.KeyTypeData,
+	DataVersion: common.InitialDataVersion,
 }
 
 var cryptor, _ = crypt.NewAESCryptor([]byte("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"))
+var receiptSigner, _ = crypt.NewEd25519Signer([]byte("BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB"))
 var authorizer = &Authorizer{
 	AccessObjectCryptor: cryptor,
+	ReceiptSigner:       receiptSigner,
+	ReceiptSigningKeyID: 3,
+}
+
+// protectAccessObject encrypts the access object as stored in the Auth Storage
+func protectAccessObject(t *testing.T) *common.ProtectedAccessObject {
+	wrappedKey, ciphertext, err := cryptor.EncodeAndEncrypt(accessObject, objectID.Bytes())
+	if err != nil {
+		t.Fatalf("EncodeAndEncrypt failed: %v", err)
+	}
+	return &common.ProtectedAccessObject{
+		ObjectID:     objectID,
+		AccessObject: ciphertext,
+		WrappedKey:   wrappedKey,
+	}
 }
 
 func TestRemoveUserNonExisting(t *testing.T) {
@@ -69,7 +89,7 @@ func TestCreateObject(t *testing.T) {
 	}
 	ctx := context.WithValue(context.Background(), common.AuthStorageTxCtxKey, authStoreTx)
 
-	err := authorizer.CreateAccessObject(ctx, objectID, groupID, woek)
+	err := authorizer.CreateAccessObject(ctx, objectID, groupID, common.KeyTypeData, woek)
 	if err != nil {
 		t.Fatalf("CreateAccessObject errored: %s", err)
 	}
@@ -83,7 +103,7 @@ func TestCreateObjectFail(t *testing.T) {
 	}
 	ctx := context.WithValue(context.Background(), common.AuthStorageTxCtxKey, authStoreTx)
 
-	err := authorizer.CreateAccessObject(ctx, objectID, groupID, woek)
+	err := authorizer.CreateAccessObject(ctx, objectID, groupID, common.KeyTypeData, woek)
 	if err == nil || err.Error() != "mock error" {
 		t.Error("CreateObject should have errored")
 	}
@@ -211,3 +231,90 @@ func TestDeleteObjectFailed(t *testing.T) {
 		t.Fatalf("Delete Access Object should have errored")
 	}
 }
+
+func TestShredAccessObject(t *testing.T) {
+	protected := protectAccessObject(t)
+	var stored *common.ProtectedErasureReceipt
+	deleted := false
+
+	authStoreTx := &authstorage.AuthStoreTxMock{
+		GetAccessObjectFunc: func(ctx context.Context, objectID uuid.UUID) (*common.ProtectedAccessObject, error) {
+			return protected, nil
+		},
+		InsertErasureReceiptFunc: func(ctx context.Context, protected *common.ProtectedErasureReceipt) error {
+			stored = protected
+			return nil
+		},
+		GetErasureReceiptFunc: func(ctx context.Context, objectID uuid.UUID) (*common.ProtectedErasureReceipt, error) {
+			return stored, nil
+		},
+		DeleteAccessObjectFunc: func(ctx context.Context, objectID uuid.UUID) error {
+			deleted = true
+			return nil
+		},
+	}
+	ctx := context.WithValue(context.Background(), common.AuthStorageTxCtxKey, authStoreTx)
+
+	receipt, err := authorizer.ShredAccessObject(ctx, objectID)
+	if err != nil {
+		t.Fatalf("ShredAccessObject failed: %v", err)
+	}
+	if !deleted {
+		t.Fatal("Access object not deleted")
+	}
+	if receipt.ObjectID != objectID || !reflect.DeepEqual(receipt.Digest, common.AccessObjectDigest(protected)) {
+		t.Fatalf("Unexpected receipt: %v", receipt)
+	}
+	if receipt.KeyID != 3 || !reflect.DeepEqual(receipt.GroupIDs, accessObject.GroupIDs) {
+		t.Fatalf("Unexpected receipt key ID or groups: %v", receipt)
+	}
+	if !ed25519.Verify(receiptSigner.PublicKey().(ed25519.PublicKey), receipt.SignedData(), receipt.Signature) {
+		t.Fatal("Receipt signature invalid")
+	}
+
+	fetched, err := authorizer.FetchErasureReceipt(ctx, objectID)
+	if err != nil {
+		t.Fatalf("FetchErasureReceipt failed: %v", err)
+	}
+	if !fetched.ShreddedAt.Equal(receipt.ShreddedAt) || !reflect.DeepEqual(fetched.Digest, receipt.Digest) ||
+		!reflect.DeepEqual(fetched.Signature, receipt.Signature) || fetched.KeyID != receipt.KeyID ||
+		!reflect.DeepEqual(fetched.GroupIDs, receipt.GroupIDs) {
+		t.Fatalf("Stored receipt doesn't match: %v != %v", fetched, receipt)
+	}
+
+	// The receipt can't be passed off as the receipt of another object
+	_, err = authorizer.FetchErasureReceipt(ctx, groupID)
+	if err == nil {
+		t.Fatal("FetchErasureReceipt with wrong object ID should have failed")
+	}
+}
+
+func TestShredAccessObjectNoSigner(t *testing.T) {
+	protected := protectAccessObject(t)
+	authStoreTx := &authstorage.AuthStoreTxMock{
+		GetAccessObjectFunc: func(ctx context.Context, objectID uuid.UUID) (*common.ProtectedAccessObject, error) {
+			return protected, nil
+		},
+	}
+	ctx := context.WithValue(context.Background(), common.AuthStorageTxCtxKey, authStoreTx)
+
+	unsigned := &Authorizer{AccessObjectCryptor: cryptor}
+	_, err := unsigned.ShredAccessObject(ctx, objectID)
+	if !errors.Is(err, ErrNoReceiptSigner) {
+		t.Fatalf("Expected ErrNoReceiptSigner, got %v", err)
+	}
+}
+
+func TestShredAccessObjectNotFound(t *testing.T) {
+	authStoreTx := &authstorage.AuthStoreTxMock{
+		GetAccessObjectFunc: func(ctx context.Context, objectID uuid.UUID) (*common.ProtectedAccessObject, error) {
+			return nil, interfaces.ErrNotFound
+		},
+	}
+	ctx := context.WithValue(context.Background(), common.AuthStorageTxCtxKey, authStoreTx)
+
+	_, err := authorizer.ShredAccessObject(ctx, objectID)
+	if !errors.Is(err, interfaces.ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+}
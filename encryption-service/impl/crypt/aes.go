@@ -25,57 +25,44 @@ type AESCrypter struct {
 const nonceLength = 12
 const tagLength = 16
 
-const Overhead = int(tagLength + nonceLength)
+// legacyOverhead is the overhead of ciphertexts without an envelope header
+const legacyOverhead = int(tagLength + nonceLength)
 
 // Encrypt encrypts a plaintext with additional associated data (aad) using the provided key returning the resulting ciphertext.
-// The backing array of plaintext is likely modified during this operation.
+// The ciphertext is an envelope with the body nonce || ciphertext || tag. The envelope header is authenticated along with the aad.
 func (c *AESCrypter) Encrypt(plaintext, aad, key []byte) ([]byte, error) {
-	ciphertext := append(plaintext, make([]byte, Overhead)...) // make sure we also have space
-	nonce, err := Random(nonceLength)
-	if err != nil {
-		return nil, err
-	}
-	copy(ciphertext[len(plaintext)+tagLength:], nonce)
-
-	data := ciphertext[:len(plaintext)]
-	aesblock, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, err
-	}
-
-	aesgcm, err := cipher.NewGCM(aesblock)
-	if err != nil {
-		return nil, err
-	}
-
-	aesgcm.Seal(data[:0], nonce, data, aad)
-
-	return ciphertext, nil
+	return sealEnvelope(AlgorithmAES256GCM, plaintext, aad, key)
 }
 
 // Decrypt decrypts a ciphertext with additional associated data (aad) using the provided key returning the resulting plaintext.
-// ciphertext is modified during this operation.
+// The algorithm is taken from the envelope header. Legacy ciphertexts without an envelope header (ciphertext || tag || nonce)
+// are also accepted. These are modified during this operation.
 func (c *AESCrypter) Decrypt(ciphertext, aad, key []byte) ([]byte, error) {
-	if len(ciphertext) < Overhead {
+	return openEnvelope(ciphertext, aad, key)
+}
+
+// decryptLegacy decrypts a ciphertext without an envelope header in place
+func decryptLegacy(aesgcm cipher.AEAD, ciphertext, aad []byte) ([]byte, error) {
+	if len(ciphertext) < legacyOverhead {
 		return nil, fmt.Errorf("ciphertext is too short")
 	}
 
 	data := ciphertext[:len(ciphertext)-nonceLength]
 	nonce := ciphertext[len(ciphertext)-nonceLength:]
-	aesblock, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, err
-	}
 
-	aesgcm, err := cipher.NewGCM(aesblock)
+	_, err := aesgcm.Open(data[:0], nonce, data, aad)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = aesgcm.Open(data[:0], nonce, data, aad)
+	return ciphertext[:len(ciphertext)-legacyOverhead], nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	aesblock, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
 
-	return ciphertext[:len(ciphertext)-Overhead], err
+	return cipher.NewGCM(aesblock)
 }
@@ -25,7 +25,10 @@ var oek, _ = hex.DecodeString("feffe9928665731c6d6a8f9467308308feffe9928665731c6
 var nonce, _ = hex.DecodeString("cafebabefacedbaddecaf888")
 var aad, _ = hex.DecodeString("feedfacedeadbeeffeedfacedeadbeefabaddad2")
 var plaintext, _ = hex.DecodeString("d9313225f88406e5a55909c5aff5269a86a7a9531534f7da2e4c303d8a318a721c3c0c95956809532fcf0e2449a6b525b16aedf5aa0de657ba637b39")
-var ciphertextHex = "522dc1f099567d07f47f37a32a84427d643a8cdcbfe5c0c97598a2bd2555d1aa8cb08e48590dbb3da7b08b1056828838c5f61e6393ba7a0abcc9f66276fc6ece0f4e1768cddf8853bb2d551b" + hex.EncodeToString(nonce)
+var ciphertextHex = "ec010100000000" + hex.EncodeToString(nonce) + "522dc1f099567d07f47f37a32a84427d643a8cdcbfe5c0c97598a2bd2555d1aa8cb08e48590dbb3da7b08b1056828838c5f61e6393ba7a0abcc9f662eaab90a0acf3be662a9da74d4f3db4ff"
+
+// Ciphertext without envelope header (ciphertext || tag || nonce)
+var legacyCiphertextHex = "522dc1f099567d07f47f37a32a84427d643a8cdcbfe5c0c97598a2bd2555d1aa8cb08e48590dbb3da7b08b1056828838c5f61e6393ba7a0abcc9f66276fc6ece0f4e1768cddf8853bb2d551b" + hex.EncodeToString(nonce)
 
 func TestEncryptDecrypt(t *testing.T) {
 	crypter := &AESCrypter{}
@@ -55,6 +58,67 @@ func TestEncryptDecrypt(t *testing.T) {
 	}
 }
 
+func TestDecryptLegacy(t *testing.T) {
+	crypter := &AESCrypter{}
+	ciphertext, _ := hex.DecodeString(legacyCiphertextHex)
+
+	gotPlaintext, err := crypter.Decrypt(ciphertext, aad, oek)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if !bytes.Equal(plaintext, gotPlaintext) {
+		t.Fatalf("plaintext doesn't match:\n%x\n%x\n", plaintext, gotPlaintext)
+	}
+}
+
+func TestDecryptLegacyWithHeaderPrefix(t *testing.T) {
+	crypter := &AESCrypter{}
+	aesgcm, err := newGCM(oek)
+	if err != nil {
+		t.Fatalf("newGCM: %v", err)
+	}
+
+	// Choose the plaintext such that the legacy ciphertext starts with a valid envelope header, which
+	// is not a data envelope header unless the key ID also happens to be 0
+	keystream := aesgcm.Seal(nil, nonce, make([]byte, 3), nil)
+	legacyPlaintext := append([]byte(nil), plaintext...)
+	for i, b := range []byte{envelopeMagic, envelopeVersion, byte(AlgorithmAES256GCM)} {
+		legacyPlaintext[i] = keystream[i] ^ b
+	}
+	ciphertext := append(aesgcm.Seal(nil, nonce, legacyPlaintext, aad), nonce...)
+	header, _, ok := parseEnvelope(ciphertext)
+	if !ok {
+		t.Fatalf("legacy ciphertext doesn't look like an envelope: %x", ciphertext)
+	}
+	if isDataEnvelope(header) {
+		t.Skipf("legacy ciphertext looks like a data envelope: %x", ciphertext)
+	}
+
+	gotPlaintext, err := crypter.Decrypt(ciphertext, aad, oek)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if !bytes.Equal(legacyPlaintext, gotPlaintext) {
+		t.Fatalf("plaintext doesn't match:\n%x\n%x\n", legacyPlaintext, gotPlaintext)
+	}
+}
+
+func TestWrongHeader(t *testing.T) {
+	crypter := &AESCrypter{}
+	ciphertext, err := crypter.Encrypt(plaintext, aad, oek)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	// Change the key ID in the header, which is authenticated
+	ciphertext[EnvelopeHeaderLength-1] ^= 1
+
+	_, err = crypter.Decrypt(ciphertext, aad, oek)
+	if err == nil {
+		t.Fatalf("Decryption of modified header should have failed")
+	}
+}
+
 func TestWrongTag(t *testing.T) {
 	crypter := &AESCrypter{}
 	plaintext := append([]byte(nil), plaintext...)
@@ -64,12 +128,18 @@ func TestWrongTag(t *testing.T) {
 	}
 
 	// Change a bit in the ciphertext
-	ciphertext[0] ^= 1
+	ciphertext[EnvelopeHeaderLength+nonceLength] ^= 1
+	modified := append([]byte(nil), ciphertext...)
 
 	_, err = crypter.Decrypt(ciphertext, aad, oek)
 	if err == nil {
 		t.Fatalf("Decryption of modified ciphertext should have failed")
 	}
+
+	// A legacy decryption would have been attempted in place, which clears the ciphertext
+	if !bytes.Equal(ciphertext, modified) {
+		t.Fatalf("Modified envelope was decrypted as a legacy ciphertext")
+	}
 }
 
 func TestAssociatedDataSizes(t *testing.T) {
@@ -16,8 +16,12 @@ package crypt
 import (
 	"bytes"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/gob"
 	"errors"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
 
 	"encryption-service/interfaces"
 )
@@ -31,9 +35,31 @@ func Random(n int) ([]byte, error) {
 	return bytes, nil
 }
 
+// DeriveKey derives an n byte key from `key` with HKDF-SHA256 using `info`. Different infos give
+// independent keys.
+func DeriveKey(key, info []byte, n int) ([]byte, error) {
+	derived := make([]byte, n)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, info), derived); err != nil {
+		return nil, err
+	}
+	return derived, nil
+}
+
+// crypter encrypts and decrypts data under a raw key
+type crypter interface {
+	Encrypt(plaintext, aad, key []byte) ([]byte, error)
+	Decrypt(ciphertext, aad, key []byte) ([]byte, error)
+}
+
 type AESCryptor struct {
 	keyWrap interfaces.KeyWrapperInterface
-	crypter *AESCrypter
+	crypter crypter
+}
+
+// XChaChaCryptor behaves like AESCryptor, but encrypts data with XChaCha20-Poly1305. It decrypts data
+// encrypted by either cryptor.
+type XChaChaCryptor struct {
+	AESCryptor
 }
 
 func NewAESCryptor(KEK []byte) (*AESCryptor, error) {
@@ -52,6 +78,15 @@ func NewAESCryptorWithKeyWrap(keyWrap interfaces.KeyWrapperInterface) *AESCrypto
 	}
 }
 
+func NewXChaChaCryptorWithKeyWrap(keyWrap interfaces.KeyWrapperInterface) *XChaChaCryptor {
+	return &XChaChaCryptor{
+		AESCryptor: AESCryptor{
+			keyWrap: keyWrap,
+			crypter: &XChaChaCrypter{},
+		},
+	}
+}
+
 func (c *AESCryptor) Encrypt(data, aad []byte) ([]byte, []byte, error) {
 	key, err := Random(32)
 	if err != nil {
@@ -106,25 +141,33 @@ func (c *AESCryptor) Decrypt(wrappedKey, ciphertext, aad []byte) ([]byte, error)
 	return data, nil
 }
 
-// EncodeAndEncrypt serializes the data, but otherwise behaves like `Encrypt`
-func (c *AESCryptor) EncodeAndEncrypt(data interface{}, aad []byte) ([]byte, []byte, error) {
-	var buffer bytes.Buffer
-	enc := gob.NewEncoder(&buffer)
-	if err := enc.Encode(data); err != nil {
+// recordFormatProtobuf prefixes serialized protobuf records. Legacy records are gob encoded, and a gob
+// stream never starts with a zero byte.
+const recordFormatProtobuf = 0x00
+
+// EncodeAndEncrypt serializes the record, but otherwise behaves like `Encrypt`
+func (c *AESCryptor) EncodeAndEncrypt(data interfaces.RecordInterface, aad []byte) ([]byte, []byte, error) {
+	record, err := data.MarshalRecord()
+	if err != nil {
 		return nil, nil, err
 	}
 
-	dataBytes := buffer.Bytes()
-	return c.Encrypt(dataBytes, aad)
+	return c.Encrypt(append([]byte{recordFormatProtobuf}, record...), aad)
 }
 
-// DecodeAndDecrypt behaves like `Decrypt` by deserializes the result into `data`
-func (c *AESCryptor) DecodeAndDecrypt(data interface{}, wrappedKey, ciphertext, aad []byte) error {
+// DecodeAndDecrypt behaves like `Decrypt` by deserializes the result into `data`. Records written
+// before the protobuf schema was introduced are gob encoded, and are re-encoded as protobuf the next
+// time they are written.
+func (c *AESCryptor) DecodeAndDecrypt(data interfaces.RecordInterface, wrappedKey, ciphertext, aad []byte) error {
 	plaintext, err := c.Decrypt(wrappedKey, ciphertext, aad)
 	if err != nil {
 		return err
 	}
 
+	if len(plaintext) > 0 && plaintext[0] == recordFormatProtobuf {
+		return data.UnmarshalRecord(plaintext[1:])
+	}
+
 	dec := gob.NewDecoder(bytes.NewReader(plaintext))
 	return dec.Decode(data)
 }
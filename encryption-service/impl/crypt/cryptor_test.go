@@ -16,8 +16,14 @@ package crypt
 import (
 	"bytes"
 	"crypto/rand"
+	"encoding/gob"
 	"encoding/hex"
+	"reflect"
 	"testing"
+
+	"github.com/gofrs/uuid"
+
+	"encryption-service/common"
 )
 
 func TestAESCrypter(t *testing.T) {
@@ -59,6 +65,103 @@ func TestAESCrypter(t *testing.T) {
 	}
 }
 
+func TestXChaChaCryptor(t *testing.T) {
+	keyWrap, err := NewKWP(GetRandomBytes(32))
+	if err != nil {
+		t.Fatalf("NewKWP failed: %v", err)
+	}
+	aesCryptor := NewAESCryptorWithKeyWrap(keyWrap)
+	xchachaCryptor := NewXChaChaCryptorWithKeyWrap(keyWrap)
+
+	data := GetRandomBytes(64)
+	aad := GetRandomBytes(16)
+	wrappedKey, ciphertext, err := xchachaCryptor.Encrypt(data, aad)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if header, _, ok := parseEnvelope(ciphertext); !ok || header.algorithm != AlgorithmXChaCha20Poly1305 {
+		t.Fatalf("Wrong envelope: %x", ciphertext)
+	}
+
+	// Objects encrypted with AES-GCM can still be decrypted and updated after switching cipher
+	plaintext, err := aesCryptor.Decrypt(wrappedKey, ciphertext, aad)
+	if err != nil || !bytes.Equal(data, plaintext) {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+
+	ciphertext, err = aesCryptor.EncryptWithKey(data, aad, wrappedKey)
+	if err != nil {
+		t.Fatalf("EncryptWithKey failed: %v", err)
+	}
+	plaintext, err = xchachaCryptor.Decrypt(wrappedKey, ciphertext, aad)
+	if err != nil || !bytes.Equal(data, plaintext) {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+}
+
+func TestEncodeAndDecrypt(t *testing.T) {
+	crypter, err := NewAESCryptor(GetRandomBytes(32))
+	if err != nil {
+		t.Fatalf("NewAESCryptor failed: %v", err)
+	}
+
+	accessObject := common.NewAccessObject(uuid.Must(uuid.NewV4()), common.KeyTypeData, GetRandomBytes(40))
+	aad := GetRandomBytes(16)
+
+	wrappedKey, ciphertext, err := crypter.EncodeAndEncrypt(accessObject, aad)
+	if err != nil {
+		t.Fatalf("EncodeAndEncrypt failed: %v", err)
+	}
+
+	plaintext, err := crypter.Decrypt(wrappedKey, ciphertext, aad)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if plaintext[0] != recordFormatProtobuf {
+		t.Fatalf("Record is not protobuf encoded: %x", plaintext)
+	}
+
+	decoded := &common.AccessObject{}
+	if err := crypter.DecodeAndDecrypt(decoded, wrappedKey, ciphertext, aad); err != nil {
+		t.Fatalf("DecodeAndDecrypt failed: %v", err)
+	}
+	if !reflect.DeepEqual(accessObject, decoded) {
+		t.Fatalf("Decoded record doesn't match: %v != %v", accessObject, decoded)
+	}
+}
+
+func TestDecodeLegacyGob(t *testing.T) {
+	crypter, err := NewAESCryptor(GetRandomBytes(32))
+	if err != nil {
+		t.Fatalf("NewAESCryptor failed: %v", err)
+	}
+
+	userData := &common.UserData{
+		HashedPassword: []byte("hash"),
+		Salt:           []byte("salt"),
+		GroupIDs:       map[uuid.UUID]bool{uuid.Must(uuid.NewV4()): true},
+	}
+	aad := GetRandomBytes(16)
+
+	// Records written by earlier versions are gob encoded
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(userData); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	wrappedKey, ciphertext, err := crypter.Encrypt(buffer.Bytes(), aad)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	decoded := &common.UserData{}
+	if err := crypter.DecodeAndDecrypt(decoded, wrappedKey, ciphertext, aad); err != nil {
+		t.Fatalf("DecodeAndDecrypt failed: %v", err)
+	}
+	if !reflect.DeepEqual(userData, decoded) {
+		t.Fatalf("Decoded record doesn't match: %v != %v", userData, decoded)
+	}
+}
+
 func TestAESCrypterEncryptWrap(t *testing.T) {
 	KEK, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F")
 	key, _ := hex.DecodeString("00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F")
@@ -89,7 +192,7 @@ func TestAESCrypterEncryption(t *testing.T) {
 	nonce, _ := hex.DecodeString("cafebabefacedbaddecaf888")
 	aad, _ := hex.DecodeString("feedfacedeadbeeffeedfacedeadbeefabaddad2")
 	expectedPlaintext, _ := hex.DecodeString("d9313225f88406e5a55909c5aff5269a86a7a9531534f7da2e4c303d8a318a721c3c0c95956809532fcf0e2449a6b525b16aedf5aa0de657ba637b39")
-	expectedCipherText, _ := hex.DecodeString("522dc1f099567d07f47f37a32a84427d643a8cdcbfe5c0c97598a2bd2555d1aa8cb08e48590dbb3da7b08b1056828838c5f61e6393ba7a0abcc9f66276fc6ece0f4e1768cddf8853bb2d551b" + "cafebabefacedbaddecaf888")
+	expectedCipherText, _ := hex.DecodeString("ec010100000000cafebabefacedbaddecaf888522dc1f099567d07f47f37a32a84427d643a8cdcbfe5c0c97598a2bd2555d1aa8cb08e48590dbb3da7b08b1056828838c5f61e6393ba7a0abcc9f662eaab90a0acf3be662a9da74d4f3db4ff")
 
 	tmpReader := rand.Reader
 	defer func() { rand.Reader = tmpReader }()
@@ -125,7 +228,7 @@ func TestAESCrypterEncryptWithKey(t *testing.T) {
 	nonce, _ := hex.DecodeString("cafebabefacedbaddecaf888")
 	aad, _ := hex.DecodeString("feedfacedeadbeeffeedfacedeadbeefabaddad2")
 	expectedPlaintext, _ := hex.DecodeString("d9313225f88406e5a55909c5aff5269a86a7a9531534f7da2e4c303d8a318a721c3c0c95956809532fcf0e2449a6b525b16aedf5aa0de657ba637b39")
-	expectedCipherText, _ := hex.DecodeString("522dc1f099567d07f47f37a32a84427d643a8cdcbfe5c0c97598a2bd2555d1aa8cb08e48590dbb3da7b08b1056828838c5f61e6393ba7a0abcc9f66276fc6ece0f4e1768cddf8853bb2d551b" + "cafebabefacedbaddecaf888")
+	expectedCipherText, _ := hex.DecodeString("ec010100000000cafebabefacedbaddecaf888522dc1f099567d07f47f37a32a84427d643a8cdcbfe5c0c97598a2bd2555d1aa8cb08e48590dbb3da7b08b1056828838c5f61e6393ba7a0abcc9f662eaab90a0acf3be662a9da74d4f3db4ff")
 
 	tmpReader := rand.Reader
 	defer func() { rand.Reader = tmpReader }()
@@ -188,3 +291,22 @@ func TestAESCrypterInvalidLength(t *testing.T) {
 		t.Fatalf("Expected EncryptWithKey to fail due to invalid key length")
 	}
 }
+
+func TestDeriveKey(t *testing.T) {
+	// Test case 3 of RFC 5869
+	key, err := DeriveKey(bytes.Repeat([]byte{0x0b}, 22), nil, 42)
+	if err != nil {
+		t.Fatalf("DeriveKey failed: %v", err)
+	}
+	if hex.EncodeToString(key) != "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8" {
+		t.Fatalf("Unexpected key: %x", key)
+	}
+
+	other, err := DeriveKey(bytes.Repeat([]byte{0x0b}, 22), []byte("info"), 42)
+	if err != nil {
+		t.Fatalf("DeriveKey failed: %v", err)
+	}
+	if bytes.Equal(key, other) {
+		t.Fatal("Different infos gave equal keys")
+	}
+}
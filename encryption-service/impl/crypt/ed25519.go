@@ -0,0 +1,50 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"errors"
+)
+
+// Ed25519Signer signs messages with an Ed25519 private key
+type Ed25519Signer struct {
+	privateKey ed25519.PrivateKey
+}
+
+// NewEd25519Signer creates a signer from an Ed25519 private key seed as defined in RFC 8032
+func NewEd25519Signer(seed []byte) (*Ed25519Signer, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, errors.New("invalid seed length")
+	}
+
+	return &Ed25519Signer{privateKey: ed25519.NewKeyFromSeed(seed)}, nil
+}
+
+// Sign returns the Ed25519 signature of the given message
+func (s *Ed25519Signer) Sign(msg []byte) ([]byte, error) {
+	return ed25519.Sign(s.privateKey, msg), nil
+}
+
+// Verify checks whether the signature matches the given message
+func (s *Ed25519Signer) Verify(msg, signature []byte) (bool, error) {
+	publicKey := s.privateKey.Public().(ed25519.PublicKey)
+	return ed25519.Verify(publicKey, msg, signature), nil
+}
+
+// PublicKey returns the public key of the signer as an ed25519.PublicKey
+func (s *Ed25519Signer) PublicKey() crypto.PublicKey {
+	return s.privateKey.Public()
+}
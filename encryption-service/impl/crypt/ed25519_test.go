@@ -0,0 +1,79 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+
+	"encryption-service/interfaces"
+)
+
+// RFC 8032 test 1
+var signingSeed, _ = hex.DecodeString("9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60")
+var signingPublicKeyHex = "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a"
+var signatureHex = "e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b"
+
+var _ interfaces.SignerInterface = &Ed25519Signer{}
+
+func TestEd25519Sign(t *testing.T) {
+	signer, err := NewEd25519Signer(signingSeed)
+	if err != nil {
+		t.Fatalf("NewEd25519Signer: %v", err)
+	}
+
+	publicKey := signer.PublicKey().(ed25519.PublicKey)
+	if signingPublicKeyHex != hex.EncodeToString(publicKey) {
+		t.Fatalf("public key doesn't match:\n%s\n%x\n", signingPublicKeyHex, publicKey)
+	}
+
+	signature, err := signer.Sign([]byte{})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if signatureHex != hex.EncodeToString(signature) {
+		t.Fatalf("signature doesn't match:\n%s\n%x\n", signatureHex, signature)
+	}
+}
+
+func TestEd25519Verify(t *testing.T) {
+	signer, err := NewEd25519Signer(signingSeed)
+	if err != nil {
+		t.Fatalf("NewEd25519Signer: %v", err)
+	}
+
+	signature, _ := hex.DecodeString(signatureHex)
+	valid, err := signer.Verify([]byte{}, signature)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !valid {
+		t.Fatal("valid signature rejected")
+	}
+
+	valid, err = signer.Verify([]byte("message"), signature)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if valid {
+		t.Fatal("signature of another message accepted")
+	}
+}
+
+func TestEd25519InvalidSeed(t *testing.T) {
+	if _, err := NewEd25519Signer(make([]byte, ed25519.SeedSize-1)); err == nil {
+		t.Fatal("short seed accepted")
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Algorithm identifies the algorithm used to produce an envelope
+type Algorithm byte
+
+const (
+	// AES-256-GCM with a 96 bit random nonce. Body: nonce || ciphertext || tag
+	AlgorithmAES256GCM Algorithm = 1
+	// AES-256 key wrap with padding (RFC 5649). Body: wrapped key
+	AlgorithmAES256KWP Algorithm = 2
+	// XChaCha20-Poly1305 with a 192 bit random nonce. Body: nonce || ciphertext || tag
+	AlgorithmXChaCha20Poly1305 Algorithm = 3
+)
+
+const envelopeMagic byte = 0xEC
+const envelopeVersion byte = 1
+
+// EnvelopeHeaderLength is the length of the envelope header
+const EnvelopeHeaderLength = 7
+
+// envelopeHeader describes the contents of an envelope. All ciphertexts and wrapped keys are prefixed
+// with an envelope header, which allows changing algorithms and key IDs without breaking existing
+// data.
+//
+// Format: magic (0xEC) || version (1 byte) || algorithm (1 byte) || key_id (4 bytes, big endian)
+//
+// The key ID identifies the key that the envelope was produced with. It is 0 for keys that are not
+// identified, e.g. random object keys.
+type envelopeHeader struct {
+	algorithm Algorithm
+	keyID     uint32
+}
+
+// marshal returns the encoded header
+func (h envelopeHeader) marshal() []byte {
+	header := make([]byte, EnvelopeHeaderLength)
+	header[0] = envelopeMagic
+	header[1] = envelopeVersion
+	header[2] = byte(h.algorithm)
+	binary.BigEndian.PutUint32(header[3:], h.keyID)
+	return header
+}
+
+// aad returns the associated data of an envelope, which binds the header to the body
+func (h envelopeHeader) aad(aad []byte) []byte {
+	return append(h.marshal(), aad...)
+}
+
+// parseEnvelope splits an envelope into header and body. Returns false if the data does not start
+// with a valid envelope header.
+func parseEnvelope(data []byte) (envelopeHeader, []byte, bool) {
+	if len(data) < EnvelopeHeaderLength || data[0] != envelopeMagic || data[1] != envelopeVersion {
+		return envelopeHeader{}, nil, false
+	}
+
+	header := envelopeHeader{
+		algorithm: Algorithm(data[2]),
+		keyID:     binary.BigEndian.Uint32(data[3:]),
+	}
+	return header, data[EnvelopeHeaderLength:], true
+}
+
+// newAEAD creates an AEAD for the given algorithm
+func newAEAD(algorithm Algorithm, key []byte) (cipher.AEAD, error) {
+	switch algorithm {
+	case AlgorithmAES256GCM:
+		return newGCM(key)
+	case AlgorithmXChaCha20Poly1305:
+		return chacha20poly1305.NewX(key)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %d", algorithm)
+	}
+}
+
+// sealEnvelope encrypts a plaintext into an envelope with the given AEAD algorithm and a random
+// nonce. The envelope header is authenticated along with the aad.
+func sealEnvelope(algorithm Algorithm, plaintext, aad, key []byte) ([]byte, error) {
+	aead, err := newAEAD(algorithm, key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := Random(aead.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+
+	header := envelopeHeader{algorithm: algorithm}
+	ciphertext := make([]byte, 0, EnvelopeHeaderLength+len(nonce)+len(plaintext)+aead.Overhead())
+	ciphertext = append(ciphertext, header.marshal()...)
+	ciphertext = append(ciphertext, nonce...)
+
+	return aead.Seal(ciphertext, nonce, plaintext, header.aad(aad)), nil
+}
+
+// isDataEnvelope reports whether an envelope header was produced by `sealEnvelope`. These always use
+// an AEAD algorithm and key ID 0, so a legacy ciphertext is only mistaken for an envelope with
+// probability 2^-55.
+func isDataEnvelope(header envelopeHeader) bool {
+	switch header.algorithm {
+	case AlgorithmAES256GCM, AlgorithmXChaCha20Poly1305:
+		return header.keyID == 0
+	default:
+		return false
+	}
+}
+
+// openEnvelope decrypts an envelope produced by any supported AEAD algorithm. Legacy AES-GCM
+// ciphertexts without an envelope header are decrypted in place.
+func openEnvelope(ciphertext, aad, key []byte) ([]byte, error) {
+	header, body, ok := parseEnvelope(ciphertext)
+	if !ok || !isDataEnvelope(header) {
+		aesgcm, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		return decryptLegacy(aesgcm, ciphertext, aad)
+	}
+
+	aead, err := newAEAD(header.algorithm, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < aead.NonceSize()+aead.Overhead() {
+		return nil, errors.New("ciphertext is too short")
+	}
+
+	nonce, data := body[:aead.NonceSize()], body[aead.NonceSize():]
+	return aead.Open(nil, nonce, data, header.aad(aad))
+}
@@ -0,0 +1,219 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	// FF1MaxRadix is the largest radix supported by FF1
+	FF1MaxRadix = 1 << 16
+
+	// FF1MaxTweakLength is the maximum tweak length in bytes accepted by `FF1`
+	FF1MaxTweakLength = 256
+
+	// FF1MaxLength is the maximum number of numerals accepted by `FF1`. NIST SP 800-38G allows up
+	// to 2^32, but the cost of FF1 grows quadratically with the length of the input.
+	FF1MaxLength = 1024
+
+	// Minimum number of possible inputs of a given length, see NIST SP 800-38G Rev. 1
+	ff1MinDomainSize = 1000000
+
+	ff1Rounds = 10
+)
+
+// ErrFF1InvalidInput is returned when the input to `FF1` is not a valid numeral string or tweak
+var ErrFF1InvalidInput = errors.New("ff1: invalid input")
+
+// FF1 implements the format-preserving encryption mode FF1 as specified in NIST SP 800-38G. It
+// encrypts strings of numerals in a fixed radix to strings of the same length and radix.
+type FF1 struct {
+	block cipher.Block
+	radix int
+}
+
+// NewFF1 creates an FF1 instance using AES with the provided key, which must be 16, 24 or 32 bytes
+// long. Numerals must be smaller than `radix`, which must be between 2 and `FF1MaxRadix`.
+func NewFF1(key []byte, radix int) (*FF1, error) {
+	if radix < 2 || radix > FF1MaxRadix {
+		return nil, fmt.Errorf("ff1: invalid radix %d", radix)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FF1{block: block, radix: radix}, nil
+}
+
+// Encrypt encrypts the numeral string under the given tweak
+func (f *FF1) Encrypt(tweak []byte, numerals []uint16) ([]uint16, error) {
+	return f.crypt(tweak, numerals, true)
+}
+
+// Decrypt decrypts the numeral string under the given tweak
+func (f *FF1) Decrypt(tweak []byte, numerals []uint16) ([]uint16, error) {
+	return f.crypt(tweak, numerals, false)
+}
+
+// crypt runs the Feistel network of FF1 forwards for encryption and backwards for decryption
+func (f *FF1) crypt(tweak []byte, numerals []uint16, encrypt bool) ([]uint16, error) {
+	if err := f.checkInput(tweak, numerals); err != nil {
+		return nil, err
+	}
+
+	n := len(numerals)
+	u := n / 2
+	v := n - u
+	radix := big.NewInt(int64(f.radix))
+	modU := new(big.Int).Exp(radix, big.NewInt(int64(u)), nil)
+	modV := new(big.Int).Exp(radix, big.NewInt(int64(v)), nil)
+	a := f.num(numerals[:u])
+	b := f.num(numerals[u:])
+
+	// Number of bytes needed to represent the larger half, and number of bytes of the round function
+	// output
+	byteLen := (new(big.Int).Sub(modV, big.NewInt(1)).BitLen() + 7) / 8
+	d := 4*((byteLen+3)/4) + 4
+
+	var p [aes.BlockSize]byte
+	p[0], p[1], p[2] = 1, 2, 1
+	p[3], p[4], p[5] = byte(f.radix>>16), byte(f.radix>>8), byte(f.radix)
+	p[6] = ff1Rounds
+	p[7] = byte(u)
+	binary.BigEndian.PutUint32(p[8:], uint32(n))
+	binary.BigEndian.PutUint32(p[12:], uint32(len(tweak)))
+	f.block.Encrypt(p[:], p[:])
+
+	padding := (aes.BlockSize - (len(tweak)+byteLen+1)%aes.BlockSize) % aes.BlockSize
+	q := make([]byte, len(tweak)+padding+1+byteLen)
+	copy(q, tweak)
+	roundIndex := len(tweak) + padding
+
+	s := make([]byte, (d+aes.BlockSize-1)/aes.BlockSize*aes.BlockSize)
+	y := new(big.Int)
+	for i := 0; i < ff1Rounds; i++ {
+		round := i
+		if !encrypt {
+			round = ff1Rounds - 1 - i
+		}
+		mod := modU
+		if round%2 == 1 {
+			mod = modV
+		}
+
+		// The round function is applied to B when encrypting and to A when decrypting
+		input := b
+		if !encrypt {
+			input = a
+		}
+		q[roundIndex] = byte(round)
+		for j := roundIndex + 1; j < len(q); j++ {
+			q[j] = 0
+		}
+		input.FillBytes(q[len(q)-byteLen:])
+
+		f.expand(s, f.prf(p, q))
+		y.SetBytes(s[:d])
+
+		c := new(big.Int)
+		if encrypt {
+			c.Add(a, y)
+			c.Mod(c, mod)
+			a, b = b, c
+		} else {
+			c.Sub(b, y)
+			c.Mod(c, mod)
+			a, b = c, a
+		}
+	}
+
+	return append(f.str(a, u), f.str(b, v)...), nil
+}
+
+// checkInput checks that the numerals are within the radix, that their number is within bounds and
+// that the domain is large enough
+func (f *FF1) checkInput(tweak []byte, numerals []uint16) error {
+	if len(tweak) > FF1MaxTweakLength || len(numerals) < 2 || len(numerals) > FF1MaxLength {
+		return ErrFF1InvalidInput
+	}
+	for _, numeral := range numerals {
+		if int(numeral) >= f.radix {
+			return ErrFF1InvalidInput
+		}
+	}
+
+	domainSize := 1
+	for i := 0; i < len(numerals) && domainSize < ff1MinDomainSize; i++ {
+		domainSize *= f.radix
+	}
+	if domainSize < ff1MinDomainSize {
+		return ErrFF1InvalidInput
+	}
+	return nil
+}
+
+// prf computes the CBC-MAC of Q, continuing from the encrypted block P
+func (f *FF1) prf(p [aes.BlockSize]byte, q []byte) [aes.BlockSize]byte {
+	r := p
+	for len(q) > 0 {
+		xorBytes(r[:], r[:], q[:aes.BlockSize])
+		f.block.Encrypt(r[:], r[:])
+		q = q[aes.BlockSize:]
+	}
+	return r
+}
+
+// expand fills s with R || CIPH(R xor [1]) || CIPH(R xor [2]) || ...
+func (f *FF1) expand(s []byte, r [aes.BlockSize]byte) {
+	copy(s, r[:])
+	for j := 1; j < len(s)/aes.BlockSize; j++ {
+		block := r
+		var counter [8]byte
+		binary.BigEndian.PutUint64(counter[:], uint64(j))
+		xorBytes(block[8:], block[8:], counter[:])
+		f.block.Encrypt(s[j*aes.BlockSize:], block[:])
+	}
+}
+
+// num interprets the numerals as a number in the radix, most significant numeral first
+func (f *FF1) num(numerals []uint16) *big.Int {
+	radix := big.NewInt(int64(f.radix))
+	x := new(big.Int)
+	for _, numeral := range numerals {
+		x.Mul(x, radix)
+		x.Add(x, big.NewInt(int64(numeral)))
+	}
+	return x
+}
+
+// str converts x to m numerals in the radix, most significant numeral first
+func (f *FF1) str(x *big.Int, m int) []uint16 {
+	radix := big.NewInt(int64(f.radix))
+	x = new(big.Int).Set(x)
+	numeral := new(big.Int)
+	numerals := make([]uint16, m)
+	for i := m - 1; i >= 0; i-- {
+		x.DivMod(x, radix, numeral)
+		numerals[i] = uint16(numeral.Uint64())
+	}
+	return numerals
+}
@@ -0,0 +1,175 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+const ff1TestAlphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+type ff1TestVector struct {
+	key        string
+	radix      int
+	tweak      string
+	plaintext  string
+	ciphertext string
+}
+
+// NIST SP 800-38G FF1 samples 1-3 and 7-9
+var ff1TestVectors = []ff1TestVector{
+	{
+		key:        "2b7e151628aed2a6abf7158809cf4f3c",
+		radix:      10,
+		plaintext:  "0123456789",
+		ciphertext: "2433477484",
+	},
+	{
+		key:        "2b7e151628aed2a6abf7158809cf4f3c",
+		radix:      10,
+		tweak:      "39383736353433323130",
+		plaintext:  "0123456789",
+		ciphertext: "6124200773",
+	},
+	{
+		key:        "2b7e151628aed2a6abf7158809cf4f3c",
+		radix:      36,
+		tweak:      "3737373770717273373737",
+		plaintext:  "0123456789abcdefghi",
+		ciphertext: "a9tv40mll9kdu509eum",
+	},
+	{
+		key:        "2b7e151628aed2a6abf7158809cf4f3cef4359d8d580aa4f7f036d6f04fc6a94",
+		radix:      10,
+		plaintext:  "0123456789",
+		ciphertext: "6657667009",
+	},
+	{
+		key:        "2b7e151628aed2a6abf7158809cf4f3cef4359d8d580aa4f7f036d6f04fc6a94",
+		radix:      10,
+		tweak:      "39383736353433323130",
+		plaintext:  "0123456789",
+		ciphertext: "1001623463",
+	},
+	{
+		key:        "2b7e151628aed2a6abf7158809cf4f3cef4359d8d580aa4f7f036d6f04fc6a94",
+		radix:      36,
+		tweak:      "3737373770717273373737",
+		plaintext:  "0123456789abcdefghi",
+		ciphertext: "xs8a0azh2avyalyzuwd",
+	},
+}
+
+func toNumerals(s string) []uint16 {
+	numerals := make([]uint16, len(s))
+	for i, c := range s {
+		numerals[i] = uint16(strings.IndexRune(ff1TestAlphabet, c))
+	}
+	return numerals
+}
+
+func fromNumerals(numerals []uint16) string {
+	var s strings.Builder
+	for _, numeral := range numerals {
+		s.WriteByte(ff1TestAlphabet[numeral])
+	}
+	return s.String()
+}
+
+func TestFF1(t *testing.T) {
+	for _, v := range ff1TestVectors {
+		key, _ := hex.DecodeString(v.key)
+		tweak, _ := hex.DecodeString(v.tweak)
+		ff1, err := NewFF1(key, v.radix)
+		if err != nil {
+			t.Fatalf("NewFF1: %v", err)
+		}
+
+		ciphertext, err := ff1.Encrypt(tweak, toNumerals(v.plaintext))
+		if err != nil {
+			t.Fatalf("Encrypt: %v", err)
+		}
+		if fromNumerals(ciphertext) != v.ciphertext {
+			t.Fatalf("ciphertext doesn't match:\n%s\n%s\n", v.ciphertext, fromNumerals(ciphertext))
+		}
+
+		plaintext, err := ff1.Decrypt(tweak, ciphertext)
+		if err != nil {
+			t.Fatalf("Decrypt: %v", err)
+		}
+		if fromNumerals(plaintext) != v.plaintext {
+			t.Fatalf("plaintext doesn't match:\n%s\n%s\n", v.plaintext, fromNumerals(plaintext))
+		}
+	}
+}
+
+func TestFF1OddLength(t *testing.T) {
+	ff1, err := NewFF1(make([]byte, 32), 10)
+	if err != nil {
+		t.Fatalf("NewFF1: %v", err)
+	}
+
+	plaintext := toNumerals("4111111111111111111")
+	ciphertext, err := ff1.Encrypt(nil, plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if len(ciphertext) != len(plaintext) {
+		t.Fatalf("ciphertext length %d differs from plaintext length %d", len(ciphertext), len(plaintext))
+	}
+
+	decrypted, err := ff1.Decrypt(nil, ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if fromNumerals(decrypted) != fromNumerals(plaintext) {
+		t.Fatal("decrypted plaintext doesn't match")
+	}
+}
+
+func TestFF1InvalidInput(t *testing.T) {
+	ff1, err := NewFF1(make([]byte, 32), 10)
+	if err != nil {
+		t.Fatalf("NewFF1: %v", err)
+	}
+
+	// Domain smaller than one million
+	if _, err := ff1.Encrypt(nil, toNumerals("12345")); err != ErrFF1InvalidInput {
+		t.Fatalf("short input accepted: %v", err)
+	}
+	// Numeral outside the radix
+	if _, err := ff1.Encrypt(nil, toNumerals("12345678a")); err != ErrFF1InvalidInput {
+		t.Fatalf("invalid numeral accepted: %v", err)
+	}
+	// Tweak too long
+	if _, err := ff1.Encrypt(make([]byte, FF1MaxTweakLength+1), toNumerals("123456789")); err != ErrFF1InvalidInput {
+		t.Fatalf("long tweak accepted: %v", err)
+	}
+	// Input too long
+	if _, err := ff1.Encrypt(nil, make([]uint16, FF1MaxLength+1)); err != ErrFF1InvalidInput {
+		t.Fatalf("long input accepted: %v", err)
+	}
+	if _, err := ff1.Decrypt(nil, make([]uint16, FF1MaxLength+1)); err != ErrFF1InvalidInput {
+		t.Fatalf("long input accepted: %v", err)
+	}
+	if _, err := ff1.Encrypt(nil, make([]uint16, FF1MaxLength)); err != nil {
+		t.Fatalf("input of maximum length rejected: %v", err)
+	}
+
+	if _, err := NewFF1(make([]byte, 32), 1); err == nil {
+		t.Fatal("NewFF1 accepted invalid radix")
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+)
+
+// MinMACKeyLength is the minimum length of HMAC keys in bytes
+const MinMACKeyLength = 32
+
+// HMACAuthenticator is a message authenticator using HMAC-SHA256
+type HMACAuthenticator struct {
+	key []byte
+}
+
+// NewHMACAuthenticator creates a message authenticator using the provided key, which must be at
+// least `MinMACKeyLength` bytes long
+func NewHMACAuthenticator(key []byte) (*HMACAuthenticator, error) {
+	if len(key) < MinMACKeyLength {
+		return nil, errors.New("invalid key length")
+	}
+
+	return &HMACAuthenticator{key: key}, nil
+}
+
+// Tag returns the HMAC-SHA256 tag of the given message
+func (h *HMACAuthenticator) Tag(msg []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, h.key)
+	if _, err := mac.Write(msg); err != nil {
+		return nil, err
+	}
+	return mac.Sum(nil), nil
+}
+
+// Verify checks in constant time whether the tag matches the given message
+func (h *HMACAuthenticator) Verify(msg, msgTag []byte) (bool, error) {
+	tag, err := h.Tag(msg)
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal(tag, msgTag), nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"encryption-service/interfaces"
+)
+
+// RFC 4231 test case 6
+var macKey = bytes.Repeat([]byte{0xaa}, 131)
+var macMessage = []byte("Test Using Larger Than Block-Size Key - Hash Key First")
+var macTagHex = "60e431591ee0b67f0d8a26aacbf5b77f8e0bc6213728c5140546040f0ee37f54"
+
+var _ interfaces.MessageAuthenticatorInterface = &HMACAuthenticator{}
+
+func TestHMACTag(t *testing.T) {
+	authenticator, err := NewHMACAuthenticator(macKey)
+	if err != nil {
+		t.Fatalf("NewHMACAuthenticator: %v", err)
+	}
+
+	tag, err := authenticator.Tag(macMessage)
+	if err != nil {
+		t.Fatalf("Tag: %v", err)
+	}
+	if macTagHex != hex.EncodeToString(tag) {
+		t.Fatalf("tag doesn't match:\n%s\n%x\n", macTagHex, tag)
+	}
+}
+
+func TestHMACVerify(t *testing.T) {
+	authenticator, err := NewHMACAuthenticator(macKey)
+	if err != nil {
+		t.Fatalf("NewHMACAuthenticator: %v", err)
+	}
+
+	tag, _ := hex.DecodeString(macTagHex)
+	valid, err := authenticator.Verify(macMessage, tag)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !valid {
+		t.Fatal("valid tag rejected")
+	}
+
+	tag[0] ^= 1
+	valid, err = authenticator.Verify(macMessage, tag)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if valid {
+		t.Fatal("modified tag accepted")
+	}
+
+	valid, err = authenticator.Verify(macMessage, tag[:16])
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if valid {
+		t.Fatal("truncated tag accepted")
+	}
+}
+
+func TestHMACShortKey(t *testing.T) {
+	if _, err := NewHMACAuthenticator(make([]byte, MinMACKeyLength-1)); err == nil {
+		t.Fatal("short key accepted")
+	}
+}
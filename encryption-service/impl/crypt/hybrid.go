@@ -0,0 +1,142 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+// HybridVersion is the format version of hybrid ciphertexts
+const HybridVersion byte = 1
+
+const hybridHeaderLength = 1 + curve25519.PointSize
+
+// HybridOverhead is the number of bytes added to a plaintext by hybrid encryption
+const HybridOverhead = hybridHeaderLength + nonceLength + tagLength
+
+// Info string of the key derivation of hybrid encryption
+var hybridInfo = []byte("Encryptonize hybrid encryption v1")
+
+// NewX25519PrivateKey returns a random X25519 private key
+func NewX25519PrivateKey() ([]byte, error) {
+	return Random(curve25519.ScalarSize)
+}
+
+// X25519PublicKey returns the public key of an X25519 private key
+func X25519PublicKey(privateKey []byte) ([]byte, error) {
+	return curve25519.X25519(privateKey, curve25519.Basepoint)
+}
+
+// HybridEncrypt encrypts a plaintext with additional associated data (aad) to an X25519 public key.
+// An ephemeral X25519 key is agreed with the public key, and the shared secret is expanded with
+// HKDF-SHA256 into an AES-256-GCM key. The ciphertext has the format
+//
+//	version (1) || ephemeral public key (32) || nonce (12) || ciphertext || tag (16)
+//
+// The version and ephemeral public key are authenticated along with the aad.
+func HybridEncrypt(publicKey, plaintext, aad []byte) ([]byte, error) {
+	ephemeralPrivateKey, err := NewX25519PrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	ephemeralPublicKey, err := X25519PublicKey(ephemeralPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := hybridKey(ephemeralPrivateKey, publicKey, ephemeralPublicKey, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	aesgcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := Random(nonceLength)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext := make([]byte, 0, HybridOverhead+len(plaintext))
+	ciphertext = append(ciphertext, HybridVersion)
+	ciphertext = append(ciphertext, ephemeralPublicKey...)
+	header := ciphertext
+	ciphertext = append(ciphertext, nonce...)
+
+	return aesgcm.Seal(ciphertext, nonce, plaintext, hybridAAD(header, aad)), nil
+}
+
+// HybridDecrypt decrypts a ciphertext produced by `HybridEncrypt` with the X25519 private key
+// matching the public key it was encrypted to
+func HybridDecrypt(privateKey, ciphertext, aad []byte) ([]byte, error) {
+	if len(ciphertext) < HybridOverhead {
+		return nil, errors.New("ciphertext is too short")
+	}
+	if ciphertext[0] != HybridVersion {
+		return nil, errors.New("unsupported hybrid ciphertext version")
+	}
+
+	header := ciphertext[:hybridHeaderLength]
+	ephemeralPublicKey := header[1:]
+	nonce := ciphertext[hybridHeaderLength : hybridHeaderLength+nonceLength]
+	data := ciphertext[hybridHeaderLength+nonceLength:]
+
+	publicKey, err := X25519PublicKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hybridKey(privateKey, ephemeralPublicKey, ephemeralPublicKey, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	aesgcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return aesgcm.Open(nil, nonce, data, hybridAAD(header, aad))
+}
+
+// hybridKey agrees a shared secret between `privateKey` and `peerPublicKey` and derives an AES-256
+// key from it, salted with the ephemeral and recipient public keys
+func hybridKey(privateKey, peerPublicKey, ephemeralPublicKey, recipientPublicKey []byte) ([]byte, error) {
+	// X25519 fails on low order points, which would result in an all zero shared secret
+	sharedSecret, err := curve25519.X25519(privateKey, peerPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 0, 2*curve25519.PointSize)
+	salt = append(salt, ephemeralPublicKey...)
+	salt = append(salt, recipientPublicKey...)
+
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, sharedSecret, salt, hybridInfo), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// hybridAAD binds the ciphertext header to the associated data
+func hybridAAD(header, aad []byte) []byte {
+	bound := make([]byte, 0, len(header)+len(aad))
+	bound = append(bound, header...)
+	return append(bound, aad...)
+}
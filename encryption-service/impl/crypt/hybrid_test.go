@@ -0,0 +1,79 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHybridEncryptDecrypt(t *testing.T) {
+	privateKey, err := NewX25519PrivateKey()
+	if err != nil {
+		t.Fatalf("NewX25519PrivateKey: %v", err)
+	}
+	publicKey, err := X25519PublicKey(privateKey)
+	if err != nil {
+		t.Fatalf("X25519PublicKey: %v", err)
+	}
+
+	plaintext := []byte("plaintext")
+	aad := []byte("aad")
+	ciphertext, err := HybridEncrypt(publicKey, plaintext, aad)
+	if err != nil {
+		t.Fatalf("HybridEncrypt: %v", err)
+	}
+	if len(ciphertext) != len(plaintext)+HybridOverhead {
+		t.Fatalf("unexpected ciphertext length %d", len(ciphertext))
+	}
+
+	decrypted, err := HybridDecrypt(privateKey, ciphertext, aad)
+	if err != nil {
+		t.Fatalf("HybridDecrypt: %v", err)
+	}
+	if !bytes.Equal(plaintext, decrypted) {
+		t.Fatalf("plaintext doesn't match:\n%x\n%x\n", plaintext, decrypted)
+	}
+}
+
+func TestHybridDecryptFail(t *testing.T) {
+	privateKey, _ := NewX25519PrivateKey()
+	publicKey, _ := X25519PublicKey(privateKey)
+	otherPrivateKey, _ := NewX25519PrivateKey()
+
+	aad := []byte("aad")
+	ciphertext, err := HybridEncrypt(publicKey, []byte("plaintext"), aad)
+	if err != nil {
+		t.Fatalf("HybridEncrypt: %v", err)
+	}
+
+	if _, err := HybridDecrypt(otherPrivateKey, ciphertext, aad); err == nil {
+		t.Fatal("decryption with wrong private key succeeded")
+	}
+	if _, err := HybridDecrypt(privateKey, ciphertext, []byte("other aad")); err == nil {
+		t.Fatal("decryption with wrong aad succeeded")
+	}
+
+	for i := range ciphertext {
+		modified := append([]byte(nil), ciphertext...)
+		modified[i] ^= 1
+		if _, err := HybridDecrypt(privateKey, modified, aad); err == nil {
+			t.Fatalf("decryption of ciphertext modified at byte %d succeeded", i)
+		}
+	}
+
+	if _, err := HybridDecrypt(privateKey, ciphertext[:HybridOverhead-1], aad); err == nil {
+		t.Fatal("decryption of truncated ciphertext succeeded")
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"encryption-service/interfaces"
+)
+
+// LegacyKeyID is the ID assumed for wrapped keys that do not carry a key ID, i.e. keys that were
+// wrapped before key rings were introduced.
+const LegacyKeyID uint32 = 0
+
+const keyIDLength = 4
+
+// KeyRing is a key wrapper that holds a current wrapping key and any number of retired wrapping
+// keys, each identified by a key ID. Keys are always wrapped with the current wrapping key, and the
+// wrapped key is an envelope carrying the ID of that wrapping key. This makes it possible to rotate
+// the wrapping key while keys wrapped under a retired wrapping key can still be unwrapped.
+//
+// Format: envelope header (algorithm AES256KWP, key ID) || wrapped_key
+//
+// Since KWP always produces wrapped keys whose length is a multiple of 8, the format of a wrapped key
+// is recognized by its length:
+//   - len % 8 == 0: legacy wrapped key without key ID, unwrapped with the key with ID `LegacyKeyID`
+//   - len % 8 == 4: key_id (4 bytes, big endian) || wrapped_key, as written by earlier versions
+//   - len % 8 == 7: envelope
+type KeyRing struct {
+	currentID uint32
+	wrappers  map[uint32]interfaces.KeyWrapperInterface
+}
+
+// NewKeyRing creates a key ring from a set of key wrappers indexed by key ID. The wrapper with ID
+// `currentID` is used for all new wrappings.
+func NewKeyRing(currentID uint32, wrappers map[uint32]interfaces.KeyWrapperInterface) (*KeyRing, error) {
+	if _, ok := wrappers[currentID]; !ok {
+		return nil, fmt.Errorf("keyring: current key ID %d not in key ring", currentID)
+	}
+
+	return &KeyRing{
+		currentID: currentID,
+		wrappers:  wrappers,
+	}, nil
+}
+
+// NewKWPKeyRing creates a key ring of KWP key wrappers from a set of raw wrapping keys indexed by key
+// ID.
+func NewKWPKeyRing(currentID uint32, keys map[uint32][]byte) (*KeyRing, error) {
+	wrappers, err := newKWPWrappers(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKeyRing(currentID, wrappers)
+}
+
+// NewKeyRingWithRetiredKeys creates a key ring with the given current key wrapper and KWP key
+// wrappers for a set of raw retired wrapping keys indexed by key ID.
+func NewKeyRingWithRetiredKeys(currentID uint32, current interfaces.KeyWrapperInterface, retired map[uint32][]byte) (*KeyRing, error) {
+	if _, ok := retired[currentID]; ok {
+		return nil, fmt.Errorf("keyring: current key ID %d used by retired key", currentID)
+	}
+
+	wrappers, err := newKWPWrappers(retired)
+	if err != nil {
+		return nil, err
+	}
+	wrappers[currentID] = current
+
+	return NewKeyRing(currentID, wrappers)
+}
+
+// newKWPWrappers creates KWP key wrappers from a set of raw wrapping keys
+func newKWPWrappers(keys map[uint32][]byte) (map[uint32]interfaces.KeyWrapperInterface, error) {
+	wrappers := make(map[uint32]interfaces.KeyWrapperInterface, len(keys)+1)
+	for keyID, key := range keys {
+		kwp, err := NewKWP(key)
+		if err != nil {
+			return nil, err
+		}
+		wrappers[keyID] = kwp
+	}
+
+	return wrappers, nil
+}
+
+// CurrentKeyID returns the ID of the key used for wrapping.
+func (k *KeyRing) CurrentKeyID() uint32 {
+	return k.currentID
+}
+
+// Wrap wraps the provided key material with the current wrapping key.
+func (k *KeyRing) Wrap(data []byte) ([]byte, error) {
+	wrapped, err := k.wrappers[k.currentID].Wrap(data)
+	if err != nil {
+		return nil, err
+	}
+
+	header := envelopeHeader{algorithm: AlgorithmAES256KWP, keyID: k.currentID}
+	return append(header.marshal(), wrapped...), nil
+}
+
+// Unwrap unwraps a wrapped key with the wrapping key it was wrapped under.
+func (k *KeyRing) Unwrap(data []byte) ([]byte, error) {
+	keyID, wrapped, err := k.parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapper, ok := k.wrappers[keyID]
+	if !ok {
+		return nil, fmt.Errorf("keyring: unknown key ID %d", keyID)
+	}
+
+	return wrapper.Unwrap(wrapped)
+}
+
+// KeyID returns the ID of the wrapping key that a wrapped key was wrapped under.
+func (k *KeyRing) KeyID(data []byte) (uint32, error) {
+	keyID, _, err := k.parse(data)
+	return keyID, err
+}
+
+// Rewrap unwraps a wrapped key and wraps it again under the current wrapping key. If the key is
+// already wrapped under the current wrapping key it is returned unchanged and `changed` is false.
+func (k *KeyRing) Rewrap(data []byte) ([]byte, bool, error) {
+	keyID, _, err := k.parse(data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Keys in an older format are always rewrapped
+	if keyID == k.currentID && len(data)%8 == EnvelopeHeaderLength {
+		return data, false, nil
+	}
+
+	key, err := k.Unwrap(data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	wrapped, err := k.Wrap(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return wrapped, true, nil
+}
+
+// parse splits a wrapped key into key ID and the actual wrapped key.
+func (k *KeyRing) parse(data []byte) (uint32, []byte, error) {
+	switch len(data) % 8 {
+	case 0:
+		return LegacyKeyID, data, nil
+	case keyIDLength:
+		return binary.BigEndian.Uint32(data), data[keyIDLength:], nil
+	case EnvelopeHeaderLength:
+		header, wrapped, ok := parseEnvelope(data)
+		if !ok || header.algorithm != AlgorithmAES256KWP {
+			return 0, nil, fmt.Errorf("keyring: invalid wrapped key header")
+		}
+		return header.keyID, wrapped, nil
+	default:
+		return 0, nil, fmt.Errorf("keyring: invalid wrapped key length")
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func newTestKeyRing(t *testing.T, currentID uint32, keys map[uint32][]byte) *KeyRing {
+	keyRing, err := NewKWPKeyRing(currentID, keys)
+	if err != nil {
+		t.Fatalf("NewKWPKeyRing failed: %v", err)
+	}
+	return keyRing
+}
+
+func TestKeyRingWrapUnwrap(t *testing.T) {
+	keyRing := newTestKeyRing(t, 7, map[uint32][]byte{7: GetRandomBytes(32)})
+	key := GetRandomBytes(32)
+
+	wrapped, err := keyRing.Wrap(key)
+	if err != nil {
+		t.Fatalf("Wrap failed: %v", err)
+	}
+
+	keyID, err := keyRing.KeyID(wrapped)
+	if err != nil {
+		t.Fatalf("KeyID failed: %v", err)
+	}
+	if keyID != 7 {
+		t.Fatalf("Wrong key ID: %v", keyID)
+	}
+
+	unwrapped, err := keyRing.Unwrap(wrapped)
+	if err != nil {
+		t.Fatalf("Unwrap failed: %v", err)
+	}
+	if !bytes.Equal(key, unwrapped) {
+		t.Fatal("unwrapped doesn't match original key")
+	}
+}
+
+func TestKeyRingMissingCurrentKey(t *testing.T) {
+	_, err := NewKWPKeyRing(1, map[uint32][]byte{0: GetRandomBytes(32)})
+	if err == nil {
+		t.Fatal("Expected NewKWPKeyRing to fail")
+	}
+}
+
+func TestKeyRingLegacy(t *testing.T) {
+	kek := GetRandomBytes(32)
+	kwp, err := NewKWP(kek)
+	if err != nil {
+		t.Fatalf("NewKWP failed: %v", err)
+	}
+
+	key := GetRandomBytes(32)
+	legacy, err := kwp.Wrap(key)
+	if err != nil {
+		t.Fatalf("Wrap failed: %v", err)
+	}
+
+	keyRing := newTestKeyRing(t, 1, map[uint32][]byte{LegacyKeyID: kek, 1: GetRandomBytes(32)})
+	unwrapped, err := keyRing.Unwrap(legacy)
+	if err != nil {
+		t.Fatalf("Unwrap failed: %v", err)
+	}
+	if !bytes.Equal(key, unwrapped) {
+		t.Fatal("unwrapped doesn't match original key")
+	}
+
+	// Legacy keys are re-wrapped even if the legacy key is the current key
+	keyRing = newTestKeyRing(t, LegacyKeyID, map[uint32][]byte{LegacyKeyID: kek})
+	rewrapped, changed, err := keyRing.Rewrap(legacy)
+	if err != nil {
+		t.Fatalf("Rewrap failed: %v", err)
+	}
+	if !changed {
+		t.Fatal("Legacy key was not re-wrapped")
+	}
+	keyID, err := keyRing.KeyID(rewrapped)
+	if err != nil || keyID != LegacyKeyID {
+		t.Fatalf("Wrong key ID: %v, %v", keyID, err)
+	}
+}
+
+func TestKeyRingKeyIDPrefix(t *testing.T) {
+	kek := GetRandomBytes(32)
+	kwp, err := NewKWP(kek)
+	if err != nil {
+		t.Fatalf("NewKWP failed: %v", err)
+	}
+
+	key := GetRandomBytes(32)
+	wrapped, err := kwp.Wrap(key)
+	if err != nil {
+		t.Fatalf("Wrap failed: %v", err)
+	}
+	prefixed := append([]byte{0, 0, 0, 3}, wrapped...)
+
+	keyRing := newTestKeyRing(t, 3, map[uint32][]byte{3: kek})
+	unwrapped, err := keyRing.Unwrap(prefixed)
+	if err != nil {
+		t.Fatalf("Unwrap failed: %v", err)
+	}
+	if !bytes.Equal(key, unwrapped) {
+		t.Fatal("unwrapped doesn't match original key")
+	}
+
+	// Keys with a key ID prefix are re-wrapped into an envelope even under the current key
+	rewrapped, changed, err := keyRing.Rewrap(prefixed)
+	if err != nil {
+		t.Fatalf("Rewrap failed: %v", err)
+	}
+	if !changed {
+		t.Fatal("Key was not re-wrapped")
+	}
+	header, _, ok := parseEnvelope(rewrapped)
+	if !ok || header.algorithm != AlgorithmAES256KWP || header.keyID != 3 {
+		t.Fatalf("Wrong envelope header: %x", rewrapped)
+	}
+}
+
+func TestKeyRingRewrap(t *testing.T) {
+	oldKEK := GetRandomBytes(32)
+	newKEK := GetRandomBytes(32)
+	oldKeyRing := newTestKeyRing(t, 1, map[uint32][]byte{1: oldKEK})
+	newKeyRing := newTestKeyRing(t, 2, map[uint32][]byte{1: oldKEK, 2: newKEK})
+
+	key := GetRandomBytes(32)
+	wrapped, err := oldKeyRing.Wrap(key)
+	if err != nil {
+		t.Fatalf("Wrap failed: %v", err)
+	}
+
+	rewrapped, changed, err := newKeyRing.Rewrap(wrapped)
+	if err != nil {
+		t.Fatalf("Rewrap failed: %v", err)
+	}
+	if !changed {
+		t.Fatal("Key was not re-wrapped")
+	}
+
+	// The new key ring no longer needs the old KEK
+	rotatedKeyRing := newTestKeyRing(t, 2, map[uint32][]byte{2: newKEK})
+	unwrapped, err := rotatedKeyRing.Unwrap(rewrapped)
+	if err != nil {
+		t.Fatalf("Unwrap failed: %v", err)
+	}
+	if !bytes.Equal(key, unwrapped) {
+		t.Fatal("unwrapped doesn't match original key")
+	}
+
+	// Re-wrapping again is a no-op
+	again, changed, err := newKeyRing.Rewrap(rewrapped)
+	if err != nil {
+		t.Fatalf("Rewrap failed: %v", err)
+	}
+	if changed || !bytes.Equal(again, rewrapped) {
+		t.Fatal("Key was re-wrapped twice")
+	}
+}
+
+func TestKeyRingUnknownKeyID(t *testing.T) {
+	oldKeyRing := newTestKeyRing(t, 1, map[uint32][]byte{1: GetRandomBytes(32)})
+	newKeyRing := newTestKeyRing(t, 2, map[uint32][]byte{2: GetRandomBytes(32)})
+
+	wrapped, err := oldKeyRing.Wrap(GetRandomBytes(32))
+	if err != nil {
+		t.Fatalf("Wrap failed: %v", err)
+	}
+
+	if _, err := newKeyRing.Unwrap(wrapped); err == nil {
+		t.Fatal("Expected Unwrap to fail")
+	}
+	if _, err := newKeyRing.Unwrap(wrapped[:len(wrapped)-1]); err == nil {
+		t.Fatal("Expected Unwrap to fail on invalid length")
+	}
+}
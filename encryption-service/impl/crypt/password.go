@@ -16,32 +16,132 @@ package crypt
 import (
 	"crypto/subtle"
 	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/pbkdf2"
 	"golang.org/x/crypto/sha3"
 )
 
-// GenerateUserPassword generates a random base64 encoded password and a salt
-func GenerateUserPassword() (string, []byte, error) {
+// PasswordHashParams are the Argon2id parameters used to hash a password
+type PasswordHashParams struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+}
+
+// DefaultPasswordHashParams are the parameters used for new password hashes. Stored hashes with a
+// lower cost are upgraded on the next successful login, so the cost can be raised over time.
+var DefaultPasswordHashParams = PasswordHashParams{
+	Time:    2,
+	Memory:  19 * 1024,
+	Threads: 1,
+}
+
+const (
+	passwordHashSaltLength = 16
+	passwordHashLength     = 32
+)
+
+// GenerateUserPassword generates a random base64 encoded password
+func GenerateUserPassword() (string, error) {
 	password, err := Random(32)
 	if err != nil {
-		return "", nil, err
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(password), nil
+}
+
+// HashPassword hashes a password with Argon2id using the default parameters
+func HashPassword(password string) ([]byte, error) {
+	return HashPasswordWithParams(password, DefaultPasswordHashParams)
+}
+
+// HashPasswordWithParams hashes a password with Argon2id using the given parameters. The hash is
+// encoded in the PHC string format and records the parameters and the salt, e.g.
+// `$argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>`.
+func HashPasswordWithParams(password string, params PasswordHashParams) ([]byte, error) {
+	salt, err := Random(passwordHashSaltLength)
+	if err != nil {
+		return nil, err
 	}
 
-	salt, err := Random(8)
+	hash := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, passwordHashLength)
+	encoded := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, params.Memory, params.Time, params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(hash))
+	return []byte(encoded), nil
+}
+
+// CompareHashAndPassword returns true if hash and password are equal. The salt is only set for
+// legacy PBKDF2 hashes, which don't record their salt.
+func CompareHashAndPassword(password string, hash []byte, salt []byte) bool {
+	if isLegacyHash(salt) {
+		return subtle.ConstantTimeCompare(hashPasswordPBKDF2(password, salt), hash) == 1
+	}
+
+	params, salt, hash, err := parsePasswordHash(hash)
 	if err != nil {
-		return "", nil, err
+		return false
+	}
+	return subtle.ConstantTimeCompare(argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(hash))), hash) == 1
+}
+
+// PasswordNeedsRehash returns true if a hash is a legacy PBKDF2 hash, or an Argon2id hash with a
+// lower time or memory cost than the current default parameters
+func PasswordNeedsRehash(hash []byte, salt []byte) bool {
+	if isLegacyHash(salt) {
+		return true
 	}
-	return base64.RawURLEncoding.EncodeToString(password), salt, nil
+
+	params, _, _, err := parsePasswordHash(hash)
+	return err != nil || params.Time < DefaultPasswordHashParams.Time || params.Memory < DefaultPasswordHashParams.Memory
 }
 
-// HashPassword hashes a password according to
+// hashPasswordPBKDF2 computes a legacy password hash according to
 // https://pages.nist.gov/800-63-3/sp800-63b.html#-5112-memorized-secret-verifiers
-func HashPassword(password string, salt []byte) []byte {
+func hashPasswordPBKDF2(password string, salt []byte) []byte {
 	return pbkdf2.Key([]byte(password), salt, 10000, 32, sha3.New256)
 }
 
-// CompareHashAndPassword returns true if hash and password are equal
-func CompareHashAndPassword(password string, hash []byte, salt []byte) bool {
-	return subtle.ConstantTimeCompare(HashPassword(password, salt), hash) == 1
+// isLegacyHash distinguishes legacy raw PBKDF2 hashes from PHC encoded hashes. The raw hash may
+// start with any byte, so the format is decided by the separately stored salt, which is only set
+// for legacy hashes.
+func isLegacyHash(salt []byte) bool {
+	return len(salt) > 0
+}
+
+// parsePasswordHash parses a PHC encoded Argon2id hash
+func parsePasswordHash(encoded []byte) (PasswordHashParams, []byte, []byte, error) {
+	var params PasswordHashParams
+	var version int
+
+	fields := strings.Split(string(encoded), "$")
+	if len(fields) != 6 || fields[0] != "" || fields[1] != "argon2id" {
+		return params, nil, nil, errors.New("unsupported password hash")
+	}
+	if _, err := fmt.Sscanf(fields[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errors.New("unsupported argon2 version")
+	}
+	if _, err := fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, err
+	}
+	if params.Time == 0 || params.Memory == 0 || params.Threads == 0 {
+		return params, nil, nil, errors.New("invalid argon2 parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(fields[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	hash, err := base64.RawStdEncoding.DecodeString(fields[5])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	if len(hash) == 0 {
+		return params, nil, nil, errors.New("empty password hash")
+	}
+
+	return params, salt, hash, nil
 }
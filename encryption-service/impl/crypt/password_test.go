@@ -0,0 +1,135 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("password")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	if !strings.HasPrefix(string(hash), "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Fatalf("Unexpected hash format: %s", hash)
+	}
+
+	if !CompareHashAndPassword("password", hash, nil) {
+		t.Fatal("CompareHashAndPassword rejected correct password")
+	}
+	if CompareHashAndPassword("Password", hash, nil) {
+		t.Fatal("CompareHashAndPassword accepted wrong password")
+	}
+	if PasswordNeedsRehash(hash, nil) {
+		t.Fatal("Hash with default parameters needs rehash")
+	}
+
+	// Salts must be random
+	otherHash, err := HashPassword("password")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	if string(hash) == string(otherHash) {
+		t.Fatal("Hashes of the same password are equal")
+	}
+}
+
+func TestComparePasswordEncoded(t *testing.T) {
+	// Hash of "password" with salt "somesaltsomesalt" and non-default parameters
+	hash := []byte("$argon2id$v=19$m=1024,t=2,p=1$c29tZXNhbHRzb21lc2FsdA$CKGe5/bX9YnCq2rxjW5yQXKxn31v1GKzhDCrMc6r6vA")
+
+	if !CompareHashAndPassword("password", hash, nil) {
+		t.Fatal("CompareHashAndPassword rejected correct password")
+	}
+	if !PasswordNeedsRehash(hash, nil) {
+		t.Fatal("Hash with outdated parameters doesn't need rehash")
+	}
+}
+
+func TestPasswordNeedsRehashStronger(t *testing.T) {
+	params := DefaultPasswordHashParams
+	params.Time++
+	params.Memory *= 2
+	params.Threads++
+	hash, err := HashPasswordWithParams("password", params)
+	if err != nil {
+		t.Fatalf("HashPasswordWithParams failed: %v", err)
+	}
+
+	if !CompareHashAndPassword("password", hash, nil) {
+		t.Fatal("CompareHashAndPassword rejected correct password")
+	}
+	if PasswordNeedsRehash(hash, nil) {
+		t.Fatal("Hash with stronger parameters needs rehash")
+	}
+}
+
+func TestCompareLegacyPassword(t *testing.T) {
+	salt := []byte("Salt")
+	hash := hashPasswordPBKDF2("password", salt)
+
+	if !CompareHashAndPassword("password", hash, salt) {
+		t.Fatal("CompareHashAndPassword rejected correct password")
+	}
+	if CompareHashAndPassword("password", hash, []byte("Pepper")) {
+		t.Fatal("CompareHashAndPassword accepted wrong salt")
+	}
+	if !PasswordNeedsRehash(hash, salt) {
+		t.Fatal("Legacy hash doesn't need rehash")
+	}
+}
+
+func TestCompareLegacyPasswordDollarPrefix(t *testing.T) {
+	// Find a salt for which the raw legacy hash starts with '$'
+	var salt, hash []byte
+	for i := 0; ; i++ {
+		salt = []byte(fmt.Sprintf("Salt%d", i))
+		hash = hashPasswordPBKDF2("password", salt)
+		if hash[0] == '$' {
+			break
+		}
+	}
+
+	if !CompareHashAndPassword("password", hash, salt) {
+		t.Fatal("CompareHashAndPassword rejected correct password")
+	}
+	if CompareHashAndPassword("Password", hash, salt) {
+		t.Fatal("CompareHashAndPassword accepted wrong password")
+	}
+	if !PasswordNeedsRehash(hash, salt) {
+		t.Fatal("Legacy hash doesn't need rehash")
+	}
+}
+
+func TestCompareMalformedPassword(t *testing.T) {
+	hashes := []string{
+		"$argon2i$v=19$m=1024,t=2,p=1$c29tZXNhbHRzb21lc2FsdA$CKGe5/bX9YnCq2rxjW5yQXKxn31v1GKzhDCrMc6r6vA",
+		"$argon2id$v=16$m=1024,t=2,p=1$c29tZXNhbHRzb21lc2FsdA$CKGe5/bX9YnCq2rxjW5yQXKxn31v1GKzhDCrMc6r6vA",
+		"$argon2id$v=19$m=1024,t=0,p=1$c29tZXNhbHRzb21lc2FsdA$CKGe5/bX9YnCq2rxjW5yQXKxn31v1GKzhDCrMc6r6vA",
+		"$argon2id$v=19$m=1024,t=2,p=1$c29tZXNhbHRzb21lc2FsdA$",
+		"$argon2id$v=19$m=1024,t=2,p=1$c29tZXNhbHRzb21lc2FsdA",
+	}
+
+	for _, hash := range hashes {
+		if CompareHashAndPassword("password", []byte(hash), nil) {
+			t.Errorf("CompareHashAndPassword accepted malformed hash %s", hash)
+		}
+		if !PasswordNeedsRehash([]byte(hash), nil) {
+			t.Errorf("Malformed hash %s doesn't need rehash", hash)
+		}
+	}
+}
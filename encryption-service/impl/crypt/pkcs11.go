@@ -0,0 +1,316 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build pkcs11
+// +build pkcs11
+
+package crypt
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/miekg/pkcs11"
+)
+
+// DefaultPKCS11MaxSessions is the number of sessions opened by `PKCS11KeyWrapper` if no maximum is
+// configured
+const DefaultPKCS11MaxSessions = 16
+
+// PKCS11KeyWrapper wraps and unwraps keys with an AES key that never leaves a PKCS#11 token. Keys
+// are wrapped using CKM_AES_KEY_WRAP_PAD, i.e. the wrapped keys are compatible with `KWP`.
+//
+// A session must not be used concurrently, so the wrapper keeps a pool of sessions that are opened
+// on demand. Sessions that fail because the token was removed or restarted are closed, and the
+// operation is retried on a new session.
+type PKCS11KeyWrapper struct {
+	ctx        *pkcs11.Ctx
+	tokenLabel string
+	pin        string
+	keyLabel   string
+
+	// Idle sessions, and one element for every open session, which bounds the number of sessions
+	idle chan *pkcs11Session
+	open chan struct{}
+}
+
+// pkcs11Session is a logged in session together with the handle of the wrapping key in it
+type pkcs11Session struct {
+	handle pkcs11.SessionHandle
+	key    pkcs11.ObjectHandle
+}
+
+var pkcs11Mechanism = []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_KEY_WRAP_PAD, nil)}
+
+// Errors after which a session can not be used any more, e.g. because the token was restarted
+var pkcs11SessionErrors = []pkcs11.Error{
+	pkcs11.CKR_SESSION_HANDLE_INVALID,
+	pkcs11.CKR_SESSION_CLOSED,
+	pkcs11.CKR_USER_NOT_LOGGED_IN,
+	pkcs11.CKR_KEY_HANDLE_INVALID,
+	pkcs11.CKR_OBJECT_HANDLE_INVALID,
+	pkcs11.CKR_DEVICE_ERROR,
+	pkcs11.CKR_DEVICE_REMOVED,
+	pkcs11.CKR_TOKEN_NOT_PRESENT,
+}
+
+// isPKCS11SessionError reports whether the session that returned the error must be discarded
+func isPKCS11SessionError(err error) bool {
+	for _, sessionErr := range pkcs11SessionErrors {
+		if errors.Is(err, sessionErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewPKCS11KeyWrapper loads the PKCS#11 module at `module`, logs into the token labeled
+// `tokenLabel` and looks up the secret key labeled `keyLabel`. At most `maxSessions` sessions are
+// opened, or `DefaultPKCS11MaxSessions` if it is zero.
+func NewPKCS11KeyWrapper(module, tokenLabel, pin, keyLabel string, maxSessions int) (*PKCS11KeyWrapper, error) {
+	if maxSessions < 0 {
+		return nil, fmt.Errorf("pkcs11: invalid maximum number of sessions %d", maxSessions)
+	}
+	if maxSessions == 0 {
+		maxSessions = DefaultPKCS11MaxSessions
+	}
+
+	ctx := pkcs11.New(module)
+	if ctx == nil {
+		return nil, fmt.Errorf("pkcs11: could not load module %v", module)
+	}
+
+	err := ctx.Initialize()
+	if err != nil && !errors.Is(err, pkcs11.Error(pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED)) {
+		ctx.Destroy()
+		return nil, err
+	}
+
+	w := &PKCS11KeyWrapper{
+		ctx:        ctx,
+		tokenLabel: tokenLabel,
+		pin:        pin,
+		keyLabel:   keyLabel,
+		idle:       make(chan *pkcs11Session, maxSessions),
+		open:       make(chan struct{}, maxSessions),
+	}
+
+	// Open the first session right away, such that configuration errors are reported at startup
+	session, err := w.getSession()
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	w.putSession(session, nil)
+
+	return w, nil
+}
+
+// openSession opens a session on the token, logs in and finds the wrapping key
+func (w *PKCS11KeyWrapper) openSession() (*pkcs11Session, error) {
+	slots, err := w.ctx.GetSlotList(true)
+	if err != nil {
+		return nil, err
+	}
+
+	var slot *uint
+	for i := range slots {
+		info, err := w.ctx.GetTokenInfo(slots[i])
+		if err != nil {
+			return nil, err
+		}
+		if info.Label == w.tokenLabel {
+			slot = &slots[i]
+			break
+		}
+	}
+	if slot == nil {
+		return nil, fmt.Errorf("pkcs11: token %v not found", w.tokenLabel)
+	}
+
+	handle, err := w.ctx.OpenSession(*slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		return nil, err
+	}
+	session := &pkcs11Session{handle: handle}
+
+	if err := session.init(w.ctx, w.pin, w.keyLabel); err != nil {
+		_ = w.ctx.CloseSession(handle)
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// init logs into the token and finds the wrapping key. The login state is shared by all sessions of
+// the application, so it is fine if another session already logged in.
+func (s *pkcs11Session) init(ctx *pkcs11.Ctx, pin, keyLabel string) error {
+	err := ctx.Login(s.handle, pkcs11.CKU_USER, pin)
+	if err != nil && !errors.Is(err, pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN)) {
+		return err
+	}
+
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_AES),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, keyLabel),
+	}
+	if err := ctx.FindObjectsInit(s.handle, template); err != nil {
+		return err
+	}
+	keys, _, err := ctx.FindObjects(s.handle, 2)
+	if err != nil {
+		return err
+	}
+	if err := ctx.FindObjectsFinal(s.handle); err != nil {
+		return err
+	}
+	if len(keys) != 1 {
+		return fmt.Errorf("pkcs11: expected one AES key labeled %v, found %d", keyLabel, len(keys))
+	}
+	s.key = keys[0]
+
+	return nil
+}
+
+// getSession returns an idle session, or opens a new one if fewer than the maximum number of
+// sessions are open. Otherwise it waits for a session to become idle.
+func (w *PKCS11KeyWrapper) getSession() (*pkcs11Session, error) {
+	select {
+	case session := <-w.idle:
+		return session, nil
+	default:
+	}
+
+	select {
+	case session := <-w.idle:
+		return session, nil
+	case w.open <- struct{}{}:
+		session, err := w.openSession()
+		if err != nil {
+			<-w.open
+			return nil, err
+		}
+		return session, nil
+	}
+}
+
+// putSession returns a session to the pool after it was used. If the operation failed with an error
+// that invalidates the session, the session is closed, and so are all idle sessions, as they are
+// most likely affected too.
+func (w *PKCS11KeyWrapper) putSession(session *pkcs11Session, err error) {
+	if !isPKCS11SessionError(err) {
+		w.idle <- session
+		return
+	}
+
+	w.closeSession(session)
+	for {
+		select {
+		case idle := <-w.idle:
+			w.closeSession(idle)
+		default:
+			return
+		}
+	}
+}
+
+// closeSession closes a session and frees its place in the pool
+func (w *PKCS11KeyWrapper) closeSession(session *pkcs11Session) {
+	_ = w.ctx.CloseSession(session.handle)
+	<-w.open
+}
+
+// withSession runs `operation` on a session from the pool. If the session turns out to be invalid,
+// the operation is retried once on a new session.
+func (w *PKCS11KeyWrapper) withSession(operation func(session *pkcs11Session) ([]byte, error)) ([]byte, error) {
+	var result []byte
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var session *pkcs11Session
+		session, err = w.getSession()
+		if err != nil {
+			return nil, err
+		}
+
+		result, err = operation(session)
+		w.putSession(session, err)
+		if !isPKCS11SessionError(err) {
+			break
+		}
+	}
+	return result, err
+}
+
+// Close logs out of the token, closes all sessions and unloads the PKCS#11 module. It must not be
+// called while keys are wrapped or unwrapped.
+func (w *PKCS11KeyWrapper) Close() error {
+	for loggedOut := false; len(w.idle) > 0; loggedOut = true {
+		session := <-w.idle
+		if !loggedOut {
+			_ = w.ctx.Logout(session.handle)
+		}
+		w.closeSession(session)
+	}
+
+	err := w.ctx.Finalize()
+	w.ctx.Destroy()
+	return err
+}
+
+// Wrap wraps the provided key material inside the token.
+func (w *PKCS11KeyWrapper) Wrap(data []byte) ([]byte, error) {
+	return w.withSession(func(session *pkcs11Session) ([]byte, error) {
+		// Import the key material as a temporary session object, so the token can wrap it
+		template := []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+			pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_GENERIC_SECRET),
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, false),
+			pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_VALUE, data),
+		}
+		object, err := w.ctx.CreateObject(session.handle, template)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = w.ctx.DestroyObject(session.handle, object) }()
+
+		return w.ctx.WrapKey(session.handle, pkcs11Mechanism, session.key, object)
+	})
+}
+
+// Unwrap unwraps a wrapped key inside the token.
+func (w *PKCS11KeyWrapper) Unwrap(data []byte) ([]byte, error) {
+	return w.withSession(func(session *pkcs11Session) ([]byte, error) {
+		template := []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+			pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_GENERIC_SECRET),
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, false),
+			pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, false),
+			pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, true),
+		}
+		object, err := w.ctx.UnwrapKey(session.handle, pkcs11Mechanism, session.key, data, template)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = w.ctx.DestroyObject(session.handle, object) }()
+
+		attributes, err := w.ctx.GetAttributeValue(session.handle, object, []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_VALUE, nil)})
+		if err != nil {
+			return nil, err
+		}
+
+		return attributes[0].Value, nil
+	})
+}
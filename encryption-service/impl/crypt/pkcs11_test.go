@@ -0,0 +1,244 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build pkcs11
+// +build pkcs11
+
+package crypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/miekg/pkcs11"
+)
+
+// The tests require a PKCS#11 token, e.g. SoftHSM, see `scripts/pkcs11_tests.sh`
+func pkcs11TestConfig(t *testing.T) (module, tokenLabel, pin string) {
+	module = os.Getenv("ECTNZ_PKCS11_TEST_MODULE")
+	tokenLabel = os.Getenv("ECTNZ_PKCS11_TEST_TOKENLABEL")
+	pin = os.Getenv("ECTNZ_PKCS11_TEST_PIN")
+	if module == "" {
+		t.Skip("ECTNZ_PKCS11_TEST_MODULE not set")
+	}
+	return module, tokenLabel, pin
+}
+
+// Creates an extractable AES key in the token, so the test can compare against `KWP`. Returns the
+// key label, the key value and a function destroying the key.
+func createPKCS11TestKey(t *testing.T, module, tokenLabel, pin string) (string, []byte, func()) {
+	ctx := pkcs11.New(module)
+	if ctx == nil {
+		t.Fatalf("could not load module %v", module)
+	}
+	if err := ctx.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	var session pkcs11.SessionHandle
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		t.Fatalf("GetSlotList failed: %v", err)
+	}
+	for _, slot := range slots {
+		info, err := ctx.GetTokenInfo(slot)
+		if err != nil {
+			t.Fatalf("GetTokenInfo failed: %v", err)
+		}
+		if info.Label != tokenLabel {
+			continue
+		}
+		session, err = ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+		if err != nil {
+			t.Fatalf("OpenSession failed: %v", err)
+		}
+	}
+	if session == 0 {
+		t.Fatalf("token %v not found", tokenLabel)
+	}
+	if err := ctx.Login(session, pkcs11.CKU_USER, pin); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	label := hex.EncodeToString(GetRandomBytes(8))
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_AES),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE_LEN, 32),
+		pkcs11.NewAttribute(pkcs11.CKA_WRAP, true),
+		pkcs11.NewAttribute(pkcs11.CKA_UNWRAP, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, true),
+	}
+	key, err := ctx.GenerateKey(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_KEY_GEN, nil)}, template)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+
+	value, err := ctx.GetAttributeValue(session, key, []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_VALUE, nil)})
+	if err != nil {
+		t.Fatalf("GetAttributeValue failed: %v", err)
+	}
+
+	return label, value[0].Value, func() {
+		if err := ctx.DestroyObject(session, key); err != nil {
+			t.Errorf("DestroyObject failed: %v", err)
+		}
+		_ = ctx.Logout(session)
+		_ = ctx.CloseSession(session)
+		if err := ctx.Finalize(); err != nil {
+			t.Errorf("Finalize failed: %v", err)
+		}
+		ctx.Destroy()
+	}
+}
+
+func TestPKCS11WrapUnwrap(t *testing.T) {
+	module, tokenLabel, pin := pkcs11TestConfig(t)
+	keyLabel, kek, destroy := createPKCS11TestKey(t, module, tokenLabel, pin)
+	defer destroy()
+
+	wrapper, err := NewPKCS11KeyWrapper(module, tokenLabel, pin, keyLabel, 0)
+	if err != nil {
+		t.Fatalf("NewPKCS11KeyWrapper failed: %v", err)
+	}
+
+	kwp, err := NewKWP(kek)
+	if err != nil {
+		t.Fatalf("NewKWP failed: %v", err)
+	}
+
+	for _, size := range []uint32{16, 32, 33, 64} {
+		key := GetRandomBytes(size)
+
+		wrapped, err := wrapper.Wrap(key)
+		if err != nil {
+			t.Fatalf("Wrap failed: %v", err)
+		}
+		unwrapped, err := wrapper.Unwrap(wrapped)
+		if err != nil {
+			t.Fatalf("Unwrap failed: %v", err)
+		}
+		if !bytes.Equal(key, unwrapped) {
+			t.Fatal("unwrapped doesn't match original key")
+		}
+
+		// Wrapped keys are compatible with KWP
+		unwrapped, err = kwp.Unwrap(wrapped)
+		if err != nil {
+			t.Fatalf("KWP Unwrap failed: %v", err)
+		}
+		if !bytes.Equal(key, unwrapped) {
+			t.Fatal("KWP unwrapped doesn't match original key")
+		}
+	}
+
+	wrapped, err := wrapper.Wrap(GetRandomBytes(32))
+	if err != nil {
+		t.Fatalf("Wrap failed: %v", err)
+	}
+	wrapped[0] ^= 1
+	if _, err := wrapper.Unwrap(wrapped); err == nil {
+		t.Fatal("Unwrap of modified key succeeded")
+	}
+}
+
+func TestPKCS11UnknownKey(t *testing.T) {
+	module, tokenLabel, pin := pkcs11TestConfig(t)
+
+	if _, err := NewPKCS11KeyWrapper(module, tokenLabel, pin, "no such key", 0); err == nil {
+		t.Fatal("Expected NewPKCS11KeyWrapper to fail")
+	}
+}
+
+func TestPKCS11Concurrent(t *testing.T) {
+	module, tokenLabel, pin := pkcs11TestConfig(t)
+	keyLabel, _, destroy := createPKCS11TestKey(t, module, tokenLabel, pin)
+	defer destroy()
+
+	wrapper, err := NewPKCS11KeyWrapper(module, tokenLabel, pin, keyLabel, 4)
+	if err != nil {
+		t.Fatalf("NewPKCS11KeyWrapper failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := GetRandomBytes(32)
+			wrapped, err := wrapper.Wrap(key)
+			if err != nil {
+				errs <- err
+				return
+			}
+			unwrapped, err := wrapper.Unwrap(wrapped)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if !bytes.Equal(key, unwrapped) {
+				errs <- errors.New("unwrapped doesn't match original key")
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Concurrent wrap and unwrap failed: %v", err)
+	}
+	if len(wrapper.open) > 4 {
+		t.Errorf("Wrapper opened %d sessions, expected at most 4", len(wrapper.open))
+	}
+}
+
+// Test that sessions invalidated behind the back of the wrapper, e.g. by a restart of the token,
+// are replaced
+func TestPKCS11InvalidSession(t *testing.T) {
+	module, tokenLabel, pin := pkcs11TestConfig(t)
+	keyLabel, _, destroy := createPKCS11TestKey(t, module, tokenLabel, pin)
+	defer destroy()
+
+	wrapper, err := NewPKCS11KeyWrapper(module, tokenLabel, pin, keyLabel, 0)
+	if err != nil {
+		t.Fatalf("NewPKCS11KeyWrapper failed: %v", err)
+	}
+
+	session := <-wrapper.idle
+	if err := wrapper.ctx.CloseSession(session.handle); err != nil {
+		t.Fatalf("CloseSession failed: %v", err)
+	}
+	wrapper.idle <- session
+
+	key := GetRandomBytes(32)
+	wrapped, err := wrapper.Wrap(key)
+	if err != nil {
+		t.Fatalf("Wrap after invalidated session failed: %v", err)
+	}
+	unwrapped, err := wrapper.Unwrap(wrapped)
+	if err != nil {
+		t.Fatalf("Unwrap failed: %v", err)
+	}
+	if !bytes.Equal(key, unwrapped) {
+		t.Fatal("unwrapped doesn't match original key")
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+)
+
+// Names of the power-on self-tests
+const (
+	SelfTestAESGCM       = "aes-gcm"
+	SelfTestKWP          = "kwp"
+	SelfTestPasswordHash = "password-hash"
+	SelfTestRandom       = "random"
+)
+
+var errKnownAnswerMismatch = errors.New("known answer mismatch")
+
+// SelfTestResult is the outcome of a power-on self-test. Err is nil if the test passed.
+type SelfTestResult struct {
+	Name string
+	Err  error
+}
+
+var selfTests = []struct {
+	name string
+	run  func() error
+}{
+	{SelfTestAESGCM, selfTestAESGCM},
+	{SelfTestKWP, selfTestKWP},
+	{SelfTestPasswordHash, selfTestPasswordHash},
+	{SelfTestRandom, selfTestRandom},
+}
+
+// RunSelfTests runs known-answer tests of the cryptographic primitives of the service and a health
+// test of the random number generator. All tests are run, even if some of them fail.
+func RunSelfTests() []SelfTestResult {
+	results := make([]SelfTestResult, 0, len(selfTests))
+	for _, test := range selfTests {
+		results = append(results, SelfTestResult{Name: test.name, Err: test.run()})
+	}
+	return results
+}
+
+// SelfTestsPassed returns true if none of the self-tests failed
+func SelfTestsPassed(results []SelfTestResult) bool {
+	for _, result := range results {
+		if result.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// AES-256-GCM vector from the GCM specification (test case 16), wrapped in an envelope
+var (
+	gcmKATKey        = mustDecodeHex("feffe9928665731c6d6a8f9467308308feffe9928665731c6d6a8f9467308308")
+	gcmKATNonce      = mustDecodeHex("cafebabefacedbaddecaf888")
+	gcmKATAAD        = mustDecodeHex("feedfacedeadbeeffeedfacedeadbeefabaddad2")
+	gcmKATPlaintext  = mustDecodeHex("d9313225f88406e5a55909c5aff5269a86a7a9531534f7da2e4c303d8a318a721c3c0c95956809532fcf0e2449a6b525b16aedf5aa0de657ba637b39")
+	gcmKATCiphertext = mustDecodeHex("ec010100000000cafebabefacedbaddecaf888522dc1f099567d07f47f37a32a84427d643a8cdcbfe5c0c97598a2bd2555d1aa8cb08e48590dbb3da7b08b1056828838c5f61e6393ba7a0abcc9f662eaab90a0acf3be662a9da74d4f3db4ff")
+)
+
+// selfTestAESGCM checks encryption and decryption of the known envelope, and that a modified
+// envelope is rejected
+func selfTestAESGCM() error {
+	aead, err := newGCM(gcmKATKey)
+	if err != nil {
+		return err
+	}
+	header := envelopeHeader{algorithm: AlgorithmAES256GCM}
+	prefix := append(header.marshal(), gcmKATNonce...)
+	if !bytes.Equal(aead.Seal(prefix, gcmKATNonce, gcmKATPlaintext, header.aad(gcmKATAAD)), gcmKATCiphertext) {
+		return errKnownAnswerMismatch
+	}
+
+	crypter := &AESCrypter{}
+	plaintext, err := crypter.Decrypt(append([]byte{}, gcmKATCiphertext...), gcmKATAAD, gcmKATKey)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(plaintext, gcmKATPlaintext) {
+		return errKnownAnswerMismatch
+	}
+
+	modified := append([]byte{}, gcmKATCiphertext...)
+	modified[len(modified)-1] ^= 1
+	if _, err := crypter.Decrypt(modified, gcmKATAAD, gcmKATKey); err == nil {
+		return errors.New("modified ciphertext was accepted")
+	}
+	return nil
+}
+
+// AES-256 key wrap with padding vector from Wycheproof (test case 169)
+var (
+	kwpKATKey        = mustDecodeHex("aa0ab9d68ed4a04e723f81b44c0c88d0bcde7a80cfd476eb4b8836d9aa01ec4c")
+	kwpKATPlaintext  = mustDecodeHex("57faa8766f6d6a0aa1cf643f857c150df5b31303b50af480e21c4b5e8c8a15d5")
+	kwpKATCiphertext = mustDecodeHex("0e9e2e9aa34bbf973d67bc534ac86fc5b5a5f9da5f026866177894ec6077a5c84501510e1bf4afb3")
+)
+
+// selfTestKWP checks wrapping and unwrapping of the known key
+func selfTestKWP() error {
+	kwp, err := NewKWP(kwpKATKey)
+	if err != nil {
+		return err
+	}
+
+	wrapped, err := kwp.Wrap(kwpKATPlaintext)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(wrapped, kwpKATCiphertext) {
+		return errKnownAnswerMismatch
+	}
+
+	unwrapped, err := kwp.Unwrap(kwpKATCiphertext)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(unwrapped, kwpKATPlaintext) {
+		return errKnownAnswerMismatch
+	}
+	return nil
+}
+
+// Password hash vectors. The Argon2id hash can be reproduced with the reference implementation:
+// `echo -n password | argon2 somesalt -id -t 2 -m 16 -p 4 -l 24`. The legacy hash is PBKDF2 with
+// SHA3-256, 10000 iterations and the salt "salt".
+var (
+	passwordKATPassword   = "password"
+	passwordKATHash       = []byte("$argon2id$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$F1jG2CV3/Nr+yRuIsPKw0J9r4s7cJHBU")
+	passwordKATLegacySalt = []byte("salt")
+	passwordKATLegacyHash = mustDecodeHex("d0881507757178773906ac2335c8f3d4138cf2ec6a312f15e4eb52dd8d258aa5")
+)
+
+// selfTestPasswordHash checks the known Argon2id and legacy hashes, and that a hash created with the
+// default parameters verifies
+func selfTestPasswordHash() error {
+	if !CompareHashAndPassword(passwordKATPassword, passwordKATHash, nil) {
+		return errKnownAnswerMismatch
+	}
+	if CompareHashAndPassword("wrong "+passwordKATPassword, passwordKATHash, nil) {
+		return errors.New("wrong password was accepted")
+	}
+	if !CompareHashAndPassword(passwordKATPassword, passwordKATLegacyHash, passwordKATLegacySalt) {
+		return errKnownAnswerMismatch
+	}
+
+	hash, err := HashPassword(passwordKATPassword)
+	if err != nil {
+		return err
+	}
+	if !CompareHashAndPassword(passwordKATPassword, hash, nil) {
+		return errors.New("new password hash does not verify")
+	}
+	return nil
+}
+
+// selfTestRandom checks that `Random` does not return all zeros or the same output twice. The
+// output of a random number generator can't be known in advance, so this is a health test rather
+// than a known-answer test.
+func selfTestRandom() error {
+	first, err := Random(32)
+	if err != nil {
+		return err
+	}
+	second, err := Random(32)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(first, make([]byte, len(first))) || bytes.Equal(first, second) {
+		return errors.New("random number generator is stuck")
+	}
+	return nil
+}
+
+// mustDecodeHex decodes a hex encoded test vector
+func mustDecodeHex(s string) []byte {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestRunSelfTests(t *testing.T) {
+	results := RunSelfTests()
+	if len(results) != len(selfTests) {
+		t.Fatalf("Expected %d results, got %d", len(selfTests), len(results))
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("Self-test %s failed: %v", result.Name, result.Err)
+		}
+	}
+	if !SelfTestsPassed(results) {
+		t.Fatal("SelfTestsPassed returned false")
+	}
+}
+
+func TestSelfTestAESGCMMismatch(t *testing.T) {
+	original := gcmKATCiphertext
+	defer func() { gcmKATCiphertext = original }()
+	gcmKATCiphertext = append([]byte{}, original...)
+	gcmKATCiphertext[EnvelopeHeaderLength+nonceLength] ^= 1
+
+	if err := selfTestAESGCM(); err == nil {
+		t.Fatal("AES-GCM self-test passed with wrong known answer")
+	}
+}
+
+func TestSelfTestKWPMismatch(t *testing.T) {
+	original := kwpKATCiphertext
+	defer func() { kwpKATCiphertext = original }()
+	kwpKATCiphertext = append([]byte{}, original...)
+	kwpKATCiphertext[0] ^= 1
+
+	if err := selfTestKWP(); err == nil {
+		t.Fatal("KWP self-test passed with wrong known answer")
+	}
+}
+
+func TestSelfTestPasswordHashMismatch(t *testing.T) {
+	original := passwordKATLegacyHash
+	defer func() { passwordKATLegacyHash = original }()
+	passwordKATLegacyHash = append([]byte{}, original...)
+	passwordKATLegacyHash[0] ^= 1
+
+	if err := selfTestPasswordHash(); err == nil {
+		t.Fatal("Password hash self-test passed with wrong known answer")
+	}
+}
+
+func TestSelfTestRandomStuck(t *testing.T) {
+	tmpReader := rand.Reader
+	defer func() { rand.Reader = tmpReader }()
+	rand.Reader = bytes.NewReader(bytes.Repeat([]byte{0x42}, 64))
+
+	if err := selfTestRandom(); err == nil {
+		t.Fatal("Random self-test passed with stuck generator")
+	}
+}
+
+func TestSelfTestsPassedFailure(t *testing.T) {
+	results := []SelfTestResult{
+		{Name: SelfTestAESGCM},
+		{Name: SelfTestRandom, Err: errKnownAnswerMismatch},
+	}
+	if SelfTestsPassed(results) {
+		t.Fatal("SelfTestsPassed returned true with a failing test")
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Shamir's secret sharing over GF(2^8) using the AES field polynomial x^8 + x^4 + x^3 + x + 1.
+// Every byte of the secret is shared separately using a random polynomial of degree threshold-1.
+//
+// Share format: y_1 || ... || y_len(secret) || x
+
+var gfExp, gfLog = gfTables()
+
+// gfTables computes exponentiation and logarithm tables of GF(2^8) for the generator 3
+func gfTables() ([512]byte, [256]byte) {
+	var exp [512]byte
+	var log [256]byte
+
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		exp[i] = x
+		log[x] = byte(i)
+
+		// x *= 3
+		x2 := x << 1
+		if x&0x80 != 0 {
+			x2 ^= 0x1b
+		}
+		x ^= x2
+	}
+	// Avoid reducing the exponent modulo 255 when multiplying
+	for i := 255; i < 512; i++ {
+		exp[i] = exp[i-255]
+	}
+
+	return exp, log
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// ShamirSplit splits a secret into `parts` shares, such that any `threshold` shares can be combined
+// to reconstruct the secret, while fewer shares reveal nothing about it.
+func ShamirSplit(secret []byte, parts, threshold int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("shamir: secret must not be empty")
+	}
+	if threshold < 2 || threshold > parts || parts > 255 {
+		return nil, fmt.Errorf("shamir: invalid threshold %d for %d parts", threshold, parts)
+	}
+
+	// Pick distinct non-zero x coordinates
+	xs := make([]byte, 0, parts)
+	used := make(map[byte]bool, parts)
+	for len(xs) < parts {
+		r, err := Random(1)
+		if err != nil {
+			return nil, err
+		}
+		if r[0] == 0 || used[r[0]] {
+			continue
+		}
+		used[r[0]] = true
+		xs = append(xs, r[0])
+	}
+
+	shares := make([][]byte, parts)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][len(secret)] = xs[i]
+	}
+
+	for j, s := range secret {
+		coefficients, err := Random(threshold - 1)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, x := range xs {
+			// Horner's method for s + c_1 x + ... + c_{t-1} x^{t-1}
+			y := byte(0)
+			for k := len(coefficients) - 1; k >= 0; k-- {
+				y = gfMul(y, x) ^ coefficients[k]
+			}
+			shares[i][j] = gfMul(y, x) ^ s
+		}
+	}
+
+	return shares, nil
+}
+
+// ShamirCombine reconstructs a secret from shares created by `ShamirSplit`. If fewer shares than the
+// threshold are provided, the result is a random value.
+func ShamirCombine(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("shamir: at least two shares are needed")
+	}
+
+	length := len(shares[0])
+	if length < 2 {
+		return nil, errors.New("shamir: share is too short")
+	}
+
+	xs := make([]byte, len(shares))
+	used := make(map[byte]bool, len(shares))
+	for i, share := range shares {
+		if len(share) != length {
+			return nil, errors.New("shamir: shares have different lengths")
+		}
+		x := share[length-1]
+		if x == 0 || used[x] {
+			return nil, errors.New("shamir: invalid or duplicate share")
+		}
+		used[x] = true
+		xs[i] = x
+	}
+
+	// Lagrange interpolation at x = 0
+	secret := make([]byte, length-1)
+	for i := range shares {
+		basis := byte(1)
+		for k := range shares {
+			if k != i {
+				basis = gfMul(basis, gfDiv(xs[k], xs[k]^xs[i]))
+			}
+		}
+
+		for j := range secret {
+			secret[j] ^= gfMul(shares[i][j], basis)
+		}
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestShamirSplitCombine(t *testing.T) {
+	secret := GetRandomBytes(32)
+
+	for _, params := range [][2]int{{2, 2}, {3, 2}, {5, 3}, {10, 10}, {255, 4}} {
+		parts, threshold := params[0], params[1]
+		t.Run(fmt.Sprintf("%dof%d", threshold, parts), func(t *testing.T) {
+			shares, err := ShamirSplit(secret, parts, threshold)
+			if err != nil {
+				t.Fatalf("ShamirSplit failed: %v", err)
+			}
+			if len(shares) != parts {
+				t.Fatalf("Wrong number of shares: %d", len(shares))
+			}
+
+			// Every window of `threshold` shares reconstructs the secret
+			for i := 0; i+threshold <= parts; i++ {
+				combined, err := ShamirCombine(shares[i : i+threshold])
+				if err != nil {
+					t.Fatalf("ShamirCombine failed: %v", err)
+				}
+				if !bytes.Equal(secret, combined) {
+					t.Fatal("Combined secret doesn't match")
+				}
+			}
+
+			// Fewer shares don't reconstruct the secret
+			if threshold > 2 {
+				combined, err := ShamirCombine(shares[:threshold-1])
+				if err != nil {
+					t.Fatalf("ShamirCombine failed: %v", err)
+				}
+				if bytes.Equal(secret, combined) {
+					t.Fatal("Secret reconstructed from too few shares")
+				}
+			}
+		})
+	}
+}
+
+func TestShamirInvalid(t *testing.T) {
+	secret := GetRandomBytes(32)
+
+	for _, params := range [][2]int{{1, 1}, {2, 1}, {2, 3}, {256, 2}} {
+		if _, err := ShamirSplit(secret, params[0], params[1]); err == nil {
+			t.Errorf("Expected ShamirSplit to fail for %v", params)
+		}
+	}
+	if _, err := ShamirSplit(nil, 3, 2); err == nil {
+		t.Error("Expected ShamirSplit to fail for empty secret")
+	}
+
+	shares, err := ShamirSplit(secret, 3, 2)
+	if err != nil {
+		t.Fatalf("ShamirSplit failed: %v", err)
+	}
+	if _, err := ShamirCombine(shares[:1]); err == nil {
+		t.Error("Expected ShamirCombine to fail for a single share")
+	}
+	if _, err := ShamirCombine([][]byte{shares[0], shares[0]}); err == nil {
+		t.Error("Expected ShamirCombine to fail for duplicate shares")
+	}
+	if _, err := ShamirCombine([][]byte{shares[0], shares[1][1:]}); err == nil {
+		t.Error("Expected ShamirCombine to fail for shares of different lengths")
+	}
+}
+
+func TestGFArithmetic(t *testing.T) {
+	// Examples from FIPS 197
+	if gfMul(0x57, 0x83) != 0xc1 || gfMul(0x53, 0xca) != 0x01 {
+		t.Fatal("GF(2^8) multiplication failed")
+	}
+
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			if gfDiv(gfMul(byte(a), byte(b)), byte(b)) != byte(a) {
+				t.Fatalf("GF(2^8) arithmetic failed for %d, %d", a, b)
+			}
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+)
+
+// SIVLength is the length of the synthetic IV prepended to AES-SIV ciphertexts in bytes
+const SIVLength = aes.BlockSize
+
+// Maximum number of associated data components, see RFC 5297 section 7
+const sivMaxAssociatedData = 126
+
+// AESSIV implements deterministic authenticated encryption using AES-SIV as specified in RFC 5297.
+// Unlike `AESCrypter` it does not use a random nonce, so the same plaintext and associated data
+// always encrypt to the same ciphertext under a given key. This leaks equality of plaintexts and must
+// only be used where that is intended.
+type AESSIV struct {
+	mac        cipher.Block
+	ctr        cipher.Block
+	macSubkey1 [aes.BlockSize]byte
+	macSubkey2 [aes.BlockSize]byte
+}
+
+// NewAESSIV creates an AES-SIV instance. The key must be 32, 48 or 64 bytes long. The first half of
+// the key is used for S2V, and the second half for encryption.
+func NewAESSIV(key []byte) (*AESSIV, error) {
+	switch len(key) {
+	case 32, 48, 64:
+	default:
+		return nil, fmt.Errorf("siv: invalid key size; want 32, 48 or 64, got %d", len(key))
+	}
+
+	mac, err := aes.NewCipher(key[:len(key)/2])
+	if err != nil {
+		return nil, err
+	}
+	ctr, err := aes.NewCipher(key[len(key)/2:])
+	if err != nil {
+		return nil, err
+	}
+
+	s := &AESSIV{mac: mac, ctr: ctr}
+	var l [aes.BlockSize]byte
+	mac.Encrypt(l[:], l[:])
+	s.macSubkey1 = dbl(l)
+	s.macSubkey2 = dbl(s.macSubkey1)
+	return s, nil
+}
+
+// Seal encrypts and authenticates the plaintext and authenticates the associated data components.
+// Returns the synthetic IV followed by the ciphertext.
+func (s *AESSIV) Seal(plaintext []byte, associatedData ...[]byte) ([]byte, error) {
+	if len(associatedData) > sivMaxAssociatedData {
+		return nil, errors.New("siv: too many associated data components")
+	}
+
+	v := s.s2v(plaintext, associatedData)
+	ciphertext := make([]byte, SIVLength+len(plaintext))
+	copy(ciphertext, v[:])
+	s.xorKeyStream(ciphertext[SIVLength:], plaintext, v)
+	return ciphertext, nil
+}
+
+// Open decrypts the ciphertext and verifies the integrity of the plaintext and associated data
+// components
+func (s *AESSIV) Open(ciphertext []byte, associatedData ...[]byte) ([]byte, error) {
+	if len(associatedData) > sivMaxAssociatedData {
+		return nil, errors.New("siv: too many associated data components")
+	}
+	if len(ciphertext) < SIVLength {
+		return nil, errors.New("siv: ciphertext too short")
+	}
+
+	var v [aes.BlockSize]byte
+	copy(v[:], ciphertext[:SIVLength])
+	plaintext := make([]byte, len(ciphertext)-SIVLength)
+	s.xorKeyStream(plaintext, ciphertext[SIVLength:], v)
+
+	t := s.s2v(plaintext, associatedData)
+	if subtle.ConstantTimeCompare(t[:], v[:]) != 1 {
+		for i := range plaintext {
+			plaintext[i] = 0
+		}
+		return nil, errors.New("siv: message authentication failed")
+	}
+	return plaintext, nil
+}
+
+// xorKeyStream applies AES-CTR keyed by the synthetic IV with the 31st and 63rd bit cleared
+func (s *AESSIV) xorKeyStream(dst, src []byte, v [aes.BlockSize]byte) {
+	v[8] &= 0x7f
+	v[12] &= 0x7f
+	cipher.NewCTR(s.ctr, v[:]).XORKeyStream(dst, src)
+}
+
+// s2v computes the synthetic IV from the associated data components and the plaintext
+func (s *AESSIV) s2v(plaintext []byte, associatedData [][]byte) [aes.BlockSize]byte {
+	var zero [aes.BlockSize]byte
+	d := s.cmac(zero[:])
+	for _, ad := range associatedData {
+		d = dbl(d)
+		mac := s.cmac(ad)
+		xorBytes(d[:], d[:], mac[:])
+	}
+
+	if len(plaintext) >= aes.BlockSize {
+		t := append([]byte(nil), plaintext...)
+		end := t[len(t)-aes.BlockSize:]
+		xorBytes(end, end, d[:])
+		return s.cmac(t)
+	}
+
+	d = dbl(d)
+	var t [aes.BlockSize]byte
+	copy(t[:], plaintext)
+	t[len(plaintext)] = 0x80
+	xorBytes(t[:], t[:], d[:])
+	return s.cmac(t[:])
+}
+
+// cmac computes the AES-CMAC of msg as specified in RFC 4493
+func (s *AESSIV) cmac(msg []byte) [aes.BlockSize]byte {
+	var x [aes.BlockSize]byte
+	for len(msg) > aes.BlockSize {
+		xorBytes(x[:], x[:], msg[:aes.BlockSize])
+		s.mac.Encrypt(x[:], x[:])
+		msg = msg[aes.BlockSize:]
+	}
+
+	var last [aes.BlockSize]byte
+	copy(last[:], msg)
+	if len(msg) == aes.BlockSize {
+		xorBytes(last[:], last[:], s.macSubkey1[:])
+	} else {
+		last[len(msg)] = 0x80
+		xorBytes(last[:], last[:], s.macSubkey2[:])
+	}
+	xorBytes(x[:], x[:], last[:])
+	s.mac.Encrypt(x[:], x[:])
+	return x
+}
+
+// dbl multiplies a block by x in GF(2^128)
+func dbl(b [aes.BlockSize]byte) [aes.BlockSize]byte {
+	var r [aes.BlockSize]byte
+	for i := 0; i < aes.BlockSize-1; i++ {
+		r[i] = b[i]<<1 | b[i+1]>>7
+	}
+	r[aes.BlockSize-1] = b[aes.BlockSize-1] << 1
+	if b[0]&0x80 != 0 {
+		r[aes.BlockSize-1] ^= 0x87
+	}
+	return r
+}
+
+// xorBytes sets dst[i] = a[i] ^ b[i] for all i < len(dst)
+func xorBytes(dst, a, b []byte) {
+	for i := range dst {
+		dst[i] = a[i] ^ b[i]
+	}
+}
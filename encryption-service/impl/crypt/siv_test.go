@@ -0,0 +1,144 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+type sivTestVector struct {
+	key            string
+	associatedData []string
+	plaintext      string
+	ciphertext     string
+}
+
+// RFC 5297 appendix A. The nonce of A.2 is the last associated data component.
+var sivTestVectors = []sivTestVector{
+	{
+		key:            "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+		associatedData: []string{"101112131415161718191a1b1c1d1e1f2021222324252627"},
+		plaintext:      "112233445566778899aabbccddee",
+		ciphertext:     "85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c",
+	},
+	{
+		key: "7f7e7d7c7b7a79787776757473727170404142434445464748494a4b4c4d4e4f",
+		associatedData: []string{
+			"00112233445566778899aabbccddeeffdeaddadadeaddadaffeeddccbbaa99887766554433221100",
+			"102030405060708090a0",
+			"09f911029d74e35bd84156c5635688c0",
+		},
+		plaintext:  "7468697320697320736f6d6520706c61696e7465787420746f20656e6372797074207573696e67205349562d414553",
+		ciphertext: "7bdb6e3b432667eb06f4d14bff2fbd0fcb900f2fddbe404326601965c889bf17dba77ceb094fa663b7a3f748ba8af829ea64ad544a272e9c485b62a3fd5c0d",
+	},
+}
+
+func decodeSIVTestVector(t *testing.T, v sivTestVector) (*AESSIV, [][]byte, []byte, []byte) {
+	key, _ := hex.DecodeString(v.key)
+	siv, err := NewAESSIV(key)
+	if err != nil {
+		t.Fatalf("NewAESSIV: %v", err)
+	}
+
+	associatedData := make([][]byte, 0, len(v.associatedData))
+	for _, ad := range v.associatedData {
+		decoded, _ := hex.DecodeString(ad)
+		associatedData = append(associatedData, decoded)
+	}
+	plaintext, _ := hex.DecodeString(v.plaintext)
+	ciphertext, _ := hex.DecodeString(v.ciphertext)
+	return siv, associatedData, plaintext, ciphertext
+}
+
+func TestAESSIVSeal(t *testing.T) {
+	for _, v := range sivTestVectors {
+		siv, associatedData, plaintext, expected := decodeSIVTestVector(t, v)
+
+		ciphertext, err := siv.Seal(plaintext, associatedData...)
+		if err != nil {
+			t.Fatalf("Seal: %v", err)
+		}
+		if !bytes.Equal(ciphertext, expected) {
+			t.Fatalf("ciphertext doesn't match:\n%x\n%x\n", expected, ciphertext)
+		}
+	}
+}
+
+func TestAESSIVOpen(t *testing.T) {
+	for _, v := range sivTestVectors {
+		siv, associatedData, expected, ciphertext := decodeSIVTestVector(t, v)
+
+		plaintext, err := siv.Open(ciphertext, associatedData...)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		if !bytes.Equal(plaintext, expected) {
+			t.Fatalf("plaintext doesn't match:\n%x\n%x\n", expected, plaintext)
+		}
+	}
+}
+
+func TestAESSIVModified(t *testing.T) {
+	siv, associatedData, _, ciphertext := decodeSIVTestVector(t, sivTestVectors[0])
+
+	for i := range ciphertext {
+		modified := append([]byte(nil), ciphertext...)
+		modified[i] ^= 1
+		if _, err := siv.Open(modified, associatedData...); err == nil {
+			t.Fatalf("modified ciphertext at byte %d accepted", i)
+		}
+	}
+
+	if _, err := siv.Open(ciphertext, []byte("wrong associated data")); err == nil {
+		t.Fatal("wrong associated data accepted")
+	}
+}
+
+func TestAESSIVDeterministic(t *testing.T) {
+	siv, err := NewAESSIV(bytes.Repeat([]byte{0x42}, 64))
+	if err != nil {
+		t.Fatalf("NewAESSIV: %v", err)
+	}
+
+	// Cover plaintexts shorter than, equal to and longer than the block size
+	for _, plaintext := range [][]byte{nil, []byte("short"), bytes.Repeat([]byte{1}, 16), bytes.Repeat([]byte{2}, 33)} {
+		first, err := siv.Seal(plaintext, []byte("aad"))
+		if err != nil {
+			t.Fatalf("Seal: %v", err)
+		}
+		second, err := siv.Seal(plaintext, []byte("aad"))
+		if err != nil {
+			t.Fatalf("Seal: %v", err)
+		}
+		if !bytes.Equal(first, second) {
+			t.Fatal("equal plaintexts encrypted to different ciphertexts")
+		}
+
+		decrypted, err := siv.Open(first, []byte("aad"))
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Fatal("decrypted plaintext doesn't match")
+		}
+	}
+}
+
+func TestAESSIVInvalidKeySize(t *testing.T) {
+	if _, err := NewAESSIV(make([]byte, 16)); err == nil {
+		t.Fatal("NewAESSIV accepted invalid key size")
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+// XChaChaCrypter encrypts data with XChaCha20-Poly1305. Its 192 bit random nonces make it safe to
+// encrypt practically unlimited numbers of messages under the same key, and it is fast on CPUs
+// without AES instructions.
+type XChaChaCrypter struct {
+}
+
+// Encrypt encrypts a plaintext with additional associated data (aad) using the provided key returning the resulting ciphertext.
+// The ciphertext is an envelope with the body nonce || ciphertext || tag. The envelope header is authenticated along with the aad.
+func (c *XChaChaCrypter) Encrypt(plaintext, aad, key []byte) ([]byte, error) {
+	return sealEnvelope(AlgorithmXChaCha20Poly1305, plaintext, aad, key)
+}
+
+// Decrypt decrypts a ciphertext with additional associated data (aad) using the provided key returning the resulting plaintext.
+// The algorithm is taken from the envelope header, so ciphertexts produced by AESCrypter are also accepted.
+func (c *XChaChaCrypter) Decrypt(ciphertext, aad, key []byte) ([]byte, error) {
+	return openEnvelope(ciphertext, aad, key)
+}
@@ -0,0 +1,108 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package crypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// Test vector from draft-irtf-cfrg-xchacha-03, appendix A.3.1
+func TestXChaCha20Poly1305KAT(t *testing.T) {
+	key, _ := hex.DecodeString("808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f")
+	nonce, _ := hex.DecodeString("404142434445464748494a4b4c4d4e4f5051525354555657")
+	aad, _ := hex.DecodeString("50515253c0c1c2c3c4c5c6c7")
+	plaintext := []byte("Ladies and Gentlemen of the class of '99: If I could offer you only one tip for the future, sunscreen would be it.")
+	expected := "bd6d179d3e83d43b9576579493c0e939572a1700252bfaccbed2902c21396cbb731c7f1b0b4aa6440bf3a82f4eda7e39ae64c6708c54c216cb96b72e1213b4522f8c9ba40db5d945b11b69b982c1bb9e3f3fac2bc369488f76b2383565d3fff921f9664c97637da9768812f615c68b13b52e" + "c0875924c1c7987947deafd8780acf49"
+
+	aead, err := newAEAD(AlgorithmXChaCha20Poly1305, key)
+	if err != nil {
+		t.Fatalf("newAEAD: %v", err)
+	}
+
+	ciphertext := aead.Seal(nil, nonce, plaintext, aad)
+	if expected != hex.EncodeToString(ciphertext) {
+		t.Fatalf("ciphertext doesn't match:\n%s\n%x\n", expected, ciphertext)
+	}
+}
+
+func TestXChaChaEncryptDecrypt(t *testing.T) {
+	crypter := &XChaChaCrypter{}
+
+	for sz := uint32(0); sz < 256; sz++ {
+		plaintext := GetRandomBytes(sz)
+		ciphertext, err := crypter.Encrypt(plaintext, aad, oek)
+		if err != nil {
+			t.Fatalf("Encrypt: %v", err)
+		}
+
+		header, body, ok := parseEnvelope(ciphertext)
+		if !ok || header.algorithm != AlgorithmXChaCha20Poly1305 || len(body) != len(plaintext)+24+16 {
+			t.Fatalf("Wrong envelope: %x", ciphertext)
+		}
+
+		gotPlaintext, err := crypter.Decrypt(ciphertext, aad, oek)
+		if err != nil {
+			t.Fatalf("Decrypt: %v", err)
+		}
+		if !bytes.Equal(plaintext, gotPlaintext) {
+			t.Fatalf("plaintext doesn't match:\n%x\n%x\n", plaintext, gotPlaintext)
+		}
+	}
+}
+
+func TestXChaChaWrongHeader(t *testing.T) {
+	crypter := &XChaChaCrypter{}
+	ciphertext, err := crypter.Encrypt(plaintext, aad, oek)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	// Claiming that the ciphertext is AES-GCM must not work
+	ciphertext[2] = byte(AlgorithmAES256GCM)
+	if _, err := crypter.Decrypt(ciphertext, aad, oek); err == nil {
+		t.Fatal("Decryption of modified header should have failed")
+	}
+}
+
+func TestCrypterInterop(t *testing.T) {
+	aesCrypter := &AESCrypter{}
+	xchachaCrypter := &XChaChaCrypter{}
+
+	// Both crypters decrypt each others ciphertexts, including legacy ciphertexts
+	legacyCiphertext, _ := hex.DecodeString(legacyCiphertextHex)
+	gotPlaintext, err := xchachaCrypter.Decrypt(legacyCiphertext, aad, oek)
+	if err != nil || !bytes.Equal(plaintext, gotPlaintext) {
+		t.Fatalf("Decrypt of legacy ciphertext failed: %v", err)
+	}
+
+	ciphertext, err := aesCrypter.Encrypt(plaintext, aad, oek)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	gotPlaintext, err = xchachaCrypter.Decrypt(ciphertext, aad, oek)
+	if err != nil || !bytes.Equal(plaintext, gotPlaintext) {
+		t.Fatalf("Decrypt of AES ciphertext failed: %v", err)
+	}
+
+	ciphertext, err = xchachaCrypter.Encrypt(plaintext, aad, oek)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	gotPlaintext, err = aesCrypter.Decrypt(ciphertext, aad, oek)
+	if err != nil || !bytes.Equal(plaintext, gotPlaintext) {
+		t.Fatalf("Decrypt of XChaCha ciphertext failed: %v", err)
+	}
+}
@@ -23,8 +23,10 @@ import (
 	"os"
 
 	"encryption-service/common"
+	"encryption-service/interfaces"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -97,6 +99,7 @@ func (o *ObjectStore) Store(ctx context.Context, objectID string, object []byte)
 }
 
 // Retrieve an object with a given object ID
+// Errors with interfaces.ErrNotFound if the object doesn't exist
 func (o *ObjectStore) Retrieve(ctx context.Context, objectID string) ([]byte, error) {
 	requestID, ok := ctx.Value(common.RequestIDCtxKey).(uuid.UUID)
 	if !ok {
@@ -107,6 +110,10 @@ func (o *ObjectStore) Retrieve(ctx context.Context, objectID string) ([]byte, er
 		Bucket: &o.bucket,
 		Key:    &objectID,
 	}, request.WithSetRequestHeaders(map[string]string{"Request-ID": requestID.String()}))
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, interfaces.ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -16,6 +16,7 @@ package interfaces
 
 import (
 	"context"
+	"crypto"
 	"errors"
 
 	"github.com/gofrs/uuid"
@@ -24,6 +25,7 @@ import (
 )
 
 var ErrNotFound = errors.New("not found")
+var ErrAlreadyExists = errors.New("already exists")
 
 // Interface representing a connection to the Auth Store
 type AuthStoreInterface interface {
@@ -54,19 +56,28 @@ type AuthStoreTxInterface interface {
 	// Get user's confidential data
 	GetUserData(ctx context.Context, userID uuid.UUID) (protected *common.ProtectedUserData, err error)
 
+	// List up to `limit` IDs of users that are not removed and greater than `after` in ascending order
+	ListUserIDs(ctx context.Context, after uuid.UUID, limit int) (userIDs []uuid.UUID, err error)
+
 	// GroupExists checks if a group exists in the auth store
 	GroupExists(ctx context.Context, groupID uuid.UUID) (res bool, err error)
 
 	// Insert a group
 	InsertGroup(ctx context.Context, groupData *common.ProtectedGroupData) (err error)
 
+	// Update an existing group
+	UpdateGroup(ctx context.Context, groupData *common.ProtectedGroupData) (err error)
+
+	// List up to `limit` group IDs greater than `after` in ascending order
+	ListGroupIDs(ctx context.Context, after uuid.UUID, limit int) (groupIDs []uuid.UUID, err error)
+
 	// Get one or more groups' confidential data
 	GetGroupDataBatch(ctx context.Context, groupIDs []uuid.UUID) (groupDataBatch []common.ProtectedGroupData, err error)
 
 	//  Retrieve an existing access object
 	GetAccessObject(ctx context.Context, objectID uuid.UUID) (protected *common.ProtectedAccessObject, err error)
 
-	// Insert a new access object
+	// Insert a new access object. Returns ErrAlreadyExists if an access object with the same ID exists.
 	InsertAcccessObject(ctx context.Context, protected *common.ProtectedAccessObject) (err error)
 
 	// Update an existing access object
@@ -74,6 +85,31 @@ type AuthStoreTxInterface interface {
 
 	// Delete an existing access object
 	DeleteAccessObject(ctx context.Context, objectID uuid.UUID) (err error)
+
+	// List up to `limit` access object IDs greater than `after` in ascending order
+	ListAccessObjectIDs(ctx context.Context, after uuid.UUID, limit int) (objectIDs []uuid.UUID, err error)
+
+	// Retrieve the erasure receipt of a shredded object
+	GetErasureReceipt(ctx context.Context, objectID uuid.UUID) (protected *common.ProtectedErasureReceipt, err error)
+
+	// Insert a new erasure receipt
+	InsertErasureReceipt(ctx context.Context, protected *common.ProtectedErasureReceipt) (err error)
+
+	// Update an existing erasure receipt
+	UpdateErasureReceipt(ctx context.Context, protected *common.ProtectedErasureReceipt) (err error)
+
+	// List up to `limit` IDs of shredded objects greater than `after` in ascending order
+	ListErasureReceiptIDs(ctx context.Context, after uuid.UUID, limit int) (objectIDs []uuid.UUID, err error)
+
+	// Retrieve a token of a tokenization namespace
+	GetToken(ctx context.Context, namespace uuid.UUID, token string) (protected *common.ProtectedToken, err error)
+
+	// Retrieve the token of a tokenization namespace with the given value tag
+	GetTokenByValueTag(ctx context.Context, namespace uuid.UUID, valueTag []byte) (protected *common.ProtectedToken, err error)
+
+	// Insert a new token. Returns ErrAlreadyExists if the token or its value tag is already used in
+	// the namespace.
+	InsertToken(ctx context.Context, protected *common.ProtectedToken) (err error)
 }
 
 // Interface representing a connection to the object store
@@ -96,14 +132,23 @@ type CryptorInterface interface {
 	// EncryptWithKey encrypts data + aad with a wrapped key and returns the ciphertext
 	EncryptWithKey(data, aad, key []byte) (ciphertext []byte, err error)
 
-	// EncodeAndEncrypt serializes the data, but otherwise behaves like `Encrypt`
-	EncodeAndEncrypt(data interface{}, aad []byte) (wrappedKey, ciphertext []byte, err error)
+	// EncodeAndEncrypt serializes the record, but otherwise behaves like `Encrypt`
+	EncodeAndEncrypt(data RecordInterface, aad []byte) (wrappedKey, ciphertext []byte, err error)
 
 	// Decrypt decrypts a ciphertext + aad with a wrapped key
 	Decrypt(wrappedKey, ciphertext, aad []byte) (plaintext []byte, err error)
 
 	// DecodeAndDecrypt behaves like `Decrypt` by deserializes the result into `data`
-	DecodeAndDecrypt(data interface{}, wrappedKey, ciphertext, aad []byte) (err error)
+	DecodeAndDecrypt(data RecordInterface, wrappedKey, ciphertext, aad []byte) (err error)
+}
+
+// RecordInterface is implemented by records that are serialized with a versioned protobuf schema
+type RecordInterface interface {
+	// MarshalRecord serializes the record
+	MarshalRecord() (data []byte, err error)
+
+	// UnmarshalRecord deserializes the record
+	UnmarshalRecord(data []byte) (err error)
 }
 
 // KeyWrapperInterface offers an API to wrap / unwrap key material
@@ -115,6 +160,13 @@ type KeyWrapperInterface interface {
 	Unwrap(data []byte) ([]byte, error)
 }
 
+// KeyRewrapperInterface offers an API to move wrapped key material to the current wrapping key
+type KeyRewrapperInterface interface {
+	// Rewrap re-wraps a wrapped key under the current wrapping key. `changed` is false if the key
+	// was already wrapped under the current wrapping key.
+	Rewrap(data []byte) (wrapped []byte, changed bool, err error)
+}
+
 // Interface for authenticating and creating users and groups
 type UserAuthenticatorInterface interface {
 	// Create a new user
@@ -147,17 +199,37 @@ type UserAuthenticatorInterface interface {
 
 // Interface for authenticating and creating Access Objects
 type AccessObjectAuthenticatorInterface interface {
-	// Creates a new Access Object and inserts it into the Authstorage
-	CreateAccessObject(ctx context.Context, objectID, groupID uuid.UUID, woek []byte) (err error)
+	// Creates a new Access Object holding a key of the given type and inserts it into the Authstorage
+	CreateAccessObject(ctx context.Context, objectID, groupID uuid.UUID, keyType common.KeyType, woek []byte) (err error)
+
+	// Creates a new Access Object for data encrypted under an imported key and inserts it into the Authstorage
+	CreateAccessObjectWithKeyID(ctx context.Context, objectID, groupID, keyID uuid.UUID) (err error)
 
 	// Fetches an existing Access Object
 	FetchAccessObject(ctx context.Context, objectID uuid.UUID) (accessObject *common.AccessObject, err error)
 
+	// Fetches the wrapped key the data of an object is encrypted under. Returns
+	// common.ErrImportedKeyDeleted if the object is encrypted under an imported key that no longer exists
+	FetchObjectKey(ctx context.Context, accessObject *common.AccessObject) (woek []byte, err error)
+
 	// Updates the AccessObject into the Authstorage
 	UpdateAccessObject(ctx context.Context, objectID uuid.UUID, accessObject common.AccessObject) (err error)
 
 	// Deletes an existing Access Object
 	DeleteAccessObject(ctx context.Context, objectID uuid.UUID) (err error)
+
+	// Destroys an existing Access Object and records an erasure receipt
+	ShredAccessObject(ctx context.Context, objectID uuid.UUID) (receipt *common.ErasureReceipt, err error)
+
+	// Fetches the erasure receipt of a shredded object
+	FetchErasureReceipt(ctx context.Context, objectID uuid.UUID) (receipt *common.ErasureReceipt, err error)
+}
+
+// Interface for re-encrypting stored objects
+type ObjectRekeyerInterface interface {
+	// Re-encrypts the object under a new random key and stores the Access Object with the new key.
+	// Commits the auth storage transaction found in the context.
+	RekeyObject(ctx context.Context, objectID uuid.UUID, accessObject common.AccessObject) (err error)
 }
 
 // Interface for authentication of data
@@ -169,6 +241,18 @@ type MessageAuthenticatorInterface interface {
 	Verify(msg, msgTag []byte) (res bool, err error)
 }
 
+// Interface for digital signatures
+type SignerInterface interface {
+	// Create a signature of the given message
+	Sign(msg []byte) (signature []byte, err error)
+
+	// Check whether a signature matches the given message
+	Verify(msg, signature []byte) (res bool, err error)
+
+	// Get the public key used to verify signatures
+	PublicKey() (publicKey crypto.PublicKey)
+}
+
 // Interface representing an access token
 type AccessTokenInterface interface {
 	// Get the user ID contained in the token
@@ -15,30 +15,62 @@ package main
 
 import (
 	"context"
+	"errors"
+	"io"
 
 	"encryption-service/buildtags"
 	"encryption-service/config"
 	authnimpl "encryption-service/impl/authn"
 	authzimpl "encryption-service/impl/authz"
 	"encryption-service/impl/crypt"
+	"encryption-service/interfaces"
 	log "encryption-service/logger"
 	"encryption-service/services/app"
 	"encryption-service/services/authn"
 	"encryption-service/services/authz"
 	"encryption-service/services/enc"
+	"encryption-service/services/fpe"
+	"encryption-service/services/mac"
+	"encryption-service/services/sign"
 	"encryption-service/services/storage"
+	"encryption-service/services/vault"
 )
 
 func main() {
 	ctx := context.TODO()
 	log.Info(ctx, "Encryption Server started")
 
+	selfTestResults := crypt.RunSelfTests()
+	for _, result := range selfTestResults {
+		if result.Err != nil {
+			log.Errorf(ctx, result.Err, "Self-test %s failed", result.Name)
+		}
+	}
+	if !crypt.SelfTestsPassed(selfTestResults) {
+		log.Fatal(ctx, errors.New("self-tests failed"), "Refusing to start")
+	}
+	log.Info(ctx, "Self-tests passed")
+
+	if app.ExecuteKeyFileCommand() {
+		return
+	}
+
 	config, err := config.ParseConfig()
 	if err != nil {
 		log.Fatal(ctx, err, "Config parse failed")
 	}
 	log.Info(ctx, "Config parsed")
 
+	if config.Keys.SealedKeyFile != "" {
+		keys := app.UnsealKeys(config.Keys.SealedKeyFile)
+		if keys == nil {
+			log.Info(ctx, "Shutting down")
+			return
+		}
+		keys.CheckInsecure()
+		config.Keys = *keys
+	}
+
 	// Setup authentication storage DB Pool connection
 	authStore, err := buildtags.SetupAuthStore(context.Background(), config.AuthStorage)
 	if err != nil {
@@ -46,25 +78,29 @@ func main() {
 	}
 	defer authStore.Close()
 
-	accessObjectCryptor, err := crypt.NewAESCryptor(config.Keys.AEK)
+	accessObjectKeyRing, err := crypt.NewKWPKeyRing(config.Keys.AEKID, config.Keys.AEKs())
 	if err != nil {
-		log.Fatal(ctx, err, "NewAESCryptor (access object) failed")
+		log.Fatal(ctx, err, "NewKWPKeyRing (access object) failed")
 	}
+	accessObjectCryptor := newCryptor(config.Crypto.Cipher, accessObjectKeyRing)
 
-	tokenCryptor, err := crypt.NewAESCryptor(config.Keys.TEK)
+	tokenKeyRing, err := crypt.NewKWPKeyRing(config.Keys.TEKID, config.Keys.TEKs())
 	if err != nil {
-		log.Fatal(ctx, err, "NewAESCryptor (token) failed")
+		log.Fatal(ctx, err, "NewKWPKeyRing (token) failed")
 	}
+	tokenCryptor := newCryptor(config.Crypto.Cipher, tokenKeyRing)
 
-	userCryptor, err := crypt.NewAESCryptor(config.Keys.UEK)
+	userKeyRing, err := crypt.NewKWPKeyRing(config.Keys.UEKID, config.Keys.UEKs())
 	if err != nil {
-		log.Fatal(ctx, err, "NewAESCryptor (user) failed")
+		log.Fatal(ctx, err, "NewKWPKeyRing (user) failed")
 	}
+	userCryptor := newCryptor(config.Crypto.Cipher, userKeyRing)
 
-	groupCryptor, err := crypt.NewAESCryptor(config.Keys.GEK)
+	groupKeyRing, err := crypt.NewKWPKeyRing(config.Keys.GEKID, config.Keys.GEKs())
 	if err != nil {
-		log.Fatal(ctx, err, "NewAESCryptor (user) failed")
+		log.Fatal(ctx, err, "NewKWPKeyRing (group) failed")
 	}
+	groupCryptor := newCryptor(config.Crypto.Cipher, groupKeyRing)
 
 	userAuthenticator := &authnimpl.UserAuthenticator{
 		TokenCryptor: tokenCryptor,
@@ -72,15 +108,34 @@ func main() {
 		GroupCryptor: groupCryptor,
 	}
 
-	dataCryptor, err := crypt.NewAESCryptor(config.Keys.KEK)
+	kekWrapper, err := buildtags.SetupKEKWrapper(ctx, config.Keys)
 	if err != nil {
-		log.Fatal(ctx, err, "NewAESCryptor (data) failed")
+		log.Fatal(ctx, err, "KEK wrapper setup failed")
+	}
+	if closer, ok := kekWrapper.(io.Closer); ok {
+		defer closer.Close()
 	}
 
-	authorizer := &authzimpl.Authorizer{AccessObjectCryptor: accessObjectCryptor}
+	dataKeyRing, err := crypt.NewKeyRingWithRetiredKeys(config.Keys.KEKID, kekWrapper, config.Keys.RetiredKEKs)
+	if err != nil {
+		log.Fatal(ctx, err, "NewKeyRing (data) failed")
+	}
+	dataCryptor := newCryptor(config.Crypto.Cipher, dataKeyRing)
+
+	receiptSigner, err := crypt.NewEd25519Signer(config.Keys.ReceiptSigningKey)
+	if err != nil {
+		log.Fatal(ctx, err, "NewEd25519Signer (erasure receipt) failed")
+	}
+
+	authorizer := &authzimpl.Authorizer{
+		AccessObjectCryptor: accessObjectCryptor,
+		ReceiptSigner:       receiptSigner,
+		ReceiptSigningKeyID: config.Keys.ReceiptSigningKeyID,
+	}
 
 	var storageService storage.EncryptonizeServer
 	var encService enc.EncryptonizeServer
+	var objectRekeyer interfaces.ObjectRekeyerInterface
 
 	if config.Features.StorageService {
 		objectStore, err := buildtags.SetupObjectStore("objects", config.ObjectStorage)
@@ -88,12 +143,15 @@ func main() {
 			log.Fatal(ctx, err, "Objectstorage connect failed")
 		}
 
-		storageService = &storage.Storage{
-			Authorizer:  authorizer,
-			AuthStore:   authStore,
-			ObjectStore: objectStore,
-			DataCryptor: dataCryptor,
+		strg := &storage.Storage{
+			Authorizer:           authorizer,
+			AuthStore:            authStore,
+			ObjectStore:          objectStore,
+			DataCryptor:          dataCryptor,
+			MaxEncryptionsPerKey: config.Crypto.MaxEncryptionsPerKey,
 		}
+		storageService = strg
+		objectRekeyer = strg
 		log.Info(ctx, "Storage service is enabled")
 	} else {
 		storageService = &storage.Disabled{}
@@ -102,9 +160,12 @@ func main() {
 
 	if config.Features.EncryptionService {
 		encService = &enc.Enc{
-			Authorizer:  authorizer,
-			AuthStore:   authStore,
-			DataCryptor: dataCryptor,
+			Authorizer:           authorizer,
+			AuthStore:            authStore,
+			DataCryptor:          dataCryptor,
+			KeyWrapper:           dataKeyRing,
+			MaxEncryptionsPerKey: config.Crypto.MaxEncryptionsPerKey,
+			UserAuthenticator:    userAuthenticator,
 		}
 		log.Info(ctx, "Encryption service is enabled")
 	} else {
@@ -112,22 +173,67 @@ func main() {
 		log.Info(ctx, "Encryption service is disabled")
 	}
 
+	macService := &mac.MAC{
+		Authorizer: authorizer,
+		AuthStore:  authStore,
+		KeyWrapper: dataKeyRing,
+	}
+
+	signingService := &sign.Signing{
+		Authorizer: authorizer,
+		AuthStore:  authStore,
+		KeyWrapper: dataKeyRing,
+	}
+
+	fpeService := &fpe.FPE{
+		Authorizer: authorizer,
+		AuthStore:  authStore,
+		KeyWrapper: dataKeyRing,
+	}
+
+	vaultService := &vault.Vault{
+		Authorizer: authorizer,
+		AuthStore:  authStore,
+		KeyWrapper: dataKeyRing,
+	}
+
 	authnService := &authn.Authn{
 		AuthStore:         authStore,
 		UserAuthenticator: userAuthenticator,
 	}
 
 	authzService := &authz.Authz{
-		Authorizer:        authorizer,
-		UserAuthenticator: userAuthenticator,
+		Authorizer:               authorizer,
+		UserAuthenticator:        userAuthenticator,
+		AuthStore:                authStore,
+		DataKeyRewrapper:         dataKeyRing,
+		AccessObjectKeyRewrapper: accessObjectKeyRing,
+		UserKeyRewrapper:         userKeyRing,
+		GroupKeyRewrapper:        groupKeyRing,
+		ObjectRekeyer:            objectRekeyer,
+		ReceiptSigner:            receiptSigner,
+		ReceiptSigningKeyID:      config.Keys.ReceiptSigningKeyID,
 	}
 
 	app := &app.App{
 		StorageService:    storageService,
 		EncryptionService: encService,
+		MACService:        macService,
+		SigningService:    signingService,
+		FPEService:        fpeService,
+		VaultService:      vaultService,
 		AuthnService:      authnService,
 		AuthzService:      authzService,
+		SelfTestResults:   selfTestResults,
 	}
 
 	app.StartServer()
 }
+
+// newCryptor creates a cryptor using the configured cipher
+func newCryptor(cipher string, keyWrap interfaces.KeyWrapperInterface) interfaces.CryptorInterface {
+	if cipher == config.CipherXChaCha20Poly1305 {
+		return crypt.NewXChaChaCryptorWithKeyWrap(keyWrap)
+	}
+	return crypt.NewAESCryptorWithKeyWrap(keyWrap)
+}
@@ -19,13 +19,17 @@ set -euo pipefail
 source ./scripts/dev-env
 
 USER_INFO=""
+KEY_INFO=""
 
 if [[ "${1}" == "local" ]]; then
   USER_INFO=$(./encryption-service create-user m 2> /dev/null)
+  KEY_INFO=$(./encryption-service receipt-signing-key 2> /dev/null)
 elif [[ "${1}" == "docker" ]]; then
   USER_INFO=$(docker exec encryption-service /encryption-service create-user m 2> /dev/null)
+  KEY_INFO=$(docker exec encryption-service /encryption-service receipt-signing-key 2> /dev/null)
 elif [[ "${1}" == "kubernetes" ]]; then
   USER_INFO=$(kubectl -n encryptonize exec -it deployment/encryptonize -- /encryption-service create-user m | tail -n 1)
+  KEY_INFO=$(kubectl -n encryptonize exec -it deployment/encryptonize -- /encryption-service receipt-signing-key | tail -n 1)
 else
   echo "Unknown argument '${1}'"
   exit 1
@@ -33,3 +37,4 @@ fi
 
 echo "export E2E_TEST_UID=$(echo $USER_INFO | jq -r ".user_id")"
 echo "export E2E_TEST_PASS=$(echo $USER_INFO | jq -r ".password")"
+echo "export E2E_TEST_RECEIPT_PUBLIC_KEY=$(echo $KEY_INFO | jq -r ".publicKey")"
@@ -0,0 +1,39 @@
+#!/bin/bash
+
+# Copyright 2021 CYBERCRYPT
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Run the PKCS#11 unit tests against a temporary SoftHSM token. Requires SoftHSM v2. Usage:
+#   ./scripts/pkcs11_tests.sh [path to PKCS#11 module]
+
+set -euo pipefail
+
+source ./scripts/build-env
+
+export ECTNZ_PKCS11_TEST_MODULE=${1-/usr/lib/softhsm/libsofthsm2.so}
+export ECTNZ_PKCS11_TEST_TOKENLABEL="encryptonize-test"
+export ECTNZ_PKCS11_TEST_PIN="1234"
+
+TOKEN_DIR=$(mktemp -d)
+trap 'rm -rf "${TOKEN_DIR}"' EXIT
+
+export SOFTHSM2_CONF="${TOKEN_DIR}/softhsm2.conf"
+echo "directories.tokendir = ${TOKEN_DIR}" > "${SOFTHSM2_CONF}"
+
+echo '[*] initializing SoftHSM token'
+softhsm2-util --init-token --free --label "${ECTNZ_PKCS11_TEST_TOKENLABEL}" \
+  --so-pin "${ECTNZ_PKCS11_TEST_PIN}" --pin "${ECTNZ_PKCS11_TEST_PIN}"
+
+echo '[*] running PKCS#11 unit tests'
+go test -count=1 -tags pkcs11 -run PKCS11 ./impl/crypt/
@@ -36,30 +36,37 @@ import (
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 
+	"encryption-service/impl/crypt"
 	log "encryption-service/logger"
 	"encryption-service/services/authn"
 	"encryption-service/services/authz"
 	"encryption-service/services/enc"
+	"encryption-service/services/fpe"
 	"encryption-service/services/health"
+	"encryption-service/services/mac"
+	"encryption-service/services/sign"
 	"encryption-service/services/storage"
+	"encryption-service/services/vault"
 )
 
+// The port of the gRPC API
+const port = 9000
+
 type App struct {
 	StorageService    storage.EncryptonizeServer
 	EncryptionService enc.EncryptonizeServer
+	MACService        *mac.MAC
+	SigningService    *sign.Signing
+	FPEService        *fpe.FPE
+	VaultService      *vault.Vault
 	AuthnService      *authn.Authn
 	AuthzService      *authz.Authz
+	SelfTestResults   []crypt.SelfTestResult
 	UnimplementedEncryptonizeServer
 }
 
-func (app *App) initgRPC(port int) (*grpc.Server, net.Listener) {
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
-	if err != nil {
-		msg := fmt.Sprintf("Failed to listen on port: %s", fmt.Sprint(port))
-		log.Fatal(context.TODO(), err, msg)
-	}
-
-	// make gprc_recovery log panics and return generic errors to the caller
+// recoveryInterceptor makes grpc_recovery log panics and return generic errors to the caller
+func recoveryInterceptor() grpc.UnaryServerInterceptor {
 	recoveryOpt := grpc_recovery.WithRecoveryHandlerContext(
 		func(ctx context.Context, p interface{}) error {
 			stack := make([]byte, 4096)
@@ -69,9 +76,23 @@ func (app *App) initgRPC(port int) (*grpc.Server, net.Listener) {
 			return status.Errorf(codes.Internal, "internal error")
 		},
 	)
+	return grpc_recovery.UnaryServerInterceptor(recoveryOpt)
+}
+
+func listen(port int) net.Listener {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		msg := fmt.Sprintf("Failed to listen on port: %s", fmt.Sprint(port))
+		log.Fatal(context.TODO(), err, msg)
+	}
+	return lis
+}
+
+func (app *App) initgRPC(port int) (*grpc.Server, net.Listener) {
+	lis := listen(port)
 
 	unaryInterceptors := []grpc.UnaryServerInterceptor{
-		grpc_recovery.UnaryServerInterceptor(recoveryOpt),
+		recoveryInterceptor(),
 		log.UnaryRequestIDInterceptor(),
 		log.UnaryMethodNameInterceptor(),
 		log.UnaryObjectIDInterceptor(),
@@ -92,12 +113,16 @@ func (app *App) initgRPC(port int) (*grpc.Server, net.Listener) {
 
 	storage.RegisterEncryptonizeServer(grpcServer, app.StorageService)
 	enc.RegisterEncryptonizeServer(grpcServer, app.EncryptionService)
+	mac.RegisterEncryptonizeServer(grpcServer, app.MACService)
+	sign.RegisterEncryptonizeServer(grpcServer, app.SigningService)
+	fpe.RegisterEncryptonizeServer(grpcServer, app.FPEService)
+	vault.RegisterEncryptonizeServer(grpcServer, app.VaultService)
 	authn.RegisterEncryptonizeServer(grpcServer, app.AuthnService)
 	authz.RegisterEncryptonizeServer(grpcServer, app.AuthzService)
 	RegisterEncryptonizeServer(grpcServer, app)
 
 	// Register health checker to grpc server
-	healthService := health.NewHealthChecker()
+	healthService := health.NewHealthCheckerWithSelfTests(app.SelfTestResults)
 	grpc_health_v1.RegisterHealthServer(grpcServer, healthService)
 
 	// Register grpc reflection handler
@@ -106,11 +131,16 @@ func (app *App) initgRPC(port int) (*grpc.Server, net.Listener) {
 	return grpcServer, lis
 }
 
+// cliMode reports whether the service was started with a cli command
+func cliMode() bool {
+	return len(os.Args) > 1 && filepath.Base(os.Args[0]) != "encryption-service.test"
+}
+
 func (app *App) StartServer() {
 	ctx := context.TODO()
 
 	// execute cli commands
-	if len(os.Args) > 1 && filepath.Base(os.Args[0]) != "encryption-service.test" {
+	if cliMode() {
 		log.Info(ctx, "Running in cli mode")
 
 		cmd := os.Args[1]
@@ -124,6 +154,47 @@ func (app *App) StartServer() {
 			if err := app.AuthnService.CreateCLIUser(os.Args[2]); err != nil {
 				log.Fatal(ctx, err, "CreateUserCommand")
 			}
+		case "rotate-kek":
+			if err := app.AuthzService.RotateKEKCLI(); err != nil {
+				log.Fatal(ctx, err, "RotateKEKCommand")
+			}
+		case "rotate-auth-keys":
+			if err := app.AuthzService.RotateAuthKeysCLI(); err != nil {
+				log.Fatal(ctx, err, "RotateAuthKeysCommand")
+			}
+		case "shred-object":
+			if len(os.Args) != 3 {
+				log.Fatal(ctx, errors.New("Object ID argument missing"), "ShredObjectCommand")
+			}
+			if err := app.AuthzService.ShredObjectCLI(os.Args[2]); err != nil {
+				log.Fatal(ctx, err, "ShredObjectCommand")
+			}
+		case "shred-group":
+			if len(os.Args) != 3 {
+				log.Fatal(ctx, errors.New("Group ID argument missing"), "ShredGroupCommand")
+			}
+			if err := app.AuthzService.ShredGroupCLI(os.Args[2]); err != nil {
+				log.Fatal(ctx, err, "ShredGroupCommand")
+			}
+		case "get-erasure-receipt":
+			if len(os.Args) != 3 {
+				log.Fatal(ctx, errors.New("Object ID argument missing"), "GetErasureReceiptCommand")
+			}
+			if err := app.AuthzService.GetErasureReceiptCLI(os.Args[2]); err != nil {
+				log.Fatal(ctx, err, "GetErasureReceiptCommand")
+			}
+		case "receipt-signing-key":
+			if err := app.AuthzService.ReceiptSigningKeyCLI(); err != nil {
+				log.Fatal(ctx, err, "ReceiptSigningKeyCommand")
+			}
+		case "migrate-objects":
+			storageService, ok := app.StorageService.(*storage.Storage)
+			if !ok {
+				log.Fatal(ctx, errors.New("storage service is disabled"), "MigrateObjectsCommand")
+			}
+			if err := storageService.MigrateObjectsCLI(); err != nil {
+				log.Fatal(ctx, err, "MigrateObjectsCommand")
+			}
 		default:
 			msg := fmt.Sprintf("Invalid command: %v", cmd)
 			log.Fatal(ctx, errors.New(""), msg)
@@ -133,7 +204,6 @@ func (app *App) StartServer() {
 	}
 
 	// Setup gRPC listener
-	var port = 9000
 	grpcServer, lis := app.initgRPC(port)
 
 	go func() {
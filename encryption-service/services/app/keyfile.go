@@ -0,0 +1,146 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"encryption-service/config"
+	log "encryption-service/logger"
+)
+
+// keyFileInfo describes the contents of a key file without revealing any key material
+type keyFileInfo struct {
+	Version int                  `json:"version"`
+	KDF     keyFileKDFInfo       `json:"kdf"`
+	Keys    map[string]keyIDInfo `json:"keys"`
+}
+
+type keyFileKDFInfo struct {
+	Algorithm string `json:"algorithm"`
+	Time      uint32 `json:"time"`
+	Memory    uint32 `json:"memory"`
+	Threads   uint8  `json:"threads"`
+}
+
+type keyIDInfo struct {
+	ID      uint32   `json:"id"`
+	Retired []uint32 `json:"retired,omitempty"`
+}
+
+// keyFileCLI creates, inspects or re-encrypts a key file protected by a passphrase. The passphrase
+// is read from the configured passphrase file or file descriptor.
+func keyFileCLI(cmd, path string, args []string) error {
+	// Load the configuration without parsing, the keys are stored as hex strings
+	cfg := config.Config{}
+	if err := config.LoadConfig(&cfg); err != nil {
+		return err
+	}
+
+	passphrase, err := cfg.Keys.ReadPassphrase()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case cmd == "create" && len(args) == 0:
+		keys := cfg.Keys
+		keys.KeyFile, keys.PassphraseFile, keys.PassphraseFD = "", "", 0
+		keyFile, err := config.EncryptKeys(keys, passphrase)
+		if err != nil {
+			return err
+		}
+		if err := keyFile.Write(path); err != nil {
+			return err
+		}
+		log.Infof(context.TODO(), "Keys written to %v", path)
+
+	case cmd == "inspect" && len(args) == 0:
+		keyFile, err := config.ReadKeyFile(path)
+		if err != nil {
+			return err
+		}
+		keys, err := keyFile.OpenWithPassphrase(passphrase)
+		if err != nil {
+			return err
+		}
+
+		info, err := json.MarshalIndent(newKeyFileInfo(keyFile, keys), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(info))
+
+	case cmd == "reencrypt" && len(args) == 1:
+		newPassphrase, err := (&config.Keys{PassphraseFile: args[0]}).ReadPassphrase()
+		if err != nil {
+			return err
+		}
+
+		keyFile, err := config.ReadKeyFile(path)
+		if err != nil {
+			return err
+		}
+		keyFile, err = keyFile.ChangePassphrase(passphrase, newPassphrase)
+		if err != nil {
+			return err
+		}
+		if err := keyFile.Replace(path); err != nil {
+			return err
+		}
+		log.Infof(context.TODO(), "Keys in %v re-encrypted", path)
+
+	default:
+		return fmt.Errorf("invalid keyfile command: %v", cmd)
+	}
+
+	return nil
+}
+
+func newKeyFileInfo(keyFile *config.KeyFile, keys *config.Keys) *keyFileInfo {
+	info := &keyFileInfo{
+		Version: keyFile.Version,
+		KDF: keyFileKDFInfo{
+			Algorithm: "argon2id",
+			Time:      keyFile.KDF.Time,
+			Memory:    keyFile.KDF.Memory,
+			Threads:   keyFile.KDF.Threads,
+		},
+		Keys: map[string]keyIDInfo{
+			"aek":               newKeyIDInfo(keys.AEKID, keys.RetiredAEKs),
+			"tek":               newKeyIDInfo(keys.TEKID, keys.RetiredTEKs),
+			"uek":               newKeyIDInfo(keys.UEKID, keys.RetiredUEKs),
+			"gek":               newKeyIDInfo(keys.GEKID, keys.RetiredGEKs),
+			"receiptsigningkey": newKeyIDInfo(keys.ReceiptSigningKeyID, nil),
+		},
+	}
+	if len(keys.KEK) > 0 {
+		info.Keys["kek"] = newKeyIDInfo(keys.KEKID, keys.RetiredKEKs)
+	}
+
+	return info
+}
+
+func newKeyIDInfo(id uint32, retired map[uint32][]byte) keyIDInfo {
+	info := keyIDInfo{ID: id}
+	for retiredID := range retired {
+		info.Retired = append(info.Retired, retiredID)
+	}
+	sort.Slice(info.Retired, func(i, j int) bool { return info.Retired[i] < info.Retired[j] })
+
+	return info
+}
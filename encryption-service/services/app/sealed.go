@@ -0,0 +1,238 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	"encryption-service/config"
+	log "encryption-service/logger"
+	"encryption-service/services/health"
+	"encryption-service/services/unseal"
+)
+
+// ExecuteKeyFileCommand executes the cli commands that manage key files. These commands do not need
+// the configured keys, so they are handled before the keys are loaded. Returns true if a command was
+// executed.
+func ExecuteKeyFileCommand() bool {
+	ctx := context.TODO()
+	if !cliMode() {
+		return false
+	}
+
+	switch os.Args[1] {
+	case "seal-keys":
+		if len(os.Args) != 5 {
+			log.Fatal(ctx, errors.New("usage: seal-keys <key file> <shares> <threshold>"), "SealKeysCommand")
+		}
+		if err := sealKeysCLI(os.Args[2], os.Args[3], os.Args[4]); err != nil {
+			log.Fatal(ctx, err, "SealKeysCommand")
+		}
+	case "unseal":
+		if len(os.Args) != 3 {
+			log.Fatal(ctx, errors.New("Share argument missing"), "UnsealCommand")
+		}
+		if err := unsealCLI(os.Args[2]); err != nil {
+			log.Fatal(ctx, err, "UnsealCommand")
+		}
+	case "keyfile":
+		if len(os.Args) < 4 {
+			log.Fatal(ctx, errors.New("usage: keyfile create|inspect|reencrypt <key file> [new passphrase file]"), "KeyFileCommand")
+		}
+		if err := keyFileCLI(os.Args[2], os.Args[3], os.Args[4:]); err != nil {
+			log.Fatal(ctx, err, "KeyFileCommand")
+		}
+	default:
+		return false
+	}
+
+	return true
+}
+
+// sealKeysCLI seals the keys from the configuration into a new key file and prints the unseal
+// shares to stdout, one per line.
+func sealKeysCLI(path, sharesArg, thresholdArg string) error {
+	shares, err := strconv.Atoi(sharesArg)
+	if err != nil {
+		return err
+	}
+	threshold, err := strconv.Atoi(thresholdArg)
+	if err != nil {
+		return err
+	}
+
+	// Load the configuration without parsing, the keys are sealed as hex strings
+	cfg := config.Config{}
+	if err := config.LoadConfig(&cfg); err != nil {
+		return err
+	}
+
+	keyFile, unsealShares, err := config.SealKeys(cfg.Keys, shares, threshold)
+	if err != nil {
+		return err
+	}
+	if err := keyFile.Write(path); err != nil {
+		return err
+	}
+
+	for _, share := range unsealShares {
+		fmt.Println(unseal.EncodeShare(share))
+	}
+	log.Infof(context.TODO(), "Keys sealed in %v, %d of %d shares are needed to unseal", path, threshold, shares)
+
+	return nil
+}
+
+// unsealCLI submits an unseal share to a sealed server running on the local host
+func unsealCLI(share string) error {
+	ctx := context.TODO()
+
+	connection, err := grpc.Dial(fmt.Sprintf("localhost:%d", port), grpc.WithInsecure())
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+
+	response, err := unseal.NewEncryptonizeClient(connection).Unseal(ctx, &unseal.UnsealRequest{Share: share})
+	if err != nil {
+		return err
+	}
+
+	if response.Sealed {
+		log.Infof(ctx, "Share accepted, %d of %d shares submitted", response.Progress, response.Threshold)
+	} else {
+		log.Info(ctx, "Keys unsealed")
+	}
+
+	return nil
+}
+
+// UnsealKeys waits until the keys in the sealed key file at `path` are unsealed. In cli mode the
+// unseal shares are read from stdin, one per line. Otherwise a sealed server is started which only
+// accepts unseal requests. Returns nil if the service was shut down while sealed.
+func UnsealKeys(path string) *config.Keys {
+	ctx := context.TODO()
+
+	keyFile, err := config.ReadKeyFile(path)
+	if err != nil {
+		log.Fatal(ctx, err, "Reading sealed key file failed")
+	}
+
+	if cliMode() {
+		keys, err := unsealStdin(keyFile)
+		if err != nil {
+			log.Fatal(ctx, err, "Unseal failed")
+		}
+		return keys
+	}
+
+	unsealService := unseal.NewUnseal(keyFile)
+	grpcServer, lis := initSealedgRPC(port, unsealService)
+
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			msg := fmt.Sprintf("Failed to serve gRPC server over port %d", port)
+			log.Fatal(ctx, err, msg)
+		}
+	}()
+
+	msg := fmt.Sprintf("Service is sealed, waiting for %d unseal shares on port :%v", keyFile.Threshold, port)
+	log.Info(ctx, msg)
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGTERM)
+	signal.Notify(c, syscall.SIGINT)
+	defer signal.Stop(c)
+
+	var keys *config.Keys
+	select {
+	case keys = <-unsealService.Unsealed:
+		log.Info(ctx, "Service unsealed")
+	case <-c:
+		log.Info(ctx, "Received shutdown signal")
+	}
+
+	// The unseal request has already been answered, so this does not block for long
+	grpcServer.GracefulStop()
+
+	return keys
+}
+
+// unsealStdin reads unseal shares from stdin until the keys can be unsealed
+func unsealStdin(keyFile *config.KeyFile) (*config.Keys, error) {
+	log.Infof(context.TODO(), "Service is sealed, reading %d unseal shares from stdin", keyFile.Threshold)
+
+	var shares [][]byte
+	scanner := bufio.NewScanner(os.Stdin)
+	for len(shares) < keyFile.Threshold && scanner.Scan() {
+		share, err := hex.DecodeString(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return keyFile.Unseal(shares)
+}
+
+// initSealedgRPC sets up a gRPC server that only serves the unseal, health and reflection services.
+// All other requests are rejected.
+func initSealedgRPC(port int, unsealService *unseal.Unseal) (*grpc.Server, net.Listener) {
+	lis := listen(port)
+
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		recoveryInterceptor(),
+		log.UnaryRequestIDInterceptor(),
+		log.UnaryMethodNameInterceptor(),
+		log.UnaryLogInterceptor(),
+	}
+
+	grpcServer := grpc.NewServer(
+		grpc_middleware.WithUnaryServerChain(
+			unaryInterceptors...,
+		),
+		grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+			return status.Errorf(codes.Unavailable, "service is sealed")
+		}),
+	)
+
+	unseal.RegisterEncryptonizeServer(grpcServer, unsealService)
+
+	// Report that the service is not ready until it is unsealed
+	healthService := health.NewHealthCheckerWithStatus(grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	grpc_health_v1.RegisterHealthServer(grpcServer, healthService)
+
+	reflection.Register(grpcServer)
+
+	return grpcServer, lis
+}
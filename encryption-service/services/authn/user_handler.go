@@ -144,6 +144,19 @@ func (au *Authn) LoginUser(ctx context.Context, request *LoginUserRequest) (*Log
 		return nil, status.Errorf(codes.Internal, "error encountered while logging in user")
 	}
 
+	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while logging in user")
+		log.Error(ctx, err, "LoginUser: Could not typecast authstorage to AuthStoreTxInterface")
+		return nil, err
+	}
+
+	// Store the password hash if it was upgraded
+	if err := authStorageTx.Commit(ctx); err != nil {
+		log.Error(ctx, err, "LoginUser: Failed to commit auth storage transaction")
+		return nil, status.Errorf(codes.Internal, "error encountered while logging in user")
+	}
+
 	resp := &LoginUserResponse{
 		AccessToken: token,
 	}
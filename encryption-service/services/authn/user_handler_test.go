@@ -228,7 +228,16 @@ func TestLoginUser(t *testing.T) {
 		Password: loginPassword,
 	}
 
-	response, err := authn.LoginUser(context.Background(), &request)
+	commitCall := false
+	authStoreTx := &authstorage.AuthStoreTxMock{
+		CommitFunc: func(ctx context.Context) error {
+			commitCall = true
+			return nil
+		},
+	}
+	ctx := context.WithValue(context.Background(), common.AuthStorageTxCtxKey, authStoreTx)
+
+	response, err := authn.LoginUser(ctx, &request)
 	if err != nil {
 		t.Fatalf("LoginUser failed: %s", err)
 	}
@@ -238,6 +247,9 @@ func TestLoginUser(t *testing.T) {
 	if !loginUserCall {
 		t.Fatal("Failed to log in user")
 	}
+	if !commitCall {
+		t.Fatal("Upgraded password hash was not committed")
+	}
 }
 
 func TestFailLoginUser(t *testing.T) {
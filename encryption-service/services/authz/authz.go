@@ -17,7 +17,17 @@ import "encryption-service/interfaces"
 
 // Encryptonize Permission service
 type Authz struct {
-	Authorizer        interfaces.AccessObjectAuthenticatorInterface
-	UserAuthenticator interfaces.UserAuthenticatorInterface
+	Authorizer               interfaces.AccessObjectAuthenticatorInterface
+	UserAuthenticator        interfaces.UserAuthenticatorInterface
+	AuthStore                interfaces.AuthStoreInterface
+	DataKeyRewrapper         interfaces.KeyRewrapperInterface
+	AccessObjectKeyRewrapper interfaces.KeyRewrapperInterface
+	UserKeyRewrapper         interfaces.KeyRewrapperInterface
+	GroupKeyRewrapper        interfaces.KeyRewrapperInterface
+	// Nil if the Storage service is disabled
+	ObjectRekeyer interfaces.ObjectRekeyerInterface
+	// Signer of erasure receipts and its key ID, used to publish the public key
+	ReceiptSigner       interfaces.SignerInterface
+	ReceiptSigningKeyID uint32
 	UnimplementedEncryptonizeServer
 }
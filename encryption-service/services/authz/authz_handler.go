@@ -15,12 +15,14 @@ package authz
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 
 	"github.com/gofrs/uuid"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"encryption-service/common"
 	"encryption-service/interfaces"
@@ -145,20 +147,113 @@ func (a *Authz) RemovePermission(ctx context.Context, request *RemovePermissionR
 
 	// Remove the permission from the access object
 	accessObject.RemoveGroup(target)
-	err = a.Authorizer.UpdateAccessObject(ctx, oid, *accessObject)
+	if request.Rekey {
+		if err := a.rekeyObject(ctx, oid, accessObject); err != nil {
+			return nil, err
+		}
+	} else {
+		err = a.Authorizer.UpdateAccessObject(ctx, oid, *accessObject)
+		if err != nil {
+			msg := fmt.Sprintf("RemovePermission: Failed to remove group %v from access object %v", target, oid)
+			log.Error(ctx, err, msg)
+			return nil, status.Errorf(codes.Internal, "error encountered while removing permission")
+		}
+
+		if err := authStorageTx.Commit(ctx); err != nil {
+			log.Error(ctx, err, "RemovePermission: Failed to commit auth storage transaction")
+			return nil, status.Errorf(codes.Internal, "error encountered while removing permission")
+		}
+	}
+
+	ctx = context.WithValue(ctx, common.TargetIDCtxKey, target)
+	log.Info(ctx, "RemovePermission: Permission removed")
+
+	return &RemovePermissionResponse{}, nil
+}
+
+// Destroy the access object, and with it the key, of an object.
+// The requesting user has to be authorized to access the object.
+func (a *Authz) Shred(ctx context.Context, request *ShredRequest) (*ShredResponse, error) {
+	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while shredding object")
+		log.Error(ctx, err, "Shred: Could not typecast authstorage to AuthStoreTxInterface")
+		return nil, err
+	}
+
+	oid, err := uuid.FromString(request.ObjectId)
 	if err != nil {
-		msg := fmt.Sprintf("RemovePermission: Failed to remove group %v from access object %v", target, oid)
+		log.Error(ctx, err, "Shred: Failed to parse object ID as UUID")
+		return nil, status.Errorf(codes.InvalidArgument, "invalid object ID")
+	}
+
+	receipt, err := a.Authorizer.ShredAccessObject(ctx, oid)
+	if err != nil {
+		msg := fmt.Sprintf("Shred: Failed to shred access object %v", oid)
 		log.Error(ctx, err, msg)
-		return nil, status.Errorf(codes.Internal, "error encountered while removing permission")
+		return nil, status.Errorf(codes.Internal, "error encountered while shredding object")
 	}
 
 	if err := authStorageTx.Commit(ctx); err != nil {
-		log.Error(ctx, err, "RemovePermission: Failed to commit auth storage transaction")
-		return nil, status.Errorf(codes.Internal, "error encountered while removing permission")
+		log.Error(ctx, err, "Shred: Failed to commit auth storage transaction")
+		return nil, status.Errorf(codes.Internal, "error encountered while shredding object")
 	}
 
-	ctx = context.WithValue(ctx, common.TargetIDCtxKey, target)
-	log.Info(ctx, "RemovePermission: Permission removed")
+	log.Info(ctx, "Shred: Object shredded")
 
-	return &RemovePermissionResponse{}, nil
+	return &ShredResponse{Receipt: newErasureReceipt(receipt)}, nil
+}
+
+// Retrieve the signed erasure receipt of a shredded object.
+// The receipt is fetched by the authorization middleware, which authorizes the user against the
+// groups that had access to the object when it was shredded.
+func (a *Authz) GetErasureReceipt(ctx context.Context, request *GetErasureReceiptRequest) (*GetErasureReceiptResponse, error) {
+	receipt, ok := ctx.Value(common.ErasureReceiptCtxKey).(*common.ErasureReceipt)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while fetching erasure receipt")
+		log.Error(ctx, err, "GetErasureReceipt: Could not typecast erasure receipt to ErasureReceipt")
+		return nil, err
+	}
+
+	log.Info(ctx, "GetErasureReceipt: Erasure receipt fetched")
+
+	return &GetErasureReceiptResponse{Receipt: newErasureReceipt(receipt)}, nil
+}
+
+// newErasureReceipt converts an erasure receipt to its API representation
+func newErasureReceipt(receipt *common.ErasureReceipt) *ErasureReceipt {
+	return &ErasureReceipt{
+		ObjectId:   receipt.ObjectID.String(),
+		ShreddedAt: timestamppb.New(receipt.ShreddedAt),
+		Digest:     receipt.Digest,
+		Signature:  receipt.Signature,
+		KeyId:      receipt.KeyID,
+	}
+}
+
+// rekeyObject re-encrypts the object under a new key and stores the updated access object in the
+// same transaction
+func (a *Authz) rekeyObject(ctx context.Context, oid uuid.UUID, accessObject *common.AccessObject) error {
+	if a.ObjectRekeyer == nil {
+		err := status.Errorf(codes.FailedPrecondition, "re-keying requires the storage service")
+		log.Error(ctx, err, "RemovePermission: Storage service is disabled")
+		return err
+	}
+
+	err := a.ObjectRekeyer.RekeyObject(ctx, oid, *accessObject)
+	if errors.Is(err, interfaces.ErrNotFound) {
+		log.Error(ctx, err, "RemovePermission: Object not found in object storage")
+		return status.Errorf(codes.FailedPrecondition, "object is not stored by the storage service")
+	}
+	if errors.Is(err, common.ErrImportedKeyDeleted) {
+		log.Error(ctx, err, "RemovePermission: Imported key of object not found")
+		return status.Errorf(codes.FailedPrecondition, "imported key has been deleted")
+	}
+	if err != nil {
+		msg := fmt.Sprintf("RemovePermission: Failed to re-key object %v", oid)
+		log.Error(ctx, err, msg)
+		return status.Errorf(codes.Internal, "error encountered while removing permission")
+	}
+
+	return nil
 }
@@ -15,20 +15,29 @@ package authz
 
 import (
 	"context"
+	"crypto/ed25519"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
 	"encryption-service/common"
 	"encryption-service/impl/authstorage"
 	authzimpl "encryption-service/impl/authz"
 	"encryption-service/impl/crypt"
+	"encryption-service/interfaces"
 )
 
 var cryptor, _ = crypt.NewAESCryptor([]byte("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"))
+var receiptSigner, _ = crypt.NewEd25519Signer([]byte("BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB"))
 var authorizer = &authzimpl.Authorizer{
 	AccessObjectCryptor: cryptor,
+	ReceiptSigner:       receiptSigner,
+	ReceiptSigningKeyID: 3,
 }
 
 var permissions = Authz{
@@ -185,3 +194,183 @@ func TestRemovePermissionsMissingAccessObject(t *testing.T) {
 		t.Fatalf("No access object given, should have failed")
 	}
 }
+
+// objectRekeyerMock records the access object it is asked to store
+type objectRekeyerMock struct {
+	accessObject *common.AccessObject
+	err          error
+}
+
+func (o *objectRekeyerMock) RekeyObject(ctx context.Context, objectID uuid.UUID, accessObject common.AccessObject) error {
+	o.accessObject = &accessObject
+	return o.err
+}
+
+func TestRemovePermissionRekey(t *testing.T) {
+	accessObject := &common.AccessObject{
+		GroupIDs: map[uuid.UUID]bool{userID: true, targetID: true},
+		Woek:     Woek,
+	}
+	ctx := context.WithValue(context.Background(), common.AccessObjectCtxKey, accessObject)
+	ctx = context.WithValue(ctx, common.AuthStorageTxCtxKey, authnStorageTxMock)
+
+	rekeyer := &objectRekeyerMock{}
+	permissions := Authz{Authorizer: authorizer, ObjectRekeyer: rekeyer}
+
+	_, err = permissions.RemovePermission(ctx, &RemovePermissionRequest{ObjectId: objectID.String(), Target: targetID.String(), Rekey: true})
+	if err != nil {
+		t.Fatalf("Couldn't remove user: %v", err)
+	}
+
+	if rekeyer.accessObject == nil {
+		t.Fatal("Object was not re-keyed")
+	}
+	if _, ok := rekeyer.accessObject.GetGroups()[targetID]; ok {
+		t.Fatal("Re-keyed access object still contains the removed group")
+	}
+}
+
+func TestRemovePermissionRekeyFail(t *testing.T) {
+	tests := map[string]*Authz{
+		"storage service disabled": {Authorizer: authorizer},
+		"object not stored":        {Authorizer: authorizer, ObjectRekeyer: &objectRekeyerMock{err: interfaces.ErrNotFound}},
+	}
+
+	for name, permissions := range tests {
+		t.Run(name, func(t *testing.T) {
+			accessObject := &common.AccessObject{
+				GroupIDs: map[uuid.UUID]bool{userID: true, targetID: true},
+				Woek:     Woek,
+			}
+			ctx := context.WithValue(context.Background(), common.AccessObjectCtxKey, accessObject)
+			ctx = context.WithValue(ctx, common.AuthStorageTxCtxKey, authnStorageTxMock)
+
+			_, err := permissions.RemovePermission(ctx, &RemovePermissionRequest{ObjectId: objectID.String(), Target: targetID.String(), Rekey: true})
+			if status.Code(err) != codes.FailedPrecondition {
+				t.Fatalf("Expected FailedPrecondition, got %v", err)
+			}
+		})
+	}
+}
+
+func TestShred(t *testing.T) {
+	deleted := false
+	authStoreTx := &authstorage.AuthStoreTxMock{
+		GetAccessObjectFunc: authnStorageTxMock.GetAccessObjectFunc,
+		InsertErasureReceiptFunc: func(ctx context.Context, protected *common.ProtectedErasureReceipt) error {
+			return nil
+		},
+		DeleteAccessObjectFunc: func(ctx context.Context, objectID uuid.UUID) error {
+			deleted = true
+			return nil
+		},
+		CommitFunc: func(ctx context.Context) error {
+			return nil
+		},
+	}
+	ctx := context.WithValue(context.Background(), common.AccessObjectCtxKey, accessObject)
+	ctx = context.WithValue(ctx, common.AuthStorageTxCtxKey, authStoreTx)
+
+	response, err := permissions.Shred(ctx, &ShredRequest{ObjectId: objectID.String()})
+	if err != nil {
+		t.Fatalf("Couldn't shred object: %v", err)
+	}
+	if !deleted {
+		t.Fatal("Access object not deleted")
+	}
+	if response.Receipt.ObjectId != objectID.String() || len(response.Receipt.Digest) == 0 {
+		t.Fatalf("Unexpected receipt: %v", response.Receipt)
+	}
+}
+
+func TestGetErasureReceipt(t *testing.T) {
+	var stored *common.ProtectedErasureReceipt
+	authStoreTx := &authstorage.AuthStoreTxMock{
+		GetAccessObjectFunc: authnStorageTxMock.GetAccessObjectFunc,
+		InsertErasureReceiptFunc: func(ctx context.Context, protected *common.ProtectedErasureReceipt) error {
+			stored = protected
+			return nil
+		},
+		GetErasureReceiptFunc: func(ctx context.Context, oid uuid.UUID) (*common.ProtectedErasureReceipt, error) {
+			if stored == nil || oid != stored.ObjectID {
+				return nil, interfaces.ErrNotFound
+			}
+			return stored, nil
+		},
+		DeleteAccessObjectFunc: func(ctx context.Context, objectID uuid.UUID) error {
+			return nil
+		},
+		CommitFunc: func(ctx context.Context) error {
+			return nil
+		},
+	}
+	ctx := context.WithValue(context.Background(), common.AuthStorageTxCtxKey, authStoreTx)
+
+	shredResponse, err := permissions.Shred(ctx, &ShredRequest{ObjectId: objectID.String()})
+	if err != nil {
+		t.Fatalf("Couldn't shred object: %v", err)
+	}
+
+	// The receipt is fetched by the authorization middleware
+	fetched, err := authorizer.FetchErasureReceipt(ctx, objectID)
+	if err != nil {
+		t.Fatalf("Couldn't fetch erasure receipt: %v", err)
+	}
+	if !fetched.GroupIDs[userID] {
+		t.Fatalf("Former groups of the object not recorded in receipt: %v", fetched.GroupIDs)
+	}
+	ctx = context.WithValue(ctx, common.ErasureReceiptCtxKey, fetched)
+
+	response, err := permissions.GetErasureReceipt(ctx, &GetErasureReceiptRequest{ObjectId: objectID.String()})
+	if err != nil {
+		t.Fatalf("Couldn't get erasure receipt: %v", err)
+	}
+	if !proto.Equal(response.Receipt, shredResponse.Receipt) {
+		t.Fatalf("Fetched receipt doesn't match: %v != %v", response.Receipt, shredResponse.Receipt)
+	}
+	if response.Receipt.KeyId != 3 {
+		t.Fatalf("Unexpected receipt key ID: %v", response.Receipt.KeyId)
+	}
+
+	// The signature verifies with the public key of the receipt signing key
+	receipt := &common.ErasureReceipt{
+		ObjectID:   objectID,
+		ShreddedAt: response.Receipt.ShreddedAt.AsTime(),
+		Digest:     response.Receipt.Digest,
+		KeyID:      response.Receipt.KeyId,
+	}
+	publicKey := receiptSigner.PublicKey().(ed25519.PublicKey)
+	if !ed25519.Verify(publicKey, receipt.SignedData(), response.Receipt.Signature) {
+		t.Fatal("Receipt signature invalid")
+	}
+
+	// The signature doesn't verify if the receipt is altered
+	receipt.ShreddedAt = receipt.ShreddedAt.Add(time.Second)
+	if ed25519.Verify(publicKey, receipt.SignedData(), response.Receipt.Signature) {
+		t.Fatal("Signature of altered receipt accepted")
+	}
+	receipt.ShreddedAt = response.Receipt.ShreddedAt.AsTime()
+	receipt.KeyID++
+	if ed25519.Verify(publicKey, receipt.SignedData(), response.Receipt.Signature) {
+		t.Fatal("Signature of receipt with altered key ID accepted")
+	}
+}
+
+func TestGetErasureReceiptMissingReceipt(t *testing.T) {
+	ctx := context.WithValue(context.Background(), common.AuthStorageTxCtxKey, authnStorageTxMock)
+
+	_, err := permissions.GetErasureReceipt(ctx, &GetErasureReceiptRequest{ObjectId: objectID.String()})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("Expected Internal, got %v", err)
+	}
+}
+
+func TestShredMissingOID(t *testing.T) {
+	ctx := context.WithValue(context.Background(), common.AccessObjectCtxKey, accessObject)
+	ctx = context.WithValue(ctx, common.AuthStorageTxCtxKey, authnStorageTxMock)
+
+	_, err = permissions.Shred(ctx, &ShredRequest{})
+	if err == nil {
+		t.Fatalf("No object id given, should have failed")
+	}
+}
@@ -29,21 +29,42 @@ import (
 const baseAppPath string = "/app.Encryptonize/"
 const baseStoragePath string = "/storage.Encryptonize/"
 const baseAuthPath string = "/authn.Encryptonize/"
+const baseAuthzPath string = "/authz.Encryptonize/"
 const baseEncPath string = "/enc.Encryptonize/"
+const baseMACPath string = "/mac.Encryptonize/"
+const baseSignPath string = "/sign.Encryptonize/"
+const baseFPEPath string = "/fpe.Encryptonize/"
+const baseVaultPath string = "/vault.Encryptonize/"
 
 var skippedAuthorizeMethods = map[string]bool{
-	health.HealthEndpointCheck:           true,
-	health.HealthEndpointWatch:           true,
-	health.ReflectionEndpoint:            true,
-	baseAppPath + "Version":              true,
-	baseStoragePath + "Store":            true,
-	baseEncPath + "Encrypt":              true,
-	baseAuthPath + "LoginUser":           true,
-	baseAuthPath + "CreateUser":          true,
-	baseAuthPath + "RemoveUser":          true,
-	baseAuthPath + "CreateGroup":         true,
-	baseAuthPath + "AddUserToGroup":      true,
-	baseAuthPath + "RemoveUserFromGroup": true,
+	health.HealthEndpointCheck:             true,
+	health.HealthEndpointWatch:             true,
+	health.ReflectionEndpoint:              true,
+	baseAppPath + "Version":                true,
+	baseStoragePath + "Store":              true,
+	baseEncPath + "Encrypt":                true,
+	baseEncPath + "GenerateDataKey":        true,
+	baseEncPath + "CreateIngestKey":        true,
+	baseEncPath + "CreateDeterministicKey": true,
+	baseMACPath + "CreateKey":              true,
+	baseSignPath + "CreateSigningKey":      true,
+	baseFPEPath + "CreateKey":              true,
+	baseVaultPath + "CreateNamespace":      true,
+	baseAuthPath + "LoginUser":             true,
+	baseAuthPath + "CreateUser":            true,
+	baseAuthPath + "RemoveUser":            true,
+	baseAuthPath + "CreateGroup":           true,
+	baseAuthPath + "AddUserToGroup":        true,
+	baseAuthPath + "RemoveUserFromGroup":   true,
+}
+
+// getErasureReceiptMethod is authorized against the former groups recorded in the erasure receipt,
+// as the access object no longer exists after shredding
+const getErasureReceiptMethod = baseAuthzPath + "GetErasureReceipt"
+
+// keyRequest is implemented by requests that create a new object under the key of an existing one
+type keyRequest interface {
+	GetKeyId() string
 }
 
 // AuthorizationUnaryServerInterceptor acts as authorization middleware. It expects a UID and OID to
@@ -60,14 +81,24 @@ func (authz *Authz) AuthorizationUnaryServerInterceptor() grpc.UnaryServerInterc
 
 		// IMPORTANT! This check MUST stay at the top of this function
 		if _, ok := skippedAuthorizeMethods[methodName]; ok {
-			return handler(ctx, req)
-		}
+			// Requests creating a new object under an existing key must be authorized for the key
+			keyReq, ok := req.(keyRequest)
+			if !ok || keyReq.GetKeyId() == "" {
+				return handler(ctx, req)
+			}
 
-		userID, ok := ctx.Value(common.UserIDCtxKey).(uuid.UUID)
-		if !ok {
-			err := status.Errorf(codes.Internal, "Internal error during authorization")
-			log.Error(ctx, err, "Could not typecast userID to uuid.UUID")
-			return nil, err
+			keyID, err := uuid.FromString(keyReq.GetKeyId())
+			if err != nil {
+				log.Error(ctx, err, "Couldn't parse key ID")
+				return nil, status.Errorf(codes.InvalidArgument, "invalid key ID")
+			}
+
+			keyAccessObject, err := authz.authorizeObject(ctx, methodName, keyID)
+			if err != nil {
+				return nil, err
+			}
+			newCtx := context.WithValue(ctx, common.KeyAccessObjectCtxKey, keyAccessObject)
+			return handler(newCtx, req)
 		}
 
 		objectID, ok := ctx.Value(common.ObjectIDCtxKey).(uuid.UUID)
@@ -77,54 +108,105 @@ func (authz *Authz) AuthorizationUnaryServerInterceptor() grpc.UnaryServerInterc
 			return nil, err
 		}
 
-		accessObject, err := authz.Authorizer.FetchAccessObject(ctx, objectID)
-		if err != nil {
-			log.Error(ctx, err, "Couldn't fetch AccessObject")
-			return nil, status.Errorf(codes.NotFound, "error encountered while authorizing user")
+		if methodName == getErasureReceiptMethod {
+			receipt, err := authz.authorizeErasureReceipt(ctx, methodName, objectID)
+			if err != nil {
+				return nil, err
+			}
+			newCtx := context.WithValue(ctx, common.ErasureReceiptCtxKey, receipt)
+			return handler(newCtx, req)
 		}
 
-		userData, err := authz.UserAuthenticator.GetUserData(ctx, userID)
+		accessObject, err := authz.authorizeObject(ctx, methodName, objectID)
 		if err != nil {
-			log.Error(ctx, err, "Couldn't fetch userData")
-			return nil, status.Errorf(codes.NotFound, "error encountered while authorizing user")
-		}
-
-		reqScope, ok := common.MethodScopeMap[methodName]
-		if !ok {
-			err = status.Errorf(codes.InvalidArgument, "invalid endpoint")
-			log.Error(ctx, err, "AuthzMiddleware: Invalid Endpoint")
 			return nil, err
 		}
 
-		// Find the intersection of the user's groups and the object's groups
-		a := userData.GroupIDs
-		b := accessObject.GetGroups()
-		if len(a) > len(b) {
-			a, b = b, a
-		}
+		// User authorized, call next handler
+		newCtx := context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+		return handler(newCtx, req)
+	}
+}
 
-		groupIDs := make([]uuid.UUID, 0, len(a))
-		for groupID := range a {
-			if _, ok := b[groupID]; ok {
-				groupIDs = append(groupIDs, groupID)
-			}
-		}
+// authorizeObject checks that the user in the context has the scope required by the method in one
+// of the groups of the object, and returns the access object of the object
+func (authz *Authz) authorizeObject(ctx context.Context, methodName string, objectID uuid.UUID) (*common.AccessObject, error) {
+	accessObject, err := authz.Authorizer.FetchAccessObject(ctx, objectID)
+	if err != nil {
+		log.Error(ctx, err, "Couldn't fetch AccessObject")
+		return nil, status.Errorf(codes.NotFound, "error encountered while authorizing user")
+	}
 
-		groupDataBatch, err := authz.UserAuthenticator.GetGroupDataBatch(ctx, groupIDs)
-		if err != nil {
-			log.Error(ctx, err, "Couldn't fetch groupData")
-			return nil, status.Errorf(codes.NotFound, "error encountered while authorizing user")
-		}
+	if err := authz.authorizeGroups(ctx, methodName, accessObject.GetGroups()); err != nil {
+		return nil, err
+	}
+	return accessObject, nil
+}
 
-		for _, groupData := range groupDataBatch {
-			// User authorized, call next handler
-			if groupData.Scopes.HasScopes(reqScope) {
-				newCtx := context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
-				return handler(newCtx, req)
-			}
+// authorizeErasureReceipt checks that the user in the context has the scope required by the method
+// in one of the groups that had access to the shredded object, and returns the erasure receipt
+func (authz *Authz) authorizeErasureReceipt(ctx context.Context, methodName string, objectID uuid.UUID) (*common.ErasureReceipt, error) {
+	receipt, err := authz.Authorizer.FetchErasureReceipt(ctx, objectID)
+	if err != nil {
+		log.Error(ctx, err, "Couldn't fetch ErasureReceipt")
+		return nil, status.Errorf(codes.NotFound, "error encountered while authorizing user")
+	}
+
+	if err := authz.authorizeGroups(ctx, methodName, receipt.GroupIDs); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// authorizeGroups checks that the user in the context has the scope required by the method in one
+// of the given groups
+func (authz *Authz) authorizeGroups(ctx context.Context, methodName string, objectGroupIDs map[uuid.UUID]bool) error {
+	userID, ok := ctx.Value(common.UserIDCtxKey).(uuid.UUID)
+	if !ok {
+		err := status.Errorf(codes.Internal, "Internal error during authorization")
+		log.Error(ctx, err, "Could not typecast userID to uuid.UUID")
+		return err
+	}
+
+	userData, err := authz.UserAuthenticator.GetUserData(ctx, userID)
+	if err != nil {
+		log.Error(ctx, err, "Couldn't fetch userData")
+		return status.Errorf(codes.NotFound, "error encountered while authorizing user")
+	}
+
+	reqScope, ok := common.MethodScopeMap[methodName]
+	if !ok {
+		err = status.Errorf(codes.InvalidArgument, "invalid endpoint")
+		log.Error(ctx, err, "AuthzMiddleware: Invalid Endpoint")
+		return err
+	}
+
+	// Find the intersection of the user's groups and the object's groups
+	a := userData.GroupIDs
+	b := objectGroupIDs
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+
+	groupIDs := make([]uuid.UUID, 0, len(a))
+	for groupID := range a {
+		if _, ok := b[groupID]; ok {
+			groupIDs = append(groupIDs, groupID)
 		}
+	}
 
-		log.Warn(ctx, "Couldn't authorize user")
-		return nil, status.Errorf(codes.PermissionDenied, "access not authorized")
+	groupDataBatch, err := authz.UserAuthenticator.GetGroupDataBatch(ctx, groupIDs)
+	if err != nil {
+		log.Error(ctx, err, "Couldn't fetch groupData")
+		return status.Errorf(codes.NotFound, "error encountered while authorizing user")
 	}
+
+	for _, groupData := range groupDataBatch {
+		if groupData.Scopes.HasScopes(reqScope) {
+			return nil
+		}
+	}
+
+	log.Warn(ctx, "Couldn't authorize user")
+	return status.Errorf(codes.PermissionDenied, "access not authorized")
 }
@@ -41,10 +41,15 @@ func failOnSuccess(message string, err error, t *testing.T) {
 }
 
 type AuthorizerMock struct {
-	accessObject *common.AccessObject
+	accessObject   *common.AccessObject
+	erasureReceipt *common.ErasureReceipt
 }
 
-func (a *AuthorizerMock) CreateAccessObject(_ context.Context, _, _ uuid.UUID, _ []byte) error {
+func (a *AuthorizerMock) CreateAccessObject(_ context.Context, _, _ uuid.UUID, _ common.KeyType, _ []byte) error {
+	return nil
+}
+
+func (a *AuthorizerMock) CreateAccessObjectWithKeyID(_ context.Context, _, _, _ uuid.UUID) error {
 	return nil
 }
 
@@ -55,6 +60,10 @@ func (a *AuthorizerMock) FetchAccessObject(ctx context.Context, objectID uuid.UU
 	return a.accessObject, nil
 }
 
+func (a *AuthorizerMock) FetchObjectKey(_ context.Context, accessObject *common.AccessObject) ([]byte, error) {
+	return accessObject.GetWOEK(), nil
+}
+
 func (a *AuthorizerMock) UpdateAccessObject(_ context.Context, _ uuid.UUID, _ common.AccessObject) error {
 	return nil
 }
@@ -63,13 +72,25 @@ func (a *AuthorizerMock) DeleteAccessObject(_ context.Context, _ uuid.UUID) erro
 	return nil
 }
 
+func (a *AuthorizerMock) ShredAccessObject(_ context.Context, _ uuid.UUID) (*common.ErasureReceipt, error) {
+	return nil, nil
+}
+
+func (a *AuthorizerMock) FetchErasureReceipt(_ context.Context, _ uuid.UUID) (*common.ErasureReceipt, error) {
+	if a.erasureReceipt == nil {
+		return nil, errors.New("No receipt")
+	}
+	return a.erasureReceipt, nil
+}
+
 type MockData struct {
-	methodName   string
-	userID       uuid.UUID
-	objectID     uuid.UUID
-	accessObject *common.AccessObject
-	userData     *common.UserData
-	groupData    map[uuid.UUID]common.GroupData
+	methodName     string
+	userID         uuid.UUID
+	objectID       uuid.UUID
+	accessObject   *common.AccessObject
+	erasureReceipt *common.ErasureReceipt
+	userData       *common.UserData
+	groupData      map[uuid.UUID]common.GroupData
 }
 
 func SetupMocks(mockData MockData) (context.Context, *Authz) {
@@ -106,7 +127,7 @@ func SetupMocks(mockData MockData) (context.Context, *Authz) {
 	}
 
 	authz := &Authz{
-		Authorizer:        &AuthorizerMock{accessObject: mockData.accessObject},
+		Authorizer:        &AuthorizerMock{accessObject: mockData.accessObject, erasureReceipt: mockData.erasureReceipt},
 		UserAuthenticator: userAuthenticatorMock,
 	}
 
@@ -309,3 +330,138 @@ func TestAuthzSkippedMethod(t *testing.T) {
 	_, err = authz.AuthorizationUnaryServerInterceptor()(ctx, nil, nil, handler)
 	failOnError("Expected authorization to be skipped", err, t)
 }
+
+type keyRequestMock struct {
+	keyID string
+}
+
+func (r *keyRequestMock) GetKeyId() string {
+	return r.keyID
+}
+
+func keyMockData(scopes common.ScopeType) MockData {
+	groupID := uuid.Must(uuid.NewV4())
+	return MockData{
+		methodName: "/enc.Encryptonize/Encrypt",
+		userID:     uuid.Must(uuid.NewV4()),
+		accessObject: &common.AccessObject{
+			GroupIDs: map[uuid.UUID]bool{groupID: true},
+			KeyType:  common.KeyTypeImported,
+		},
+		userData: &common.UserData{
+			GroupIDs: map[uuid.UUID]bool{groupID: true},
+		},
+		groupData: map[uuid.UUID]common.GroupData{
+			groupID: {Scopes: scopes},
+		},
+	}
+}
+
+func TestAuthzSkippedMethodWithKey(t *testing.T) {
+	keyData := keyMockData(common.ScopeCreate)
+	ctx, authz := SetupMocks(keyData)
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		keyAccessObject, ok := ctx.Value(common.KeyAccessObjectCtxKey).(*common.AccessObject)
+		if !ok {
+			t.Fatal("Key access object not added to context")
+		}
+		if !reflect.DeepEqual(keyData.accessObject, keyAccessObject) {
+			t.Fatal("Key access object in context not equal to original")
+		}
+		return nil, nil
+	}
+
+	request := &keyRequestMock{keyID: uuid.Must(uuid.NewV4()).String()}
+	_, err = authz.AuthorizationUnaryServerInterceptor()(ctx, request, nil, handler)
+	failOnError("Expected user to be authorized for the key", err, t)
+}
+
+func TestAuthzSkippedMethodWithKeyUnauthorized(t *testing.T) {
+	ctx, authz := SetupMocks(keyMockData(common.ScopeRead))
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		t.Fatal("Handler should not have been called")
+		return nil, nil
+	}
+
+	request := &keyRequestMock{keyID: uuid.Must(uuid.NewV4()).String()}
+	_, err = authz.AuthorizationUnaryServerInterceptor()(ctx, request, nil, handler)
+	failOnSuccess("User should not be authorized for the key", err, t)
+
+	if errStatus, _ := status.FromError(err); codes.PermissionDenied != errStatus.Code() {
+		t.Fatalf("Wrong error returned: expected %v, but got %v", codes.PermissionDenied, errStatus)
+	}
+
+	request = &keyRequestMock{keyID: "not a uuid"}
+	_, err = authz.AuthorizationUnaryServerInterceptor()(ctx, request, nil, handler)
+	if errStatus, _ := status.FromError(err); codes.InvalidArgument != errStatus.Code() {
+		t.Fatalf("Wrong error returned: expected %v, but got %v", codes.InvalidArgument, errStatus)
+	}
+}
+
+// erasureReceiptMockData returns mock data for a user with the delete scope, who is a member of a
+// former group of the shredded object if `formerMember` is set
+func erasureReceiptMockData(formerMember bool) MockData {
+	groupID := uuid.Must(uuid.NewV4())
+	userGroupID := uuid.Must(uuid.NewV4())
+	if formerMember {
+		userGroupID = groupID
+	}
+	return MockData{
+		methodName: "/authz.Encryptonize/GetErasureReceipt",
+		userID:     uuid.Must(uuid.NewV4()),
+		objectID:   uuid.Must(uuid.NewV4()),
+		erasureReceipt: &common.ErasureReceipt{
+			GroupIDs: map[uuid.UUID]bool{groupID: true},
+		},
+		userData: &common.UserData{
+			GroupIDs: map[uuid.UUID]bool{userGroupID: true},
+		},
+		groupData: map[uuid.UUID]common.GroupData{
+			groupID:     {Scopes: common.ScopeDelete},
+			userGroupID: {Scopes: common.ScopeDelete},
+		},
+	}
+}
+
+func TestAuthzErasureReceipt(t *testing.T) {
+	receiptData := erasureReceiptMockData(true)
+	ctx, authz := SetupMocks(receiptData)
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		receipt, ok := ctx.Value(common.ErasureReceiptCtxKey).(*common.ErasureReceipt)
+		if !ok {
+			t.Fatal("Erasure receipt not added to context")
+		}
+		if receipt != receiptData.erasureReceipt {
+			t.Fatal("Erasure receipt in context not equal to original")
+		}
+		return nil, nil
+	}
+
+	_, err = authz.AuthorizationUnaryServerInterceptor()(ctx, nil, nil, handler)
+	failOnError("Expected user in a former group of the object to be authorized", err, t)
+}
+
+func TestAuthzErasureReceiptUnauthorized(t *testing.T) {
+	ctx, authz := SetupMocks(erasureReceiptMockData(false))
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		t.Fatal("Handler should not have been called")
+		return nil, nil
+	}
+
+	_, err = authz.AuthorizationUnaryServerInterceptor()(ctx, nil, nil, handler)
+	if errStatus, _ := status.FromError(err); codes.PermissionDenied != errStatus.Code() {
+		t.Fatalf("Wrong error returned: expected %v, but got %v", codes.PermissionDenied, errStatus)
+	}
+
+	receiptData := erasureReceiptMockData(true)
+	receiptData.erasureReceipt = nil
+	ctx, authz = SetupMocks(receiptData)
+	_, err = authz.AuthorizationUnaryServerInterceptor()(ctx, nil, nil, handler)
+	if errStatus, _ := status.FromError(err); codes.NotFound != errStatus.Code() {
+		t.Fatalf("Wrong error returned: expected %v, but got %v", codes.NotFound, errStatus)
+	}
+}
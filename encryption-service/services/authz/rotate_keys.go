@@ -0,0 +1,224 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package authz
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+
+	"encryption-service/common"
+	"encryption-service/interfaces"
+	log "encryption-service/logger"
+)
+
+// Number of records rotated per transaction
+const rotateBatchSize = 100
+
+// listIDsFunc lists up to `limit` record IDs greater than `after`
+type listIDsFunc func(authStoreTx interfaces.AuthStoreTxInterface, ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error)
+
+// rotateFunc rotates the record with the given ID and reports whether the record was changed
+type rotateFunc func(ctx context.Context, authStoreTx interfaces.AuthStoreTxInterface, id uuid.UUID) (bool, error)
+
+// RotateKEKCLI re-wraps the object key of every access object under the current KEK. Access objects
+// whose key is already wrapped under the current KEK are left untouched, so the command can safely
+// be re-run if it is interrupted. This function is intended to be used for CLI operation.
+func (au *Authz) RotateKEKCLI() error {
+	ctx, err := newCLIContext()
+	if err != nil {
+		return err
+	}
+
+	return rotateInBatches(ctx, au.AuthStore, "access objects", interfaces.AuthStoreTxInterface.ListAccessObjectIDs,
+		func(ctx context.Context, authStoreTx interfaces.AuthStoreTxInterface, objectID uuid.UUID) (bool, error) {
+			ctx = context.WithValue(ctx, common.AuthStorageTxCtxKey, authStoreTx)
+			accessObject, err := au.Authorizer.FetchAccessObject(ctx, objectID)
+			if err != nil {
+				return false, err
+			}
+
+			woek, changed, err := au.DataKeyRewrapper.Rewrap(accessObject.Woek)
+			if err != nil || !changed {
+				return false, err
+			}
+
+			accessObject.Woek = woek
+			return true, au.Authorizer.UpdateAccessObject(ctx, objectID, *accessObject)
+		},
+	)
+}
+
+// RotateAuthKeysCLI re-wraps the record keys of all users, groups, access objects and erasure
+// receipts in the Auth Storage under the current UEK, GEK and AEK respectively. Records that are
+// already protected by the current keys are left untouched, so the command can safely be re-run if
+// it is interrupted. This function is intended to be used for CLI operation.
+func (au *Authz) RotateAuthKeysCLI() error {
+	ctx, err := newCLIContext()
+	if err != nil {
+		return err
+	}
+
+	err = rotateInBatches(ctx, au.AuthStore, "users", interfaces.AuthStoreTxInterface.ListUserIDs,
+		func(ctx context.Context, authStoreTx interfaces.AuthStoreTxInterface, userID uuid.UUID) (bool, error) {
+			protected, err := authStoreTx.GetUserData(ctx, userID)
+			if err != nil {
+				return false, err
+			}
+
+			wrappedKey, changed, err := au.UserKeyRewrapper.Rewrap(protected.WrappedKey)
+			if err != nil || !changed {
+				return false, err
+			}
+
+			protected.WrappedKey = wrappedKey
+			return true, authStoreTx.UpdateUser(ctx, protected)
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = rotateInBatches(ctx, au.AuthStore, "groups", interfaces.AuthStoreTxInterface.ListGroupIDs,
+		func(ctx context.Context, authStoreTx interfaces.AuthStoreTxInterface, groupID uuid.UUID) (bool, error) {
+			protectedBatch, err := authStoreTx.GetGroupDataBatch(ctx, []uuid.UUID{groupID})
+			if err != nil {
+				return false, err
+			}
+			if len(protectedBatch) != 1 {
+				return false, interfaces.ErrNotFound
+			}
+			protected := &protectedBatch[0]
+
+			wrappedKey, changed, err := au.GroupKeyRewrapper.Rewrap(protected.WrappedKey)
+			if err != nil || !changed {
+				return false, err
+			}
+
+			protected.WrappedKey = wrappedKey
+			return true, authStoreTx.UpdateGroup(ctx, protected)
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = rotateInBatches(ctx, au.AuthStore, "access objects", interfaces.AuthStoreTxInterface.ListAccessObjectIDs,
+		func(ctx context.Context, authStoreTx interfaces.AuthStoreTxInterface, objectID uuid.UUID) (bool, error) {
+			protected, err := authStoreTx.GetAccessObject(ctx, objectID)
+			if err != nil {
+				return false, err
+			}
+
+			wrappedKey, changed, err := au.AccessObjectKeyRewrapper.Rewrap(protected.WrappedKey)
+			if err != nil || !changed {
+				return false, err
+			}
+
+			protected.WrappedKey = wrappedKey
+			return true, authStoreTx.UpdateAccessObject(ctx, protected)
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// Erasure receipts are protected by the AEK as well
+	return rotateInBatches(ctx, au.AuthStore, "erasure receipts", interfaces.AuthStoreTxInterface.ListErasureReceiptIDs,
+		func(ctx context.Context, authStoreTx interfaces.AuthStoreTxInterface, objectID uuid.UUID) (bool, error) {
+			protected, err := authStoreTx.GetErasureReceipt(ctx, objectID)
+			if err != nil {
+				return false, err
+			}
+
+			wrappedKey, changed, err := au.AccessObjectKeyRewrapper.Rewrap(protected.WrappedKey)
+			if err != nil || !changed {
+				return false, err
+			}
+
+			protected.WrappedKey = wrappedKey
+			return true, authStoreTx.UpdateErasureReceipt(ctx, protected)
+		},
+	)
+}
+
+// newCLIContext creates a context for CLI operations
+func newCLIContext() (context.Context, error) {
+	// Need to inject requestID manually, as these calls don't pass the usual middleware
+	requestID, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(context.Background(), common.RequestIDCtxKey, requestID), nil
+}
+
+// rotateInBatches calls `rotate` for every record listed by `list`, using one transaction per batch
+func rotateInBatches(ctx context.Context, authStore interfaces.AuthStoreInterface, name string, list listIDsFunc, rotate rotateFunc) error {
+	var rotated, total int
+	after := uuid.Nil
+	for {
+		ids, n, err := rotateBatch(ctx, authStore, after, list, rotate)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		after = ids[len(ids)-1]
+		rotated += n
+		total += len(ids)
+		log.Infof(ctx, "Processed %d %s, %d rotated", total, name, rotated)
+	}
+
+	log.Infof(ctx, "Rotation done, %d of %d %s rotated", rotated, total, name)
+	return nil
+}
+
+// rotateBatch rotates the next batch of records following `after` in a single transaction. It
+// returns the processed IDs and the number of rotated records.
+func rotateBatch(ctx context.Context, authStore interfaces.AuthStoreInterface, after uuid.UUID, list listIDsFunc, rotate rotateFunc) ([]uuid.UUID, int, error) {
+	authStoreTx, err := authStore.NewTransaction(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		err := authStoreTx.Rollback(ctx)
+		if err != nil {
+			log.Error(ctx, err, "Performing rollback")
+		}
+	}()
+
+	ids, err := list(authStoreTx, ctx, after, rotateBatchSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rotated := 0
+	for _, id := range ids {
+		changed, err := rotate(ctx, authStoreTx, id)
+		if err != nil {
+			return nil, 0, err
+		}
+		if changed {
+			rotated++
+		}
+	}
+
+	if err := authStoreTx.Commit(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	return ids, rotated, nil
+}
@@ -0,0 +1,238 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package authz
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofrs/uuid"
+
+	"encryption-service/common"
+	authnimpl "encryption-service/impl/authn"
+	"encryption-service/impl/authstorage"
+	authzimpl "encryption-service/impl/authz"
+	"encryption-service/impl/crypt"
+)
+
+func TestRotateKEK(t *testing.T) {
+	authStore, err := authstorage.NewMemoryAuthStore(filepath.Join(t.TempDir(), "auth.db"))
+	if err != nil {
+		t.Fatalf("NewMemoryAuthStore failed: %v", err)
+	}
+	defer authStore.Close()
+
+	oldKeyRing, newKeyRing, rotatedKeyRing := newTestKeyRings(t)
+
+	// Create more access objects than fit in a single batch
+	ctx := context.Background()
+	keys := make(map[uuid.UUID][]byte)
+	tx, err := authStore.NewTransaction(ctx)
+	if err != nil {
+		t.Fatalf("NewTransaction failed: %v", err)
+	}
+	txCtx := context.WithValue(ctx, common.AuthStorageTxCtxKey, tx)
+	for i := 0; i < rotateBatchSize+5; i++ {
+		objectID := uuid.Must(uuid.NewV4())
+		keys[objectID], _ = crypt.Random(32)
+		woek, err := oldKeyRing.Wrap(keys[objectID])
+		if err != nil {
+			t.Fatalf("Wrap failed: %v", err)
+		}
+		if err := authorizer.CreateAccessObject(txCtx, objectID, userID, common.KeyTypeData, woek); err != nil {
+			t.Fatalf("CreateAccessObject failed: %v", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	authzService := &Authz{
+		Authorizer:       &authzimpl.Authorizer{AccessObjectCryptor: cryptor},
+		AuthStore:        authStore,
+		DataKeyRewrapper: newKeyRing,
+	}
+
+	// Running the rotation twice must yield the same result
+	for i := 0; i < 2; i++ {
+		if err := authzService.RotateKEKCLI(); err != nil {
+			t.Fatalf("RotateKEKCLI failed: %v", err)
+		}
+	}
+
+	tx, err = authStore.NewTransaction(ctx)
+	if err != nil {
+		t.Fatalf("NewTransaction failed: %v", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	txCtx = context.WithValue(ctx, common.AuthStorageTxCtxKey, tx)
+	for objectID, key := range keys {
+		accessObject, err := authzService.Authorizer.FetchAccessObject(txCtx, objectID)
+		if err != nil {
+			t.Fatalf("FetchAccessObject failed: %v", err)
+		}
+		if accessObject.Version != 1 {
+			t.Errorf("Access object updated %d times", accessObject.Version)
+		}
+
+		unwrapped, err := rotatedKeyRing.Unwrap(accessObject.Woek)
+		if err != nil {
+			t.Fatalf("Unwrap with new KEK failed: %v", err)
+		}
+		if !bytes.Equal(key, unwrapped) {
+			t.Fatal("Object key changed during rotation")
+		}
+	}
+}
+
+func newTestKeyRings(t *testing.T) (oldKeyRing, newKeyRing, rotatedKeyRing *crypt.KeyRing) {
+	oldKey, _ := crypt.Random(32)
+	newKey, _ := crypt.Random(32)
+
+	var err error
+	oldKeyRing, err = crypt.NewKWPKeyRing(0, map[uint32][]byte{0: oldKey})
+	if err != nil {
+		t.Fatalf("NewKWPKeyRing failed: %v", err)
+	}
+	newKeyRing, err = crypt.NewKWPKeyRing(1, map[uint32][]byte{0: oldKey, 1: newKey})
+	if err != nil {
+		t.Fatalf("NewKWPKeyRing failed: %v", err)
+	}
+	rotatedKeyRing, err = crypt.NewKWPKeyRing(1, map[uint32][]byte{1: newKey})
+	if err != nil {
+		t.Fatalf("NewKWPKeyRing failed: %v", err)
+	}
+
+	return oldKeyRing, newKeyRing, rotatedKeyRing
+}
+
+func TestRotateAuthKeys(t *testing.T) {
+	authStore, err := authstorage.NewMemoryAuthStore(filepath.Join(t.TempDir(), "auth.db"))
+	if err != nil {
+		t.Fatalf("NewMemoryAuthStore failed: %v", err)
+	}
+	defer authStore.Close()
+
+	oldAEKs, newAEKs, rotatedAEKs := newTestKeyRings(t)
+	oldUEKs, newUEKs, rotatedUEKs := newTestKeyRings(t)
+	oldGEKs, newGEKs, rotatedGEKs := newTestKeyRings(t)
+	tokenCryptor := crypt.NewAESCryptorWithKeyWrap(rotatedAEKs)
+
+	oldAuthorizer := &authzimpl.Authorizer{
+		AccessObjectCryptor: crypt.NewAESCryptorWithKeyWrap(oldAEKs),
+		ReceiptSigner:       receiptSigner,
+	}
+	oldAuthenticator := &authnimpl.UserAuthenticator{
+		TokenCryptor: tokenCryptor,
+		UserCryptor:  crypt.NewAESCryptorWithKeyWrap(oldUEKs),
+		GroupCryptor: crypt.NewAESCryptorWithKeyWrap(oldGEKs),
+	}
+
+	// Populate the Auth Storage using the old keys
+	ctx := context.Background()
+	tx, err := authStore.NewTransaction(ctx)
+	if err != nil {
+		t.Fatalf("NewTransaction failed: %v", err)
+	}
+	txCtx := context.WithValue(ctx, common.AuthStorageTxCtxKey, tx)
+
+	passwords := make(map[uuid.UUID]string)
+	for i := 0; i < 3; i++ {
+		userID, password, err := oldAuthenticator.NewUser(txCtx)
+		if err != nil {
+			t.Fatalf("NewUser failed: %v", err)
+		}
+		if err := oldAuthenticator.NewGroupWithID(txCtx, *userID, common.ScopeRead); err != nil {
+			t.Fatalf("NewGroupWithID failed: %v", err)
+		}
+		if err := oldAuthorizer.CreateAccessObject(txCtx, *userID, *userID, common.KeyTypeData, Woek); err != nil {
+			t.Fatalf("CreateAccessObject failed: %v", err)
+		}
+		passwords[*userID] = password
+	}
+
+	// Removed users are skipped
+	removedUserID, _, err := oldAuthenticator.NewUser(txCtx)
+	if err != nil {
+		t.Fatalf("NewUser failed: %v", err)
+	}
+	if err := oldAuthenticator.RemoveUser(txCtx, *removedUserID); err != nil {
+		t.Fatalf("RemoveUser failed: %v", err)
+	}
+
+	// Erasure receipts are rotated as well
+	shreddedID := uuid.Must(uuid.NewV4())
+	if err := oldAuthorizer.CreateAccessObject(txCtx, shreddedID, userID, common.KeyTypeData, Woek); err != nil {
+		t.Fatalf("CreateAccessObject failed: %v", err)
+	}
+	receipt, err := oldAuthorizer.ShredAccessObject(txCtx, shreddedID)
+	if err != nil {
+		t.Fatalf("ShredAccessObject failed: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	authzService := &Authz{
+		AuthStore:                authStore,
+		AccessObjectKeyRewrapper: newAEKs,
+		UserKeyRewrapper:         newUEKs,
+		GroupKeyRewrapper:        newGEKs,
+	}
+
+	// Running the rotation twice must yield the same result
+	for i := 0; i < 2; i++ {
+		if err := authzService.RotateAuthKeysCLI(); err != nil {
+			t.Fatalf("RotateAuthKeysCLI failed: %v", err)
+		}
+	}
+
+	// All records must be accessible without the old keys
+	rotatedAuthorizer := &authzimpl.Authorizer{AccessObjectCryptor: crypt.NewAESCryptorWithKeyWrap(rotatedAEKs)}
+	rotatedAuthenticator := &authnimpl.UserAuthenticator{
+		TokenCryptor: tokenCryptor,
+		UserCryptor:  crypt.NewAESCryptorWithKeyWrap(rotatedUEKs),
+		GroupCryptor: crypt.NewAESCryptorWithKeyWrap(rotatedGEKs),
+	}
+
+	tx, err = authStore.NewTransaction(ctx)
+	if err != nil {
+		t.Fatalf("NewTransaction failed: %v", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	txCtx = context.WithValue(ctx, common.AuthStorageTxCtxKey, tx)
+
+	for userID, password := range passwords {
+		if _, err := rotatedAuthenticator.LoginUser(txCtx, userID, password); err != nil {
+			t.Fatalf("LoginUser failed: %v", err)
+		}
+		accessObject, err := rotatedAuthorizer.FetchAccessObject(txCtx, userID)
+		if err != nil {
+			t.Fatalf("FetchAccessObject failed: %v", err)
+		}
+		if !bytes.Equal(accessObject.Woek, Woek) {
+			t.Fatal("Access object changed during rotation")
+		}
+	}
+
+	rotatedReceipt, err := rotatedAuthorizer.FetchErasureReceipt(txCtx, shreddedID)
+	if err != nil {
+		t.Fatalf("FetchErasureReceipt failed: %v", err)
+	}
+	if !bytes.Equal(rotatedReceipt.Signature, receipt.Signature) || rotatedReceipt.KeyID != receipt.KeyID {
+		t.Fatal("Erasure receipt signature changed during rotation")
+	}
+}
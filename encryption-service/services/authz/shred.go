@@ -0,0 +1,222 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package authz
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"encryption-service/common"
+	log "encryption-service/logger"
+)
+
+// ShredObjectCLI destroys the access object, and with it the key, of a single object and records an
+// erasure receipt. This function is intended to be used for CLI operation.
+func (au *Authz) ShredObjectCLI(objectIDString string) error {
+	objectID, err := uuid.FromString(objectIDString)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := newCLIContext()
+	if err != nil {
+		return err
+	}
+
+	_, err = au.shredObject(ctx, objectID, uuid.Nil)
+	return err
+}
+
+// ShredGroupCLI destroys the access objects, and with them the keys, of all objects that can only
+// be accessed by the given group and records an erasure receipt for each. Each object is shredded in
+// its own transaction, so the command can safely be re-run if it is interrupted. This function is
+// intended to be used for CLI operation.
+func (au *Authz) ShredGroupCLI(groupIDString string) error {
+	groupID, err := uuid.FromString(groupIDString)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := newCLIContext()
+	if err != nil {
+		return err
+	}
+
+	var shredded, total int
+	after := uuid.Nil
+	for {
+		objectIDs, err := au.listAccessObjectIDs(ctx, after)
+		if err != nil {
+			return err
+		}
+		if len(objectIDs) == 0 {
+			break
+		}
+
+		for _, objectID := range objectIDs {
+			changed, err := au.shredObject(ctx, objectID, groupID)
+			if err != nil {
+				log.Errorf(ctx, err, "Failed to shred object %s", objectID)
+				return err
+			}
+			if changed {
+				shredded++
+			}
+		}
+
+		after = objectIDs[len(objectIDs)-1]
+		total += len(objectIDs)
+		log.Infof(ctx, "Processed %d objects, %d shredded", total, shredded)
+	}
+
+	log.Infof(ctx, "Shredding done, %d of %d objects shredded", shredded, total)
+	return nil
+}
+
+// GetErasureReceiptCLI prints the signed erasure receipt of a shredded object to stdout as JSON, in
+// the same format as returned by the GetErasureReceipt endpoint. This function is intended to be
+// used for CLI operation.
+func (au *Authz) GetErasureReceiptCLI(objectIDString string) error {
+	objectID, err := uuid.FromString(objectIDString)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := newCLIContext()
+	if err != nil {
+		return err
+	}
+
+	receipt, err := au.fetchErasureReceipt(ctx, objectID)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := protojson.Marshal(newErasureReceipt(receipt))
+	if err != nil {
+		return err
+	}
+
+	log.Info(ctx, "Erasure receipt fetched, printing to stdout")
+	fmt.Println(string(encoded))
+	return nil
+}
+
+// receiptSigningKeyInfo is the JSON output of `ReceiptSigningKeyCLI`
+type receiptSigningKeyInfo struct {
+	KeyID uint32 `json:"keyId"`
+	// PKIX DER encoded public key
+	PublicKey []byte `json:"publicKey"`
+}
+
+// ReceiptSigningKeyCLI prints the ID and public key of the receipt signing key to stdout as JSON.
+// The public key should be published, such that verifiers of erasure receipts can pin it. This
+// function is intended to be used for CLI operation.
+func (au *Authz) ReceiptSigningKeyCLI() error {
+	if au.ReceiptSigner == nil {
+		return errors.New("no erasure receipt signer configured")
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(au.ReceiptSigner.PublicKey())
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(receiptSigningKeyInfo{KeyID: au.ReceiptSigningKeyID, PublicKey: publicKey})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(encoded))
+	return nil
+}
+
+// fetchErasureReceipt fetches the erasure receipt of an object in its own transaction
+func (au *Authz) fetchErasureReceipt(ctx context.Context, objectID uuid.UUID) (*common.ErasureReceipt, error) {
+	authStoreTx, err := au.AuthStore.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := authStoreTx.Rollback(ctx)
+		if err != nil {
+			log.Error(ctx, err, "Performing rollback")
+		}
+	}()
+	ctx = context.WithValue(ctx, common.AuthStorageTxCtxKey, authStoreTx)
+
+	return au.Authorizer.FetchErasureReceipt(ctx, objectID)
+}
+
+// listAccessObjectIDs lists the next batch of access object IDs following `after`
+func (au *Authz) listAccessObjectIDs(ctx context.Context, after uuid.UUID) ([]uuid.UUID, error) {
+	authStoreTx, err := au.AuthStore.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := authStoreTx.Rollback(ctx)
+		if err != nil {
+			log.Error(ctx, err, "Performing rollback")
+		}
+	}()
+
+	return authStoreTx.ListAccessObjectIDs(ctx, after, rotateBatchSize)
+}
+
+// shredObject shreds an object in its own transaction and reports whether it was shredded. If
+// `groupID` is not nil, the object is only shredded if that group is the only one with access.
+func (au *Authz) shredObject(ctx context.Context, objectID, groupID uuid.UUID) (bool, error) {
+	authStoreTx, err := au.AuthStore.NewTransaction(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		err := authStoreTx.Rollback(ctx)
+		if err != nil {
+			log.Error(ctx, err, "Performing rollback")
+		}
+	}()
+	ctx = context.WithValue(ctx, common.AuthStorageTxCtxKey, authStoreTx)
+
+	if groupID != uuid.Nil {
+		accessObject, err := au.Authorizer.FetchAccessObject(ctx, objectID)
+		if err != nil {
+			return false, err
+		}
+		groups := accessObject.GetGroups()
+		if len(groups) != 1 || !accessObject.ContainsGroup(groupID) {
+			return false, nil
+		}
+	}
+
+	receipt, err := au.Authorizer.ShredAccessObject(ctx, objectID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := authStoreTx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	log.Infof(ctx, "Object %s shredded at %s, receipt digest %x", receipt.ObjectID, receipt.ShreddedAt.Format(time.RFC3339), receipt.Digest)
+	return true, nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package authz
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gofrs/uuid"
+
+	"encryption-service/common"
+	"encryption-service/impl/authstorage"
+	"encryption-service/interfaces"
+)
+
+func TestShredCLI(t *testing.T) {
+	authStore, err := authstorage.NewMemoryAuthStore(filepath.Join(t.TempDir(), "auth.db"))
+	if err != nil {
+		t.Fatalf("NewMemoryAuthStore failed: %v", err)
+	}
+	defer authStore.Close()
+
+	groupID := uuid.Must(uuid.NewV4())
+	otherGroupID := uuid.Must(uuid.NewV4())
+
+	// Create more objects than fit in a single batch, only some of which are reachable by the group
+	// alone
+	ctx := context.Background()
+	tx, err := authStore.NewTransaction(ctx)
+	if err != nil {
+		t.Fatalf("NewTransaction failed: %v", err)
+	}
+	txCtx := context.WithValue(ctx, common.AuthStorageTxCtxKey, tx)
+	shredded := make(map[uuid.UUID]*common.ProtectedAccessObject)
+	kept := make(map[uuid.UUID]bool)
+	for i := 0; i < rotateBatchSize+5; i++ {
+		objectID := uuid.Must(uuid.NewV4())
+		if err := authorizer.CreateAccessObject(txCtx, objectID, groupID, common.KeyTypeData, Woek); err != nil {
+			t.Fatalf("CreateAccessObject failed: %v", err)
+		}
+
+		switch i % 3 {
+		case 0:
+			protected, err := tx.GetAccessObject(ctx, objectID)
+			if err != nil {
+				t.Fatalf("GetAccessObject failed: %v", err)
+			}
+			shredded[objectID] = protected
+		case 1:
+			accessObject := common.NewAccessObject(groupID, common.KeyTypeData, Woek)
+			accessObject.AddGroup(otherGroupID)
+			if err := authorizer.UpdateAccessObject(txCtx, objectID, *accessObject); err != nil {
+				t.Fatalf("UpdateAccessObject failed: %v", err)
+			}
+			kept[objectID] = true
+		case 2:
+			if err := authorizer.UpdateAccessObject(txCtx, objectID, *common.NewAccessObject(otherGroupID, common.KeyTypeData, Woek)); err != nil {
+				t.Fatalf("UpdateAccessObject failed: %v", err)
+			}
+			kept[objectID] = true
+		}
+	}
+	singleObjectID := uuid.Must(uuid.NewV4())
+	if err := authorizer.CreateAccessObject(txCtx, singleObjectID, otherGroupID, common.KeyTypeData, Woek); err != nil {
+		t.Fatalf("CreateAccessObject failed: %v", err)
+	}
+	protected, err := tx.GetAccessObject(ctx, singleObjectID)
+	if err != nil {
+		t.Fatalf("GetAccessObject failed: %v", err)
+	}
+	shredded[singleObjectID] = protected
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	authzService := &Authz{
+		Authorizer: authorizer,
+		AuthStore:  authStore,
+	}
+
+	if err := authzService.ShredGroupCLI(groupID.String()); err != nil {
+		t.Fatalf("ShredGroupCLI failed: %v", err)
+	}
+	// Running it again must not fail
+	if err := authzService.ShredGroupCLI(groupID.String()); err != nil {
+		t.Fatalf("ShredGroupCLI failed: %v", err)
+	}
+	if err := authzService.ShredObjectCLI(singleObjectID.String()); err != nil {
+		t.Fatalf("ShredObjectCLI failed: %v", err)
+	}
+	if err := authzService.GetErasureReceiptCLI(singleObjectID.String()); err != nil {
+		t.Fatalf("GetErasureReceiptCLI failed: %v", err)
+	}
+	receipt, err := authzService.fetchErasureReceipt(ctx, singleObjectID)
+	if err != nil {
+		t.Fatalf("fetchErasureReceipt failed: %v", err)
+	}
+	if ok, err := receiptSigner.Verify(receipt.SignedData(), receipt.Signature); err != nil || !ok {
+		t.Fatalf("Erasure receipt signature invalid: %v", err)
+	}
+	for objectID := range kept {
+		if err := authzService.GetErasureReceiptCLI(objectID.String()); !errors.Is(err, interfaces.ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound for object that was not shredded, got %v", err)
+		}
+		break
+	}
+
+	tx, err = authStore.NewTransaction(ctx)
+	if err != nil {
+		t.Fatalf("NewTransaction failed: %v", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	txCtx = context.WithValue(ctx, common.AuthStorageTxCtxKey, tx)
+
+	for objectID, protected := range shredded {
+		if _, err := authorizer.FetchAccessObject(txCtx, objectID); !errors.Is(err, interfaces.ErrNotFound) {
+			t.Fatalf("Access object %v not shredded: %v", objectID, err)
+		}
+		receipt, err := authorizer.FetchErasureReceipt(txCtx, objectID)
+		if err != nil {
+			t.Fatalf("FetchErasureReceipt failed: %v", err)
+		}
+		if !reflect.DeepEqual(receipt.Digest, common.AccessObjectDigest(protected)) {
+			t.Fatalf("Receipt digest of %v doesn't match the shredded access object", objectID)
+		}
+	}
+	for objectID := range kept {
+		if _, err := authorizer.FetchAccessObject(txCtx, objectID); err != nil {
+			t.Fatalf("Access object %v was shredded: %v", objectID, err)
+		}
+		if _, err := authorizer.FetchErasureReceipt(txCtx, objectID); !errors.Is(err, interfaces.ErrNotFound) {
+			t.Fatalf("Unexpected erasure receipt for %v: %v", objectID, err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package enc
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"encryption-service/common"
+	"encryption-service/impl/crypt"
+	"encryption-service/interfaces"
+	log "encryption-service/logger"
+)
+
+// Size of generated data keys in bytes
+const dataKeySize = 32
+
+// Associated data of wrapped data keys. It separates wrapped data keys from ciphertexts returned by
+// `Encrypt`, which are protected by the same kind of access object.
+var dataKeyAAD = []byte("data key")
+
+// API exposed function, generates a data key and wraps it with the key of a new access object
+// Returns the data key in plaintext and wrapped form together with the object ID
+func (enc *Enc) GenerateDataKey(ctx context.Context, request *GenerateDataKeyRequest) (*GenerateDataKeyResponse, error) {
+	userID, ok := ctx.Value(common.UserIDCtxKey).(uuid.UUID)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while generating data key")
+		log.Error(ctx, err, "GenerateDataKey: Could not typecast userID to uuid.UUID")
+		return nil, err
+	}
+
+	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while generating data key")
+		log.Error(ctx, err, "GenerateDataKey: Could not typecast authstorage to AuthStoreTxInterface")
+		return nil, err
+	}
+
+	objectID, err := uuid.NewV4()
+	if err != nil {
+		log.Error(ctx, err, "GenerateDataKey: Failed to generate new object ID")
+		return nil, status.Errorf(codes.Internal, "error encountered while generating data key")
+	}
+	objectIDString := objectID.String()
+
+	dataKey, err := crypt.Random(dataKeySize)
+	if err != nil {
+		log.Error(ctx, err, "GenerateDataKey: Failed to generate data key")
+		return nil, status.Errorf(codes.Internal, "error encountered while generating data key")
+	}
+
+	aad := common.DataAAD(objectID, common.InitialDataVersion, dataKeyAAD)
+	woek, wrappedDataKey, err := enc.DataCryptor.Encrypt(dataKey, aad)
+	if err != nil {
+		log.Error(ctx, err, "GenerateDataKey: Failed to wrap data key")
+		return nil, status.Errorf(codes.Internal, "error encountered while generating data key")
+	}
+
+	err = enc.Authorizer.CreateAccessObject(ctx, objectID, userID, common.KeyTypeDataKey, woek)
+	if err != nil {
+		log.Error(ctx, err, "GenerateDataKey: Failed to create new access object")
+		return nil, status.Errorf(codes.Internal, "error encountered while generating data key")
+	}
+
+	if err := authStorageTx.Commit(ctx); err != nil {
+		log.Error(ctx, err, "GenerateDataKey: Failed to commit auth storage transaction")
+		return nil, status.Errorf(codes.Internal, "error encountered while generating data key")
+	}
+
+	ctx = context.WithValue(ctx, common.ObjectIDCtxKey, objectIDString)
+	log.Info(ctx, "GenerateDataKey: Data key generated")
+
+	return &GenerateDataKeyResponse{
+		DataKey:        dataKey,
+		WrappedDataKey: wrappedDataKey,
+		ObjectId:       objectIDString,
+	}, nil
+}
+
+// API exposed function, unwraps a data key generated by `GenerateDataKey`
+// and returns the plaintext data key in the response
+func (enc *Enc) DecryptDataKey(ctx context.Context, request *DecryptDataKeyRequest) (*DecryptDataKeyResponse, error) {
+	accessObject, ok := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while decrypting data key")
+		log.Error(ctx, err, "DecryptDataKey: Could not typecast access object to AccessObject")
+		return nil, err
+	}
+
+	if !accessObject.HasKeyType(common.KeyTypeDataKey) {
+		err := status.Errorf(codes.InvalidArgument, "object is not a data key")
+		log.Error(ctx, err, "DecryptDataKey: Object has the wrong key type")
+		return nil, err
+	}
+
+	objectID, err := uuid.FromString(request.ObjectId)
+	if err != nil {
+		log.Errorf(ctx, err, "DecryptDataKey: Failed to parse object ID %s as UUID", request.ObjectId)
+		return nil, status.Errorf(codes.InvalidArgument, "invalid object ID")
+	}
+
+	aad := common.DataAAD(objectID, accessObject.DataVersion, dataKeyAAD)
+	dataKey, err := enc.DataCryptor.Decrypt(accessObject.GetWOEK(), request.WrappedDataKey, aad)
+	if err != nil {
+		log.Error(ctx, err, "DecryptDataKey: Failed to unwrap data key")
+		return nil, status.Errorf(codes.Internal, "error encountered while decrypting data key")
+	}
+
+	log.Info(ctx, "DecryptDataKey: Data key decrypted")
+
+	return &DecryptDataKeyResponse{
+		DataKey: dataKey,
+	}, nil
+}
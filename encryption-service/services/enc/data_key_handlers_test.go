@@ -0,0 +1,128 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package enc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid"
+
+	"encryption-service/common"
+)
+
+func TestGenerateDecryptDataKey(t *testing.T) {
+	ctx := setCtxKeys()
+
+	generateResponse, err := enc.GenerateDataKey(ctx, &GenerateDataKeyRequest{})
+	if err != nil {
+		t.Fatalf("Generating data key failed: %v", err)
+	}
+	if len(generateResponse.DataKey) != dataKeySize {
+		t.Fatalf("Data key has wrong size: %d", len(generateResponse.DataKey))
+	}
+	if bytes.Contains(generateResponse.WrappedDataKey, generateResponse.DataKey) {
+		t.Fatalf("Wrapped data key contains the plaintext data key")
+	}
+
+	accessObject, err := enc.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(generateResponse.ObjectId))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %v", err)
+	}
+	ctx = context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+
+	decryptResponse, err := enc.DecryptDataKey(ctx, &DecryptDataKeyRequest{
+		ObjectId:       generateResponse.ObjectId,
+		WrappedDataKey: generateResponse.WrappedDataKey,
+	})
+	if err != nil {
+		t.Fatalf("Decrypting data key failed: %v", err)
+	}
+	if !bytes.Equal(decryptResponse.DataKey, generateResponse.DataKey) {
+		t.Fatalf("Decrypted data key does not equal generated data key")
+	}
+}
+
+func TestGenerateDataKeyUnique(t *testing.T) {
+	ctx := setCtxKeys()
+
+	first, err := enc.GenerateDataKey(ctx, &GenerateDataKeyRequest{})
+	if err != nil {
+		t.Fatalf("Generating data key failed: %v", err)
+	}
+	second, err := enc.GenerateDataKey(ctx, &GenerateDataKeyRequest{})
+	if err != nil {
+		t.Fatalf("Generating data key failed: %v", err)
+	}
+
+	if first.ObjectId == second.ObjectId {
+		t.Fatalf("Data keys share object ID")
+	}
+	if bytes.Equal(first.DataKey, second.DataKey) {
+		t.Fatalf("Generated data keys are equal")
+	}
+}
+
+func TestDecryptDataKeyWrongOID(t *testing.T) {
+	ctx := setCtxKeys()
+
+	first, err := enc.GenerateDataKey(ctx, &GenerateDataKeyRequest{})
+	if err != nil {
+		t.Fatalf("Generating data key failed: %v", err)
+	}
+	second, err := enc.GenerateDataKey(ctx, &GenerateDataKeyRequest{})
+	if err != nil {
+		t.Fatalf("Generating data key failed: %v", err)
+	}
+
+	// Authorized for the second object, but presenting the wrapped key of the first one
+	accessObject, err := enc.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(second.ObjectId))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %v", err)
+	}
+	ctx = context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+
+	_, err = enc.DecryptDataKey(ctx, &DecryptDataKeyRequest{
+		ObjectId:       second.ObjectId,
+		WrappedDataKey: first.WrappedDataKey,
+	})
+	if err == nil {
+		t.Fatalf("Decrypting data key of another object should have failed")
+	}
+}
+
+func TestDecryptDataKeyAsCiphertext(t *testing.T) {
+	ctx := setCtxKeys()
+
+	generateResponse, err := enc.GenerateDataKey(ctx, &GenerateDataKeyRequest{})
+	if err != nil {
+		t.Fatalf("Generating data key failed: %v", err)
+	}
+
+	accessObject, err := enc.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(generateResponse.ObjectId))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %v", err)
+	}
+	ctx = context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+
+	// A wrapped data key must not be accepted by Decrypt
+	_, err = enc.Decrypt(ctx, &DecryptRequest{
+		ObjectId:   generateResponse.ObjectId,
+		Ciphertext: generateResponse.WrappedDataKey,
+	})
+	if err == nil {
+		t.Fatalf("Decrypting wrapped data key as ciphertext should have failed")
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package enc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"encryption-service/common"
+	"encryption-service/impl/crypt"
+	"encryption-service/interfaces"
+	log "encryption-service/logger"
+)
+
+// Size of deterministic keys in bytes. AES-SIV splits the key into a MAC and an encryption key, so
+// this gives AES-256 for both.
+const deterministicKeySize = 64
+
+// Namespace of the object IDs of deterministic keys, which are derived from the group ID
+var deterministicKeyNamespace = uuid.Must(uuid.FromString("4d1a02ef-9f03-45f5-b865-380b6d3b73a4"))
+
+// API exposed function, returns the object ID of the deterministic encryption key of the requested
+// group, or of the user's own group if none is requested. The object ID is derived from the group ID.
+// A random key is created under this ID when it is first requested, and returned to all members of
+// the group afterwards. Equal values encrypted by members of the same group thus give equal
+// ciphertexts.
+func (enc *Enc) CreateDeterministicKey(ctx context.Context, request *CreateDeterministicKeyRequest) (*CreateDeterministicKeyResponse, error) {
+	userID, ok := ctx.Value(common.UserIDCtxKey).(uuid.UUID)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while creating deterministic key")
+		log.Error(ctx, err, "CreateDeterministicKey: Could not typecast userID to uuid.UUID")
+		return nil, err
+	}
+
+	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while creating deterministic key")
+		log.Error(ctx, err, "CreateDeterministicKey: Could not typecast authstorage to AuthStoreTxInterface")
+		return nil, err
+	}
+
+	// Every user is a member of the group with their own ID
+	groupID := userID
+	if request.GroupId != "" {
+		var err error
+		groupID, err = uuid.FromString(request.GroupId)
+		if err != nil {
+			log.Errorf(ctx, err, "CreateDeterministicKey: Failed to parse group ID %s as UUID", request.GroupId)
+			return nil, status.Errorf(codes.InvalidArgument, "invalid group ID")
+		}
+	}
+
+	if groupID != userID {
+		userData, err := enc.UserAuthenticator.GetUserData(ctx, userID)
+		if err != nil {
+			log.Error(ctx, err, "CreateDeterministicKey: Failed to fetch user data")
+			return nil, status.Errorf(codes.Internal, "error encountered while creating deterministic key")
+		}
+		if !userData.GroupIDs[groupID] {
+			log.Warn(ctx, "CreateDeterministicKey: User is not a member of the group")
+			return nil, status.Errorf(codes.PermissionDenied, "user is not a member of the group")
+		}
+	}
+
+	objectID := uuid.NewV5(deterministicKeyNamespace, groupID.String())
+	objectIDString := objectID.String()
+	ctx = context.WithValue(ctx, common.ObjectIDCtxKey, objectIDString)
+
+	// The key of the group is only created once and returned to all members afterwards
+	_, err := enc.Authorizer.FetchAccessObject(ctx, objectID)
+	if err == nil {
+		log.Info(ctx, "CreateDeterministicKey: Key exists")
+		return &CreateDeterministicKeyResponse{ObjectId: objectIDString}, nil
+	}
+	if !errors.Is(err, interfaces.ErrNotFound) {
+		log.Error(ctx, err, "CreateDeterministicKey: Failed to fetch access object")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating deterministic key")
+	}
+
+	// The object ID of a shredded key stays reserved for its erasure receipt
+	_, err = enc.Authorizer.FetchErasureReceipt(ctx, objectID)
+	if err == nil {
+		log.Warn(ctx, "CreateDeterministicKey: Key of the group has been shredded")
+		return nil, status.Errorf(codes.FailedPrecondition, "deterministic key of the group has been shredded")
+	}
+	if !errors.Is(err, interfaces.ErrNotFound) {
+		log.Error(ctx, err, "CreateDeterministicKey: Failed to fetch erasure receipt")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating deterministic key")
+	}
+
+	key, err := crypt.Random(deterministicKeySize)
+	if err != nil {
+		log.Error(ctx, err, "CreateDeterministicKey: Failed to generate key")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating deterministic key")
+	}
+
+	wrappedKey, err := enc.KeyWrapper.Wrap(key)
+	if err != nil {
+		log.Error(ctx, err, "CreateDeterministicKey: Failed to wrap key")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating deterministic key")
+	}
+
+	err = enc.Authorizer.CreateAccessObject(ctx, objectID, groupID, common.KeyTypeDeterministic, wrappedKey)
+	if err == nil {
+		err = authStorageTx.Commit(ctx)
+	}
+	if err != nil {
+		// A concurrent request may have created the key of the group in the meantime. Depending on
+		// the auth storage this fails with ErrAlreadyExists or with a serialization failure, so look
+		// for the key in either case.
+		exists, lookupErr := enc.deterministicKeyExists(ctx, authStorageTx, objectID)
+		if lookupErr == nil && exists {
+			log.Info(ctx, "CreateDeterministicKey: Concurrently created key returned")
+			return &CreateDeterministicKeyResponse{ObjectId: objectIDString}, nil
+		}
+		if lookupErr != nil {
+			log.Error(ctx, lookupErr, "CreateDeterministicKey: Failed to look up concurrently created key")
+		}
+		log.Error(ctx, err, "CreateDeterministicKey: Failed to create new access object")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating deterministic key")
+	}
+
+	log.Info(ctx, "CreateDeterministicKey: Key created")
+
+	return &CreateDeterministicKeyResponse{
+		ObjectId: objectIDString,
+	}, nil
+}
+
+// deterministicKeyExists checks in a new auth storage transaction whether the access object of a
+// deterministic key exists. It is used when the transaction of the request failed, which is rolled
+// back first, as the auth storage may not allow a second transaction while it is open.
+func (enc *Enc) deterministicKeyExists(ctx context.Context, failedTx interfaces.AuthStoreTxInterface, objectID uuid.UUID) (bool, error) {
+	if err := failedTx.Rollback(ctx); err != nil {
+		return false, err
+	}
+
+	authStoreTx, err := enc.AuthStore.NewTransaction(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		err := authStoreTx.Rollback(ctx)
+		if err != nil {
+			log.Error(ctx, err, "Performing rollback")
+		}
+	}()
+
+	_, err = authStoreTx.GetAccessObject(ctx, objectID)
+	if errors.Is(err, interfaces.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// API exposed function, deterministically encrypts provided plaintext under the key of the requested
+// object and returns it with the object ID in the response
+func (enc *Enc) EncryptDeterministic(ctx context.Context, request *EncryptDeterministicRequest) (*EncryptResponse, error) {
+	accessObject, ok := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while encrypting object")
+		log.Error(ctx, err, "EncryptDeterministic: Could not typecast access object to AccessObject")
+		return nil, err
+	}
+
+	if !accessObject.HasKeyType(common.KeyTypeDeterministic) {
+		err := status.Errorf(codes.InvalidArgument, "object is not a deterministic encryption key")
+		log.Error(ctx, err, "EncryptDeterministic: Object has the wrong key type")
+		return nil, err
+	}
+
+	objectID, err := uuid.FromString(request.ObjectId)
+	if err != nil {
+		log.Errorf(ctx, err, "EncryptDeterministic: Failed to parse object ID %s as UUID", request.ObjectId)
+		return nil, status.Errorf(codes.InvalidArgument, "invalid object ID")
+	}
+
+	siv, err := enc.newAESSIV(accessObject)
+	if err != nil {
+		log.Error(ctx, err, "EncryptDeterministic: Failed to create cipher")
+		return nil, status.Errorf(codes.Internal, "error encountered while encrypting object")
+	}
+
+	ciphertext, err := siv.Seal(request.Plaintext, objectID.Bytes(), request.AssociatedData)
+	if err != nil {
+		log.Error(ctx, err, "EncryptDeterministic: Failed to encrypt object")
+		return nil, status.Errorf(codes.Internal, "error encountered while encrypting object")
+	}
+
+	log.Info(ctx, "EncryptDeterministic: Object encrypted")
+
+	return &EncryptResponse{
+		Ciphertext:     ciphertext,
+		AssociatedData: request.AssociatedData,
+		ObjectId:       request.ObjectId,
+	}, nil
+}
+
+// API exposed function, decrypts provided ciphertext under the key of the requested object
+// and returns the plaintext in the response
+func (enc *Enc) DecryptDeterministic(ctx context.Context, request *DecryptRequest) (*DecryptResponse, error) {
+	accessObject, ok := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while decrypting object")
+		log.Error(ctx, err, "DecryptDeterministic: Could not typecast access object to AccessObject")
+		return nil, err
+	}
+
+	if !accessObject.HasKeyType(common.KeyTypeDeterministic) {
+		err := status.Errorf(codes.InvalidArgument, "object is not a deterministic encryption key")
+		log.Error(ctx, err, "DecryptDeterministic: Object has the wrong key type")
+		return nil, err
+	}
+
+	objectID, err := uuid.FromString(request.ObjectId)
+	if err != nil {
+		log.Errorf(ctx, err, "DecryptDeterministic: Failed to parse object ID %s as UUID", request.ObjectId)
+		return nil, status.Errorf(codes.InvalidArgument, "invalid object ID")
+	}
+
+	siv, err := enc.newAESSIV(accessObject)
+	if err != nil {
+		log.Error(ctx, err, "DecryptDeterministic: Failed to create cipher")
+		return nil, status.Errorf(codes.Internal, "error encountered while decrypting object")
+	}
+
+	plaintext, err := siv.Open(request.Ciphertext, objectID.Bytes(), request.AssociatedData)
+	if err != nil {
+		log.Error(ctx, err, "DecryptDeterministic: Failed to decrypt object")
+		return nil, status.Errorf(codes.Internal, "error encountered while decrypting object")
+	}
+
+	return &DecryptResponse{
+		Plaintext:      plaintext,
+		AssociatedData: request.AssociatedData,
+	}, nil
+}
+
+// newAESSIV unwraps the key of the access object and returns an AES-SIV instance for it. The object
+// ID is authenticated as the first associated data component, such that ciphertexts cannot be moved
+// between keys.
+func (enc *Enc) newAESSIV(accessObject *common.AccessObject) (*crypt.AESSIV, error) {
+	key, err := enc.KeyWrapper.Unwrap(accessObject.GetWOEK())
+	if err != nil {
+		return nil, err
+	}
+
+	return crypt.NewAESSIV(key)
+}
@@ -0,0 +1,302 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package enc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"encryption-service/common"
+	"encryption-service/impl/authn"
+	"encryption-service/impl/authstorage"
+	"encryption-service/interfaces"
+)
+
+// createDeterministicKey creates the deterministic key of a new user and returns its object ID and a
+// context of the user authorized for it
+func createDeterministicKey(t *testing.T) (string, context.Context) {
+	ctx := context.WithValue(setCtxKeys(), common.UserIDCtxKey, uuid.Must(uuid.NewV4()))
+
+	createResponse, err := enc.CreateDeterministicKey(ctx, &CreateDeterministicKeyRequest{})
+	if err != nil {
+		t.Fatalf("Creating deterministic key failed: %v", err)
+	}
+
+	accessObject, err := enc.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(createResponse.ObjectId))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %v", err)
+	}
+
+	return createResponse.ObjectId, context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+}
+
+func TestEncryptDeterministic(t *testing.T) {
+	objectID, ctx := createDeterministicKey(t)
+
+	request := &EncryptDeterministicRequest{
+		ObjectId:       objectID,
+		Plaintext:      []byte("plaintext_bytes"),
+		AssociatedData: []byte("associated_data_bytes"),
+	}
+	first, err := enc.EncryptDeterministic(ctx, request)
+	if err != nil {
+		t.Fatalf("Encrypting object failed: %v", err)
+	}
+	second, err := enc.EncryptDeterministic(ctx, request)
+	if err != nil {
+		t.Fatalf("Encrypting object failed: %v", err)
+	}
+	if !bytes.Equal(first.Ciphertext, second.Ciphertext) {
+		t.Fatalf("Equal plaintexts encrypted to different ciphertexts")
+	}
+
+	decryptResponse, err := enc.DecryptDeterministic(ctx, &DecryptRequest{
+		ObjectId:       first.ObjectId,
+		Ciphertext:     first.Ciphertext,
+		AssociatedData: first.AssociatedData,
+	})
+	if err != nil {
+		t.Fatalf("Decrypting object failed: %v", err)
+	}
+	if !bytes.Equal(decryptResponse.Plaintext, request.Plaintext) {
+		t.Fatalf("Decrypted plaintext does not equal original plaintext!")
+	}
+}
+
+func TestEncryptDeterministicDifferentKeys(t *testing.T) {
+	objectID, ctx := createDeterministicKey(t)
+	otherObjectID, otherCtx := createDeterministicKey(t)
+
+	plaintext := []byte("plaintext_bytes")
+	first, err := enc.EncryptDeterministic(ctx, &EncryptDeterministicRequest{ObjectId: objectID, Plaintext: plaintext})
+	if err != nil {
+		t.Fatalf("Encrypting object failed: %v", err)
+	}
+	second, err := enc.EncryptDeterministic(otherCtx, &EncryptDeterministicRequest{ObjectId: otherObjectID, Plaintext: plaintext})
+	if err != nil {
+		t.Fatalf("Encrypting object failed: %v", err)
+	}
+	if bytes.Equal(first.Ciphertext, second.Ciphertext) {
+		t.Fatalf("Equal plaintexts encrypted to equal ciphertexts under different keys")
+	}
+
+	// The ciphertext is bound to the object ID
+	_, err = enc.DecryptDeterministic(ctx, &DecryptRequest{ObjectId: otherObjectID, Ciphertext: first.Ciphertext})
+	if err == nil {
+		t.Fatalf("Decrypting object with wrong object ID should have failed")
+	}
+}
+
+func TestDecryptDeterministicWrongAAD(t *testing.T) {
+	objectID, ctx := createDeterministicKey(t)
+
+	encryptResponse, err := enc.EncryptDeterministic(ctx, &EncryptDeterministicRequest{
+		ObjectId:       objectID,
+		Plaintext:      []byte("plaintext_bytes"),
+		AssociatedData: []byte("associated_data_bytes"),
+	})
+	if err != nil {
+		t.Fatalf("Encrypting object failed: %v", err)
+	}
+
+	_, err = enc.DecryptDeterministic(ctx, &DecryptRequest{
+		ObjectId:       objectID,
+		Ciphertext:     encryptResponse.Ciphertext,
+		AssociatedData: []byte("wrong_associated_data"),
+	})
+	if err == nil {
+		t.Fatalf("Decrypting object with wrong associated data should have failed")
+	}
+}
+
+// Test that all members of a group get the same deterministic key
+func TestDeterministicKeyPerGroup(t *testing.T) {
+	groupID := uuid.Must(uuid.NewV4())
+	userData := map[uuid.UUID]*common.UserData{}
+	groupEnc := enc
+	groupEnc.UserAuthenticator = &authn.UserAuthenticatorMock{
+		GetUserDataFunc: func(ctx context.Context, userID uuid.UUID) (*common.UserData, error) {
+			return userData[userID], nil
+		},
+	}
+
+	plaintext := []byte("plaintext_bytes")
+	var objectIDs []string
+	var ciphertexts [][]byte
+	for i := 0; i < 2; i++ {
+		memberID := uuid.Must(uuid.NewV4())
+		userData[memberID] = &common.UserData{GroupIDs: map[uuid.UUID]bool{memberID: true, groupID: true}}
+		ctx := context.WithValue(setCtxKeys(), common.UserIDCtxKey, memberID)
+
+		createResponse, err := groupEnc.CreateDeterministicKey(ctx, &CreateDeterministicKeyRequest{GroupId: groupID.String()})
+		if err != nil {
+			t.Fatalf("Creating deterministic key failed: %v", err)
+		}
+
+		accessObject, err := groupEnc.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(createResponse.ObjectId))
+		if err != nil {
+			t.Fatalf("Failed to fetch access object: %v", err)
+		}
+		if !accessObject.ContainsGroup(groupID) {
+			t.Fatalf("Group has no access to its deterministic key")
+		}
+
+		ctx = context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+		encryptResponse, err := groupEnc.EncryptDeterministic(ctx, &EncryptDeterministicRequest{ObjectId: createResponse.ObjectId, Plaintext: plaintext})
+		if err != nil {
+			t.Fatalf("Encrypting object failed: %v", err)
+		}
+
+		objectIDs = append(objectIDs, createResponse.ObjectId)
+		ciphertexts = append(ciphertexts, encryptResponse.Ciphertext)
+	}
+	if objectIDs[0] != objectIDs[1] {
+		t.Fatalf("Members of the same group got different keys: %v != %v", objectIDs[0], objectIDs[1])
+	}
+	if !bytes.Equal(ciphertexts[0], ciphertexts[1]) {
+		t.Fatalf("Equal plaintexts encrypted by members of the same group gave different ciphertexts")
+	}
+
+	outsiderID := uuid.Must(uuid.NewV4())
+	userData[outsiderID] = &common.UserData{GroupIDs: map[uuid.UUID]bool{outsiderID: true}}
+	ctx := context.WithValue(setCtxKeys(), common.UserIDCtxKey, outsiderID)
+	_, err := groupEnc.CreateDeterministicKey(ctx, &CreateDeterministicKeyRequest{GroupId: groupID.String()})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Creating the key of another group should have been denied: %v", err)
+	}
+
+	_, err = groupEnc.CreateDeterministicKey(ctx, &CreateDeterministicKeyRequest{GroupId: "not a uuid"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Creating the key of an invalid group should have failed: %v", err)
+	}
+}
+
+// Test that the key of a group is random, such that it can't be recreated once deleted or shredded
+func TestDeterministicKeyRandom(t *testing.T) {
+	ctx := context.WithValue(setCtxKeys(), common.UserIDCtxKey, uuid.Must(uuid.NewV4()))
+
+	createKey := func() (uuid.UUID, []byte) {
+		createResponse, err := enc.CreateDeterministicKey(ctx, &CreateDeterministicKeyRequest{})
+		if err != nil {
+			t.Fatalf("Creating deterministic key failed: %v", err)
+		}
+		objectID := uuid.FromStringOrNil(createResponse.ObjectId)
+
+		accessObject, err := enc.Authorizer.FetchAccessObject(ctx, objectID)
+		if err != nil {
+			t.Fatalf("Failed to fetch access object: %v", err)
+		}
+		key, err := enc.KeyWrapper.Unwrap(accessObject.GetWOEK())
+		if err != nil {
+			t.Fatalf("Failed to unwrap key: %v", err)
+		}
+		return objectID, key
+	}
+
+	objectID, key := createKey()
+
+	// The existing key is returned
+	existingID, existingKey := createKey()
+	if existingID != objectID || !bytes.Equal(existingKey, key) {
+		t.Fatalf("Existing key not returned")
+	}
+
+	// A deleted key is replaced by a new key under the same object ID
+	if err := enc.Authorizer.DeleteAccessObject(ctx, objectID); err != nil {
+		t.Fatalf("Deleting access object failed: %v", err)
+	}
+	recreatedID, recreatedKey := createKey()
+	if recreatedID != objectID {
+		t.Fatalf("Recreated key has a different object ID: %v != %v", recreatedID, objectID)
+	}
+	if bytes.Equal(recreatedKey, key) {
+		t.Fatalf("Deleted key was recreated")
+	}
+
+	// The object ID of a shredded key stays reserved for its erasure receipt
+	if _, err := enc.Authorizer.ShredAccessObject(ctx, objectID); err != nil {
+		t.Fatalf("Shredding key failed: %v", err)
+	}
+	_, err := enc.CreateDeterministicKey(ctx, &CreateDeterministicKeyRequest{})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Creating a shredded key anew should have failed: %v", err)
+	}
+}
+
+// Test that a key created by a concurrent request is returned if the insert fails
+func TestDeterministicKeyConcurrent(t *testing.T) {
+	userID := uuid.Must(uuid.NewV4())
+	objectID := uuid.NewV5(deterministicKeyNamespace, userID.String())
+	rolledBack := false
+
+	failedTx := &authstorage.AuthStoreTxMock{
+		GetAccessObjectFunc: func(ctx context.Context, objectID uuid.UUID) (*common.ProtectedAccessObject, error) {
+			return nil, interfaces.ErrNotFound
+		},
+		GetErasureReceiptFunc: func(ctx context.Context, objectID uuid.UUID) (*common.ProtectedErasureReceipt, error) {
+			return nil, interfaces.ErrNotFound
+		},
+		InsertAcccessObjectFunc: func(ctx context.Context, protected *common.ProtectedAccessObject) error {
+			return interfaces.ErrAlreadyExists
+		},
+		RollbackFunc: func(ctx context.Context) error {
+			rolledBack = true
+			return nil
+		},
+	}
+	existing := false
+	lookupTx := &authstorage.AuthStoreTxMock{
+		GetAccessObjectFunc: func(ctx context.Context, oid uuid.UUID) (*common.ProtectedAccessObject, error) {
+			if !existing || oid != objectID {
+				return nil, interfaces.ErrNotFound
+			}
+			return &common.ProtectedAccessObject{ObjectID: oid}, nil
+		},
+		RollbackFunc: func(ctx context.Context) error {
+			return nil
+		},
+	}
+	concurrentEnc := enc
+	concurrentEnc.AuthStore = &authstorage.AuthStoreMock{
+		NewTransactionFunc: func(ctx context.Context) (interfaces.AuthStoreTxInterface, error) {
+			return lookupTx, nil
+		},
+	}
+
+	ctx := context.WithValue(context.Background(), common.UserIDCtxKey, userID)
+	ctx = context.WithValue(ctx, common.AuthStorageTxCtxKey, failedTx)
+
+	// The insert failed for another reason
+	_, err := concurrentEnc.CreateDeterministicKey(ctx, &CreateDeterministicKeyRequest{})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("Expected Internal, got %v", err)
+	}
+
+	existing = true
+	response, err := concurrentEnc.CreateDeterministicKey(ctx, &CreateDeterministicKeyRequest{})
+	if err != nil {
+		t.Fatalf("Creating deterministic key failed: %v", err)
+	}
+	if response.ObjectId != objectID.String() {
+		t.Fatalf("Unexpected object ID: %v != %v", response.ObjectId, objectID)
+	}
+	if !rolledBack {
+		t.Fatal("Failed transaction not rolled back")
+	}
+}
@@ -14,6 +14,7 @@
 package enc
 
 import (
+	"encryption-service/common"
 	"encryption-service/interfaces"
 )
 
@@ -22,5 +23,16 @@ type Enc struct {
 	Authorizer  interfaces.AccessObjectAuthenticatorInterface
 	AuthStore   interfaces.AuthStoreInterface
 	DataCryptor interfaces.CryptorInterface
+	KeyWrapper  interfaces.KeyWrapperInterface
+	// MaxEncryptionsPerKey is the number of encryptions allowed under an imported key. If zero,
+	// common.DefaultMaxEncryptionsPerKey is used.
+	MaxEncryptionsPerKey uint64
+	// UserAuthenticator is used to check group membership when creating deterministic keys
+	UserAuthenticator interfaces.UserAuthenticatorInterface
 	UnimplementedEncryptonizeServer
 }
+
+// maxEncryptionsPerKey returns the configured number of encryptions allowed under an imported key
+func (enc *Enc) maxEncryptionsPerKey() uint64 {
+	return common.MaxEncryptionsPerKey(enc.MaxEncryptionsPerKey)
+}
@@ -2,6 +2,7 @@ package enc
 
 import (
 	"context"
+	"errors"
 
 	"github.com/gofrs/uuid"
 	"google.golang.org/grpc/codes"
@@ -37,13 +38,33 @@ func (enc *Enc) Encrypt(ctx context.Context, request *EncryptRequest) (*EncryptR
 		return nil, err
 	}
 
-	woek, ciphertext, err := enc.DataCryptor.Encrypt(request.Plaintext, request.AssociatedData)
-	if err != nil {
-		log.Error(ctx, err, "Encrypt: Failed to encrypt object")
-		return nil, status.Errorf(codes.Internal, "error encountered while encrypting object")
-	}
+	aad := common.DataAAD(objectID, common.InitialDataVersion, request.AssociatedData)
+	var ciphertext []byte
+	if request.KeyId == "" {
+		var woek []byte
+		woek, ciphertext, err = enc.DataCryptor.Encrypt(request.Plaintext, aad)
+		if err != nil {
+			log.Error(ctx, err, "Encrypt: Failed to encrypt object")
+			return nil, status.Errorf(codes.Internal, "error encountered while encrypting object")
+		}
+
+		err = enc.Authorizer.CreateAccessObject(ctx, objectID, userID, common.KeyTypeData, woek)
+	} else {
+		var keyID uuid.UUID
+		var woek []byte
+		keyID, woek, err = enc.importedKey(ctx, "Encrypt", request.KeyId)
+		if err != nil {
+			return nil, err
+		}
+
+		ciphertext, err = enc.DataCryptor.EncryptWithKey(request.Plaintext, aad, woek)
+		if err != nil {
+			log.Error(ctx, err, "Encrypt: Failed to encrypt object")
+			return nil, status.Errorf(codes.Internal, "error encountered while encrypting object")
+		}
 
-	err = enc.Authorizer.CreateAccessObject(ctx, objectID, userID, woek)
+		err = enc.Authorizer.CreateAccessObjectWithKeyID(ctx, objectID, userID, keyID)
+	}
 	if err != nil {
 		log.Error(ctx, err, "Encrypt: Failed to create new access object")
 		return nil, status.Errorf(codes.Internal, "error encountered while encrypting object")
@@ -74,7 +95,30 @@ func (enc *Enc) Decrypt(ctx context.Context, request *DecryptRequest) (*DecryptR
 		return nil, err
 	}
 
-	plaintext, err := enc.DataCryptor.Decrypt(accessObject.GetWOEK(), request.Ciphertext, request.AssociatedData)
+	if !accessObject.HasKeyType(common.KeyTypeData) && !accessObject.HasKeyType(common.KeyTypeImported) {
+		err := status.Errorf(codes.InvalidArgument, "object is not an encrypted object")
+		log.Error(ctx, err, "Decrypt: Object has the wrong key type")
+		return nil, err
+	}
+
+	objectID, err := uuid.FromString(request.ObjectId)
+	if err != nil {
+		log.Errorf(ctx, err, "Decrypt: Failed to parse object ID %s as UUID", request.ObjectId)
+		return nil, status.Errorf(codes.InvalidArgument, "invalid object ID")
+	}
+
+	woek, err := enc.Authorizer.FetchObjectKey(ctx, accessObject)
+	if errors.Is(err, common.ErrImportedKeyDeleted) {
+		log.Error(ctx, err, "Decrypt: Imported key of object not found")
+		return nil, status.Errorf(codes.FailedPrecondition, "imported key has been deleted")
+	}
+	if err != nil {
+		log.Error(ctx, err, "Decrypt: Failed to fetch object key")
+		return nil, status.Errorf(codes.Internal, "error encountered while decrypting object")
+	}
+
+	aad := common.DataAAD(objectID, accessObject.DataVersion, request.AssociatedData)
+	plaintext, err := enc.DataCryptor.Decrypt(woek, request.Ciphertext, aad)
 	if err != nil {
 		log.Error(ctx, err, "Decrypt: Failed to decrypt object")
 		return nil, status.Errorf(codes.Internal, "error encountered while decrypting object")
@@ -34,3 +34,63 @@ func (enc *Disabled) Decrypt(ctx context.Context, request *DecryptRequest) (*Dec
 	log.Info(ctx, "Decrypt: Requested inactive endpoint")
 	return enc.UnimplementedEncryptonizeServer.Decrypt(ctx, request)
 }
+
+// API Enc disabled GenerateDataKey handler
+func (enc *Disabled) GenerateDataKey(ctx context.Context, request *GenerateDataKeyRequest) (*GenerateDataKeyResponse, error) {
+	log.Info(ctx, "GenerateDataKey: Requested inactive endpoint")
+	return enc.UnimplementedEncryptonizeServer.GenerateDataKey(ctx, request)
+}
+
+// API Enc disabled DecryptDataKey handler
+func (enc *Disabled) DecryptDataKey(ctx context.Context, request *DecryptDataKeyRequest) (*DecryptDataKeyResponse, error) {
+	log.Info(ctx, "DecryptDataKey: Requested inactive endpoint")
+	return enc.UnimplementedEncryptonizeServer.DecryptDataKey(ctx, request)
+}
+
+// API Enc disabled CreateIngestKey handler
+func (enc *Disabled) CreateIngestKey(ctx context.Context, request *CreateIngestKeyRequest) (*CreateIngestKeyResponse, error) {
+	log.Info(ctx, "CreateIngestKey: Requested inactive endpoint")
+	return enc.UnimplementedEncryptonizeServer.CreateIngestKey(ctx, request)
+}
+
+// API Enc disabled GetIngestPublicKey handler
+func (enc *Disabled) GetIngestPublicKey(ctx context.Context, request *GetIngestPublicKeyRequest) (*GetIngestPublicKeyResponse, error) {
+	log.Info(ctx, "GetIngestPublicKey: Requested inactive endpoint")
+	return enc.UnimplementedEncryptonizeServer.GetIngestPublicKey(ctx, request)
+}
+
+// API Enc disabled Ingest handler
+func (enc *Disabled) Ingest(ctx context.Context, request *IngestRequest) (*IngestResponse, error) {
+	log.Info(ctx, "Ingest: Requested inactive endpoint")
+	return enc.UnimplementedEncryptonizeServer.Ingest(ctx, request)
+}
+
+// API Enc disabled ImportKey handler
+func (enc *Disabled) ImportKey(ctx context.Context, request *ImportKeyRequest) (*ImportKeyResponse, error) {
+	log.Info(ctx, "ImportKey: Requested inactive endpoint")
+	return enc.UnimplementedEncryptonizeServer.ImportKey(ctx, request)
+}
+
+// API Enc disabled EncryptWithKey handler
+func (enc *Disabled) EncryptWithKey(ctx context.Context, request *EncryptWithKeyRequest) (*EncryptResponse, error) {
+	log.Info(ctx, "EncryptWithKey: Requested inactive endpoint")
+	return enc.UnimplementedEncryptonizeServer.EncryptWithKey(ctx, request)
+}
+
+// API Enc disabled CreateDeterministicKey handler
+func (enc *Disabled) CreateDeterministicKey(ctx context.Context, request *CreateDeterministicKeyRequest) (*CreateDeterministicKeyResponse, error) {
+	log.Info(ctx, "CreateDeterministicKey: Requested inactive endpoint")
+	return enc.UnimplementedEncryptonizeServer.CreateDeterministicKey(ctx, request)
+}
+
+// API Enc disabled EncryptDeterministic handler
+func (enc *Disabled) EncryptDeterministic(ctx context.Context, request *EncryptDeterministicRequest) (*EncryptResponse, error) {
+	log.Info(ctx, "EncryptDeterministic: Requested inactive endpoint")
+	return enc.UnimplementedEncryptonizeServer.EncryptDeterministic(ctx, request)
+}
+
+// API Enc disabled DecryptDeterministic handler
+func (enc *Disabled) DecryptDeterministic(ctx context.Context, request *DecryptRequest) (*DecryptResponse, error) {
+	log.Info(ctx, "DecryptDeterministic: Requested inactive endpoint")
+	return enc.UnimplementedEncryptonizeServer.DecryptDeterministic(ctx, request)
+}
@@ -16,9 +16,12 @@ package enc
 import (
 	"bytes"
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"encryption-service/common"
 	"encryption-service/impl/authstorage"
@@ -28,13 +31,18 @@ import (
 )
 
 var cryptor, _ = crypt.NewAESCryptor([]byte("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"))
+var keyWrapper, _ = crypt.NewKWP([]byte("BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB"))
+var dataCryptor = crypt.NewAESCryptorWithKeyWrap(keyWrapper)
+var receiptSigner, _ = crypt.NewEd25519Signer([]byte("DDDDDDDDDDDDDDDDDDDDDDDDDDDDDDDD"))
 var authorizer = &authzimpl.Authorizer{
 	AccessObjectCryptor: cryptor,
+	ReceiptSigner:       receiptSigner,
 }
 
 var enc = Enc{
 	Authorizer:  authorizer,
-	DataCryptor: cryptor,
+	DataCryptor: dataCryptor,
+	KeyWrapper:  keyWrapper,
 }
 
 var userID = uuid.Must(uuid.NewV4())
@@ -42,12 +50,17 @@ var woek, _ = crypt.Random(32)
 var accessObject = &common.AccessObject{Woek: woek}
 
 var accessObjectStore = make(map[uuid.UUID]common.ProtectedAccessObject)
+var erasureReceiptStore = make(map[uuid.UUID]common.ProtectedErasureReceipt)
 
 var authStorageTxMock = &authstorage.AuthStoreTxMock{
 	InsertAcccessObjectFunc: func(ctx context.Context, protected *common.ProtectedAccessObject) error {
 		accessObjectStore[protected.ObjectID] = *protected
 		return nil
 	},
+	UpdateAccessObjectFunc: func(ctx context.Context, protected *common.ProtectedAccessObject) error {
+		accessObjectStore[protected.ObjectID] = *protected
+		return nil
+	},
 	GetAccessObjectFunc: func(ctx context.Context, objectID uuid.UUID) (*common.ProtectedAccessObject, error) {
 		protected, exists := accessObjectStore[objectID]
 		if !exists {
@@ -55,6 +68,21 @@ var authStorageTxMock = &authstorage.AuthStoreTxMock{
 		}
 		return &protected, nil
 	},
+	DeleteAccessObjectFunc: func(ctx context.Context, objectID uuid.UUID) error {
+		delete(accessObjectStore, objectID)
+		return nil
+	},
+	GetErasureReceiptFunc: func(ctx context.Context, objectID uuid.UUID) (*common.ProtectedErasureReceipt, error) {
+		protected, exists := erasureReceiptStore[objectID]
+		if !exists {
+			return nil, interfaces.ErrNotFound
+		}
+		return &protected, nil
+	},
+	InsertErasureReceiptFunc: func(ctx context.Context, protected *common.ProtectedErasureReceipt) error {
+		erasureReceiptStore[protected.ObjectID] = *protected
+		return nil
+	},
 	CommitFunc: func(ctx context.Context) error {
 		return nil
 	},
@@ -215,3 +243,169 @@ func TestDecryptWrongOID(t *testing.T) {
 		t.Fatal("Decrypting object should've failed with wrong ObjectID")
 	}
 }
+
+func TestDecryptBoundOID(t *testing.T) {
+	ctx := setCtxKeys()
+
+	plaintext := []byte("plaintext_bytes")
+	associatedData := []byte("associated_data_bytes")
+
+	encryptResponse, err := enc.Encrypt(
+		ctx,
+		&EncryptRequest{
+			Plaintext:      plaintext,
+			AssociatedData: associatedData,
+		},
+	)
+
+	if err != nil {
+		t.Fatalf("Encrypting object failed: %v", err)
+	}
+
+	accessObject, err := enc.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(encryptResponse.ObjectId))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %s", err)
+	}
+
+	ctx = context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+
+	// Correct key, but different object ID
+	_, err = enc.Decrypt(
+		ctx,
+		&DecryptRequest{
+			ObjectId:       uuid.Must(uuid.NewV4()).String(),
+			Ciphertext:     encryptResponse.Ciphertext,
+			AssociatedData: encryptResponse.AssociatedData,
+		},
+	)
+
+	if err == nil {
+		t.Fatal("Decrypting object should've failed with wrong ObjectID")
+	}
+}
+
+func TestDecryptLegacy(t *testing.T) {
+	plaintext := []byte("plaintext_bytes")
+	associatedData := []byte("associated_data_bytes")
+
+	// Objects encrypted before the object ID was bound have data version 0
+	woek, ciphertext, err := dataCryptor.Encrypt(plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	ctx := setCtxKeys()
+	ctx = context.WithValue(ctx, common.AccessObjectCtxKey, &common.AccessObject{Woek: woek})
+
+	decryptResponse, err := enc.Decrypt(
+		ctx,
+		&DecryptRequest{
+			ObjectId:       uuid.Must(uuid.NewV4()).String(),
+			Ciphertext:     ciphertext,
+			AssociatedData: associatedData,
+		},
+	)
+
+	if err != nil {
+		t.Fatalf("Decrypting legacy object failed: %v", err)
+	}
+
+	if !bytes.Equal(decryptResponse.Plaintext, plaintext) {
+		t.Fatalf("Decrypted plaintext does not equal original plaintext!")
+	}
+}
+
+// keyContexts creates an object of every key type of the Enc service and returns the object IDs and
+// contexts authorized for them, indexed by key type
+func keyContexts(t *testing.T) map[common.KeyType]keyContext {
+	ctx := setCtxKeys()
+	encryptResponse, err := enc.Encrypt(ctx, &EncryptRequest{Plaintext: []byte("plaintext")})
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	dataKeyResponse, err := enc.GenerateDataKey(ctx, &GenerateDataKeyRequest{})
+	if err != nil {
+		t.Fatalf("GenerateDataKey failed: %v", err)
+	}
+	ingestResponse, ingestCtx := createIngestKey(t)
+	importResponse, importCtx := importKey(t, bytes.Repeat([]byte{1}, dataKeySize))
+	deterministicID, deterministicCtx := createDeterministicKey(t)
+
+	withAccessObject := func(objectID string) keyContext {
+		accessObject, err := enc.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(objectID))
+		if err != nil {
+			t.Fatalf("Failed to fetch access object: %v", err)
+		}
+		return keyContext{objectID, context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)}
+	}
+
+	return map[common.KeyType]keyContext{
+		common.KeyTypeData:          withAccessObject(encryptResponse.ObjectId),
+		common.KeyTypeDataKey:       withAccessObject(dataKeyResponse.ObjectId),
+		common.KeyTypeIngest:        {ingestResponse.ObjectId, ingestCtx},
+		common.KeyTypeImported:      {importResponse.ObjectId, importCtx},
+		common.KeyTypeDeterministic: {deterministicID, deterministicCtx},
+	}
+}
+
+type keyContext struct {
+	objectID string
+	ctx      context.Context
+}
+
+// Test that keys are only used by the operations of their own key type
+func TestWrongKeyType(t *testing.T) {
+	operations := map[string]struct {
+		keyTypes []common.KeyType
+		call     func(ctx context.Context, objectID string) error
+	}{
+		"Decrypt": {[]common.KeyType{common.KeyTypeData, common.KeyTypeImported}, func(ctx context.Context, objectID string) error {
+			_, err := enc.Decrypt(ctx, &DecryptRequest{ObjectId: objectID})
+			return err
+		}},
+		"DecryptDataKey": {[]common.KeyType{common.KeyTypeDataKey}, func(ctx context.Context, objectID string) error {
+			_, err := enc.DecryptDataKey(ctx, &DecryptDataKeyRequest{ObjectId: objectID})
+			return err
+		}},
+		"GetIngestPublicKey": {[]common.KeyType{common.KeyTypeIngest}, func(ctx context.Context, objectID string) error {
+			_, err := enc.GetIngestPublicKey(ctx, &GetIngestPublicKeyRequest{ObjectId: objectID})
+			return err
+		}},
+		"Ingest": {[]common.KeyType{common.KeyTypeIngest}, func(ctx context.Context, objectID string) error {
+			_, err := enc.Ingest(ctx, &IngestRequest{ObjectId: objectID})
+			return err
+		}},
+		"ImportKey": {[]common.KeyType{common.KeyTypeIngest}, func(ctx context.Context, objectID string) error {
+			_, err := enc.ImportKey(ctx, &ImportKeyRequest{ObjectId: objectID})
+			return err
+		}},
+		"EncryptWithKey": {[]common.KeyType{common.KeyTypeImported}, func(ctx context.Context, objectID string) error {
+			_, err := enc.EncryptWithKey(ctx, &EncryptWithKeyRequest{ObjectId: objectID})
+			return err
+		}},
+		"EncryptDeterministic": {[]common.KeyType{common.KeyTypeDeterministic}, func(ctx context.Context, objectID string) error {
+			_, err := enc.EncryptDeterministic(ctx, &EncryptDeterministicRequest{ObjectId: objectID})
+			return err
+		}},
+		"DecryptDeterministic": {[]common.KeyType{common.KeyTypeDeterministic}, func(ctx context.Context, objectID string) error {
+			_, err := enc.DecryptDeterministic(ctx, &DecryptRequest{ObjectId: objectID})
+			return err
+		}},
+	}
+
+	keys := keyContexts(t)
+	for name, operation := range operations {
+		for keyType, key := range keys {
+			err := operation.call(key.ctx, key.objectID)
+			rejected := status.Code(err) == codes.InvalidArgument && strings.HasPrefix(status.Convert(err).Message(), "object is not")
+
+			allowed := false
+			for _, allowedType := range operation.keyTypes {
+				allowed = allowed || keyType == allowedType
+			}
+			if allowed == rejected {
+				t.Errorf("%s with key type %d: allowed %t, got %v", name, keyType, allowed, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package enc
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"encryption-service/common"
+	"encryption-service/impl/crypt"
+	"encryption-service/interfaces"
+	log "encryption-service/logger"
+)
+
+// Associated data of imported keys. It separates imported keys from data ingested through the same
+// ingest key.
+var importKeyAAD = []byte("import key")
+
+// API exposed function, decrypts a key that was encrypted to the requested ingest key and wraps it as
+// the key of a new access object. Returns the object ID of the new object in the response.
+func (enc *Enc) ImportKey(ctx context.Context, request *ImportKeyRequest) (*ImportKeyResponse, error) {
+	userID, ok := ctx.Value(common.UserIDCtxKey).(uuid.UUID)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while importing key")
+		log.Error(ctx, err, "ImportKey: Could not typecast userID to uuid.UUID")
+		return nil, err
+	}
+
+	accessObject, ok := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while importing key")
+		log.Error(ctx, err, "ImportKey: Could not typecast access object to AccessObject")
+		return nil, err
+	}
+
+	if !accessObject.HasKeyType(common.KeyTypeIngest) {
+		err := status.Errorf(codes.InvalidArgument, "object is not an ingest key")
+		log.Error(ctx, err, "ImportKey: Object has the wrong key type")
+		return nil, err
+	}
+
+	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while importing key")
+		log.Error(ctx, err, "ImportKey: Could not typecast authstorage to AuthStoreTxInterface")
+		return nil, err
+	}
+
+	privateKey, err := enc.KeyWrapper.Unwrap(accessObject.GetWOEK())
+	if err != nil {
+		log.Error(ctx, err, "ImportKey: Failed to unwrap private key")
+		return nil, status.Errorf(codes.Internal, "error encountered while importing key")
+	}
+
+	key, err := crypt.HybridDecrypt(privateKey, request.WrappedKey, importKeyAAD)
+	if err != nil {
+		log.Error(ctx, err, "ImportKey: Failed to decrypt wrapped key")
+		return nil, status.Errorf(codes.InvalidArgument, "invalid wrapped key")
+	}
+	if len(key) != dataKeySize {
+		log.Errorf(ctx, nil, "ImportKey: Imported key has invalid length %d", len(key))
+		return nil, status.Errorf(codes.InvalidArgument, "invalid key length")
+	}
+
+	objectID, err := uuid.NewV4()
+	if err != nil {
+		log.Error(ctx, err, "ImportKey: Failed to generate new object ID")
+		return nil, status.Errorf(codes.Internal, "error encountered while importing key")
+	}
+	objectIDString := objectID.String()
+
+	woek, err := enc.KeyWrapper.Wrap(key)
+	if err != nil {
+		log.Error(ctx, err, "ImportKey: Failed to wrap key")
+		return nil, status.Errorf(codes.Internal, "error encountered while importing key")
+	}
+
+	err = enc.Authorizer.CreateAccessObject(ctx, objectID, userID, common.KeyTypeImported, woek)
+	if err != nil {
+		log.Error(ctx, err, "ImportKey: Failed to create new access object")
+		return nil, status.Errorf(codes.Internal, "error encountered while importing key")
+	}
+
+	if err := authStorageTx.Commit(ctx); err != nil {
+		log.Error(ctx, err, "ImportKey: Failed to commit auth storage transaction")
+		return nil, status.Errorf(codes.Internal, "error encountered while importing key")
+	}
+
+	ctx = context.WithValue(ctx, common.ObjectIDCtxKey, objectIDString)
+	log.Info(ctx, "ImportKey: Key imported")
+
+	return &ImportKeyResponse{
+		ObjectId: objectIDString,
+	}, nil
+}
+
+// API exposed function, encrypts provided plaintext under the key of the requested object
+// and returns it with the object ID in the response
+func (enc *Enc) EncryptWithKey(ctx context.Context, request *EncryptWithKeyRequest) (*EncryptResponse, error) {
+	accessObject, ok := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while encrypting object")
+		log.Error(ctx, err, "EncryptWithKey: Could not typecast access object to AccessObject")
+		return nil, err
+	}
+
+	if !accessObject.HasKeyType(common.KeyTypeImported) {
+		err := status.Errorf(codes.InvalidArgument, "object is not an imported key")
+		log.Error(ctx, err, "EncryptWithKey: Object has the wrong key type")
+		return nil, err
+	}
+
+	objectID, err := uuid.FromString(request.ObjectId)
+	if err != nil {
+		log.Errorf(ctx, err, "EncryptWithKey: Failed to parse object ID %s as UUID", request.ObjectId)
+		return nil, status.Errorf(codes.InvalidArgument, "invalid object ID")
+	}
+
+	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while encrypting object")
+		log.Error(ctx, err, "EncryptWithKey: Could not typecast authstorage to AuthStoreTxInterface")
+		return nil, err
+	}
+
+	if err := enc.countEncryption(ctx, "EncryptWithKey", objectID, accessObject); err != nil {
+		return nil, err
+	}
+
+	aad := common.DataAAD(objectID, accessObject.DataVersion, request.AssociatedData)
+	ciphertext, err := enc.DataCryptor.EncryptWithKey(request.Plaintext, aad, accessObject.GetWOEK())
+	if err != nil {
+		log.Error(ctx, err, "EncryptWithKey: Failed to encrypt object")
+		return nil, status.Errorf(codes.Internal, "error encountered while encrypting object")
+	}
+
+	// The ciphertext is only returned once the encryption is counted
+	if err := authStorageTx.Commit(ctx); err != nil {
+		log.Error(ctx, err, "EncryptWithKey: Failed to commit auth storage transaction")
+		return nil, status.Errorf(codes.Internal, "error encountered while encrypting object")
+	}
+
+	log.Info(ctx, "EncryptWithKey: Object encrypted")
+
+	return &EncryptResponse{
+		Ciphertext:     ciphertext,
+		AssociatedData: request.AssociatedData,
+		ObjectId:       request.ObjectId,
+	}, nil
+}
+
+// importedKey returns the ID and wrapped key of the imported key referenced by a request creating a
+// new object, and counts an encryption under it. The access object of the key is put in the context
+// by the authorization middleware.
+func (enc *Enc) importedKey(ctx context.Context, method, keyIDString string) (uuid.UUID, []byte, error) {
+	keyAccessObject, ok := ctx.Value(common.KeyAccessObjectCtxKey).(*common.AccessObject)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while encrypting object")
+		log.Errorf(ctx, err, "%s: Could not typecast key access object to AccessObject", method)
+		return uuid.Nil, nil, err
+	}
+
+	if !keyAccessObject.HasKeyType(common.KeyTypeImported) {
+		err := status.Errorf(codes.InvalidArgument, "key is not an imported key")
+		log.Errorf(ctx, err, "%s: Key has the wrong key type", method)
+		return uuid.Nil, nil, err
+	}
+
+	keyID, err := uuid.FromString(keyIDString)
+	if err != nil {
+		log.Errorf(ctx, err, "%s: Failed to parse key ID %s as UUID", method, keyIDString)
+		return uuid.Nil, nil, status.Errorf(codes.InvalidArgument, "invalid key ID")
+	}
+
+	if err := enc.countEncryption(ctx, method, keyID, keyAccessObject); err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	return keyID, keyAccessObject.GetWOEK(), nil
+}
+
+// countEncryption counts an encryption under the imported key of an access object and updates the
+// access object. Fails with FailedPrecondition once the key has reached its encryption limit.
+func (enc *Enc) countEncryption(ctx context.Context, method string, keyID uuid.UUID, keyAccessObject *common.AccessObject) error {
+	updated := *keyAccessObject
+	if err := updated.CountEncryption(enc.maxEncryptionsPerKey()); err != nil {
+		log.Errorf(ctx, err, "%s: Key can not be used for further encryptions", method)
+		return status.Errorf(codes.FailedPrecondition, "encryption limit of key reached")
+	}
+
+	if err := enc.Authorizer.UpdateAccessObject(ctx, keyID, updated); err != nil {
+		log.Errorf(ctx, err, "%s: Failed to update key access object", method)
+		return status.Errorf(codes.Internal, "error encountered while encrypting object")
+	}
+
+	return nil
+}
@@ -0,0 +1,266 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package enc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"encryption-service/common"
+	"encryption-service/impl/crypt"
+)
+
+// importKey imports `key` through a new ingest key and returns a context authorized for the imported key
+func importKey(t *testing.T, key []byte) (*ImportKeyResponse, context.Context) {
+	createResponse, ctx := createIngestKey(t)
+
+	wrappedKey, err := crypt.HybridEncrypt(createResponse.PublicKey, key, importKeyAAD)
+	if err != nil {
+		t.Fatalf("Hybrid encryption failed: %v", err)
+	}
+
+	importResponse, err := enc.ImportKey(ctx, &ImportKeyRequest{
+		ObjectId:   createResponse.ObjectId,
+		WrappedKey: wrappedKey,
+	})
+	if err != nil {
+		t.Fatalf("Importing key failed: %v", err)
+	}
+	if importResponse.ObjectId == createResponse.ObjectId {
+		t.Fatalf("Imported key reuses the object ID of the ingest key")
+	}
+
+	accessObject, err := enc.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(importResponse.ObjectId))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %v", err)
+	}
+
+	return importResponse, context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+}
+
+func TestImportKey(t *testing.T) {
+	key, err := crypt.Random(dataKeySize)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	importResponse, ctx := importKey(t, key)
+
+	// The imported key is the key of the new object
+	accessObject := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	unwrappedKey, err := enc.KeyWrapper.Unwrap(accessObject.GetWOEK())
+	if err != nil {
+		t.Fatalf("Failed to unwrap key: %v", err)
+	}
+	if !bytes.Equal(unwrappedKey, key) {
+		t.Fatalf("Imported key does not equal original key")
+	}
+
+	plaintext := []byte("plaintext_bytes")
+	associatedData := []byte("associated_data_bytes")
+	encryptResponse, err := enc.EncryptWithKey(ctx, &EncryptWithKeyRequest{
+		ObjectId:       importResponse.ObjectId,
+		Plaintext:      plaintext,
+		AssociatedData: associatedData,
+	})
+	if err != nil {
+		t.Fatalf("Encrypting with imported key failed: %v", err)
+	}
+	if encryptResponse.ObjectId != importResponse.ObjectId {
+		t.Fatalf("Object ID changed during encryption")
+	}
+
+	decryptResponse, err := enc.Decrypt(ctx, &DecryptRequest{
+		ObjectId:       encryptResponse.ObjectId,
+		Ciphertext:     encryptResponse.Ciphertext,
+		AssociatedData: encryptResponse.AssociatedData,
+	})
+	if err != nil {
+		t.Fatalf("Decrypting object failed: %v", err)
+	}
+	if !bytes.Equal(decryptResponse.Plaintext, plaintext) {
+		t.Fatalf("Decrypted plaintext does not equal original plaintext!")
+	}
+}
+
+func TestImportKeyInvalidLength(t *testing.T) {
+	createResponse, ctx := createIngestKey(t)
+
+	wrappedKey, err := crypt.HybridEncrypt(createResponse.PublicKey, []byte("short key"), importKeyAAD)
+	if err != nil {
+		t.Fatalf("Hybrid encryption failed: %v", err)
+	}
+
+	_, err = enc.ImportKey(ctx, &ImportKeyRequest{
+		ObjectId:   createResponse.ObjectId,
+		WrappedKey: wrappedKey,
+	})
+	if err == nil {
+		t.Fatalf("Importing key of invalid length should have failed")
+	}
+}
+
+func TestImportKeyIngestCiphertext(t *testing.T) {
+	createResponse, ctx := createIngestKey(t)
+
+	// Data for `Ingest` must not be accepted as a key
+	wrappedKey, err := crypt.HybridEncrypt(createResponse.PublicKey, make([]byte, dataKeySize), nil)
+	if err != nil {
+		t.Fatalf("Hybrid encryption failed: %v", err)
+	}
+
+	_, err = enc.ImportKey(ctx, &ImportKeyRequest{
+		ObjectId:   createResponse.ObjectId,
+		WrappedKey: wrappedKey,
+	})
+	if err == nil {
+		t.Fatalf("Importing ingest ciphertext as key should have failed")
+	}
+}
+
+// withKeyAccessObject returns a context authorized for the current access object of the imported key,
+// as the authorization middleware would for a request referencing the key
+func withKeyAccessObject(t *testing.T, ctx context.Context, ctxKey common.GlobalContextKey, keyID string) context.Context {
+	accessObject, err := enc.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(keyID))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %v", err)
+	}
+	return context.WithValue(ctx, ctxKey, accessObject)
+}
+
+func TestEncryptUnderImportedKey(t *testing.T) {
+	importResponse, ctx := importKey(t, bytes.Repeat([]byte{2}, dataKeySize))
+	ctx = withKeyAccessObject(t, ctx, common.KeyAccessObjectCtxKey, importResponse.ObjectId)
+
+	plaintext := []byte("plaintext_bytes")
+	associatedData := []byte("associated_data_bytes")
+	encryptResponse, err := enc.Encrypt(ctx, &EncryptRequest{
+		Plaintext:      plaintext,
+		AssociatedData: associatedData,
+		KeyId:          importResponse.ObjectId,
+	})
+	if err != nil {
+		t.Fatalf("Encrypting under imported key failed: %v", err)
+	}
+	if encryptResponse.ObjectId == importResponse.ObjectId {
+		t.Fatalf("Encrypted object reuses the object ID of the imported key")
+	}
+
+	// The new object is a data object referencing the imported key without holding it
+	accessObject, err := enc.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(encryptResponse.ObjectId))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %v", err)
+	}
+	if !accessObject.HasKeyType(common.KeyTypeData) {
+		t.Fatalf("Encrypted object has key type %v", accessObject.KeyType)
+	}
+	if accessObject.KeyID != uuid.FromStringOrNil(importResponse.ObjectId) {
+		t.Fatalf("Encrypted object does not reference the imported key")
+	}
+	if len(accessObject.GetWOEK()) != 0 {
+		t.Fatalf("Encrypted object holds a copy of the imported key")
+	}
+
+	decryptResponse, err := enc.Decrypt(context.WithValue(ctx, common.AccessObjectCtxKey, accessObject), &DecryptRequest{
+		ObjectId:       encryptResponse.ObjectId,
+		Ciphertext:     encryptResponse.Ciphertext,
+		AssociatedData: encryptResponse.AssociatedData,
+	})
+	if err != nil {
+		t.Fatalf("Decrypting object failed: %v", err)
+	}
+	if !bytes.Equal(decryptResponse.Plaintext, plaintext) {
+		t.Fatalf("Decrypted plaintext does not equal original plaintext!")
+	}
+
+	// The encryption is counted by the imported key
+	keyAccessObject, err := enc.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(importResponse.ObjectId))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %v", err)
+	}
+	if keyAccessObject.Reencryptions != 1 {
+		t.Fatalf("Imported key counted %d encryptions, expected 1", keyAccessObject.Reencryptions)
+	}
+
+	// Deleting the imported key revokes access to the object
+	if err := enc.Authorizer.DeleteAccessObject(ctx, uuid.FromStringOrNil(importResponse.ObjectId)); err != nil {
+		t.Fatalf("Failed to delete imported key: %v", err)
+	}
+	_, err = enc.Decrypt(context.WithValue(ctx, common.AccessObjectCtxKey, accessObject), &DecryptRequest{
+		ObjectId:       encryptResponse.ObjectId,
+		Ciphertext:     encryptResponse.Ciphertext,
+		AssociatedData: encryptResponse.AssociatedData,
+	})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Decrypting object after deleting its key should have failed with FailedPrecondition: %v", err)
+	}
+}
+
+func TestEncryptUnderWrongKeyType(t *testing.T) {
+	createResponse, ctx := createIngestKey(t)
+	ctx = withKeyAccessObject(t, ctx, common.KeyAccessObjectCtxKey, createResponse.ObjectId)
+
+	_, err := enc.Encrypt(ctx, &EncryptRequest{Plaintext: []byte("plaintext"), KeyId: createResponse.ObjectId})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Encrypting under an ingest key should have been rejected: %v", err)
+	}
+}
+
+func TestImportedKeyEncryptionLimit(t *testing.T) {
+	limitedEnc := Enc{
+		Authorizer:           enc.Authorizer,
+		AuthStore:            enc.AuthStore,
+		DataCryptor:          enc.DataCryptor,
+		KeyWrapper:           enc.KeyWrapper,
+		MaxEncryptionsPerKey: 2,
+	}
+	importResponse, ctx := importKey(t, bytes.Repeat([]byte{3}, dataKeySize))
+
+	// Both ways of encrypting under the imported key count towards the same limit
+	_, err := limitedEnc.EncryptWithKey(withKeyAccessObject(t, ctx, common.AccessObjectCtxKey, importResponse.ObjectId), &EncryptWithKeyRequest{
+		ObjectId:  importResponse.ObjectId,
+		Plaintext: []byte("plaintext"),
+	})
+	if err != nil {
+		t.Fatalf("Encrypting with imported key failed: %v", err)
+	}
+
+	_, err = limitedEnc.Encrypt(withKeyAccessObject(t, ctx, common.KeyAccessObjectCtxKey, importResponse.ObjectId), &EncryptRequest{
+		Plaintext: []byte("plaintext"),
+		KeyId:     importResponse.ObjectId,
+	})
+	if err != nil {
+		t.Fatalf("Encrypting under imported key failed: %v", err)
+	}
+
+	_, err = limitedEnc.EncryptWithKey(withKeyAccessObject(t, ctx, common.AccessObjectCtxKey, importResponse.ObjectId), &EncryptWithKeyRequest{
+		ObjectId:  importResponse.ObjectId,
+		Plaintext: []byte("plaintext"),
+	})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Encrypting beyond the limit should have failed: %v", err)
+	}
+
+	_, err = limitedEnc.Encrypt(withKeyAccessObject(t, ctx, common.KeyAccessObjectCtxKey, importResponse.ObjectId), &EncryptRequest{
+		Plaintext: []byte("plaintext"),
+		KeyId:     importResponse.ObjectId,
+	})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Encrypting beyond the limit should have failed: %v", err)
+	}
+}
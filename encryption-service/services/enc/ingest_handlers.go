@@ -0,0 +1,198 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package enc
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"encryption-service/common"
+	"encryption-service/impl/crypt"
+	"encryption-service/interfaces"
+	log "encryption-service/logger"
+)
+
+// API exposed function, creates a new X25519 key pair for hybrid encryption, protects the private key
+// with a new access object and returns the object ID and public key in the response
+func (enc *Enc) CreateIngestKey(ctx context.Context, request *CreateIngestKeyRequest) (*CreateIngestKeyResponse, error) {
+	userID, ok := ctx.Value(common.UserIDCtxKey).(uuid.UUID)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while creating ingest key")
+		log.Error(ctx, err, "CreateIngestKey: Could not typecast userID to uuid.UUID")
+		return nil, err
+	}
+
+	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while creating ingest key")
+		log.Error(ctx, err, "CreateIngestKey: Could not typecast authstorage to AuthStoreTxInterface")
+		return nil, err
+	}
+
+	objectID, err := uuid.NewV4()
+	if err != nil {
+		log.Error(ctx, err, "CreateIngestKey: Failed to generate new object ID")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating ingest key")
+	}
+	objectIDString := objectID.String()
+
+	privateKey, err := crypt.NewX25519PrivateKey()
+	if err != nil {
+		log.Error(ctx, err, "CreateIngestKey: Failed to generate private key")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating ingest key")
+	}
+
+	publicKey, err := crypt.X25519PublicKey(privateKey)
+	if err != nil {
+		log.Error(ctx, err, "CreateIngestKey: Failed to compute public key")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating ingest key")
+	}
+
+	wrappedPrivateKey, err := enc.KeyWrapper.Wrap(privateKey)
+	if err != nil {
+		log.Error(ctx, err, "CreateIngestKey: Failed to wrap private key")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating ingest key")
+	}
+
+	err = enc.Authorizer.CreateAccessObject(ctx, objectID, userID, common.KeyTypeIngest, wrappedPrivateKey)
+	if err != nil {
+		log.Error(ctx, err, "CreateIngestKey: Failed to create new access object")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating ingest key")
+	}
+
+	if err := authStorageTx.Commit(ctx); err != nil {
+		log.Error(ctx, err, "CreateIngestKey: Failed to commit auth storage transaction")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating ingest key")
+	}
+
+	ctx = context.WithValue(ctx, common.ObjectIDCtxKey, objectIDString)
+	log.Info(ctx, "CreateIngestKey: Ingest key created")
+
+	return &CreateIngestKeyResponse{
+		ObjectId:  objectIDString,
+		PublicKey: publicKey,
+	}, nil
+}
+
+// API exposed function, returns the public key of the requested ingest key
+func (enc *Enc) GetIngestPublicKey(ctx context.Context, request *GetIngestPublicKeyRequest) (*GetIngestPublicKeyResponse, error) {
+	accessObject, ok := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while fetching public key")
+		log.Error(ctx, err, "GetIngestPublicKey: Could not typecast access object to AccessObject")
+		return nil, err
+	}
+
+	if !accessObject.HasKeyType(common.KeyTypeIngest) {
+		err := status.Errorf(codes.InvalidArgument, "object is not an ingest key")
+		log.Error(ctx, err, "GetIngestPublicKey: Object has the wrong key type")
+		return nil, err
+	}
+
+	privateKey, err := enc.KeyWrapper.Unwrap(accessObject.GetWOEK())
+	if err != nil {
+		log.Error(ctx, err, "GetIngestPublicKey: Failed to unwrap private key")
+		return nil, status.Errorf(codes.Internal, "error encountered while fetching public key")
+	}
+
+	publicKey, err := crypt.X25519PublicKey(privateKey)
+	if err != nil {
+		log.Error(ctx, err, "GetIngestPublicKey: Failed to compute public key")
+		return nil, status.Errorf(codes.Internal, "error encountered while fetching public key")
+	}
+
+	return &GetIngestPublicKeyResponse{
+		PublicKey: publicKey,
+	}, nil
+}
+
+// API exposed function, decrypts a ciphertext that was encrypted to the requested ingest key and
+// encrypts the plaintext as a new object, which is returned with its object ID in the response
+func (enc *Enc) Ingest(ctx context.Context, request *IngestRequest) (*IngestResponse, error) {
+	userID, ok := ctx.Value(common.UserIDCtxKey).(uuid.UUID)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while ingesting object")
+		log.Error(ctx, err, "Ingest: Could not typecast userID to uuid.UUID")
+		return nil, err
+	}
+
+	accessObject, ok := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while ingesting object")
+		log.Error(ctx, err, "Ingest: Could not typecast access object to AccessObject")
+		return nil, err
+	}
+
+	if !accessObject.HasKeyType(common.KeyTypeIngest) {
+		err := status.Errorf(codes.InvalidArgument, "object is not an ingest key")
+		log.Error(ctx, err, "Ingest: Object has the wrong key type")
+		return nil, err
+	}
+
+	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while ingesting object")
+		log.Error(ctx, err, "Ingest: Could not typecast authstorage to AuthStoreTxInterface")
+		return nil, err
+	}
+
+	privateKey, err := enc.KeyWrapper.Unwrap(accessObject.GetWOEK())
+	if err != nil {
+		log.Error(ctx, err, "Ingest: Failed to unwrap private key")
+		return nil, status.Errorf(codes.Internal, "error encountered while ingesting object")
+	}
+
+	plaintext, err := crypt.HybridDecrypt(privateKey, request.Ciphertext, request.AssociatedData)
+	if err != nil {
+		log.Error(ctx, err, "Ingest: Failed to decrypt ciphertext")
+		return nil, status.Errorf(codes.InvalidArgument, "invalid ciphertext")
+	}
+
+	objectID, err := uuid.NewV4()
+	if err != nil {
+		log.Error(ctx, err, "Ingest: Failed to generate new object ID")
+		return nil, status.Errorf(codes.Internal, "error encountered while ingesting object")
+	}
+	objectIDString := objectID.String()
+
+	aad := common.DataAAD(objectID, common.InitialDataVersion, request.AssociatedData)
+	woek, ciphertext, err := enc.DataCryptor.Encrypt(plaintext, aad)
+	if err != nil {
+		log.Error(ctx, err, "Ingest: Failed to encrypt object")
+		return nil, status.Errorf(codes.Internal, "error encountered while ingesting object")
+	}
+
+	err = enc.Authorizer.CreateAccessObject(ctx, objectID, userID, common.KeyTypeData, woek)
+	if err != nil {
+		log.Error(ctx, err, "Ingest: Failed to create new access object")
+		return nil, status.Errorf(codes.Internal, "error encountered while ingesting object")
+	}
+
+	if err := authStorageTx.Commit(ctx); err != nil {
+		log.Error(ctx, err, "Ingest: Failed to commit auth storage transaction")
+		return nil, status.Errorf(codes.Internal, "error encountered while ingesting object")
+	}
+
+	ctx = context.WithValue(ctx, common.ObjectIDCtxKey, objectIDString)
+	log.Info(ctx, "Ingest: Object ingested")
+
+	return &IngestResponse{
+		Ciphertext:     ciphertext,
+		AssociatedData: request.AssociatedData,
+		ObjectId:       objectIDString,
+	}, nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package enc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid"
+
+	"encryption-service/common"
+	"encryption-service/impl/crypt"
+)
+
+// createIngestKey creates an ingest key and returns the response and a context authorized for it
+func createIngestKey(t *testing.T) (*CreateIngestKeyResponse, context.Context) {
+	ctx := setCtxKeys()
+
+	createResponse, err := enc.CreateIngestKey(ctx, &CreateIngestKeyRequest{})
+	if err != nil {
+		t.Fatalf("Creating ingest key failed: %v", err)
+	}
+
+	accessObject, err := enc.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(createResponse.ObjectId))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %v", err)
+	}
+
+	return createResponse, context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+}
+
+func TestIngest(t *testing.T) {
+	createResponse, ctx := createIngestKey(t)
+
+	publicKeyResponse, err := enc.GetIngestPublicKey(ctx, &GetIngestPublicKeyRequest{ObjectId: createResponse.ObjectId})
+	if err != nil {
+		t.Fatalf("Fetching public key failed: %v", err)
+	}
+	if !bytes.Equal(publicKeyResponse.PublicKey, createResponse.PublicKey) {
+		t.Fatalf("Public keys differ")
+	}
+
+	plaintext := []byte("plaintext_bytes")
+	associatedData := []byte("associated_data_bytes")
+	hybridCiphertext, err := crypt.HybridEncrypt(publicKeyResponse.PublicKey, plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("Hybrid encryption failed: %v", err)
+	}
+
+	ingestResponse, err := enc.Ingest(ctx, &IngestRequest{
+		ObjectId:       createResponse.ObjectId,
+		Ciphertext:     hybridCiphertext,
+		AssociatedData: associatedData,
+	})
+	if err != nil {
+		t.Fatalf("Ingesting object failed: %v", err)
+	}
+	if ingestResponse.ObjectId == createResponse.ObjectId {
+		t.Fatalf("Ingested object reuses the object ID of the ingest key")
+	}
+
+	// The ingested object is a normal object
+	accessObject, err := enc.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(ingestResponse.ObjectId))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %v", err)
+	}
+	ctx = context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+
+	decryptResponse, err := enc.Decrypt(ctx, &DecryptRequest{
+		ObjectId:       ingestResponse.ObjectId,
+		Ciphertext:     ingestResponse.Ciphertext,
+		AssociatedData: ingestResponse.AssociatedData,
+	})
+	if err != nil {
+		t.Fatalf("Decrypting ingested object failed: %v", err)
+	}
+	if !bytes.Equal(decryptResponse.Plaintext, plaintext) {
+		t.Fatalf("Decrypted plaintext does not equal original plaintext!")
+	}
+}
+
+func TestIngestWrongKey(t *testing.T) {
+	createResponse, _ := createIngestKey(t)
+	otherCreateResponse, otherCtx := createIngestKey(t)
+
+	associatedData := []byte("associated_data_bytes")
+	hybridCiphertext, err := crypt.HybridEncrypt(createResponse.PublicKey, []byte("plaintext_bytes"), associatedData)
+	if err != nil {
+		t.Fatalf("Hybrid encryption failed: %v", err)
+	}
+
+	_, err = enc.Ingest(otherCtx, &IngestRequest{
+		ObjectId:       otherCreateResponse.ObjectId,
+		Ciphertext:     hybridCiphertext,
+		AssociatedData: associatedData,
+	})
+	if err == nil {
+		t.Fatalf("Ingesting ciphertext encrypted to another key should have failed")
+	}
+}
+
+func TestIngestWrongAAD(t *testing.T) {
+	createResponse, ctx := createIngestKey(t)
+
+	hybridCiphertext, err := crypt.HybridEncrypt(createResponse.PublicKey, []byte("plaintext_bytes"), []byte("associated_data_bytes"))
+	if err != nil {
+		t.Fatalf("Hybrid encryption failed: %v", err)
+	}
+
+	_, err = enc.Ingest(ctx, &IngestRequest{
+		ObjectId:       createResponse.ObjectId,
+		Ciphertext:     hybridCiphertext,
+		AssociatedData: []byte("wrong_associated_data"),
+	})
+	if err == nil {
+		t.Fatalf("Ingesting ciphertext with wrong associated data should have failed")
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package fpe
+
+import (
+	"errors"
+
+	"encryption-service/impl/crypt"
+)
+
+// DefaultAlphabet is used when a request does not specify an alphabet
+const DefaultAlphabet = "0123456789"
+
+var errInvalidAlphabet = errors.New("invalid alphabet")
+var errInvalidCharacter = errors.New("character not in alphabet")
+
+// alphabet maps between the characters of a string and FF1 numerals
+type alphabet struct {
+	characters []rune
+	numerals   map[rune]uint16
+}
+
+// newAlphabet parses an alphabet, which must consist of at least two distinct characters. The
+// position of a character in the alphabet is its numeral.
+func newAlphabet(characters string) (*alphabet, error) {
+	if characters == "" {
+		characters = DefaultAlphabet
+	}
+
+	a := &alphabet{
+		characters: []rune(characters),
+		numerals:   make(map[rune]uint16),
+	}
+	if len(a.characters) < 2 || len(a.characters) > crypt.FF1MaxRadix {
+		return nil, errInvalidAlphabet
+	}
+	for i, c := range a.characters {
+		if _, ok := a.numerals[c]; ok {
+			return nil, errInvalidAlphabet
+		}
+		a.numerals[c] = uint16(i)
+	}
+	return a, nil
+}
+
+// radix returns the number of characters in the alphabet
+func (a *alphabet) radix() int {
+	return len(a.characters)
+}
+
+// toNumerals converts a string over the alphabet to numerals
+func (a *alphabet) toNumerals(s string) ([]uint16, error) {
+	numerals := make([]uint16, 0, len(s))
+	for _, c := range s {
+		numeral, ok := a.numerals[c]
+		if !ok {
+			return nil, errInvalidCharacter
+		}
+		numerals = append(numerals, numeral)
+	}
+	return numerals, nil
+}
+
+// fromNumerals converts numerals to a string over the alphabet
+func (a *alphabet) fromNumerals(numerals []uint16) string {
+	characters := make([]rune, len(numerals))
+	for i, numeral := range numerals {
+		characters[i] = a.characters[numeral]
+	}
+	return string(characters)
+}
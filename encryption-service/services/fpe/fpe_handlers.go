@@ -0,0 +1,236 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package fpe
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"encryption-service/common"
+	"encryption-service/impl/crypt"
+	"encryption-service/interfaces"
+	log "encryption-service/logger"
+)
+
+// Size of generated tokenization keys in bytes
+const fpeKeySize = 32
+
+// MaxTweakLength is the maximum length of user provided tweaks in bytes. The object ID is prepended
+// to the tweak before it is passed to FF1.
+const MaxTweakLength = crypt.FF1MaxTweakLength - uuid.Size
+
+// MaxLength is the maximum length of plaintexts and tokens in characters
+const MaxLength = crypt.FF1MaxLength
+
+// API exposed function, creates a new tokenization key protected by a new access object
+// and returns the object ID in the response
+func (fpe *FPE) CreateKey(ctx context.Context, request *CreateKeyRequest) (*CreateKeyResponse, error) {
+	userID, ok := ctx.Value(common.UserIDCtxKey).(uuid.UUID)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while creating key")
+		log.Error(ctx, err, "CreateKey: Could not typecast userID to uuid.UUID")
+		return nil, err
+	}
+
+	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while creating key")
+		log.Error(ctx, err, "CreateKey: Could not typecast authstorage to AuthStoreTxInterface")
+		return nil, err
+	}
+
+	objectID, err := uuid.NewV4()
+	if err != nil {
+		log.Error(ctx, err, "CreateKey: Failed to generate new object ID")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating key")
+	}
+	objectIDString := objectID.String()
+
+	key, err := crypt.Random(fpeKeySize)
+	if err != nil {
+		log.Error(ctx, err, "CreateKey: Failed to generate key")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating key")
+	}
+
+	wrappedKey, err := fpe.KeyWrapper.Wrap(key)
+	if err != nil {
+		log.Error(ctx, err, "CreateKey: Failed to wrap key")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating key")
+	}
+
+	err = fpe.Authorizer.CreateAccessObject(ctx, objectID, userID, common.KeyTypeFPE, wrappedKey)
+	if err != nil {
+		log.Error(ctx, err, "CreateKey: Failed to create new access object")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating key")
+	}
+
+	if err := authStorageTx.Commit(ctx); err != nil {
+		log.Error(ctx, err, "CreateKey: Failed to commit auth storage transaction")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating key")
+	}
+
+	ctx = context.WithValue(ctx, common.ObjectIDCtxKey, objectIDString)
+	log.Info(ctx, "CreateKey: Key created")
+
+	return &CreateKeyResponse{
+		ObjectId: objectIDString,
+	}, nil
+}
+
+// API exposed function, encrypts the provided plaintext into a token of the same length
+// and alphabet under the key of the requested object
+func (fpe *FPE) Tokenize(ctx context.Context, request *TokenizeRequest) (*TokenizeResponse, error) {
+	accessObject, ok := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while tokenizing")
+		log.Error(ctx, err, "Tokenize: Could not typecast access object to AccessObject")
+		return nil, err
+	}
+
+	if !accessObject.HasKeyType(common.KeyTypeFPE) {
+		err := status.Errorf(codes.InvalidArgument, "object is not an FPE key")
+		log.Error(ctx, err, "Tokenize: Object has the wrong key type")
+		return nil, err
+	}
+
+	objectID, err := uuid.FromString(request.ObjectId)
+	if err != nil {
+		log.Errorf(ctx, err, "Tokenize: Failed to parse object ID %s as UUID", request.ObjectId)
+		return nil, status.Errorf(codes.InvalidArgument, "invalid object ID")
+	}
+
+	if len(request.Tweak) > MaxTweakLength {
+		log.Errorf(ctx, nil, "Tokenize: Tweak too long: %d bytes", len(request.Tweak))
+		return nil, status.Errorf(codes.InvalidArgument, "invalid tweak")
+	}
+
+	if utf8.RuneCountInString(request.Plaintext) > MaxLength {
+		log.Errorf(ctx, nil, "Tokenize: Plaintext too long: %d characters", utf8.RuneCountInString(request.Plaintext))
+		return nil, status.Errorf(codes.InvalidArgument, "invalid plaintext")
+	}
+
+	alphabet, err := newAlphabet(request.Alphabet)
+	if err != nil {
+		log.Error(ctx, err, "Tokenize: Failed to parse alphabet")
+		return nil, status.Errorf(codes.InvalidArgument, "invalid alphabet")
+	}
+
+	numerals, err := alphabet.toNumerals(request.Plaintext)
+	if err != nil {
+		log.Error(ctx, err, "Tokenize: Failed to map plaintext to alphabet")
+		return nil, status.Errorf(codes.InvalidArgument, "invalid plaintext")
+	}
+
+	ff1, err := fpe.newFF1(accessObject, alphabet)
+	if err != nil {
+		log.Error(ctx, err, "Tokenize: Failed to create cipher")
+		return nil, status.Errorf(codes.Internal, "error encountered while tokenizing")
+	}
+
+	token, err := ff1.Encrypt(tweak(objectID, request.Tweak), numerals)
+	if err != nil {
+		log.Error(ctx, err, "Tokenize: Failed to encrypt plaintext")
+		return nil, status.Errorf(codes.InvalidArgument, "invalid plaintext")
+	}
+
+	log.Info(ctx, "Tokenize: Plaintext tokenized")
+
+	return &TokenizeResponse{
+		Token: alphabet.fromNumerals(token),
+	}, nil
+}
+
+// API exposed function, decrypts the provided token into the original plaintext
+// under the key of the requested object
+func (fpe *FPE) Detokenize(ctx context.Context, request *DetokenizeRequest) (*DetokenizeResponse, error) {
+	accessObject, ok := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while detokenizing")
+		log.Error(ctx, err, "Detokenize: Could not typecast access object to AccessObject")
+		return nil, err
+	}
+
+	if !accessObject.HasKeyType(common.KeyTypeFPE) {
+		err := status.Errorf(codes.InvalidArgument, "object is not an FPE key")
+		log.Error(ctx, err, "Detokenize: Object has the wrong key type")
+		return nil, err
+	}
+
+	objectID, err := uuid.FromString(request.ObjectId)
+	if err != nil {
+		log.Errorf(ctx, err, "Detokenize: Failed to parse object ID %s as UUID", request.ObjectId)
+		return nil, status.Errorf(codes.InvalidArgument, "invalid object ID")
+	}
+
+	if len(request.Tweak) > MaxTweakLength {
+		log.Errorf(ctx, nil, "Detokenize: Tweak too long: %d bytes", len(request.Tweak))
+		return nil, status.Errorf(codes.InvalidArgument, "invalid tweak")
+	}
+
+	if utf8.RuneCountInString(request.Token) > MaxLength {
+		log.Errorf(ctx, nil, "Detokenize: Token too long: %d characters", utf8.RuneCountInString(request.Token))
+		return nil, status.Errorf(codes.InvalidArgument, "invalid token")
+	}
+
+	alphabet, err := newAlphabet(request.Alphabet)
+	if err != nil {
+		log.Error(ctx, err, "Detokenize: Failed to parse alphabet")
+		return nil, status.Errorf(codes.InvalidArgument, "invalid alphabet")
+	}
+
+	numerals, err := alphabet.toNumerals(request.Token)
+	if err != nil {
+		log.Error(ctx, err, "Detokenize: Failed to map token to alphabet")
+		return nil, status.Errorf(codes.InvalidArgument, "invalid token")
+	}
+
+	ff1, err := fpe.newFF1(accessObject, alphabet)
+	if err != nil {
+		log.Error(ctx, err, "Detokenize: Failed to create cipher")
+		return nil, status.Errorf(codes.Internal, "error encountered while detokenizing")
+	}
+
+	plaintext, err := ff1.Decrypt(tweak(objectID, request.Tweak), numerals)
+	if err != nil {
+		log.Error(ctx, err, "Detokenize: Failed to decrypt token")
+		return nil, status.Errorf(codes.InvalidArgument, "invalid token")
+	}
+
+	log.Info(ctx, "Detokenize: Token detokenized")
+
+	return &DetokenizeResponse{
+		Plaintext: alphabet.fromNumerals(plaintext),
+	}, nil
+}
+
+// newFF1 unwraps the key of the access object and returns an FF1 instance for it over the alphabet
+func (fpe *FPE) newFF1(accessObject *common.AccessObject, alphabet *alphabet) (*crypt.FF1, error) {
+	key, err := fpe.KeyWrapper.Unwrap(accessObject.GetWOEK())
+	if err != nil {
+		return nil, err
+	}
+
+	return crypt.NewFF1(key, alphabet.radix())
+}
+
+// tweak prefixes the user provided tweak with the object ID
+func tweak(objectID uuid.UUID, requestTweak []byte) []byte {
+	t := make([]byte, 0, uuid.Size+len(requestTweak))
+	t = append(t, objectID.Bytes()...)
+	return append(t, requestTweak...)
+}
@@ -0,0 +1,209 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package fpe
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"encryption-service/common"
+	"encryption-service/impl/authstorage"
+	authzimpl "encryption-service/impl/authz"
+	"encryption-service/impl/crypt"
+	"encryption-service/interfaces"
+)
+
+var cryptor, _ = crypt.NewAESCryptor([]byte("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"))
+var keyWrapper, _ = crypt.NewKWP([]byte("BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB"))
+var authorizer = &authzimpl.Authorizer{
+	AccessObjectCryptor: cryptor,
+}
+
+var fpe = FPE{
+	Authorizer: authorizer,
+	KeyWrapper: keyWrapper,
+}
+
+var userID = uuid.Must(uuid.NewV4())
+
+var accessObjectStore = make(map[uuid.UUID]common.ProtectedAccessObject)
+
+var authStorageTxMock = &authstorage.AuthStoreTxMock{
+	InsertAcccessObjectFunc: func(ctx context.Context, protected *common.ProtectedAccessObject) error {
+		accessObjectStore[protected.ObjectID] = *protected
+		return nil
+	},
+	GetAccessObjectFunc: func(ctx context.Context, objectID uuid.UUID) (*common.ProtectedAccessObject, error) {
+		protected, exists := accessObjectStore[objectID]
+		if !exists {
+			return nil, interfaces.ErrNotFound
+		}
+		return &protected, nil
+	},
+	CommitFunc: func(ctx context.Context) error {
+		return nil
+	},
+}
+
+func setCtxKeys() context.Context {
+	ctx := context.WithValue(context.Background(), common.UserIDCtxKey, userID)
+	ctx = context.WithValue(ctx, common.AuthStorageTxCtxKey, authStorageTxMock)
+	return ctx
+}
+
+// createKey creates a tokenization key and returns its object ID and a context authorized for it
+func createKey(t *testing.T) (string, context.Context) {
+	ctx := setCtxKeys()
+
+	createKeyResponse, err := fpe.CreateKey(ctx, &CreateKeyRequest{})
+	if err != nil {
+		t.Fatalf("Creating key failed: %v", err)
+	}
+
+	accessObject, err := fpe.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(createKeyResponse.ObjectId))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %v", err)
+	}
+
+	return createKeyResponse.ObjectId, context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+}
+
+func TestTokenize(t *testing.T) {
+	objectID, ctx := createKey(t)
+
+	tests := []struct {
+		plaintext string
+		alphabet  string
+	}{
+		{plaintext: "4111111111111111", alphabet: ""},
+		{plaintext: "010190-1234", alphabet: "0123456789-"},
+		{plaintext: "ÆØÅæøåÆØÅ", alphabet: "ÆØÅæøå"},
+	}
+
+	for _, test := range tests {
+		tokenizeResponse, err := fpe.Tokenize(ctx, &TokenizeRequest{
+			ObjectId:  objectID,
+			Plaintext: test.plaintext,
+			Alphabet:  test.alphabet,
+			Tweak:     []byte("tweak"),
+		})
+		if err != nil {
+			t.Fatalf("Tokenizing %q failed: %v", test.plaintext, err)
+		}
+
+		alphabet, _ := newAlphabet(test.alphabet)
+		if _, err := alphabet.toNumerals(tokenizeResponse.Token); err != nil {
+			t.Fatalf("Token %q is not over the alphabet", tokenizeResponse.Token)
+		}
+		if len([]rune(tokenizeResponse.Token)) != len([]rune(test.plaintext)) {
+			t.Fatalf("Token %q has different length than plaintext %q", tokenizeResponse.Token, test.plaintext)
+		}
+
+		detokenizeResponse, err := fpe.Detokenize(ctx, &DetokenizeRequest{
+			ObjectId: objectID,
+			Token:    tokenizeResponse.Token,
+			Alphabet: test.alphabet,
+			Tweak:    []byte("tweak"),
+		})
+		if err != nil {
+			t.Fatalf("Detokenizing %q failed: %v", tokenizeResponse.Token, err)
+		}
+		if detokenizeResponse.Plaintext != test.plaintext {
+			t.Fatalf("Detokenized plaintext %q does not equal original plaintext %q", detokenizeResponse.Plaintext, test.plaintext)
+		}
+	}
+}
+
+func TestTokenizeInvalidInput(t *testing.T) {
+	objectID, ctx := createKey(t)
+
+	tests := []struct {
+		description string
+		request     *TokenizeRequest
+	}{
+		{"character not in alphabet", &TokenizeRequest{ObjectId: objectID, Plaintext: "411111111111111a"}},
+		{"duplicate character in alphabet", &TokenizeRequest{ObjectId: objectID, Plaintext: "0101010101", Alphabet: "010"}},
+		{"single character alphabet", &TokenizeRequest{ObjectId: objectID, Plaintext: "0000000000", Alphabet: "0"}},
+		{"plaintext too short", &TokenizeRequest{ObjectId: objectID, Plaintext: "1234"}},
+		{"tweak too long", &TokenizeRequest{ObjectId: objectID, Plaintext: "4111111111111111", Tweak: make([]byte, MaxTweakLength+1)}},
+		{"plaintext too long", &TokenizeRequest{ObjectId: objectID, Plaintext: strings.Repeat("4", MaxLength+1)}},
+	}
+
+	for _, test := range tests {
+		if _, err := fpe.Tokenize(ctx, test.request); err == nil {
+			t.Fatalf("Tokenize should have failed: %s", test.description)
+		}
+	}
+}
+
+// Test that plaintexts and tokens longer than the maximum length are rejected before FF1 is run
+func TestTokenizeTooLong(t *testing.T) {
+	objectID, ctx := createKey(t)
+
+	// Multi-byte characters count as one character
+	plaintext := strings.Repeat("æø", MaxLength/2)
+	tokenizeResponse, err := fpe.Tokenize(ctx, &TokenizeRequest{ObjectId: objectID, Plaintext: plaintext, Alphabet: "æø"})
+	if err != nil {
+		t.Fatalf("Tokenizing plaintext of maximum length failed: %v", err)
+	}
+	if _, err := fpe.Detokenize(ctx, &DetokenizeRequest{ObjectId: objectID, Token: tokenizeResponse.Token, Alphabet: "æø"}); err != nil {
+		t.Fatalf("Detokenizing token of maximum length failed: %v", err)
+	}
+
+	long := strings.Repeat("4", MaxLength+1)
+	if _, err := fpe.Tokenize(ctx, &TokenizeRequest{ObjectId: objectID, Plaintext: long}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Tokenize of too long plaintext: expected InvalidArgument, got %v", err)
+	}
+	if _, err := fpe.Detokenize(ctx, &DetokenizeRequest{ObjectId: objectID, Token: long}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Detokenize of too long token: expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestDetokenizeWrongTweak(t *testing.T) {
+	objectID, ctx := createKey(t)
+
+	plaintext := "4111111111111111"
+	tokenizeResponse, err := fpe.Tokenize(ctx, &TokenizeRequest{ObjectId: objectID, Plaintext: plaintext, Tweak: []byte("tweak")})
+	if err != nil {
+		t.Fatalf("Tokenizing failed: %v", err)
+	}
+
+	detokenizeResponse, err := fpe.Detokenize(ctx, &DetokenizeRequest{ObjectId: objectID, Token: tokenizeResponse.Token, Tweak: []byte("other tweak")})
+	if err != nil {
+		t.Fatalf("Detokenizing failed: %v", err)
+	}
+	if detokenizeResponse.Plaintext == plaintext {
+		t.Fatalf("Detokenizing with wrong tweak returned the original plaintext")
+	}
+}
+
+// Test that keys of other types are rejected
+func TestWrongKeyType(t *testing.T) {
+	objectID, ctx := createKey(t)
+	accessObject := *ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	accessObject.KeyType = common.KeyTypeDeterministic
+	ctx = context.WithValue(ctx, common.AccessObjectCtxKey, &accessObject)
+
+	if _, err := fpe.Tokenize(ctx, &TokenizeRequest{ObjectId: objectID}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Tokenize with wrong key type: expected InvalidArgument, got %v", err)
+	}
+	if _, err := fpe.Detokenize(ctx, &DetokenizeRequest{ObjectId: objectID}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Detokenize with wrong key type: expected InvalidArgument, got %v", err)
+	}
+}
@@ -17,28 +17,71 @@ import (
 	"context"
 
 	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"encryption-service/impl/crypt"
 )
 
 const (
 	HealthEndpointCheck string = "/grpc.health.v1.Health/Check"
 	HealthEndpointWatch string = "/grpc.health.v1.Health/Watch"
 	ReflectionEndpoint  string = "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"
+
+	// SelfTestService is the health check service reporting the combined result of the self-tests.
+	// The result of a single self-test is reported by the service "selftest.<name>".
+	SelfTestService string = "selftest"
 )
 
-type Checker struct{}
+type Checker struct {
+	status   grpc_health_v1.HealthCheckResponse_ServingStatus
+	services map[string]grpc_health_v1.HealthCheckResponse_ServingStatus
+}
 
 func NewHealthChecker() *Checker {
-	return &Checker{}
+	return NewHealthCheckerWithStatus(grpc_health_v1.HealthCheckResponse_SERVING)
+}
+
+// NewHealthCheckerWithStatus creates a health checker that always reports the given status
+func NewHealthCheckerWithStatus(status grpc_health_v1.HealthCheckResponse_ServingStatus) *Checker {
+	return &Checker{status: status, services: map[string]grpc_health_v1.HealthCheckResponse_ServingStatus{}}
+}
+
+// NewHealthCheckerWithSelfTests creates a health checker that additionally reports the results of
+// the self-tests run at startup
+func NewHealthCheckerWithSelfTests(results []crypt.SelfTestResult) *Checker {
+	checker := NewHealthChecker()
+
+	checker.services[SelfTestService] = servingStatus(crypt.SelfTestsPassed(results))
+	for _, result := range results {
+		checker.services[SelfTestService+"."+result.Name] = servingStatus(result.Err == nil)
+	}
+
+	return checker
+}
+
+func servingStatus(serving bool) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	if serving {
+		return grpc_health_v1.HealthCheckResponse_SERVING
+	}
+	return grpc_health_v1.HealthCheckResponse_NOT_SERVING
+}
+
+// serviceStatus returns the status of the requested service. The empty service name and unknown
+// service names refer to the overall status of the server.
+func (s *Checker) serviceStatus(service string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	if current, ok := s.services[service]; ok {
+		return current
+	}
+	return s.status
 }
 
 func (s *Checker) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
 	return &grpc_health_v1.HealthCheckResponse{
-		Status: grpc_health_v1.HealthCheckResponse_SERVING,
+		Status: s.serviceStatus(req.Service),
 	}, nil
 }
 
 func (s *Checker) Watch(req *grpc_health_v1.HealthCheckRequest, server grpc_health_v1.Health_WatchServer) error {
 	return server.Send(&grpc_health_v1.HealthCheckResponse{
-		Status: grpc_health_v1.HealthCheckResponse_SERVING,
+		Status: s.serviceStatus(req.Service),
 	})
 }
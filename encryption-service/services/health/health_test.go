@@ -0,0 +1,68 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"encryption-service/impl/crypt"
+)
+
+func TestCheckSelfTests(t *testing.T) {
+	checker := NewHealthCheckerWithSelfTests([]crypt.SelfTestResult{
+		{Name: crypt.SelfTestAESGCM},
+		{Name: crypt.SelfTestKWP, Err: errors.New("mismatch")},
+	})
+
+	tests := []struct {
+		service string
+		status  grpc_health_v1.HealthCheckResponse_ServingStatus
+	}{
+		{"", grpc_health_v1.HealthCheckResponse_SERVING},
+		{SelfTestService, grpc_health_v1.HealthCheckResponse_NOT_SERVING},
+		{SelfTestService + "." + crypt.SelfTestAESGCM, grpc_health_v1.HealthCheckResponse_SERVING},
+		{SelfTestService + "." + crypt.SelfTestKWP, grpc_health_v1.HealthCheckResponse_NOT_SERVING},
+	}
+
+	for _, test := range tests {
+		response, err := checker.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: test.service})
+		if err != nil {
+			t.Fatalf("Check of service %q failed: %v", test.service, err)
+		}
+		if response.Status != test.status {
+			t.Fatalf("Service %q reported status %v, expected %v", test.service, response.Status, test.status)
+		}
+	}
+}
+
+func TestCheckUnknownService(t *testing.T) {
+	for _, serving := range []grpc_health_v1.HealthCheckResponse_ServingStatus{
+		grpc_health_v1.HealthCheckResponse_SERVING,
+		grpc_health_v1.HealthCheckResponse_NOT_SERVING,
+	} {
+		checker := NewHealthCheckerWithStatus(serving)
+
+		response, err := checker.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "unknown"})
+		if err != nil {
+			t.Fatalf("Check of unknown service failed: %v", err)
+		}
+		if response.Status != serving {
+			t.Fatalf("Unknown service reported status %v, expected the overall status %v", response.Status, serving)
+		}
+	}
+}
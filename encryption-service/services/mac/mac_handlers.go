@@ -0,0 +1,179 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package mac
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"encryption-service/common"
+	"encryption-service/impl/crypt"
+	"encryption-service/interfaces"
+	log "encryption-service/logger"
+)
+
+// Size of generated MAC keys in bytes
+const macKeySize = 32
+
+// API exposed function, creates a new MAC key protected by a new access object
+// and returns the object ID in the response
+func (mac *MAC) CreateKey(ctx context.Context, request *CreateKeyRequest) (*CreateKeyResponse, error) {
+	userID, ok := ctx.Value(common.UserIDCtxKey).(uuid.UUID)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while creating key")
+		log.Error(ctx, err, "CreateKey: Could not typecast userID to uuid.UUID")
+		return nil, err
+	}
+
+	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while creating key")
+		log.Error(ctx, err, "CreateKey: Could not typecast authstorage to AuthStoreTxInterface")
+		return nil, err
+	}
+
+	objectID, err := uuid.NewV4()
+	if err != nil {
+		log.Error(ctx, err, "CreateKey: Failed to generate new object ID")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating key")
+	}
+	objectIDString := objectID.String()
+
+	key, err := crypt.Random(macKeySize)
+	if err != nil {
+		log.Error(ctx, err, "CreateKey: Failed to generate key")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating key")
+	}
+
+	wrappedKey, err := mac.KeyWrapper.Wrap(key)
+	if err != nil {
+		log.Error(ctx, err, "CreateKey: Failed to wrap key")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating key")
+	}
+
+	err = mac.Authorizer.CreateAccessObject(ctx, objectID, userID, common.KeyTypeMAC, wrappedKey)
+	if err != nil {
+		log.Error(ctx, err, "CreateKey: Failed to create new access object")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating key")
+	}
+
+	if err := authStorageTx.Commit(ctx); err != nil {
+		log.Error(ctx, err, "CreateKey: Failed to commit auth storage transaction")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating key")
+	}
+
+	ctx = context.WithValue(ctx, common.ObjectIDCtxKey, objectIDString)
+	log.Info(ctx, "CreateKey: Key created")
+
+	return &CreateKeyResponse{
+		ObjectId: objectIDString,
+	}, nil
+}
+
+// API exposed function, computes the tag of the provided message
+// under the key of the requested object
+func (mac *MAC) Tag(ctx context.Context, request *TagRequest) (*TagResponse, error) {
+	accessObject, ok := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while computing tag")
+		log.Error(ctx, err, "Tag: Could not typecast access object to AccessObject")
+		return nil, err
+	}
+
+	if !accessObject.HasKeyType(common.KeyTypeMAC) {
+		err := status.Errorf(codes.InvalidArgument, "object is not a MAC key")
+		log.Error(ctx, err, "Tag: Object has the wrong key type")
+		return nil, err
+	}
+
+	objectID, err := uuid.FromString(request.ObjectId)
+	if err != nil {
+		log.Errorf(ctx, err, "Tag: Failed to parse object ID %s as UUID", request.ObjectId)
+		return nil, status.Errorf(codes.InvalidArgument, "invalid object ID")
+	}
+
+	authenticator, err := mac.newAuthenticator(accessObject)
+	if err != nil {
+		log.Error(ctx, err, "Tag: Failed to create message authenticator")
+		return nil, status.Errorf(codes.Internal, "error encountered while computing tag")
+	}
+
+	tag, err := authenticator.Tag(common.DataAAD(objectID, accessObject.DataVersion, request.Message))
+	if err != nil {
+		log.Error(ctx, err, "Tag: Failed to compute tag")
+		return nil, status.Errorf(codes.Internal, "error encountered while computing tag")
+	}
+
+	log.Info(ctx, "Tag: Message tagged")
+
+	return &TagResponse{
+		Tag: tag,
+	}, nil
+}
+
+// API exposed function, checks the tag of the provided message
+// under the key of the requested object
+func (mac *MAC) Verify(ctx context.Context, request *VerifyRequest) (*VerifyResponse, error) {
+	accessObject, ok := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while verifying tag")
+		log.Error(ctx, err, "Verify: Could not typecast access object to AccessObject")
+		return nil, err
+	}
+
+	if !accessObject.HasKeyType(common.KeyTypeMAC) {
+		err := status.Errorf(codes.InvalidArgument, "object is not a MAC key")
+		log.Error(ctx, err, "Verify: Object has the wrong key type")
+		return nil, err
+	}
+
+	objectID, err := uuid.FromString(request.ObjectId)
+	if err != nil {
+		log.Errorf(ctx, err, "Verify: Failed to parse object ID %s as UUID", request.ObjectId)
+		return nil, status.Errorf(codes.InvalidArgument, "invalid object ID")
+	}
+
+	authenticator, err := mac.newAuthenticator(accessObject)
+	if err != nil {
+		log.Error(ctx, err, "Verify: Failed to create message authenticator")
+		return nil, status.Errorf(codes.Internal, "error encountered while verifying tag")
+	}
+
+	valid, err := authenticator.Verify(common.DataAAD(objectID, accessObject.DataVersion, request.Message), request.Tag)
+	if err != nil {
+		log.Error(ctx, err, "Verify: Failed to verify tag")
+		return nil, status.Errorf(codes.Internal, "error encountered while verifying tag")
+	}
+
+	log.Infof(ctx, "Verify: Tag verified, valid: %t", valid)
+
+	return &VerifyResponse{
+		Valid: valid,
+	}, nil
+}
+
+// newAuthenticator unwraps the key of the access object and returns a message authenticator for it.
+// Messages are bound to the object ID and data version before tagging, such that tags cannot be
+// moved between objects.
+func (mac *MAC) newAuthenticator(accessObject *common.AccessObject) (interfaces.MessageAuthenticatorInterface, error) {
+	key, err := mac.KeyWrapper.Unwrap(accessObject.GetWOEK())
+	if err != nil {
+		return nil, err
+	}
+
+	return crypt.NewHMACAuthenticator(key)
+}
@@ -0,0 +1,162 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package mac
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"encryption-service/common"
+	"encryption-service/impl/authstorage"
+	authzimpl "encryption-service/impl/authz"
+	"encryption-service/impl/crypt"
+	"encryption-service/interfaces"
+)
+
+var cryptor, _ = crypt.NewAESCryptor([]byte("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"))
+var keyWrapper, _ = crypt.NewKWP([]byte("BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB"))
+var authorizer = &authzimpl.Authorizer{
+	AccessObjectCryptor: cryptor,
+}
+
+var mac = MAC{
+	Authorizer: authorizer,
+	KeyWrapper: keyWrapper,
+}
+
+var userID = uuid.Must(uuid.NewV4())
+
+var accessObjectStore = make(map[uuid.UUID]common.ProtectedAccessObject)
+
+var authStorageTxMock = &authstorage.AuthStoreTxMock{
+	InsertAcccessObjectFunc: func(ctx context.Context, protected *common.ProtectedAccessObject) error {
+		accessObjectStore[protected.ObjectID] = *protected
+		return nil
+	},
+	GetAccessObjectFunc: func(ctx context.Context, objectID uuid.UUID) (*common.ProtectedAccessObject, error) {
+		protected, exists := accessObjectStore[objectID]
+		if !exists {
+			return nil, interfaces.ErrNotFound
+		}
+		return &protected, nil
+	},
+	CommitFunc: func(ctx context.Context) error {
+		return nil
+	},
+}
+
+func setCtxKeys() context.Context {
+	ctx := context.WithValue(context.Background(), common.UserIDCtxKey, userID)
+	ctx = context.WithValue(ctx, common.AuthStorageTxCtxKey, authStorageTxMock)
+	return ctx
+}
+
+// createKey creates a MAC key and returns its object ID and a context authorized for it
+func createKey(t *testing.T) (string, context.Context) {
+	ctx := setCtxKeys()
+
+	createKeyResponse, err := mac.CreateKey(ctx, &CreateKeyRequest{})
+	if err != nil {
+		t.Fatalf("Creating key failed: %v", err)
+	}
+
+	accessObject, err := mac.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(createKeyResponse.ObjectId))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %v", err)
+	}
+
+	return createKeyResponse.ObjectId, context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+}
+
+func TestTagVerify(t *testing.T) {
+	objectID, ctx := createKey(t)
+	message := []byte("message")
+
+	tagResponse, err := mac.Tag(ctx, &TagRequest{ObjectId: objectID, Message: message})
+	if err != nil {
+		t.Fatalf("Tagging message failed: %v", err)
+	}
+
+	verifyResponse, err := mac.Verify(ctx, &VerifyRequest{ObjectId: objectID, Message: message, Tag: tagResponse.Tag})
+	if err != nil {
+		t.Fatalf("Verifying tag failed: %v", err)
+	}
+	if !verifyResponse.Valid {
+		t.Fatalf("Valid tag was rejected")
+	}
+}
+
+func TestVerifyModifiedMessage(t *testing.T) {
+	objectID, ctx := createKey(t)
+
+	tagResponse, err := mac.Tag(ctx, &TagRequest{ObjectId: objectID, Message: []byte("message")})
+	if err != nil {
+		t.Fatalf("Tagging message failed: %v", err)
+	}
+
+	verifyResponse, err := mac.Verify(ctx, &VerifyRequest{ObjectId: objectID, Message: []byte("massage"), Tag: tagResponse.Tag})
+	if err != nil {
+		t.Fatalf("Verifying tag failed: %v", err)
+	}
+	if verifyResponse.Valid {
+		t.Fatalf("Tag of modified message was accepted")
+	}
+}
+
+func TestVerifyOtherObject(t *testing.T) {
+	objectID, ctx := createKey(t)
+	otherObjectID, otherCtx := createKey(t)
+	message := []byte("message")
+
+	tagResponse, err := mac.Tag(ctx, &TagRequest{ObjectId: objectID, Message: message})
+	if err != nil {
+		t.Fatalf("Tagging message failed: %v", err)
+	}
+
+	verifyResponse, err := mac.Verify(otherCtx, &VerifyRequest{ObjectId: otherObjectID, Message: message, Tag: tagResponse.Tag})
+	if err != nil {
+		t.Fatalf("Verifying tag failed: %v", err)
+	}
+	if verifyResponse.Valid {
+		t.Fatalf("Tag of another object was accepted")
+	}
+}
+
+func TestTagInvalidObjectID(t *testing.T) {
+	_, ctx := createKey(t)
+
+	_, err := mac.Tag(ctx, &TagRequest{ObjectId: "fakeobjectID", Message: []byte("message")})
+	if err == nil {
+		t.Fatalf("Tag should have errored")
+	}
+}
+
+// Test that keys of other types are rejected
+func TestWrongKeyType(t *testing.T) {
+	objectID, ctx := createKey(t)
+	accessObject := *ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	accessObject.KeyType = common.KeyTypeSigning
+	ctx = context.WithValue(ctx, common.AccessObjectCtxKey, &accessObject)
+
+	if _, err := mac.Tag(ctx, &TagRequest{ObjectId: objectID, Message: []byte("message")}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Tag with wrong key type: expected InvalidArgument, got %v", err)
+	}
+	if _, err := mac.Verify(ctx, &VerifyRequest{ObjectId: objectID, Message: []byte("message")}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Verify with wrong key type: expected InvalidArgument, got %v", err)
+	}
+}
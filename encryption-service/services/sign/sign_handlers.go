@@ -0,0 +1,208 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sign
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"encryption-service/common"
+	"encryption-service/impl/crypt"
+	"encryption-service/interfaces"
+	log "encryption-service/logger"
+)
+
+// Size of generated private key seeds in bytes
+const seedSize = 32
+
+// API exposed function, creates a new signing key protected by a new access object
+// and returns the object ID in the response
+func (sign *Signing) CreateSigningKey(ctx context.Context, request *CreateSigningKeyRequest) (*CreateSigningKeyResponse, error) {
+	userID, ok := ctx.Value(common.UserIDCtxKey).(uuid.UUID)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while creating signing key")
+		log.Error(ctx, err, "CreateSigningKey: Could not typecast userID to uuid.UUID")
+		return nil, err
+	}
+
+	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while creating signing key")
+		log.Error(ctx, err, "CreateSigningKey: Could not typecast authstorage to AuthStoreTxInterface")
+		return nil, err
+	}
+
+	objectID, err := uuid.NewV4()
+	if err != nil {
+		log.Error(ctx, err, "CreateSigningKey: Failed to generate new object ID")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating signing key")
+	}
+	objectIDString := objectID.String()
+
+	seed, err := crypt.Random(seedSize)
+	if err != nil {
+		log.Error(ctx, err, "CreateSigningKey: Failed to generate private key")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating signing key")
+	}
+
+	wrappedSeed, err := sign.KeyWrapper.Wrap(seed)
+	if err != nil {
+		log.Error(ctx, err, "CreateSigningKey: Failed to wrap private key")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating signing key")
+	}
+
+	err = sign.Authorizer.CreateAccessObject(ctx, objectID, userID, common.KeyTypeSigning, wrappedSeed)
+	if err != nil {
+		log.Error(ctx, err, "CreateSigningKey: Failed to create new access object")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating signing key")
+	}
+
+	if err := authStorageTx.Commit(ctx); err != nil {
+		log.Error(ctx, err, "CreateSigningKey: Failed to commit auth storage transaction")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating signing key")
+	}
+
+	ctx = context.WithValue(ctx, common.ObjectIDCtxKey, objectIDString)
+	log.Info(ctx, "CreateSigningKey: Signing key created")
+
+	return &CreateSigningKeyResponse{
+		ObjectId: objectIDString,
+	}, nil
+}
+
+// API exposed function, signs the provided message with the key of the requested object
+func (sign *Signing) Sign(ctx context.Context, request *SignRequest) (*SignResponse, error) {
+	accessObject, ok := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while signing message")
+		log.Error(ctx, err, "Sign: Could not typecast access object to AccessObject")
+		return nil, err
+	}
+
+	if !accessObject.HasKeyType(common.KeyTypeSigning) {
+		err := status.Errorf(codes.InvalidArgument, "object is not a signing key")
+		log.Error(ctx, err, "Sign: Object has the wrong key type")
+		return nil, err
+	}
+
+	signer, err := sign.newSigner(accessObject)
+	if err != nil {
+		log.Error(ctx, err, "Sign: Failed to create signer")
+		return nil, status.Errorf(codes.Internal, "error encountered while signing message")
+	}
+
+	signature, err := signer.Sign(request.Message)
+	if err != nil {
+		log.Error(ctx, err, "Sign: Failed to sign message")
+		return nil, status.Errorf(codes.Internal, "error encountered while signing message")
+	}
+
+	log.Info(ctx, "Sign: Message signed")
+
+	return &SignResponse{
+		Signature: signature,
+	}, nil
+}
+
+// API exposed function, checks the signature of the provided message
+// with the key of the requested object
+func (sign *Signing) Verify(ctx context.Context, request *VerifyRequest) (*VerifyResponse, error) {
+	accessObject, ok := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while verifying signature")
+		log.Error(ctx, err, "Verify: Could not typecast access object to AccessObject")
+		return nil, err
+	}
+
+	if !accessObject.HasKeyType(common.KeyTypeSigning) {
+		err := status.Errorf(codes.InvalidArgument, "object is not a signing key")
+		log.Error(ctx, err, "Verify: Object has the wrong key type")
+		return nil, err
+	}
+
+	signer, err := sign.newSigner(accessObject)
+	if err != nil {
+		log.Error(ctx, err, "Verify: Failed to create signer")
+		return nil, status.Errorf(codes.Internal, "error encountered while verifying signature")
+	}
+
+	valid, err := signer.Verify(request.Message, request.Signature)
+	if err != nil {
+		log.Error(ctx, err, "Verify: Failed to verify signature")
+		return nil, status.Errorf(codes.Internal, "error encountered while verifying signature")
+	}
+
+	log.Infof(ctx, "Verify: Signature verified, valid: %t", valid)
+
+	return &VerifyResponse{
+		Valid: valid,
+	}, nil
+}
+
+// API exposed function, returns the public key of the requested object
+// in the requested format
+func (sign *Signing) GetPublicKey(ctx context.Context, request *GetPublicKeyRequest) (*GetPublicKeyResponse, error) {
+	accessObject, ok := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while exporting public key")
+		log.Error(ctx, err, "GetPublicKey: Could not typecast access object to AccessObject")
+		return nil, err
+	}
+
+	if !accessObject.HasKeyType(common.KeyTypeSigning) {
+		err := status.Errorf(codes.InvalidArgument, "object is not a signing key")
+		log.Error(ctx, err, "GetPublicKey: Object has the wrong key type")
+		return nil, err
+	}
+
+	signer, err := sign.newSigner(accessObject)
+	if err != nil {
+		log.Error(ctx, err, "GetPublicKey: Failed to create signer")
+		return nil, status.Errorf(codes.Internal, "error encountered while exporting public key")
+	}
+
+	var publicKey string
+	switch request.Format {
+	case PublicKeyFormat_PEM:
+		publicKey, err = encodePEM(signer.PublicKey())
+	case PublicKeyFormat_JWK:
+		publicKey, err = encodeJWK(signer.PublicKey())
+	default:
+		log.Errorf(ctx, nil, "GetPublicKey: Invalid public key format %v", request.Format)
+		return nil, status.Errorf(codes.InvalidArgument, "invalid public key format")
+	}
+	if err != nil {
+		log.Error(ctx, err, "GetPublicKey: Failed to encode public key")
+		return nil, status.Errorf(codes.Internal, "error encountered while exporting public key")
+	}
+
+	log.Info(ctx, "GetPublicKey: Public key exported")
+
+	return &GetPublicKeyResponse{
+		PublicKey: publicKey,
+	}, nil
+}
+
+// newSigner unwraps the private key of the access object and returns a signer for it
+func (sign *Signing) newSigner(accessObject *common.AccessObject) (interfaces.SignerInterface, error) {
+	seed, err := sign.KeyWrapper.Unwrap(accessObject.GetWOEK())
+	if err != nil {
+		return nil, err
+	}
+
+	return crypt.NewEd25519Signer(seed)
+}
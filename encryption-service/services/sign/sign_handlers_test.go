@@ -0,0 +1,222 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sign
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"encryption-service/common"
+	"encryption-service/impl/authstorage"
+	authzimpl "encryption-service/impl/authz"
+	"encryption-service/impl/crypt"
+	"encryption-service/interfaces"
+)
+
+var cryptor, _ = crypt.NewAESCryptor([]byte("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"))
+var keyWrapper, _ = crypt.NewKWP([]byte("BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB"))
+var authorizer = &authzimpl.Authorizer{
+	AccessObjectCryptor: cryptor,
+}
+
+var sign = Signing{
+	Authorizer: authorizer,
+	KeyWrapper: keyWrapper,
+}
+
+var userID = uuid.Must(uuid.NewV4())
+
+var accessObjectStore = make(map[uuid.UUID]common.ProtectedAccessObject)
+
+var authStorageTxMock = &authstorage.AuthStoreTxMock{
+	InsertAcccessObjectFunc: func(ctx context.Context, protected *common.ProtectedAccessObject) error {
+		accessObjectStore[protected.ObjectID] = *protected
+		return nil
+	},
+	GetAccessObjectFunc: func(ctx context.Context, objectID uuid.UUID) (*common.ProtectedAccessObject, error) {
+		protected, exists := accessObjectStore[objectID]
+		if !exists {
+			return nil, interfaces.ErrNotFound
+		}
+		return &protected, nil
+	},
+	CommitFunc: func(ctx context.Context) error {
+		return nil
+	},
+}
+
+func setCtxKeys() context.Context {
+	ctx := context.WithValue(context.Background(), common.UserIDCtxKey, userID)
+	ctx = context.WithValue(ctx, common.AuthStorageTxCtxKey, authStorageTxMock)
+	return ctx
+}
+
+// createSigningKey creates a signing key and returns its object ID and a context authorized for it
+func createSigningKey(t *testing.T) (string, context.Context) {
+	ctx := setCtxKeys()
+
+	createResponse, err := sign.CreateSigningKey(ctx, &CreateSigningKeyRequest{})
+	if err != nil {
+		t.Fatalf("Creating signing key failed: %v", err)
+	}
+
+	accessObject, err := sign.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(createResponse.ObjectId))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %v", err)
+	}
+
+	return createResponse.ObjectId, context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+}
+
+// getPublicKey exports the public key of the signing key in the given context as PEM and parses it
+func getPublicKey(t *testing.T, ctx context.Context, objectID string) ed25519.PublicKey {
+	publicKeyResponse, err := sign.GetPublicKey(ctx, &GetPublicKeyRequest{ObjectId: objectID, Format: PublicKeyFormat_PEM})
+	if err != nil {
+		t.Fatalf("Exporting public key failed: %v", err)
+	}
+
+	block, _ := pem.Decode([]byte(publicKeyResponse.PublicKey))
+	if block == nil || block.Type != "PUBLIC KEY" {
+		t.Fatalf("Public key is not a PEM public key: %s", publicKeyResponse.PublicKey)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("Failed to parse public key: %v", err)
+	}
+	ed25519PublicKey, ok := publicKey.(ed25519.PublicKey)
+	if !ok {
+		t.Fatalf("Public key is not an Ed25519 key: %T", publicKey)
+	}
+	return ed25519PublicKey
+}
+
+func TestSignVerify(t *testing.T) {
+	objectID, ctx := createSigningKey(t)
+	message := []byte("message")
+
+	signResponse, err := sign.Sign(ctx, &SignRequest{ObjectId: objectID, Message: message})
+	if err != nil {
+		t.Fatalf("Signing message failed: %v", err)
+	}
+
+	verifyResponse, err := sign.Verify(ctx, &VerifyRequest{ObjectId: objectID, Message: message, Signature: signResponse.Signature})
+	if err != nil {
+		t.Fatalf("Verifying signature failed: %v", err)
+	}
+	if !verifyResponse.Valid {
+		t.Fatalf("Valid signature was rejected")
+	}
+
+	// The signature can be verified without the service
+	if !ed25519.Verify(getPublicKey(t, ctx, objectID), message, signResponse.Signature) {
+		t.Fatalf("Signature does not verify under the exported public key")
+	}
+}
+
+func TestVerifyModifiedMessage(t *testing.T) {
+	objectID, ctx := createSigningKey(t)
+
+	signResponse, err := sign.Sign(ctx, &SignRequest{ObjectId: objectID, Message: []byte("message")})
+	if err != nil {
+		t.Fatalf("Signing message failed: %v", err)
+	}
+
+	verifyResponse, err := sign.Verify(ctx, &VerifyRequest{ObjectId: objectID, Message: []byte("massage"), Signature: signResponse.Signature})
+	if err != nil {
+		t.Fatalf("Verifying signature failed: %v", err)
+	}
+	if verifyResponse.Valid {
+		t.Fatalf("Signature of modified message was accepted")
+	}
+}
+
+func TestVerifyOtherKey(t *testing.T) {
+	objectID, ctx := createSigningKey(t)
+	otherObjectID, otherCtx := createSigningKey(t)
+	message := []byte("message")
+
+	signResponse, err := sign.Sign(ctx, &SignRequest{ObjectId: objectID, Message: message})
+	if err != nil {
+		t.Fatalf("Signing message failed: %v", err)
+	}
+
+	verifyResponse, err := sign.Verify(otherCtx, &VerifyRequest{ObjectId: otherObjectID, Message: message, Signature: signResponse.Signature})
+	if err != nil {
+		t.Fatalf("Verifying signature failed: %v", err)
+	}
+	if verifyResponse.Valid {
+		t.Fatalf("Signature of another key was accepted")
+	}
+}
+
+func TestGetPublicKeyJWK(t *testing.T) {
+	objectID, ctx := createSigningKey(t)
+
+	publicKeyResponse, err := sign.GetPublicKey(ctx, &GetPublicKeyRequest{ObjectId: objectID, Format: PublicKeyFormat_JWK})
+	if err != nil {
+		t.Fatalf("Exporting public key failed: %v", err)
+	}
+
+	var key map[string]string
+	if err := json.Unmarshal([]byte(publicKeyResponse.PublicKey), &key); err != nil {
+		t.Fatalf("Public key is not a JSON object: %v", err)
+	}
+	if key["kty"] != "OKP" || key["crv"] != "Ed25519" {
+		t.Fatalf("Unexpected key type: %v", key)
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(key["x"])
+	if err != nil {
+		t.Fatalf("Failed to decode public key: %v", err)
+	}
+	if !getPublicKey(t, ctx, objectID).Equal(ed25519.PublicKey(x)) {
+		t.Fatalf("JWK and PEM public keys differ")
+	}
+}
+
+func TestGetPublicKeyInvalidFormat(t *testing.T) {
+	objectID, ctx := createSigningKey(t)
+
+	_, err := sign.GetPublicKey(ctx, &GetPublicKeyRequest{ObjectId: objectID, Format: PublicKeyFormat(42)})
+	if err == nil {
+		t.Fatalf("GetPublicKey should have errored")
+	}
+}
+
+// Test that keys of other types are rejected
+func TestWrongKeyType(t *testing.T) {
+	objectID, ctx := createSigningKey(t)
+	accessObject := *ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	accessObject.KeyType = common.KeyTypeMAC
+	ctx = context.WithValue(ctx, common.AccessObjectCtxKey, &accessObject)
+
+	if _, err := sign.Sign(ctx, &SignRequest{ObjectId: objectID, Message: []byte("message")}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Sign with wrong key type: expected InvalidArgument, got %v", err)
+	}
+	if _, err := sign.Verify(ctx, &VerifyRequest{ObjectId: objectID, Message: []byte("message")}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Verify with wrong key type: expected InvalidArgument, got %v", err)
+	}
+	if _, err := sign.GetPublicKey(ctx, &GetPublicKeyRequest{ObjectId: objectID}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GetPublicKey with wrong key type: expected InvalidArgument, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+
+	"encryption-service/common"
+	"encryption-service/interfaces"
+	log "encryption-service/logger"
+)
+
+// Number of access object IDs listed per transaction
+const migrateBatchSize = 100
+
+// MigrateObjectsCLI re-encrypts all legacy objects in the object store such that their ciphertexts
+// are bound to the object ID and data version. Each object is migrated in its own transaction, and
+// objects that are already migrated are left untouched, so the command can safely be re-run if it is
+// interrupted. Objects created by the Encryption service are skipped, as their ciphertexts are held
+// by the client. This function is intended to be used for CLI operation.
+func (strg *Storage) MigrateObjectsCLI() error {
+	// Need to inject requestID manually, as these calls don't pass the usual middleware
+	requestID, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	ctx := context.WithValue(context.Background(), common.RequestIDCtxKey, requestID)
+
+	var migrated, total int
+	after := uuid.Nil
+	for {
+		objectIDs, err := strg.listObjectIDs(ctx, after)
+		if err != nil {
+			return err
+		}
+		if len(objectIDs) == 0 {
+			break
+		}
+
+		for _, objectID := range objectIDs {
+			changed, err := strg.migrateObject(ctx, objectID)
+			if err != nil {
+				log.Errorf(ctx, err, "Failed to migrate object %s", objectID)
+				return err
+			}
+			if changed {
+				migrated++
+			}
+		}
+
+		after = objectIDs[len(objectIDs)-1]
+		total += len(objectIDs)
+		log.Infof(ctx, "Processed %d objects, %d migrated", total, migrated)
+	}
+
+	log.Infof(ctx, "Migration done, %d of %d objects migrated", migrated, total)
+	return nil
+}
+
+// listObjectIDs lists the next batch of access object IDs following `after`
+func (strg *Storage) listObjectIDs(ctx context.Context, after uuid.UUID) ([]uuid.UUID, error) {
+	authStoreTx, err := strg.AuthStore.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := authStoreTx.Rollback(ctx)
+		if err != nil {
+			log.Error(ctx, err, "Performing rollback")
+		}
+	}()
+
+	return authStoreTx.ListAccessObjectIDs(ctx, after, migrateBatchSize)
+}
+
+// migrateObject binds the ciphertext of a legacy object to its object ID and the initial data
+// version, and reports whether the object was changed
+func (strg *Storage) migrateObject(ctx context.Context, objectID uuid.UUID) (bool, error) {
+	authStoreTx, err := strg.AuthStore.NewTransaction(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		err := authStoreTx.Rollback(ctx)
+		if err != nil {
+			log.Error(ctx, err, "Performing rollback")
+		}
+	}()
+	ctx = context.WithValue(ctx, common.AuthStorageTxCtxKey, authStoreTx)
+
+	accessObject, err := strg.Authorizer.FetchAccessObject(ctx, objectID)
+	if err != nil {
+		return false, err
+	}
+	if accessObject.DataVersion != 0 {
+		return false, nil
+	}
+
+	objectIDString := objectID.String()
+	aad, err := strg.ObjectStore.Retrieve(ctx, objectIDString+AssociatedDataStoreSuffix)
+	if errors.Is(err, interfaces.ErrNotFound) {
+		// Not stored through the Storage service
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	ciphertext, err := strg.ObjectStore.Retrieve(ctx, objectIDString+CiphertextStoreSuffix)
+	if err != nil {
+		return false, err
+	}
+
+	plaintext, err := strg.DataCryptor.Decrypt(accessObject.GetWOEK(), ciphertext, aad)
+	if err != nil {
+		return false, err
+	}
+
+	accessObject.DataVersion = common.InitialDataVersion
+	ciphertext, err = strg.DataCryptor.EncryptWithKey(plaintext, common.DataAAD(objectID, accessObject.DataVersion, aad), accessObject.GetWOEK())
+	if err != nil {
+		return false, err
+	}
+
+	// The bound ciphertext is staged like an update, such that the legacy ciphertext stays readable
+	// until the new data version is committed
+	if err := strg.stageObject(ctx, objectIDString, aad, ciphertext); err != nil {
+		return false, err
+	}
+
+	accessObject.Reencryptions++
+	if err := strg.Authorizer.UpdateAccessObject(ctx, objectID, *accessObject); err != nil {
+		return false, err
+	}
+
+	if err := authStoreTx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	// If this fails, the staged object is moved into place by the next retrieve, update or re-key
+	return true, strg.finishRekey(ctx, objectIDString, aad, ciphertext)
+}
@@ -0,0 +1,186 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofrs/uuid"
+
+	"encryption-service/common"
+	"encryption-service/impl/authstorage"
+	"encryption-service/interfaces"
+)
+
+func TestMigrateObjects(t *testing.T) {
+	authStore, err := authstorage.NewMemoryAuthStore(filepath.Join(t.TempDir(), "auth.db"))
+	if err != nil {
+		t.Fatalf("NewMemoryAuthStore failed: %v", err)
+	}
+	defer authStore.Close()
+
+	strg := Storage{
+		Authorizer:  authorizer,
+		AuthStore:   authStore,
+		DataCryptor: cryptor,
+		ObjectStore: objectStoreMock,
+	}
+
+	ctx := context.Background()
+	tx, err := authStore.NewTransaction(ctx)
+	if err != nil {
+		t.Fatalf("NewTransaction failed: %v", err)
+	}
+	txCtx := context.WithValue(ctx, common.AuthStorageTxCtxKey, tx)
+
+	// createObject creates an access object with data version 0 and stores a ciphertext with the
+	// given data version
+	associatedData := []byte("associated_data_bytes")
+	plaintexts := make(map[uuid.UUID][]byte)
+	createObject := func(dataVersion uint64, store bool) uuid.UUID {
+		objectID := uuid.Must(uuid.NewV4())
+		plaintexts[objectID] = []byte(objectID.String())
+
+		woek, ciphertext, err := cryptor.Encrypt(plaintexts[objectID], common.DataAAD(objectID, dataVersion, associatedData))
+		if err != nil {
+			t.Fatalf("Encrypt failed: %v", err)
+		}
+		if err := authorizer.CreateAccessObject(txCtx, objectID, userID, common.KeyTypeData, woek); err != nil {
+			t.Fatalf("CreateAccessObject failed: %v", err)
+		}
+		if err := authorizer.UpdateAccessObject(txCtx, objectID, common.AccessObject{Woek: woek}); err != nil {
+			t.Fatalf("UpdateAccessObject failed: %v", err)
+		}
+
+		if store {
+			objectStore[objectID.String()+AssociatedDataStoreSuffix] = associatedData
+			objectStore[objectID.String()+CiphertextStoreSuffix] = ciphertext
+		}
+		return objectID
+	}
+
+	// Create more objects than fit in a single batch
+	for i := 0; i < migrateBatchSize+5; i++ {
+		createObject(0, true)
+	}
+	// Object with a stale staged ciphertext of a migration whose commit failed
+	staleObjectID := createObject(0, true)
+	objectStore[staleObjectID.String()+RekeyAssociatedDataStoreSuffix] = associatedData
+	objectStore[staleObjectID.String()+RekeyStoreSuffix] = []byte("stale ciphertext")
+	// Object created through the Encryption service
+	encObjectID := createObject(0, false)
+
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	// Running the migration twice must yield the same result
+	for i := 0; i < 2; i++ {
+		if err := strg.MigrateObjectsCLI(); err != nil {
+			t.Fatalf("MigrateObjectsCLI failed: %v", err)
+		}
+	}
+
+	tx, err = authStore.NewTransaction(ctx)
+	if err != nil {
+		t.Fatalf("NewTransaction failed: %v", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	txCtx = context.WithValue(ctx, common.AuthStorageTxCtxKey, tx)
+
+	for objectID, plaintext := range plaintexts {
+		accessObject, err := authorizer.FetchAccessObject(txCtx, objectID)
+		if err != nil {
+			t.Fatalf("FetchAccessObject failed: %v", err)
+		}
+
+		if objectID == encObjectID {
+			if accessObject.DataVersion != 0 {
+				t.Errorf("Encryption service object migrated")
+			}
+			continue
+		}
+
+		if accessObject.DataVersion != common.InitialDataVersion {
+			t.Fatalf("Object not migrated: data version %d", accessObject.DataVersion)
+		}
+
+		retrieveCtx := context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+		retrieveResponse, err := strg.Retrieve(retrieveCtx, &RetrieveRequest{ObjectId: objectID.String()})
+		if err != nil {
+			t.Fatalf("Retrieving migrated object failed: %v", err)
+		}
+		if !bytes.Equal(plaintext, retrieveResponse.Plaintext) {
+			t.Fatalf("Retrieved plaintext not equal to stored plaintext: %v != %v", retrieveResponse.Plaintext, plaintext)
+		}
+		if _, exists := objectStore[objectID.String()+RekeyStoreSuffix]; exists {
+			t.Fatalf("Staged ciphertext not moved into place")
+		}
+	}
+}
+
+// Test that a legacy object stays readable if committing its migration fails
+func TestMigrateObjectFailCommit(t *testing.T) {
+	ctx, objectIDString := storeObject(t, []byte("plaintext_bytes"), []byte("associated_data_bytes"))
+	objectID := uuid.FromStringOrNil(objectIDString)
+
+	// Turn the object into a legacy object
+	accessObject := *ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	woek, ciphertext, err := cryptor.Encrypt([]byte("plaintext_bytes"), []byte("associated_data_bytes"))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	accessObject.Woek = woek
+	accessObject.DataVersion = 0
+	if err := authorizer.UpdateAccessObject(ctx, objectID, accessObject); err != nil {
+		t.Fatalf("UpdateAccessObject failed: %v", err)
+	}
+	objectStore[objectIDString+CiphertextStoreSuffix] = ciphertext
+
+	commitErr := errors.New("commit failed")
+	failingTx := *authStorageTxMock
+	failingTx.CommitFunc = func(ctx context.Context) error {
+		return commitErr
+	}
+	failingTx.RollbackFunc = func(ctx context.Context) error {
+		return nil
+	}
+	strg := strg
+	strg.AuthStore = &authstorage.AuthStoreMock{
+		NewTransactionFunc: func(ctx context.Context) (interfaces.AuthStoreTxInterface, error) {
+			return &failingTx, nil
+		},
+	}
+
+	// The mock applies the update even though the commit fails, so restore the access object
+	if _, err := strg.migrateObject(ctx, objectID); !errors.Is(err, commitErr) {
+		t.Fatalf("Migration did not fail as expected: %v", err)
+	}
+	if err := authorizer.UpdateAccessObject(ctx, objectID, accessObject); err != nil {
+		t.Fatalf("UpdateAccessObject failed: %v", err)
+	}
+
+	retrieveCtx := context.WithValue(ctx, common.AccessObjectCtxKey, &accessObject)
+	retrieveResponse, err := strg.Retrieve(retrieveCtx, &RetrieveRequest{ObjectId: objectIDString})
+	if err != nil {
+		t.Fatalf("Retrieving object after failed migration failed: %v", err)
+	}
+	if !bytes.Equal(retrieveResponse.Plaintext, []byte("plaintext_bytes")) {
+		t.Fatalf("Retrieved plaintext not equal to stored plaintext: %v", retrieveResponse.Plaintext)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gofrs/uuid"
+
+	"encryption-service/common"
+	"encryption-service/interfaces"
+	log "encryption-service/logger"
+)
+
+// RekeyObject re-encrypts the object under a new random key and stores `accessObject` with the new
+// key and an incremented data version. The auth storage transaction found in the context is
+// committed. Returns `interfaces.ErrNotFound` if the object was not stored by the Storage service, and
+// `common.ErrImportedKeyDeleted` if the object is encrypted under an imported key that was deleted.
+//
+// The new ciphertext is staged next to the old one until the new key is committed. Until then the
+// old key and ciphertext stay valid, and once it is committed a subsequent re-key moves the staged
+// ciphertext into place if this is interrupted.
+func (strg *Storage) RekeyObject(ctx context.Context, objectID uuid.UUID, accessObject common.AccessObject) error {
+	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
+	if !ok {
+		return errors.New("could not typecast authstorage to AuthStoreTxInterface")
+	}
+	if !accessObject.HasKeyType(common.KeyTypeData) {
+		// Only data objects are stored by the Storage service
+		return interfaces.ErrNotFound
+	}
+
+	objectIDString := objectID.String()
+	aad, err := strg.ObjectStore.Retrieve(ctx, objectIDString+AssociatedDataStoreSuffix)
+	if err != nil {
+		return err
+	}
+
+	ciphertext, err := strg.ObjectStore.Retrieve(ctx, objectIDString+CiphertextStoreSuffix)
+	if err != nil {
+		return err
+	}
+
+	// Objects encrypted under an imported key can only be re-keyed while the key exists
+	oldWOEK, err := strg.Authorizer.FetchObjectKey(ctx, &accessObject)
+	if err != nil {
+		return err
+	}
+
+	// Legacy ciphertexts are decrypted in place, so keep the ciphertext intact for resuming
+	plaintext, err := strg.DataCryptor.Decrypt(oldWOEK, append([]byte(nil), ciphertext...), common.DataAAD(objectID, accessObject.DataVersion, aad))
+	if err != nil {
+		aad, plaintext, err = strg.resumeRekey(ctx, objectID, &accessObject, oldWOEK)
+		if err != nil {
+			return err
+		}
+		log.Info(ctx, "Resumed interrupted re-key")
+	}
+
+	accessObject.DataVersion++
+	woek, ciphertext, err := strg.DataCryptor.Encrypt(plaintext, common.DataAAD(objectID, accessObject.DataVersion, aad))
+	if err != nil {
+		return err
+	}
+	accessObject.Woek = woek
+	accessObject.Reencryptions = 0
+	accessObject.KeyID = uuid.Nil
+
+	if err := strg.stageObject(ctx, objectIDString, aad, ciphertext); err != nil {
+		return err
+	}
+
+	if err := strg.Authorizer.UpdateAccessObject(ctx, objectID, accessObject); err != nil {
+		return err
+	}
+
+	if err := authStorageTx.Commit(ctx); err != nil {
+		return err
+	}
+
+	return strg.finishRekey(ctx, objectIDString, aad, ciphertext)
+}
+
+// stageObject stores the associated data and ciphertext of a new version of an object next to the
+// current ones, where they stay until the access object of the new version is committed
+func (strg *Storage) stageObject(ctx context.Context, objectIDString string, aad, ciphertext []byte) error {
+	if err := strg.ObjectStore.Store(ctx, objectIDString+RekeyAssociatedDataStoreSuffix, aad); err != nil {
+		return err
+	}
+	return strg.ObjectStore.Store(ctx, objectIDString+RekeyStoreSuffix, ciphertext)
+}
+
+// errStaleRekey is returned if the staged object belongs to a re-key or update whose commit failed
+var errStaleRekey = errors.New("staged object does not belong to the access object")
+
+// openStaged decrypts the staged object with `woek`, the key of the access object, and returns its
+// associated data, ciphertext and plaintext. Returns `interfaces.ErrNotFound` if no object is staged,
+// and `errStaleRekey` if the staged object does not belong to the access object.
+func (strg *Storage) openStaged(ctx context.Context, objectID uuid.UUID, accessObject *common.AccessObject, woek []byte) ([]byte, []byte, []byte, error) {
+	objectIDString := objectID.String()
+	ciphertext, err := strg.ObjectStore.Retrieve(ctx, objectIDString+RekeyStoreSuffix)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	aad, err := strg.ObjectStore.Retrieve(ctx, objectIDString+RekeyAssociatedDataStoreSuffix)
+	if errors.Is(err, interfaces.ErrNotFound) {
+		// Staged by an earlier version, which only staged the ciphertext
+		aad, err = strg.ObjectStore.Retrieve(ctx, objectIDString+AssociatedDataStoreSuffix)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	plaintext, err := strg.DataCryptor.Decrypt(woek, append([]byte(nil), ciphertext...), common.DataAAD(objectID, accessObject.DataVersion, aad))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %v", errStaleRekey, err)
+	}
+
+	return aad, ciphertext, plaintext, nil
+}
+
+// resumeRekey finishes a re-key or update whose access object was committed, but whose staged
+// object was not moved into place, and returns the associated data and plaintext of the object
+func (strg *Storage) resumeRekey(ctx context.Context, objectID uuid.UUID, accessObject *common.AccessObject, woek []byte) ([]byte, []byte, error) {
+	aad, ciphertext, plaintext, err := strg.openStaged(ctx, objectID, accessObject, woek)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return aad, plaintext, strg.finishRekey(ctx, objectID.String(), aad, ciphertext)
+}
+
+// finishPendingRekey moves a staged object into place if it belongs to the access object. Objects
+// staged by a re-key or update whose commit failed are left to be overwritten.
+func (strg *Storage) finishPendingRekey(ctx context.Context, objectID uuid.UUID, accessObject *common.AccessObject, woek []byte) error {
+	_, _, err := strg.resumeRekey(ctx, objectID, accessObject, woek)
+	if errors.Is(err, interfaces.ErrNotFound) || errors.Is(err, errStaleRekey) {
+		return nil
+	}
+	return err
+}
+
+// finishRekey moves a staged object into place
+func (strg *Storage) finishRekey(ctx context.Context, objectIDString string, aad, ciphertext []byte) error {
+	if err := strg.ObjectStore.Store(ctx, objectIDString+AssociatedDataStoreSuffix, aad); err != nil {
+		return err
+	}
+	if err := strg.ObjectStore.Store(ctx, objectIDString+CiphertextStoreSuffix, ciphertext); err != nil {
+		return err
+	}
+	if err := strg.ObjectStore.Delete(ctx, objectIDString+RekeyStoreSuffix); err != nil {
+		return err
+	}
+	return strg.ObjectStore.Delete(ctx, objectIDString+RekeyAssociatedDataStoreSuffix)
+}
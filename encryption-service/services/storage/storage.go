@@ -14,6 +14,7 @@
 package storage
 
 import (
+	"encryption-service/common"
 	"encryption-service/interfaces"
 )
 
@@ -23,5 +24,13 @@ type Storage struct {
 	AuthStore   interfaces.AuthStoreInterface
 	ObjectStore interfaces.ObjectStoreInterface
 	DataCryptor interfaces.CryptorInterface
+	// MaxEncryptionsPerKey is the number of encryptions under an object key after which the object
+	// is rolled over to a new key. If zero, common.DefaultMaxEncryptionsPerKey is used.
+	MaxEncryptionsPerKey uint64
 	UnimplementedEncryptonizeServer
 }
+
+// maxEncryptionsPerKey returns the configured number of encryptions allowed under an object key
+func (strg *Storage) maxEncryptionsPerKey() uint64 {
+	return common.MaxEncryptionsPerKey(strg.MaxEncryptionsPerKey)
+}
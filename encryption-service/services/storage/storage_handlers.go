@@ -15,6 +15,8 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/gofrs/uuid"
 	"google.golang.org/grpc/codes"
@@ -28,6 +30,11 @@ import (
 const AssociatedDataStoreSuffix = "_aad"
 const CiphertextStoreSuffix = "_data"
 
+// Suffixes of the ciphertext and associated data of a re-keyed or updated object until they have
+// been moved into place
+const RekeyStoreSuffix = "_rekey"
+const RekeyAssociatedDataStoreSuffix = "_rekey_aad"
+
 // API exposed function, encrypts data and stores it in the object store
 // Assumes that user credentials are to be found in context metadata
 // Errors if authentication or storing fails
@@ -54,13 +61,33 @@ func (strg *Storage) Store(ctx context.Context, request *StoreRequest) (*StoreRe
 		return nil, err
 	}
 
-	woek, ciphertext, err := strg.DataCryptor.Encrypt(request.Plaintext, request.AssociatedData)
-	if err != nil {
-		log.Error(ctx, err, "Store: Failed to encrypt object")
-		return nil, status.Errorf(codes.Internal, "error encountered while storing object")
+	aad := common.DataAAD(objectID, common.InitialDataVersion, request.AssociatedData)
+	var ciphertext []byte
+	if request.KeyId == "" {
+		var woek []byte
+		woek, ciphertext, err = strg.DataCryptor.Encrypt(request.Plaintext, aad)
+		if err != nil {
+			log.Error(ctx, err, "Store: Failed to encrypt object")
+			return nil, status.Errorf(codes.Internal, "error encountered while storing object")
+		}
+
+		err = strg.Authorizer.CreateAccessObject(ctx, objectID, userID, common.KeyTypeData, woek)
+	} else {
+		var keyID uuid.UUID
+		var woek []byte
+		keyID, woek, err = strg.importedKey(ctx, request.KeyId)
+		if err != nil {
+			return nil, err
+		}
+
+		ciphertext, err = strg.DataCryptor.EncryptWithKey(request.Plaintext, aad, woek)
+		if err != nil {
+			log.Error(ctx, err, "Store: Failed to encrypt object")
+			return nil, status.Errorf(codes.Internal, "error encountered while storing object")
+		}
+
+		err = strg.Authorizer.CreateAccessObjectWithKeyID(ctx, objectID, userID, keyID)
 	}
-
-	err = strg.Authorizer.CreateAccessObject(ctx, objectID, userID, woek)
 	if err != nil {
 		log.Error(ctx, err, "Store: Failed to create new access object")
 		return nil, status.Errorf(codes.Internal, "error encountered while storing object")
@@ -100,6 +127,18 @@ func (strg *Storage) Retrieve(ctx context.Context, request *RetrieveRequest) (*R
 		return nil, err
 	}
 
+	if !accessObject.HasKeyType(common.KeyTypeData) {
+		err := status.Errorf(codes.InvalidArgument, "object is not a data object")
+		log.Error(ctx, err, "Retrieve: Object has the wrong key type")
+		return nil, err
+	}
+
+	objectID, err := uuid.FromString(objectIDString)
+	if err != nil {
+		log.Errorf(ctx, err, "Retrieve: Failed to parse object ID %s as UUID", objectIDString)
+		return nil, status.Errorf(codes.InvalidArgument, "invalid object ID")
+	}
+
 	aad, err := strg.ObjectStore.Retrieve(ctx, objectIDString+AssociatedDataStoreSuffix)
 	if err != nil {
 		log.Error(ctx, err, "Retrieve: Failed to retrieve associated data")
@@ -112,12 +151,33 @@ func (strg *Storage) Retrieve(ctx context.Context, request *RetrieveRequest) (*R
 		return nil, status.Errorf(codes.Internal, "error encountered while retrieving object")
 	}
 
-	plaintext, err := strg.DataCryptor.Decrypt(accessObject.GetWOEK(), ciphertext, aad)
+	woek, err := strg.Authorizer.FetchObjectKey(ctx, accessObject)
+	if errors.Is(err, common.ErrImportedKeyDeleted) {
+		log.Error(ctx, err, "Retrieve: Imported key of object not found")
+		return nil, status.Errorf(codes.FailedPrecondition, "imported key has been deleted")
+	}
 	if err != nil {
-		log.Error(ctx, err, "Retrieve: Failed to decrypt object")
+		log.Error(ctx, err, "Retrieve: Failed to fetch object key")
 		return nil, status.Errorf(codes.Internal, "error encountered while retrieving object")
 	}
 
+	plaintext, err := strg.DataCryptor.Decrypt(woek, ciphertext, common.DataAAD(objectID, accessObject.DataVersion, aad))
+	if err != nil {
+		// A re-key or update may have been interrupted before the staged object was moved into place
+		var stagedCiphertext []byte
+		aad, stagedCiphertext, plaintext, err = strg.openStaged(ctx, objectID, accessObject, woek)
+		if err != nil {
+			log.Error(ctx, err, "Retrieve: Failed to decrypt object")
+			return nil, status.Errorf(codes.Internal, "error encountered while retrieving object")
+		}
+
+		if err := strg.finishRekey(ctx, objectIDString, aad, stagedCiphertext); err != nil {
+			log.Error(ctx, err, "Retrieve: Failed to move staged object into place")
+		} else {
+			log.Info(ctx, "Retrieve: Resumed interrupted re-key")
+		}
+	}
+
 	log.Info(ctx, "Retrieve: Object retrieved")
 
 	return &RetrieveResponse{
@@ -164,6 +224,18 @@ func (strg *Storage) Delete(ctx context.Context, request *DeleteRequest) (*Delet
 		return nil, status.Errorf(codes.Internal, "error encountered while deleting object")
 	}
 
+	err = strg.ObjectStore.Delete(ctx, objectIDString+RekeyStoreSuffix)
+	if err != nil {
+		log.Error(ctx, err, "Delete: Failed to delete re-keyed object")
+		return nil, status.Errorf(codes.Internal, "error encountered while deleting object")
+	}
+
+	err = strg.ObjectStore.Delete(ctx, objectIDString+RekeyAssociatedDataStoreSuffix)
+	if err != nil {
+		log.Error(ctx, err, "Delete: Failed to delete re-keyed associated data")
+		return nil, status.Errorf(codes.Internal, "error encountered while deleting object")
+	}
+
 	if err := authStorageTx.Commit(ctx); err != nil {
 		log.Error(ctx, err, "Delete: Failed to commit auth storage transaction")
 		return nil, status.Errorf(codes.Internal, "error encountered while deleting object")
@@ -191,19 +263,85 @@ func (strg *Storage) Update(ctx context.Context, request *UpdateRequest) (*Updat
 		return nil, err
 	}
 
-	ciphertext, err := strg.DataCryptor.EncryptWithKey(request.Plaintext, request.AssociatedData, accessObject.GetWOEK())
+	if !accessObject.HasKeyType(common.KeyTypeData) {
+		err := status.Errorf(codes.InvalidArgument, "object is not a data object")
+		log.Error(ctx, err, "Update: Object has the wrong key type")
+		return nil, err
+	}
+
+	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while updating object")
+		log.Error(ctx, err, "Update: Could not typecast authstorage to AuthStoreTxInterface")
+		return nil, err
+	}
+
+	objectID, err := uuid.FromString(objectIDString)
+	if err != nil {
+		log.Errorf(ctx, err, "Update: Failed to parse object ID %s as UUID", objectIDString)
+		return nil, status.Errorf(codes.InvalidArgument, "invalid object ID")
+	}
+
+	// Bump the data version such that the previous ciphertext can't be replayed
+	updated := *accessObject
+	updated.DataVersion++
+
+	// Every encryption under the same key uses a random nonce, so roll over to a new key before the
+	// probability of a nonce collision becomes significant
+	woek, rollover, err := strg.countEncryption(ctx, &updated)
+	if errors.Is(err, common.ErrImportedKeyDeleted) {
+		log.Error(ctx, err, "Update: Imported key of object not found")
+		return nil, status.Errorf(codes.FailedPrecondition, "imported key has been deleted")
+	}
+	if errors.Is(err, common.ErrEncryptionLimitReached) {
+		log.Error(ctx, err, "Update: Imported key can not be used for further encryptions")
+		return nil, status.Errorf(codes.FailedPrecondition, "encryption limit of key reached")
+	}
+	if err != nil {
+		log.Error(ctx, err, "Update: Failed to count encryption")
+		return nil, status.Errorf(codes.Internal, "error encountered while updating object")
+	}
+
+	var ciphertext []byte
+	aad := common.DataAAD(objectID, updated.DataVersion, request.AssociatedData)
+	if rollover {
+		updated.Woek, ciphertext, err = strg.DataCryptor.Encrypt(request.Plaintext, aad)
+		updated.Reencryptions = 0
+		log.Info(ctx, "Update: Encryption limit reached, rolling over to a new key")
+	} else {
+		ciphertext, err = strg.DataCryptor.EncryptWithKey(request.Plaintext, aad, woek)
+	}
 	if err != nil {
 		log.Error(ctx, err, "Update: Failed to encrypt object")
 		return nil, status.Errorf(codes.Internal, "error encountered while updating object")
 	}
 
-	if err := strg.ObjectStore.Store(ctx, objectIDString+AssociatedDataStoreSuffix, request.AssociatedData); err != nil {
-		log.Error(ctx, err, "Update: Failed to store associated data")
+	// Staging overwrites any staged object, so finish an interrupted re-key or update first
+	if err := strg.finishPendingRekey(ctx, objectID, accessObject, woek); err != nil {
+		log.Error(ctx, err, "Update: Failed to resume interrupted re-key")
 		return nil, status.Errorf(codes.Internal, "error encountered while updating object")
 	}
 
-	if err := strg.ObjectStore.Store(ctx, objectIDString+CiphertextStoreSuffix, ciphertext); err != nil {
-		log.Error(ctx, err, "Update: Failed to store object")
+	// The new version is staged like a re-key, such that the previous version stays readable if
+	// the commit fails. This matters in particular if the object was rolled over to a new key.
+	if err := strg.stageObject(ctx, objectIDString, request.AssociatedData, ciphertext); err != nil {
+		log.Error(ctx, err, "Update: Failed to stage object")
+		return nil, status.Errorf(codes.Internal, "error encountered while updating object")
+	}
+
+	if err := strg.Authorizer.UpdateAccessObject(ctx, objectID, updated); err != nil {
+		log.Error(ctx, err, "Update: Failed to update access object")
+		return nil, status.Errorf(codes.Internal, "error encountered while updating object")
+	}
+
+	if err := authStorageTx.Commit(ctx); err != nil {
+		log.Error(ctx, err, "Update: Failed to commit auth storage transaction")
+		return nil, status.Errorf(codes.Internal, "error encountered while updating object")
+	}
+
+	// If this fails, the staged object is moved into place by the next retrieve, update or re-key
+	if err := strg.finishRekey(ctx, objectIDString, request.AssociatedData, ciphertext); err != nil {
+		log.Error(ctx, err, "Update: Failed to move staged object into place")
 		return nil, status.Errorf(codes.Internal, "error encountered while updating object")
 	}
 
@@ -211,3 +349,114 @@ func (strg *Storage) Update(ctx context.Context, request *UpdateRequest) (*Updat
 
 	return &UpdateResponse{}, nil
 }
+
+// API exposed function, re-encrypts the object with the provided object ID under a new random key
+// Assumes that user credentials are to be found in context metadata
+// Errors if authentication, authorization, or re-encrypting the object fails
+func (strg *Storage) Rekey(ctx context.Context, request *RekeyRequest) (*RekeyResponse, error) {
+	objectIDString := request.ObjectId
+	accessObject, ok := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while re-keying object")
+		log.Error(ctx, err, "Rekey: Could not typecast access object to AccessObject")
+		return nil, err
+	}
+
+	objectID, err := uuid.FromString(objectIDString)
+	if err != nil {
+		log.Errorf(ctx, err, "Rekey: Failed to parse object ID %s as UUID", objectIDString)
+		return nil, status.Errorf(codes.InvalidArgument, "invalid object ID")
+	}
+
+	err = strg.RekeyObject(ctx, objectID, *accessObject)
+	if errors.Is(err, interfaces.ErrNotFound) {
+		log.Error(ctx, err, "Rekey: Object not found in object storage")
+		return nil, status.Errorf(codes.FailedPrecondition, "object is not stored by the storage service")
+	}
+	if errors.Is(err, common.ErrImportedKeyDeleted) {
+		log.Error(ctx, err, "Rekey: Imported key of object not found")
+		return nil, status.Errorf(codes.FailedPrecondition, "imported key has been deleted")
+	}
+	if err != nil {
+		log.Error(ctx, err, "Rekey: Failed to re-key object")
+		return nil, status.Errorf(codes.Internal, "error encountered while re-keying object")
+	}
+
+	log.Info(ctx, "Rekey: Object re-keyed")
+
+	return &RekeyResponse{}, nil
+}
+
+// importedKey returns the ID and wrapped key of the imported key referenced by a request storing a
+// new object, and counts an encryption under it. The access object of the key is put in the context
+// by the authorization middleware.
+func (strg *Storage) importedKey(ctx context.Context, keyIDString string) (uuid.UUID, []byte, error) {
+	keyAccessObject, ok := ctx.Value(common.KeyAccessObjectCtxKey).(*common.AccessObject)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while storing object")
+		log.Error(ctx, err, "Store: Could not typecast key access object to AccessObject")
+		return uuid.Nil, nil, err
+	}
+
+	if !keyAccessObject.HasKeyType(common.KeyTypeImported) {
+		err := status.Errorf(codes.InvalidArgument, "key is not an imported key")
+		log.Error(ctx, err, "Store: Key has the wrong key type")
+		return uuid.Nil, nil, err
+	}
+
+	keyID, err := uuid.FromString(keyIDString)
+	if err != nil {
+		log.Errorf(ctx, err, "Store: Failed to parse key ID %s as UUID", keyIDString)
+		return uuid.Nil, nil, status.Errorf(codes.InvalidArgument, "invalid key ID")
+	}
+
+	updated := *keyAccessObject
+	if err := updated.CountEncryption(strg.maxEncryptionsPerKey()); err != nil {
+		log.Error(ctx, err, "Store: Key can not be used for further encryptions")
+		return uuid.Nil, nil, status.Errorf(codes.FailedPrecondition, "encryption limit of key reached")
+	}
+
+	if err := strg.Authorizer.UpdateAccessObject(ctx, keyID, updated); err != nil {
+		log.Error(ctx, err, "Store: Failed to update key access object")
+		return uuid.Nil, nil, status.Errorf(codes.Internal, "error encountered while storing object")
+	}
+
+	return keyID, keyAccessObject.GetWOEK(), nil
+}
+
+// countEncryption counts an encryption under the key of an object being updated and returns the
+// wrapped key the object is encrypted under, and whether the object must be rolled over to a new key
+// instead.
+//
+// Encryptions under an imported key are counted by the access object of the key, which is updated
+// in the auth storage. Such objects are never rolled over, as the data must stay revocable by
+// deleting the key, so `common.ErrEncryptionLimitReached` is returned once the key reaches the limit,
+// and `common.ErrImportedKeyDeleted` if the key has been deleted.
+func (strg *Storage) countEncryption(ctx context.Context, accessObject *common.AccessObject) ([]byte, bool, error) {
+	if accessObject.KeyID == uuid.Nil {
+		// The encryption creating the object is not counted by Reencryptions
+		if accessObject.Reencryptions+1 >= strg.maxEncryptionsPerKey() {
+			return accessObject.GetWOEK(), true, nil
+		}
+		accessObject.Reencryptions++
+		return accessObject.GetWOEK(), false, nil
+	}
+
+	keyAccessObject, err := strg.Authorizer.FetchAccessObject(ctx, accessObject.KeyID)
+	if errors.Is(err, interfaces.ErrNotFound) {
+		return nil, false, common.ErrImportedKeyDeleted
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !keyAccessObject.HasKeyType(common.KeyTypeImported) {
+		return nil, false, fmt.Errorf("key %v of object is not an imported key", accessObject.KeyID)
+	}
+
+	if err := keyAccessObject.CountEncryption(strg.maxEncryptionsPerKey()); err != nil {
+		return nil, false, err
+	}
+
+	return keyAccessObject.GetWOEK(), false, strg.Authorizer.UpdateAccessObject(ctx, accessObject.KeyID, *keyAccessObject)
+}
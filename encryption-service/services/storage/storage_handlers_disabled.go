@@ -45,3 +45,9 @@ func (strg *Disabled) Update(ctx context.Context, request *UpdateRequest) (*Upda
 	log.Info(ctx, "Update: Requested inactive endpoint")
 	return strg.UnimplementedEncryptonizeServer.Update(ctx, request)
 }
+
+// API Storage disabled Rekey handler
+func (strg *Disabled) Rekey(ctx context.Context, request *RekeyRequest) (*RekeyResponse, error) {
+	log.Info(ctx, "Rekey: Requested inactive endpoint")
+	return strg.UnimplementedEncryptonizeServer.Rekey(ctx, request)
+}
@@ -20,6 +20,8 @@ import (
 	"testing"
 
 	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"encryption-service/common"
 	"encryption-service/impl/authstorage"
@@ -78,6 +80,10 @@ var authStorageTxMock = &authstorage.AuthStoreTxMock{
 		}
 		return &protected, nil
 	},
+	UpdateAccessObjectFunc: func(ctx context.Context, protected *common.ProtectedAccessObject) error {
+		accessObjectStore[protected.ObjectID] = *protected
+		return nil
+	},
 	DeleteAccessObjectFunc: func(ctx context.Context, objectID uuid.UUID) error {
 		delete(accessObjectStore, objectID)
 		return nil
@@ -309,6 +315,17 @@ func TestUpdateObject(t *testing.T) {
 		t.Fatalf("Updating object failed: %v", err)
 	}
 
+	// Update access object in context
+	accessObject, err = strg.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(storeResponse.ObjectId))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %s", err)
+	}
+	if accessObject.DataVersion != common.InitialDataVersion+1 {
+		t.Fatalf("Data version not incremented: %d", accessObject.DataVersion)
+	}
+
+	ctx = context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+
 	retrieveResponse, err := strg.Retrieve(
 		ctx,
 		&RetrieveRequest{
@@ -328,3 +345,587 @@ func TestUpdateObject(t *testing.T) {
 		t.Fatalf("Retrieved associatedData not equal to updated associatedData: %v != %v", retrieveResponse.AssociatedData, updatedAssociatedData)
 	}
 }
+
+// storeObject stores an object and returns a context with its access object
+func storeObject(t *testing.T, plaintext, associatedData []byte) (context.Context, string) {
+	ctx := setCtxKeys()
+
+	storeResponse, err := strg.Store(
+		ctx,
+		&StoreRequest{
+			Plaintext:      plaintext,
+			AssociatedData: associatedData,
+		},
+	)
+	if err != nil {
+		t.Fatalf("Storing object failed: %v", err)
+	}
+
+	accessObject, err := strg.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(storeResponse.ObjectId))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %s", err)
+	}
+
+	return context.WithValue(ctx, common.AccessObjectCtxKey, accessObject), storeResponse.ObjectId
+}
+
+// Test that updating an object rolls it over to a new key once the encryption limit is reached
+func TestUpdateRollover(t *testing.T) {
+	strg := strg
+	strg.MaxEncryptionsPerKey = 3
+
+	ctx, objectID := storeObject(t, []byte("plaintext_bytes"), []byte("associated_data_bytes"))
+	previousWOEK := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject).GetWOEK()
+
+	tests := []struct {
+		reencryptions uint64
+		rolledOver    bool
+	}{
+		{1, false},
+		{2, false},
+		{0, true},
+		{1, false},
+	}
+
+	for i, test := range tests {
+		plaintext := []byte(fmt.Sprintf("updated_plaintext_bytes_%d", i))
+		if _, err := strg.Update(ctx, &UpdateRequest{ObjectId: objectID, Plaintext: plaintext}); err != nil {
+			t.Fatalf("Updating object failed: %v", err)
+		}
+
+		accessObject, err := strg.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(objectID))
+		if err != nil {
+			t.Fatalf("Failed to fetch access object: %s", err)
+		}
+		if accessObject.Reencryptions != test.reencryptions {
+			t.Fatalf("Update %d: expected %d re-encryptions, got %d", i, test.reencryptions, accessObject.Reencryptions)
+		}
+		if rolledOver := !reflect.DeepEqual(accessObject.GetWOEK(), previousWOEK); rolledOver != test.rolledOver {
+			t.Fatalf("Update %d: expected rolled over %v, got %v", i, test.rolledOver, rolledOver)
+		}
+		previousWOEK = accessObject.GetWOEK()
+
+		ctx = context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+		retrieveResponse, err := strg.Retrieve(ctx, &RetrieveRequest{ObjectId: objectID})
+		if err != nil {
+			t.Fatalf("Retrieving object failed: %v", err)
+		}
+		if !reflect.DeepEqual(plaintext, retrieveResponse.Plaintext) {
+			t.Fatalf("Retrieved plaintext not equal to updated plaintext: %v != %v", retrieveResponse.Plaintext, plaintext)
+		}
+	}
+}
+
+// Test that objects stored under an imported key only refer to the key and count their encryptions
+// on it, can not be updated once the key reaches its encryption limit, and become unreadable once the
+// key is deleted
+func TestStoreUnderImportedKey(t *testing.T) {
+	strg := strg
+	strg.MaxEncryptionsPerKey = 2
+
+	ctx := setCtxKeys()
+	keyID := uuid.Must(uuid.NewV4())
+	keyWOEK, _, err := cryptor.Encrypt(nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if err := strg.Authorizer.CreateAccessObject(ctx, keyID, userID, common.KeyTypeImported, keyWOEK); err != nil {
+		t.Fatalf("Failed to create key access object: %v", err)
+	}
+	keyCtx := func() context.Context {
+		keyAccessObject, err := strg.Authorizer.FetchAccessObject(ctx, keyID)
+		if err != nil {
+			t.Fatalf("Failed to fetch key access object: %v", err)
+		}
+		return context.WithValue(ctx, common.KeyAccessObjectCtxKey, keyAccessObject)
+	}
+
+	plaintext := []byte("plaintext_bytes")
+	storeResponse, err := strg.Store(keyCtx(), &StoreRequest{Plaintext: plaintext, KeyId: keyID.String()})
+	if err != nil {
+		t.Fatalf("Storing object under imported key failed: %v", err)
+	}
+	objectID := storeResponse.ObjectId
+
+	for i := uint64(1); i <= 2; i++ {
+		if i > 1 {
+			plaintext = []byte(fmt.Sprintf("updated_plaintext_bytes_%d", i))
+			if _, err := strg.Update(ctx, &UpdateRequest{ObjectId: objectID, Plaintext: plaintext}); err != nil {
+				t.Fatalf("Updating object failed: %v", err)
+			}
+		}
+
+		accessObject, err := strg.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(objectID))
+		if err != nil {
+			t.Fatalf("Failed to fetch access object: %s", err)
+		}
+		keyAccessObject := keyCtx().Value(common.KeyAccessObjectCtxKey).(*common.AccessObject)
+		if keyAccessObject.Reencryptions != i {
+			t.Fatalf("Step %d: expected %d encryptions under the key, got %d", i, i, keyAccessObject.Reencryptions)
+		}
+		if accessObject.KeyID != keyID || len(accessObject.GetWOEK()) != 0 {
+			t.Fatalf("Step %d: object should only refer to the imported key", i)
+		}
+
+		ctx = context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+		retrieveResponse, err := strg.Retrieve(ctx, &RetrieveRequest{ObjectId: objectID})
+		if err != nil {
+			t.Fatalf("Retrieving object failed: %v", err)
+		}
+		if !reflect.DeepEqual(plaintext, retrieveResponse.Plaintext) {
+			t.Fatalf("Retrieved plaintext not equal to stored plaintext: %v != %v", retrieveResponse.Plaintext, plaintext)
+		}
+	}
+
+	// Neither updates nor new objects may use the key once it reaches its limit
+	_, err = strg.Update(ctx, &UpdateRequest{ObjectId: objectID, Plaintext: []byte("rejected_plaintext_bytes")})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Updating object beyond the encryption limit should have failed: %v", err)
+	}
+	_, err = strg.Store(keyCtx(), &StoreRequest{Plaintext: plaintext, KeyId: keyID.String()})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Storing object beyond the encryption limit should have failed: %v", err)
+	}
+
+	// Only imported keys can be referenced
+	_, err = strg.Store(context.WithValue(ctx, common.KeyAccessObjectCtxKey, ctx.Value(common.AccessObjectCtxKey)), &StoreRequest{Plaintext: plaintext, KeyId: objectID})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Storing object under a data object should have failed: %v", err)
+	}
+
+	// Deleting the key revokes access to the object
+	if err := strg.Authorizer.DeleteAccessObject(ctx, keyID); err != nil {
+		t.Fatalf("Failed to delete key access object: %v", err)
+	}
+	_, err = strg.Retrieve(ctx, &RetrieveRequest{ObjectId: objectID})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Retrieving object after deleting its key should have failed with FailedPrecondition: %v", err)
+	}
+	_, err = strg.Update(ctx, &UpdateRequest{ObjectId: objectID, Plaintext: plaintext})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Updating object after deleting its key should have failed with FailedPrecondition: %v", err)
+	}
+	_, err = strg.Rekey(ctx, &RekeyRequest{ObjectId: objectID})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Re-keying object after deleting its key should have failed with FailedPrecondition: %v", err)
+	}
+}
+
+// Test that a failed commit of an update leaves the previous version readable, even if the object
+// was rolled over to a new key
+func TestUpdateFailCommit(t *testing.T) {
+	strg := strg
+	strg.MaxEncryptionsPerKey = 1
+
+	plaintext := []byte("plaintext_bytes")
+	associatedData := []byte("associated_data_bytes")
+	ctx, objectID := storeObject(t, plaintext, associatedData)
+
+	authStorageTx := &authstorage.AuthStoreTxMock{
+		UpdateAccessObjectFunc: func(ctx context.Context, protected *common.ProtectedAccessObject) error {
+			return nil
+		},
+		CommitFunc: func(ctx context.Context) error {
+			return fmt.Errorf("commit failed")
+		},
+	}
+	updateCtx := context.WithValue(ctx, common.AuthStorageTxCtxKey, authStorageTx)
+
+	updatedPlaintext := []byte("updated_plaintext_bytes")
+	_, err := strg.Update(updateCtx, &UpdateRequest{ObjectId: objectID, Plaintext: updatedPlaintext, AssociatedData: []byte("updated_associated_data_bytes")})
+	if err == nil {
+		t.Fatal("Updating object did not fail as expected")
+	}
+
+	retrieveResponse, err := strg.Retrieve(ctx, &RetrieveRequest{ObjectId: objectID})
+	if err != nil {
+		t.Fatalf("Retrieving object failed: %v", err)
+	}
+	if !reflect.DeepEqual(plaintext, retrieveResponse.Plaintext) || !reflect.DeepEqual(associatedData, retrieveResponse.AssociatedData) {
+		t.Fatalf("Retrieved object not equal to stored object: %v != %v", retrieveResponse.Plaintext, plaintext)
+	}
+
+	// A retry succeeds
+	_, err = strg.Update(ctx, &UpdateRequest{ObjectId: objectID, Plaintext: updatedPlaintext})
+	if err != nil {
+		t.Fatalf("Updating object failed: %v", err)
+	}
+	accessObject, err := strg.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(objectID))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %s", err)
+	}
+	ctx = context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+
+	retrieveResponse, err = strg.Retrieve(ctx, &RetrieveRequest{ObjectId: objectID})
+	if err != nil {
+		t.Fatalf("Retrieving object failed: %v", err)
+	}
+	if !reflect.DeepEqual(updatedPlaintext, retrieveResponse.Plaintext) {
+		t.Fatalf("Retrieved plaintext not equal to updated plaintext: %v != %v", retrieveResponse.Plaintext, updatedPlaintext)
+	}
+	if _, exists := objectStore[objectID+RekeyStoreSuffix]; exists {
+		t.Fatal("Staged ciphertext not moved into place")
+	}
+}
+
+// Test that an update rolling over to a new key stays readable if its commit succeeds but moving
+// the staged object into place fails, and that the next update finishes the move first
+func TestUpdateRolloverFailMove(t *testing.T) {
+	strg := strg
+	strg.MaxEncryptionsPerKey = 1
+
+	ctx, objectID := storeObject(t, []byte("plaintext_bytes"), []byte("associated_data_bytes"))
+
+	failingObjectStore := *objectStoreMock
+	failingObjectStore.StoreFunc = func(ctx context.Context, key string, object []byte) error {
+		if key == objectID+CiphertextStoreSuffix {
+			return fmt.Errorf("store failed")
+		}
+		return objectStoreMock.StoreFunc(ctx, key, object)
+	}
+	failingStrg := strg
+	failingStrg.ObjectStore = &failingObjectStore
+
+	updatedPlaintext := []byte("updated_plaintext_bytes")
+	if _, err := failingStrg.Update(ctx, &UpdateRequest{ObjectId: objectID, Plaintext: updatedPlaintext}); err == nil {
+		t.Fatal("Updating object did not fail as expected")
+	}
+
+	// The access object was committed with the new key
+	accessObject, err := strg.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(objectID))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %s", err)
+	}
+	if reflect.DeepEqual(accessObject.GetWOEK(), ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject).GetWOEK()) {
+		t.Fatal("Object was not rolled over to a new key")
+	}
+	ctx = context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+
+	retrieveResponse, err := failingStrg.Retrieve(ctx, &RetrieveRequest{ObjectId: objectID})
+	if err != nil {
+		t.Fatalf("Retrieving object failed: %v", err)
+	}
+	if !reflect.DeepEqual(updatedPlaintext, retrieveResponse.Plaintext) {
+		t.Fatalf("Retrieved plaintext not equal to updated plaintext: %v != %v", retrieveResponse.Plaintext, updatedPlaintext)
+	}
+
+	// The next update moves the staged object into place before staging its own
+	if _, err := strg.Update(ctx, &UpdateRequest{ObjectId: objectID, Plaintext: []byte("next_plaintext_bytes")}); err != nil {
+		t.Fatalf("Updating object failed: %v", err)
+	}
+	if _, exists := objectStore[objectID+RekeyStoreSuffix]; exists {
+		t.Fatal("Staged ciphertext not moved into place")
+	}
+}
+
+// Test that a ciphertext can't be moved to another object using the same key
+func TestRetrieveSwappedCiphertext(t *testing.T) {
+	associatedData := []byte("associated_data_bytes")
+	ctx, objectID := storeObject(t, []byte("plaintext_bytes"), associatedData)
+	_, otherObjectID := storeObject(t, []byte("other_plaintext_bytes"), associatedData)
+
+	// Encrypt the data of the other object under the key of this object, such that only the bound
+	// object ID differs
+	accessObject := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	otherCiphertext, err := strg.DataCryptor.EncryptWithKey([]byte("other_plaintext_bytes"), common.DataAAD(uuid.FromStringOrNil(otherObjectID), common.InitialDataVersion, associatedData), accessObject.GetWOEK())
+	if err != nil {
+		t.Fatalf("EncryptWithKey failed: %v", err)
+	}
+	objectStore[objectID+CiphertextStoreSuffix] = otherCiphertext
+
+	_, err = strg.Retrieve(ctx, &RetrieveRequest{ObjectId: objectID})
+	if err == nil {
+		t.Fatal("Retrieving swapped ciphertext did not fail as expected")
+	}
+}
+
+// Test that a ciphertext of an earlier version of an object can't be replayed
+func TestRetrieveReplayedCiphertext(t *testing.T) {
+	ctx, objectID := storeObject(t, []byte("plaintext_bytes"), []byte("associated_data_bytes"))
+	oldCiphertext := objectStore[objectID+CiphertextStoreSuffix]
+
+	_, err := strg.Update(
+		ctx,
+		&UpdateRequest{
+			ObjectId:       objectID,
+			Plaintext:      []byte("updated_plaintext_bytes"),
+			AssociatedData: []byte("associated_data_bytes"),
+		},
+	)
+	if err != nil {
+		t.Fatalf("Updating object failed: %v", err)
+	}
+
+	accessObject, err := strg.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(objectID))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %s", err)
+	}
+	ctx = context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+	objectStore[objectID+CiphertextStoreSuffix] = oldCiphertext
+
+	_, err = strg.Retrieve(ctx, &RetrieveRequest{ObjectId: objectID})
+	if err == nil {
+		t.Fatal("Retrieving replayed ciphertext did not fail as expected")
+	}
+}
+
+// Test that objects stored without a data version can still be retrieved and updated
+func TestLegacyObject(t *testing.T) {
+	plaintext := []byte("plaintext_bytes")
+	associatedData := []byte("associated_data_bytes")
+
+	objectID := uuid.Must(uuid.NewV4()).String()
+	woek, ciphertext, err := strg.DataCryptor.Encrypt(plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	objectStore[objectID+AssociatedDataStoreSuffix] = associatedData
+	objectStore[objectID+CiphertextStoreSuffix] = ciphertext
+
+	ctx := setCtxKeys()
+	ctx = context.WithValue(ctx, common.AccessObjectCtxKey, &common.AccessObject{Woek: woek})
+
+	retrieveResponse, err := strg.Retrieve(ctx, &RetrieveRequest{ObjectId: objectID})
+	if err != nil {
+		t.Fatalf("Retrieving legacy object failed: %v", err)
+	}
+	if !reflect.DeepEqual(plaintext, retrieveResponse.Plaintext) {
+		t.Fatalf("Retrieved plaintext not equal to stored plaintext: %v != %v", retrieveResponse.Plaintext, plaintext)
+	}
+
+	_, err = strg.Update(
+		ctx,
+		&UpdateRequest{
+			ObjectId:       objectID,
+			Plaintext:      plaintext,
+			AssociatedData: associatedData,
+		},
+	)
+	if err != nil {
+		t.Fatalf("Updating legacy object failed: %v", err)
+	}
+
+	accessObject, err := strg.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(objectID))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %s", err)
+	}
+	if accessObject.DataVersion != common.InitialDataVersion {
+		t.Fatalf("Legacy object not migrated on update: data version %d", accessObject.DataVersion)
+	}
+}
+
+// Test that a re-keyed object is encrypted under a new key and can still be retrieved
+func TestRekey(t *testing.T) {
+	plaintext := []byte("plaintext_bytes")
+	associatedData := []byte("associated_data_bytes")
+	ctx, objectID := storeObject(t, plaintext, associatedData)
+	oldAccessObject := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	oldCiphertext := objectStore[objectID+CiphertextStoreSuffix]
+
+	_, err := strg.Rekey(ctx, &RekeyRequest{ObjectId: objectID})
+	if err != nil {
+		t.Fatalf("Re-keying object failed: %v", err)
+	}
+
+	accessObject, err := strg.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(objectID))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %s", err)
+	}
+	if reflect.DeepEqual(accessObject.Woek, oldAccessObject.Woek) {
+		t.Fatal("Re-keying did not change the wrapped object key")
+	}
+	if accessObject.DataVersion != oldAccessObject.DataVersion+1 {
+		t.Fatalf("Data version not incremented: %v != %v", accessObject.DataVersion, oldAccessObject.DataVersion+1)
+	}
+	if _, exists := objectStore[objectID+RekeyStoreSuffix]; exists {
+		t.Fatal("Re-keyed ciphertext not moved into place")
+	}
+
+	// The old key must not decrypt the new ciphertext
+	ciphertext := objectStore[objectID+CiphertextStoreSuffix]
+	_, err = strg.DataCryptor.Decrypt(oldAccessObject.GetWOEK(), append([]byte(nil), ciphertext...), common.DataAAD(uuid.FromStringOrNil(objectID), accessObject.DataVersion, associatedData))
+	if err == nil {
+		t.Fatal("Decrypting re-keyed object with the old key did not fail as expected")
+	}
+
+	ctx = context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+	retrieveResponse, err := strg.Retrieve(ctx, &RetrieveRequest{ObjectId: objectID})
+	if err != nil {
+		t.Fatalf("Retrieving re-keyed object failed: %v", err)
+	}
+	if !reflect.DeepEqual(plaintext, retrieveResponse.Plaintext) {
+		t.Fatalf("Retrieved plaintext not equal to stored plaintext: %v != %v", retrieveResponse.Plaintext, plaintext)
+	}
+
+	// The old ciphertext can't be replayed
+	objectStore[objectID+CiphertextStoreSuffix] = oldCiphertext
+	_, err = strg.Retrieve(ctx, &RetrieveRequest{ObjectId: objectID})
+	if err == nil {
+		t.Fatal("Retrieving replayed ciphertext did not fail as expected")
+	}
+}
+
+// Test that a failed commit leaves the object untouched
+func TestRekeyFailCommit(t *testing.T) {
+	plaintext := []byte("plaintext_bytes")
+	ctx, objectID := storeObject(t, plaintext, []byte("associated_data_bytes"))
+
+	authStorageTx := &authstorage.AuthStoreTxMock{
+		UpdateAccessObjectFunc: func(ctx context.Context, protected *common.ProtectedAccessObject) error {
+			return nil
+		},
+		CommitFunc: func(ctx context.Context) error {
+			return fmt.Errorf("commit failed")
+		},
+	}
+	rekeyCtx := context.WithValue(ctx, common.AuthStorageTxCtxKey, authStorageTx)
+
+	_, err := strg.Rekey(rekeyCtx, &RekeyRequest{ObjectId: objectID})
+	if err == nil {
+		t.Fatal("Re-keying object did not fail as expected")
+	}
+
+	retrieveResponse, err := strg.Retrieve(ctx, &RetrieveRequest{ObjectId: objectID})
+	if err != nil {
+		t.Fatalf("Retrieving object failed: %v", err)
+	}
+	if !reflect.DeepEqual(plaintext, retrieveResponse.Plaintext) {
+		t.Fatalf("Retrieved plaintext not equal to stored plaintext: %v != %v", retrieveResponse.Plaintext, plaintext)
+	}
+
+	// A retry succeeds
+	_, err = strg.Rekey(ctx, &RekeyRequest{ObjectId: objectID})
+	if err != nil {
+		t.Fatalf("Re-keying object failed: %v", err)
+	}
+}
+
+// Test that a re-key interrupted after the commit is finished by the next re-key
+func TestRekeyResume(t *testing.T) {
+	plaintext := []byte("plaintext_bytes")
+	ctx, objectID := storeObject(t, plaintext, []byte("associated_data_bytes"))
+	oldCiphertext := objectStore[objectID+CiphertextStoreSuffix]
+
+	_, err := strg.Rekey(ctx, &RekeyRequest{ObjectId: objectID})
+	if err != nil {
+		t.Fatalf("Re-keying object failed: %v", err)
+	}
+
+	// Simulate that the new ciphertext was never moved into place
+	objectStore[objectID+RekeyStoreSuffix] = objectStore[objectID+CiphertextStoreSuffix]
+	objectStore[objectID+CiphertextStoreSuffix] = oldCiphertext
+
+	accessObject, err := strg.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(objectID))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %s", err)
+	}
+	ctx = context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+
+	_, err = strg.Rekey(ctx, &RekeyRequest{ObjectId: objectID})
+	if err != nil {
+		t.Fatalf("Resuming re-key failed: %v", err)
+	}
+
+	accessObject, err = strg.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(objectID))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %s", err)
+	}
+	ctx = context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+
+	retrieveResponse, err := strg.Retrieve(ctx, &RetrieveRequest{ObjectId: objectID})
+	if err != nil {
+		t.Fatalf("Retrieving re-keyed object failed: %v", err)
+	}
+	if !reflect.DeepEqual(plaintext, retrieveResponse.Plaintext) {
+		t.Fatalf("Retrieved plaintext not equal to stored plaintext: %v != %v", retrieveResponse.Plaintext, plaintext)
+	}
+	if _, exists := objectStore[objectID+RekeyStoreSuffix]; exists {
+		t.Fatal("Re-keyed ciphertext not moved into place")
+	}
+}
+
+// Test that a re-key whose staged object could not be moved into place is finished by retrieving
+// the object, and that an update does not overwrite the staged object
+func TestRekeyFailFinish(t *testing.T) {
+	plaintext := []byte("plaintext_bytes")
+	associatedData := []byte("associated_data_bytes")
+	ctx, objectID := storeObject(t, plaintext, associatedData)
+
+	// Fail to store the ciphertext after the commit
+	failingStrg := strg
+	failingStrg.ObjectStore = &objectstorage.ObjectStoreMock{
+		StoreFunc: func(ctx context.Context, key string, object []byte) error {
+			if key == objectID+CiphertextStoreSuffix {
+				return fmt.Errorf("store failed")
+			}
+			return objectStoreMock.Store(ctx, key, object)
+		},
+		RetrieveFunc: objectStoreMock.RetrieveFunc,
+		DeleteFunc:   objectStoreMock.DeleteFunc,
+	}
+
+	failRekey := func() context.Context {
+		_, err := failingStrg.Rekey(ctx, &RekeyRequest{ObjectId: objectID})
+		if err == nil {
+			t.Fatal("Re-keying object did not fail as expected")
+		}
+
+		accessObject, err := strg.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(objectID))
+		if err != nil {
+			t.Fatalf("Failed to fetch access object: %s", err)
+		}
+		return context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+	}
+
+	checkRetrieve := func() {
+		retrieveResponse, err := strg.Retrieve(ctx, &RetrieveRequest{ObjectId: objectID})
+		if err != nil {
+			t.Fatalf("Retrieving re-keyed object failed: %v", err)
+		}
+		if !reflect.DeepEqual(plaintext, retrieveResponse.Plaintext) || !reflect.DeepEqual(associatedData, retrieveResponse.AssociatedData) {
+			t.Fatalf("Retrieved object not equal to stored object: %v != %v", retrieveResponse.Plaintext, plaintext)
+		}
+	}
+
+	// Retrieving the object moves the staged object into place
+	ctx = failRekey()
+	checkRetrieve()
+	if _, exists := objectStore[objectID+RekeyStoreSuffix]; exists {
+		t.Fatal("Staged ciphertext not moved into place")
+	}
+
+	// An update whose commit fails must not overwrite the staged object
+	ctx = failRekey()
+	authStorageTx := &authstorage.AuthStoreTxMock{
+		UpdateAccessObjectFunc: func(ctx context.Context, protected *common.ProtectedAccessObject) error {
+			return nil
+		},
+		CommitFunc: func(ctx context.Context) error {
+			return fmt.Errorf("commit failed")
+		},
+	}
+	updateCtx := context.WithValue(ctx, common.AuthStorageTxCtxKey, authStorageTx)
+	_, err := strg.Update(updateCtx, &UpdateRequest{ObjectId: objectID, Plaintext: []byte("updated_plaintext_bytes")})
+	if err == nil {
+		t.Fatal("Updating object did not fail as expected")
+	}
+	checkRetrieve()
+}
+
+// Test that keys of other types are rejected
+func TestWrongKeyType(t *testing.T) {
+	ctx, objectID := storeObject(t, []byte("plaintext"), []byte("associated data"))
+	accessObject := *ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	accessObject.KeyType = common.KeyTypeImported
+	ctx = context.WithValue(ctx, common.AccessObjectCtxKey, &accessObject)
+
+	if _, err := strg.Retrieve(ctx, &RetrieveRequest{ObjectId: objectID}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Retrieve with wrong key type: expected InvalidArgument, got %v", err)
+	}
+	if _, err := strg.Update(ctx, &UpdateRequest{ObjectId: objectID, Plaintext: []byte("new")}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Update with wrong key type: expected InvalidArgument, got %v", err)
+	}
+	if _, err := strg.Rekey(ctx, &RekeyRequest{ObjectId: objectID}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Rekey with wrong key type: expected FailedPrecondition, got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package unseal
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sync"
+
+	"encryption-service/config"
+)
+
+// Encryptonize Unseal service. Collects unseal shares until the keys in the sealed key file can be
+// unsealed.
+type Unseal struct {
+	KeyFile *config.KeyFile
+
+	// Receives the keys once they are unsealed
+	Unsealed chan *config.Keys
+
+	mutex    sync.Mutex
+	shares   [][]byte
+	unsealed bool
+	UnimplementedEncryptonizeServer
+}
+
+func NewUnseal(keyFile *config.KeyFile) *Unseal {
+	return &Unseal{
+		KeyFile:  keyFile,
+		Unsealed: make(chan *config.Keys, 1),
+	}
+}
+
+// DecodeShare parses a hex encoded unseal share
+func DecodeShare(share string) ([]byte, error) {
+	return hex.DecodeString(share)
+}
+
+// EncodeShare hex encodes an unseal share
+func EncodeShare(share []byte) string {
+	return hex.EncodeToString(share)
+}
+
+// addShare adds a share and reports whether the share was already submitted
+func (u *Unseal) addShare(share []byte) bool {
+	for _, s := range u.shares {
+		if bytes.Equal(s, share) {
+			return false
+		}
+	}
+	u.shares = append(u.shares, share)
+	return true
+}
@@ -0,0 +1,62 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package unseal
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	log "encryption-service/logger"
+)
+
+func (u *Unseal) Unseal(ctx context.Context, request *UnsealRequest) (*UnsealResponse, error) {
+	share, err := DecodeShare(request.Share)
+	if err != nil {
+		log.Error(ctx, err, "Unseal: Couldn't parse share")
+		return nil, status.Errorf(codes.InvalidArgument, "invalid share")
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	threshold := uint32(u.KeyFile.Threshold)
+	if u.unsealed {
+		return &UnsealResponse{Sealed: false, Threshold: threshold}, nil
+	}
+
+	if !u.addShare(share) {
+		return nil, status.Errorf(codes.InvalidArgument, "share already submitted")
+	}
+	log.Infof(ctx, "Unseal: %d of %d shares submitted", len(u.shares), threshold)
+
+	if len(u.shares) < u.KeyFile.Threshold {
+		return &UnsealResponse{Sealed: true, Progress: uint32(len(u.shares)), Threshold: threshold}, nil
+	}
+
+	// Start over if the shares don't unseal the keys, as we can't tell which share is wrong
+	keys, err := u.KeyFile.Unseal(u.shares)
+	u.shares = nil
+	if err != nil {
+		log.Error(ctx, err, "Unseal: Couldn't unseal keys")
+		return nil, status.Errorf(codes.InvalidArgument, "error encountered while unsealing, all shares must be submitted again")
+	}
+
+	u.unsealed = true
+	u.Unsealed <- keys
+	log.Info(ctx, "Unseal: Keys unsealed")
+
+	return &UnsealResponse{Sealed: false, Threshold: threshold}, nil
+}
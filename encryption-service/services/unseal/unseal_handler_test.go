@@ -0,0 +1,108 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package unseal
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"encryption-service/config"
+)
+
+func newTestUnseal(t *testing.T) (*Unseal, []string) {
+	keys := config.Keys{
+		KEK:               []byte("0101010101010101010101010101010101010101010101010101010101010101"),
+		AEK:               []byte("0202020202020202020202020202020202020202020202020202020202020202"),
+		TEK:               []byte("0303030303030303030303030303030303030303030303030303030303030303"),
+		UEK:               []byte("0404040404040404040404040404040404040404040404040404040404040404"),
+		GEK:               []byte("0505050505050505050505050505050505050505050505050505050505050505"),
+		ReceiptSigningKey: []byte("0707070707070707070707070707070707070707070707070707070707070707"),
+	}
+	keyFile, shares, err := config.SealKeys(keys, 3, 2)
+	if err != nil {
+		t.Fatalf("SealKeys failed: %v", err)
+	}
+
+	encoded := make([]string, len(shares))
+	for i, share := range shares {
+		encoded[i] = EncodeShare(share)
+	}
+
+	return NewUnseal(keyFile), encoded
+}
+
+func TestUnseal(t *testing.T) {
+	unseal, shares := newTestUnseal(t)
+
+	response, err := unseal.Unseal(context.Background(), &UnsealRequest{Share: shares[2]})
+	if err != nil {
+		t.Fatalf("Unseal failed: %v", err)
+	}
+	if !response.Sealed || response.Progress != 1 || response.Threshold != 2 {
+		t.Fatalf("Wrong response: %v", response)
+	}
+
+	// Submitting the same share twice doesn't count
+	_, err = unseal.Unseal(context.Background(), &UnsealRequest{Share: shares[2]})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument, got: %v", err)
+	}
+
+	response, err = unseal.Unseal(context.Background(), &UnsealRequest{Share: shares[0]})
+	if err != nil {
+		t.Fatalf("Unseal failed: %v", err)
+	}
+	if response.Sealed {
+		t.Fatalf("Service still sealed: %v", response)
+	}
+
+	select {
+	case keys := <-unseal.Unsealed:
+		if len(keys.KEK) != 32 {
+			t.Fatalf("Wrong keys unsealed: %v", keys)
+		}
+	default:
+		t.Fatal("Keys not sent after unsealing")
+	}
+}
+
+func TestUnsealInvalidShare(t *testing.T) {
+	unseal, shares := newTestUnseal(t)
+
+	_, err := unseal.Unseal(context.Background(), &UnsealRequest{Share: "totally not hex"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument, got: %v", err)
+	}
+
+	// A wrong share resets the progress
+	_, err = unseal.Unseal(context.Background(), &UnsealRequest{Share: shares[0]})
+	if err != nil {
+		t.Fatalf("Unseal failed: %v", err)
+	}
+	_, err = unseal.Unseal(context.Background(), &UnsealRequest{Share: shares[1][:len(shares[1])-2] + "ff"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument, got: %v", err)
+	}
+
+	response, err := unseal.Unseal(context.Background(), &UnsealRequest{Share: shares[1]})
+	if err != nil {
+		t.Fatalf("Unseal failed: %v", err)
+	}
+	if !response.Sealed || response.Progress != 1 {
+		t.Fatalf("Wrong response: %v", response)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package vault
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+)
+
+const (
+	// DefaultAlphabet is used when a request does not specify an alphabet
+	DefaultAlphabet = "0123456789"
+
+	// DefaultTokenLength is used when a request does not specify a token length
+	DefaultTokenLength = 16
+
+	// MaxTokenLength is the maximum length of tokens in characters
+	MaxTokenLength = 256
+
+	// Minimum number of possible tokens of a format. It keeps random tokens from colliding often.
+	minTokenSpaceSize = 1 << 32
+)
+
+var errInvalidTokenFormat = errors.New("invalid token format")
+
+// tokenFormat describes the random tokens of a tokenization request
+type tokenFormat struct {
+	alphabet []rune
+	length   int
+}
+
+// newTokenFormat checks that the alphabet consists of at least two distinct characters and that the
+// format allows enough tokens. An empty alphabet or a zero length selects the default.
+func newTokenFormat(alphabet string, length uint32) (*tokenFormat, error) {
+	if alphabet == "" {
+		alphabet = DefaultAlphabet
+	}
+	if length == 0 {
+		length = DefaultTokenLength
+	}
+
+	f := &tokenFormat{
+		alphabet: []rune(alphabet),
+		length:   int(length),
+	}
+	if len(f.alphabet) < 2 || f.length > MaxTokenLength {
+		return nil, errInvalidTokenFormat
+	}
+
+	characters := make(map[rune]bool)
+	for _, c := range f.alphabet {
+		if characters[c] {
+			return nil, errInvalidTokenFormat
+		}
+		characters[c] = true
+	}
+
+	spaceSize := 1
+	for i := 0; i < f.length && spaceSize < minTokenSpaceSize; i++ {
+		spaceSize *= len(f.alphabet)
+	}
+	if spaceSize < minTokenSpaceSize {
+		return nil, errInvalidTokenFormat
+	}
+
+	return f, nil
+}
+
+// random returns a token with characters chosen uniformly at random from the alphabet
+func (f *tokenFormat) random() (string, error) {
+	radix := big.NewInt(int64(len(f.alphabet)))
+	token := make([]rune, f.length)
+	for i := range token {
+		n, err := rand.Int(rand.Reader, radix)
+		if err != nil {
+			return "", err
+		}
+		token[i] = f.alphabet[n.Int64()]
+	}
+	return string(token), nil
+}
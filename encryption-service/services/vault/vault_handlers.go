@@ -0,0 +1,339 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package vault
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"encryption-service/common"
+	"encryption-service/impl/crypt"
+	"encryption-service/interfaces"
+	log "encryption-service/logger"
+)
+
+// Size of generated namespace keys in bytes
+const namespaceKeySize = 32
+
+// Number of random tokens tried before giving up on finding an unused token
+const maxTokenAttempts = 10
+
+// HKDF info of the key used to compute value tags
+var valueTagInfo = []byte("token value tag")
+
+// HKDF info of the AES-SIV key used to encrypt values. AES-SIV does not use random nonces, so the
+// number of values encrypted under a namespace key needs no limit. Every value is encrypted with
+// its token as associated data, so equal values still give different ciphertexts.
+var valueKeyInfo = []byte("token value encryption")
+
+// API exposed function, creates a new tokenization namespace protected by a new access object
+// and returns the object ID in the response
+func (v *Vault) CreateNamespace(ctx context.Context, request *CreateNamespaceRequest) (*CreateNamespaceResponse, error) {
+	userID, ok := ctx.Value(common.UserIDCtxKey).(uuid.UUID)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while creating namespace")
+		log.Error(ctx, err, "CreateNamespace: Could not typecast userID to uuid.UUID")
+		return nil, err
+	}
+
+	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while creating namespace")
+		log.Error(ctx, err, "CreateNamespace: Could not typecast authstorage to AuthStoreTxInterface")
+		return nil, err
+	}
+
+	objectID, err := uuid.NewV4()
+	if err != nil {
+		log.Error(ctx, err, "CreateNamespace: Failed to generate new object ID")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating namespace")
+	}
+	objectIDString := objectID.String()
+
+	key, err := crypt.Random(namespaceKeySize)
+	if err != nil {
+		log.Error(ctx, err, "CreateNamespace: Failed to generate key")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating namespace")
+	}
+
+	wrappedKey, err := v.KeyWrapper.Wrap(key)
+	if err != nil {
+		log.Error(ctx, err, "CreateNamespace: Failed to wrap key")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating namespace")
+	}
+
+	err = v.Authorizer.CreateAccessObject(ctx, objectID, userID, common.KeyTypeTokenization, wrappedKey)
+	if err != nil {
+		log.Error(ctx, err, "CreateNamespace: Failed to create new access object")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating namespace")
+	}
+
+	if err := authStorageTx.Commit(ctx); err != nil {
+		log.Error(ctx, err, "CreateNamespace: Failed to commit auth storage transaction")
+		return nil, status.Errorf(codes.Internal, "error encountered while creating namespace")
+	}
+
+	ctx = context.WithValue(ctx, common.ObjectIDCtxKey, objectIDString)
+	log.Info(ctx, "CreateNamespace: Namespace created")
+
+	return &CreateNamespaceResponse{
+		ObjectId: objectIDString,
+	}, nil
+}
+
+// API exposed function, stores the encrypted plaintext in the requested namespace and returns a
+// random token for it. If the request asks for a unique token and the plaintext was tokenized
+// uniquely before, the existing token is returned.
+func (v *Vault) Tokenize(ctx context.Context, request *TokenizeRequest) (*TokenizeResponse, error) {
+	accessObject, ok := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while tokenizing")
+		log.Error(ctx, err, "Tokenize: Could not typecast access object to AccessObject")
+		return nil, err
+	}
+
+	if !accessObject.HasKeyType(common.KeyTypeTokenization) {
+		err := status.Errorf(codes.InvalidArgument, "object is not a tokenization namespace")
+		log.Error(ctx, err, "Tokenize: Object has the wrong key type")
+		return nil, err
+	}
+
+	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while tokenizing")
+		log.Error(ctx, err, "Tokenize: Could not typecast authstorage to AuthStoreTxInterface")
+		return nil, err
+	}
+
+	namespace, err := uuid.FromString(request.ObjectId)
+	if err != nil {
+		log.Errorf(ctx, err, "Tokenize: Failed to parse object ID %s as UUID", request.ObjectId)
+		return nil, status.Errorf(codes.InvalidArgument, "invalid object ID")
+	}
+
+	format, err := newTokenFormat(request.Alphabet, request.Length)
+	if err != nil {
+		log.Error(ctx, err, "Tokenize: Failed to parse token format")
+		return nil, status.Errorf(codes.InvalidArgument, "invalid token format")
+	}
+
+	key, err := v.KeyWrapper.Unwrap(accessObject.GetWOEK())
+	if err != nil {
+		log.Error(ctx, err, "Tokenize: Failed to unwrap namespace key")
+		return nil, status.Errorf(codes.Internal, "error encountered while tokenizing")
+	}
+
+	var valueTag []byte
+	if request.Unique {
+		valueTag, err = valueTagOf(key, request.Plaintext)
+		if err != nil {
+			log.Error(ctx, err, "Tokenize: Failed to compute value tag")
+			return nil, status.Errorf(codes.Internal, "error encountered while tokenizing")
+		}
+
+		existing, err := authStorageTx.GetTokenByValueTag(ctx, namespace, valueTag)
+		if err == nil {
+			log.Info(ctx, "Tokenize: Existing token returned")
+			return &TokenizeResponse{
+				Token: existing.Token,
+			}, nil
+		}
+		if !errors.Is(err, interfaces.ErrNotFound) {
+			log.Error(ctx, err, "Tokenize: Failed to look up existing token")
+			return nil, status.Errorf(codes.Internal, "error encountered while tokenizing")
+		}
+	}
+
+	token, err := newToken(ctx, authStorageTx, namespace, format)
+	if err != nil {
+		log.Error(ctx, err, "Tokenize: Failed to generate token")
+		return nil, status.Errorf(codes.Internal, "error encountered while tokenizing")
+	}
+
+	valueCipher, err := newValueCipher(key)
+	if err != nil {
+		log.Error(ctx, err, "Tokenize: Failed to create cipher")
+		return nil, status.Errorf(codes.Internal, "error encountered while tokenizing")
+	}
+
+	ciphertext, err := valueCipher.Seal(request.Plaintext, common.TokenAAD(namespace, token))
+	if err != nil {
+		log.Error(ctx, err, "Tokenize: Failed to encrypt plaintext")
+		return nil, status.Errorf(codes.Internal, "error encountered while tokenizing")
+	}
+
+	err = authStorageTx.InsertToken(ctx, &common.ProtectedToken{
+		Namespace: namespace,
+		Token:     token,
+		ValueTag:  valueTag,
+		Value:     ciphertext,
+	})
+	if err == nil {
+		err = authStorageTx.Commit(ctx)
+	}
+	if err != nil {
+		// A concurrent request may have tokenized the same value uniquely in the meantime. Depending
+		// on the auth storage this fails with ErrAlreadyExists or with a serialization failure, so
+		// look for its token in either case.
+		if request.Unique {
+			existing, lookupErr := v.lookupToken(ctx, authStorageTx, namespace, valueTag)
+			if lookupErr == nil {
+				log.Info(ctx, "Tokenize: Concurrently inserted token returned")
+				return &TokenizeResponse{
+					Token: existing.Token,
+				}, nil
+			}
+			log.Error(ctx, lookupErr, "Tokenize: Failed to look up concurrently inserted token")
+		}
+		log.Error(ctx, err, "Tokenize: Failed to insert token")
+		return nil, status.Errorf(codes.Internal, "error encountered while tokenizing")
+	}
+
+	log.Info(ctx, "Tokenize: Plaintext tokenized")
+
+	return &TokenizeResponse{
+		Token: token,
+	}, nil
+}
+
+// lookupToken retrieves the token with the given value tag in a new auth storage transaction. It is
+// used when the transaction of the request failed, which is rolled back first, as the auth storage
+// may not allow a second transaction while it is open.
+func (v *Vault) lookupToken(ctx context.Context, failedTx interfaces.AuthStoreTxInterface, namespace uuid.UUID, valueTag []byte) (*common.ProtectedToken, error) {
+	if err := failedTx.Rollback(ctx); err != nil {
+		return nil, err
+	}
+
+	authStoreTx, err := v.AuthStore.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := authStoreTx.Rollback(ctx)
+		if err != nil {
+			log.Error(ctx, err, "Performing rollback")
+		}
+	}()
+
+	return authStoreTx.GetTokenByValueTag(ctx, namespace, valueTag)
+}
+
+// API exposed function, resolves a token of the requested namespace into the original plaintext
+func (v *Vault) Detokenize(ctx context.Context, request *DetokenizeRequest) (*DetokenizeResponse, error) {
+	accessObject, ok := ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while detokenizing")
+		log.Error(ctx, err, "Detokenize: Could not typecast access object to AccessObject")
+		return nil, err
+	}
+
+	if !accessObject.HasKeyType(common.KeyTypeTokenization) {
+		err := status.Errorf(codes.InvalidArgument, "object is not a tokenization namespace")
+		log.Error(ctx, err, "Detokenize: Object has the wrong key type")
+		return nil, err
+	}
+
+	authStorageTx, ok := ctx.Value(common.AuthStorageTxCtxKey).(interfaces.AuthStoreTxInterface)
+	if !ok {
+		err := status.Errorf(codes.Internal, "error encountered while detokenizing")
+		log.Error(ctx, err, "Detokenize: Could not typecast authstorage to AuthStoreTxInterface")
+		return nil, err
+	}
+
+	namespace, err := uuid.FromString(request.ObjectId)
+	if err != nil {
+		log.Errorf(ctx, err, "Detokenize: Failed to parse object ID %s as UUID", request.ObjectId)
+		return nil, status.Errorf(codes.InvalidArgument, "invalid object ID")
+	}
+
+	protected, err := authStorageTx.GetToken(ctx, namespace, request.Token)
+	if errors.Is(err, interfaces.ErrNotFound) {
+		log.Error(ctx, err, "Detokenize: Token not found")
+		return nil, status.Errorf(codes.NotFound, "token not found")
+	}
+	if err != nil {
+		log.Error(ctx, err, "Detokenize: Failed to fetch token")
+		return nil, status.Errorf(codes.Internal, "error encountered while detokenizing")
+	}
+
+	key, err := v.KeyWrapper.Unwrap(accessObject.GetWOEK())
+	if err != nil {
+		log.Error(ctx, err, "Detokenize: Failed to unwrap namespace key")
+		return nil, status.Errorf(codes.Internal, "error encountered while detokenizing")
+	}
+
+	valueCipher, err := newValueCipher(key)
+	if err != nil {
+		log.Error(ctx, err, "Detokenize: Failed to create cipher")
+		return nil, status.Errorf(codes.Internal, "error encountered while detokenizing")
+	}
+
+	plaintext, err := valueCipher.Open(protected.Value, common.TokenAAD(namespace, request.Token))
+	if err != nil {
+		log.Error(ctx, err, "Detokenize: Failed to decrypt value")
+		return nil, status.Errorf(codes.Internal, "error encountered while detokenizing")
+	}
+
+	log.Info(ctx, "Detokenize: Token detokenized")
+
+	return &DetokenizeResponse{
+		Plaintext: plaintext,
+	}, nil
+}
+
+// valueTagOf computes the MAC of a value under a key derived from the namespace key
+func valueTagOf(namespaceKey, value []byte) ([]byte, error) {
+	tagKey, err := crypt.DeriveKey(namespaceKey, valueTagInfo, crypt.MinMACKeyLength)
+	if err != nil {
+		return nil, err
+	}
+
+	authenticator, err := crypt.NewHMACAuthenticator(tagKey)
+	if err != nil {
+		return nil, err
+	}
+	return authenticator.Tag(value)
+}
+
+// newValueCipher returns the AES-SIV instance encrypting the values of a namespace
+func newValueCipher(namespaceKey []byte) (*crypt.AESSIV, error) {
+	valueKey, err := crypt.DeriveKey(namespaceKey, valueKeyInfo, 64)
+	if err != nil {
+		return nil, err
+	}
+	return crypt.NewAESSIV(valueKey)
+}
+
+// newToken generates random tokens until it finds one that is not used in the namespace
+func newToken(ctx context.Context, authStorageTx interfaces.AuthStoreTxInterface, namespace uuid.UUID, format *tokenFormat) (string, error) {
+	for i := 0; i < maxTokenAttempts; i++ {
+		token, err := format.random()
+		if err != nil {
+			return "", err
+		}
+
+		_, err = authStorageTx.GetToken(ctx, namespace, token)
+		if errors.Is(err, interfaces.ErrNotFound) {
+			return token, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", errors.New("no unused token found")
+}
@@ -0,0 +1,435 @@
+// Copyright 2021 CYBERCRYPT
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"encryption-service/common"
+	"encryption-service/impl/authstorage"
+	authzimpl "encryption-service/impl/authz"
+	"encryption-service/impl/crypt"
+	"encryption-service/interfaces"
+)
+
+var cryptor, _ = crypt.NewAESCryptor([]byte("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"))
+var keyWrapper, _ = crypt.NewKWP([]byte("BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB"))
+var authorizer = &authzimpl.Authorizer{
+	AccessObjectCryptor: cryptor,
+}
+
+var vault = Vault{
+	Authorizer: authorizer,
+	AuthStore: &authstorage.AuthStoreMock{
+		NewTransactionFunc: func(ctx context.Context) (interfaces.AuthStoreTxInterface, error) {
+			return authStorageTxMock, nil
+		},
+	},
+	KeyWrapper: keyWrapper,
+}
+
+var userID = uuid.Must(uuid.NewV4())
+
+var accessObjectStore = make(map[uuid.UUID]common.ProtectedAccessObject)
+var tokenStore = make(map[string]common.ProtectedToken)
+
+func tokenKey(namespace uuid.UUID, token string) string {
+	return namespace.String() + token
+}
+
+var authStorageTxMock = &authstorage.AuthStoreTxMock{
+	InsertAcccessObjectFunc: func(ctx context.Context, protected *common.ProtectedAccessObject) error {
+		accessObjectStore[protected.ObjectID] = *protected
+		return nil
+	},
+	UpdateAccessObjectFunc: func(ctx context.Context, protected *common.ProtectedAccessObject) error {
+		accessObjectStore[protected.ObjectID] = *protected
+		return nil
+	},
+	GetAccessObjectFunc: func(ctx context.Context, objectID uuid.UUID) (*common.ProtectedAccessObject, error) {
+		protected, exists := accessObjectStore[objectID]
+		if !exists {
+			return nil, interfaces.ErrNotFound
+		}
+		return &protected, nil
+	},
+	GetTokenFunc: func(ctx context.Context, namespace uuid.UUID, token string) (*common.ProtectedToken, error) {
+		protected, exists := tokenStore[tokenKey(namespace, token)]
+		if !exists {
+			return nil, interfaces.ErrNotFound
+		}
+		return &protected, nil
+	},
+	GetTokenByValueTagFunc: func(ctx context.Context, namespace uuid.UUID, valueTag []byte) (*common.ProtectedToken, error) {
+		for _, protected := range tokenStore {
+			if protected.Namespace == namespace && protected.ValueTag != nil && bytes.Equal(protected.ValueTag, valueTag) {
+				return &protected, nil
+			}
+		}
+		return nil, interfaces.ErrNotFound
+	},
+	InsertTokenFunc: func(ctx context.Context, protected *common.ProtectedToken) error {
+		tokenStore[tokenKey(protected.Namespace, protected.Token)] = *protected
+		return nil
+	},
+	CommitFunc: func(ctx context.Context) error {
+		return nil
+	},
+	RollbackFunc: func(ctx context.Context) error {
+		return nil
+	},
+}
+
+func setCtxKeys() context.Context {
+	ctx := context.WithValue(context.Background(), common.UserIDCtxKey, userID)
+	ctx = context.WithValue(ctx, common.AuthStorageTxCtxKey, authStorageTxMock)
+	return ctx
+}
+
+// createNamespace creates a namespace and returns its object ID and a context authorized for it
+func createNamespace(t *testing.T) (string, context.Context) {
+	ctx := setCtxKeys()
+
+	createNamespaceResponse, err := vault.CreateNamespace(ctx, &CreateNamespaceRequest{})
+	if err != nil {
+		t.Fatalf("Creating namespace failed: %v", err)
+	}
+
+	accessObject, err := vault.Authorizer.FetchAccessObject(ctx, uuid.FromStringOrNil(createNamespaceResponse.ObjectId))
+	if err != nil {
+		t.Fatalf("Failed to fetch access object: %v", err)
+	}
+
+	return createNamespaceResponse.ObjectId, context.WithValue(ctx, common.AccessObjectCtxKey, accessObject)
+}
+
+func TestTokenize(t *testing.T) {
+	objectID, ctx := createNamespace(t)
+
+	tests := []struct {
+		alphabet string
+		length   uint32
+	}{
+		{alphabet: "", length: 0},
+		{alphabet: "0123456789ABCDEF", length: 12},
+		{alphabet: "ÆØÅæøå", length: 20},
+	}
+
+	plaintext := []byte("010190-1234")
+	for _, test := range tests {
+		tokenizeResponse, err := vault.Tokenize(ctx, &TokenizeRequest{
+			ObjectId:  objectID,
+			Plaintext: plaintext,
+			Alphabet:  test.alphabet,
+			Length:    test.length,
+		})
+		if err != nil {
+			t.Fatalf("Tokenizing failed: %v", err)
+		}
+
+		format, _ := newTokenFormat(test.alphabet, test.length)
+		token := []rune(tokenizeResponse.Token)
+		if len(token) != format.length {
+			t.Fatalf("Token %q has length %d, expected %d", tokenizeResponse.Token, len(token), format.length)
+		}
+		for _, c := range token {
+			if !strings.ContainsRune(string(format.alphabet), c) {
+				t.Fatalf("Token %q is not over the alphabet", tokenizeResponse.Token)
+			}
+		}
+
+		detokenizeResponse, err := vault.Detokenize(ctx, &DetokenizeRequest{
+			ObjectId: objectID,
+			Token:    tokenizeResponse.Token,
+		})
+		if err != nil {
+			t.Fatalf("Detokenizing %q failed: %v", tokenizeResponse.Token, err)
+		}
+		if !bytes.Equal(detokenizeResponse.Plaintext, plaintext) {
+			t.Fatalf("Detokenized plaintext %q does not equal original plaintext %q", detokenizeResponse.Plaintext, plaintext)
+		}
+	}
+}
+
+func TestTokenizeUnique(t *testing.T) {
+	objectID, ctx := createNamespace(t)
+	otherObjectID, otherCtx := createNamespace(t)
+
+	plaintext := []byte("4111111111111111")
+	tokenize := func(ctx context.Context, objectID string, unique bool) string {
+		tokenizeResponse, err := vault.Tokenize(ctx, &TokenizeRequest{ObjectId: objectID, Plaintext: plaintext, Unique: unique})
+		if err != nil {
+			t.Fatalf("Tokenizing failed: %v", err)
+		}
+		return tokenizeResponse.Token
+	}
+
+	token := tokenize(ctx, objectID, true)
+	if tokenize(ctx, objectID, true) != token {
+		t.Fatal("Unique tokenization returned different tokens for the same value")
+	}
+	if tokenize(ctx, objectID, false) == token {
+		t.Fatal("Non-unique tokenization returned the unique token")
+	}
+	if tokenize(otherCtx, otherObjectID, true) == token {
+		t.Fatal("Unique tokenization returned the same token in different namespaces")
+	}
+}
+
+func TestTokenizeInvalidFormat(t *testing.T) {
+	objectID, ctx := createNamespace(t)
+
+	tests := []struct {
+		description string
+		request     *TokenizeRequest
+	}{
+		{"duplicate character in alphabet", &TokenizeRequest{ObjectId: objectID, Alphabet: "010"}},
+		{"single character alphabet", &TokenizeRequest{ObjectId: objectID, Alphabet: "0"}},
+		{"too few possible tokens", &TokenizeRequest{ObjectId: objectID, Length: 6}},
+		{"token too long", &TokenizeRequest{ObjectId: objectID, Length: MaxTokenLength + 1}},
+	}
+
+	for _, test := range tests {
+		_, err := vault.Tokenize(ctx, test.request)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("Tokenize should have failed with InvalidArgument: %s: %v", test.description, err)
+		}
+	}
+}
+
+func TestDetokenizeOtherNamespace(t *testing.T) {
+	objectID, ctx := createNamespace(t)
+	otherObjectID, otherCtx := createNamespace(t)
+
+	tokenizeResponse, err := vault.Tokenize(ctx, &TokenizeRequest{ObjectId: objectID, Plaintext: []byte("secret")})
+	if err != nil {
+		t.Fatalf("Tokenizing failed: %v", err)
+	}
+
+	_, err = vault.Detokenize(otherCtx, &DetokenizeRequest{ObjectId: otherObjectID, Token: tokenizeResponse.Token})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Detokenizing in another namespace should have failed with NotFound: %v", err)
+	}
+
+	// A token moved to another namespace can't be decrypted
+	protected := tokenStore[tokenKey(uuid.FromStringOrNil(objectID), tokenizeResponse.Token)]
+	protected.Namespace = uuid.FromStringOrNil(otherObjectID)
+	tokenStore[tokenKey(protected.Namespace, protected.Token)] = protected
+	_, err = vault.Detokenize(otherCtx, &DetokenizeRequest{ObjectId: otherObjectID, Token: tokenizeResponse.Token})
+	if err == nil {
+		t.Fatal("Detokenizing a moved token should have failed")
+	}
+}
+
+func TestTokenizeCollisions(t *testing.T) {
+	objectID, ctx := createNamespace(t)
+
+	// Every token is taken
+	occupied := *authStorageTxMock
+	occupied.GetTokenFunc = func(ctx context.Context, namespace uuid.UUID, token string) (*common.ProtectedToken, error) {
+		return &common.ProtectedToken{}, nil
+	}
+	ctx = context.WithValue(ctx, common.AuthStorageTxCtxKey, &occupied)
+
+	_, err := vault.Tokenize(ctx, &TokenizeRequest{ObjectId: objectID, Plaintext: []byte("secret")})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("Tokenize should have failed when all tokens are taken: %v", err)
+	}
+}
+
+// Test that a unique tokenization losing the race against a concurrent one returns the token of the
+// concurrent request
+func TestTokenizeUniqueConcurrent(t *testing.T) {
+	objectID, ctx := createNamespace(t)
+	plaintext := []byte("5500000000000004")
+
+	// The concurrent request inserts its token between the lookup and the insert of this request
+	var concurrentToken string
+	racing := *authStorageTxMock
+	racing.InsertTokenFunc = func(ctx context.Context, protected *common.ProtectedToken) error {
+		concurrentToken = protected.Token + "-concurrent"
+		concurrent := *protected
+		concurrent.Token = concurrentToken
+		tokenStore[tokenKey(concurrent.Namespace, concurrent.Token)] = concurrent
+		return fmt.Errorf("value tag %w", interfaces.ErrAlreadyExists)
+	}
+	racingCtx := context.WithValue(ctx, common.AuthStorageTxCtxKey, &racing)
+
+	tokenizeResponse, err := vault.Tokenize(racingCtx, &TokenizeRequest{ObjectId: objectID, Plaintext: plaintext, Unique: true})
+	if err != nil {
+		t.Fatalf("Tokenizing failed: %v", err)
+	}
+	if tokenizeResponse.Token != concurrentToken {
+		t.Fatalf("Expected concurrent token %q, got %q", concurrentToken, tokenizeResponse.Token)
+	}
+
+	// Without a unique value the conflict is an error
+	_, err = vault.Tokenize(racingCtx, &TokenizeRequest{ObjectId: objectID, Plaintext: plaintext})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("Non-unique tokenization with conflicting token should have failed with Internal: %v", err)
+	}
+}
+
+// hiddenValueTagTx hides the tokens of unique values from the first lookup, as if they were inserted
+// by a concurrent request after it
+type hiddenValueTagTx struct {
+	interfaces.AuthStoreTxInterface
+	looked bool
+}
+
+func (tx *hiddenValueTagTx) GetTokenByValueTag(ctx context.Context, namespace uuid.UUID, valueTag []byte) (*common.ProtectedToken, error) {
+	if !tx.looked {
+		tx.looked = true
+		return nil, interfaces.ErrNotFound
+	}
+	return tx.AuthStoreTxInterface.GetTokenByValueTag(ctx, namespace, valueTag)
+}
+
+// Test unique tokenization against the memory auth storage, where every request runs in its own
+// transaction and tokenizing leaves the namespace access object unchanged
+func TestTokenizeUniqueMemoryAuthStore(t *testing.T) {
+	authStore, err := authstorage.NewMemoryAuthStore(filepath.Join(t.TempDir(), "auth.db"))
+	if err != nil {
+		t.Fatalf("NewMemoryAuthStore failed: %v", err)
+	}
+	defer authStore.Close()
+
+	memoryVault := vault
+	memoryVault.AuthStore = authStore
+
+	// withTx returns a context with a new transaction and, if the namespace is set, its access object
+	ctx := context.WithValue(context.Background(), common.UserIDCtxKey, userID)
+	withTx := func(namespace uuid.UUID) (context.Context, interfaces.AuthStoreTxInterface) {
+		tx, err := authStore.NewTransaction(ctx)
+		if err != nil {
+			t.Errorf("NewTransaction failed: %v", err)
+			return nil, nil
+		}
+		txCtx := context.WithValue(ctx, common.AuthStorageTxCtxKey, tx)
+		if namespace == uuid.Nil {
+			return txCtx, tx
+		}
+		accessObject, err := authorizer.FetchAccessObject(txCtx, namespace)
+		if err != nil {
+			t.Errorf("Failed to fetch access object: %v", err)
+			return nil, nil
+		}
+		return context.WithValue(txCtx, common.AccessObjectCtxKey, accessObject), tx
+	}
+
+	txCtx, _ := withTx(uuid.Nil)
+	createNamespaceResponse, err := memoryVault.CreateNamespace(txCtx, &CreateNamespaceRequest{})
+	if err != nil {
+		t.Fatalf("Creating namespace failed: %v", err)
+	}
+	objectID := createNamespaceResponse.ObjectId
+	namespace := uuid.FromStringOrNil(objectID)
+	_, tx := withTx(uuid.Nil)
+	before, err := tx.GetAccessObject(ctx, namespace)
+	if err != nil {
+		t.Fatalf("GetAccessObject failed: %v", err)
+	}
+	tx.Rollback(ctx)
+
+	plaintext := []byte("4571000000000001")
+	tokenize := func() string {
+		txCtx, tx := withTx(namespace)
+		if tx == nil {
+			return ""
+		}
+		defer tx.Rollback(ctx)
+		tokenizeResponse, err := memoryVault.Tokenize(txCtx, &TokenizeRequest{ObjectId: objectID, Plaintext: plaintext, Unique: true})
+		if err != nil {
+			t.Errorf("Tokenizing failed: %v", err)
+			return ""
+		}
+		return tokenizeResponse.Token
+	}
+
+	const requests = 10
+	tokens := make(chan string, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tokens <- tokenize()
+		}()
+	}
+	wg.Wait()
+	close(tokens)
+
+	token := tokenize()
+	for other := range tokens {
+		if other != token {
+			t.Fatalf("Concurrent unique tokenizations returned tokens %q and %q", other, token)
+		}
+	}
+
+	// A request that misses the token of a concurrent request fails to insert its own, and returns
+	// the existing token instead
+	txCtx, tx = withTx(namespace)
+	hidden := &hiddenValueTagTx{AuthStoreTxInterface: tx}
+	txCtx = context.WithValue(txCtx, common.AuthStorageTxCtxKey, hidden)
+	tokenizeResponse, err := memoryVault.Tokenize(txCtx, &TokenizeRequest{ObjectId: objectID, Plaintext: plaintext, Unique: true})
+	if err != nil {
+		t.Fatalf("Tokenizing with hidden token failed: %v", err)
+	}
+	if tokenizeResponse.Token != token {
+		t.Fatalf("Expected existing token %q, got %q", token, tokenizeResponse.Token)
+	}
+
+	// The namespace access object is not rewritten by tokenizing, and the token can be detokenized
+	txCtx, tx = withTx(namespace)
+	defer tx.Rollback(ctx)
+	after, err := tx.GetAccessObject(ctx, namespace)
+	if err != nil {
+		t.Fatalf("GetAccessObject failed: %v", err)
+	}
+	if !bytes.Equal(after.AccessObject, before.AccessObject) {
+		t.Fatal("Tokenizing changed the namespace access object")
+	}
+	detokenizeResponse, err := memoryVault.Detokenize(txCtx, &DetokenizeRequest{ObjectId: objectID, Token: token})
+	if err != nil {
+		t.Fatalf("Detokenizing failed: %v", err)
+	}
+	if !bytes.Equal(detokenizeResponse.Plaintext, plaintext) {
+		t.Fatalf("Detokenized plaintext %q does not equal original plaintext %q", detokenizeResponse.Plaintext, plaintext)
+	}
+}
+
+// Test that keys of other types are rejected
+func TestWrongKeyType(t *testing.T) {
+	objectID, ctx := createNamespace(t)
+	accessObject := *ctx.Value(common.AccessObjectCtxKey).(*common.AccessObject)
+	accessObject.KeyType = common.KeyTypeFPE
+	ctx = context.WithValue(ctx, common.AccessObjectCtxKey, &accessObject)
+
+	if _, err := vault.Tokenize(ctx, &TokenizeRequest{ObjectId: objectID}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Tokenize with wrong key type: expected InvalidArgument, got %v", err)
+	}
+	if _, err := vault.Detokenize(ctx, &DetokenizeRequest{ObjectId: objectID}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Detokenize with wrong key type: expected InvalidArgument, got %v", err)
+	}
+}
@@ -88,6 +88,7 @@ else
   ECTNZ_KEYS_TEK=$(hexdump -n 32 -e '1/4 "%08x"' /dev/urandom)
   ECTNZ_KEYS_UEK=$(hexdump -n 32 -e '1/4 "%08x"' /dev/urandom)
   ECTNZ_KEYS_GEK=$(hexdump -n 32 -e '1/4 "%08x"' /dev/urandom)
+  ECTNZ_KEYS_RECEIPTSIGNINGKEY=$(hexdump -n 32 -e '1/4 "%08x"' /dev/urandom)
 
   {
     echo "ECTNZ_KEYS_KEK=${ECTNZ_KEYS_KEK}"
@@ -95,6 +96,7 @@ else
     echo "ECTNZ_KEYS_TEK=${ECTNZ_KEYS_TEK}"
     echo "ECTNZ_KEYS_UEK=${ECTNZ_KEYS_UEK}"
     echo "ECTNZ_KEYS_GEK=${ECTNZ_KEYS_GEK}"
+    echo "ECTNZ_KEYS_RECEIPTSIGNINGKEY=${ECTNZ_KEYS_RECEIPTSIGNINGKEY}"
   } > "${ENC_OUT_FOLDER}/keys.env"
 
   echo -e "${BLUE_ON}[+] Moving over CockroachDB client certificates${COLOR_OFF}"